@@ -147,13 +147,35 @@ func NewLexer(r *parse.Input) *Lexer {
 	}
 }
 
+// NewStreamLexer returns a new Lexer for streaming input, which is read in chunks with bounded memory. The returned byte slices are only valid until the next call to Next.
+func NewStreamLexer(r *parse.StreamInput) *Lexer {
+	return NewLexer(&r.Input)
+}
+
 // Err returns the error encountered during lexing, this is often io.EOF but also other errors can be returned.
 func (l *Lexer) Err() error {
 	return l.r.Err()
 }
 
+// stream calls next, and calls it again after restoring the state of the lexer when its token ends close to the end of the buffered data of streaming input.
+func (l *Lexer) stream(next func() (TokenType, []byte)) (tt TokenType, data []byte) {
+	state := *l
+	l.r.Retry(func() {
+		*l = state
+		tt, data = next()
+	})
+	return
+}
+
 // Next returns the next Token. It returns ErrorToken when an error was encountered. Using Err() one can retrieve the error message.
 func (l *Lexer) Next() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.next)
+	}
+	return l.next()
+}
+
+func (l *Lexer) next() (TokenType, []byte) {
 	switch l.r.Peek(0) {
 	case ' ', '\t', '\n', '\r', '\f':
 		l.r.Move(1)
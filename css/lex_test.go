@@ -5,6 +5,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/test"
@@ -155,6 +156,45 @@ func TestOffset(t *testing.T) {
 	test.T(t, z.Offset(), 26) // }
 }
 
+func TestStreamLexer(t *testing.T) {
+	css := "@import url(\"a.css\");\n/* comment */ div#id.class > a:hover::before { content: '\\201C'; width: calc(100% - 2.5em); color: #fff !important }\n@media (min-width: 10px) { b { x: U+0025-00FF; y: 1e3px } } <!-- -->"
+	lex := func(l *Lexer, z *parse.Input) []string {
+		tokens := []string{}
+		for {
+			tt, data := l.Next()
+			if tt == ErrorToken {
+				test.T(t, l.Err(), io.EOF)
+				return tokens
+			}
+			tokens = append(tokens, fmt.Sprintf("%v %q %d", tt, data, z.Offset()))
+		}
+	}
+
+	z := parse.NewInputString(css)
+	expected := lex(NewLexer(z), z)
+	for _, size := range []int{1, 2, 3, 16} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			z := parse.NewStreamInputSize(iotest.OneByteReader(strings.NewReader(css)), size)
+			test.T(t, lex(NewStreamLexer(z), &z.Input), expected)
+		})
+	}
+}
+
+var lexerCSS = []byte(strings.Repeat("@media (min-width: 10px) {\n\tdiv#id.class > a:hover::before { content: '\\201C'; width: calc(100% - 2.5em); color: #fff !important }\n\t/* comment */\n\tb { background: url(a.png) no-repeat; x: U+0025-00FF; y: 1e3px }\n}\n", 500))
+
+// BenchmarkLexer lexes in-memory input, which must not be slowed down by streaming input.
+func BenchmarkLexer(b *testing.B) {
+	b.SetBytes(int64(len(lexerCSS)))
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(parse.NewInputBytes(lexerCSS))
+		for {
+			if tt, _ := l.Next(); tt == ErrorToken {
+				break
+			}
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 func ExampleNewLexer() {
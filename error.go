@@ -1,7 +1,6 @@
 package parse
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 )
@@ -30,9 +29,17 @@ func NewError(r io.Reader, offset int, message string, a ...interface{}) *Error
 
 // NewErrorLexer creates a new error from an active Lexer.
 func NewErrorLexer(l *Input, message string, a ...interface{}) *Error {
-	r := bytes.NewBuffer(l.Bytes())
-	offset := l.Offset()
-	return NewError(r, offset, message, a...)
+	// streaming input may have discarded the data before the buffer
+	line, column, context := position(NewInputBytes(l.Bytes()), l.pos, l.line, l.col)
+	if 0 < len(a) {
+		message = fmt.Sprintf(message, a...)
+	}
+	return &Error{
+		Message: message,
+		Line:    line,
+		Column:  column,
+		Context: context,
+	}
 }
 
 // Position returns the line, column, and context of the error.
@@ -2,7 +2,9 @@ package parse
 
 import (
 	"bytes"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/test"
 )
@@ -31,6 +33,30 @@ func TestErrorLexer(t *testing.T) {
 	test.T(t, err.Error(), "message on line 1 and column 4\n    1: buffer\n          ^", "error")
 }
 
+func TestErrorStreamLexer(t *testing.T) {
+	l := NewStreamInputSize(iotest.OneByteReader(bytes.NewBufferString(strings.Repeat("line\r\n", 40)+"next line\nbuffer")), 2)
+	for {
+		var c byte
+		l.Retry(func() {
+			c = l.Peek(0)
+		})
+		if c == 'f' {
+			break
+		}
+		l.Move(1)
+		l.Skip()
+	}
+	var err *Error
+	l.Retry(func() {
+		err = NewErrorLexer(&l.Input, "message")
+	})
+
+	line, column, context := err.Position()
+	test.T(t, line, 42, "line")
+	test.T(t, column, 3, "column")
+	test.T(t, "\n"+context, "\n   42: buffer\n         ^", "context")
+}
+
 func TestErrorMessages(t *testing.T) {
 	err := NewError(bytes.NewBufferString("buffer"), 3, "message %d", 5)
 	test.T(t, err.Error(), "message 5 on line 1 and column 4\n    1: buffer\n          ^", "error")
@@ -91,6 +91,11 @@ func NewLexer(r *parse.Input) *Lexer {
 	}
 }
 
+// NewStreamLexer returns a new Lexer for streaming input, which is read in chunks with bounded memory. The returned byte slices are only valid until the next call to Next.
+func NewStreamLexer(r *parse.StreamInput) *Lexer {
+	return NewLexer(&r.Input)
+}
+
 func NewTemplateLexer(r *parse.Input, tmpl [2]string) *Lexer {
 	return &Lexer{
 		r:         r,
@@ -127,8 +132,25 @@ func (l *Lexer) HasTemplate() bool {
 	return l.hasTmpl
 }
 
+// stream calls next, and calls it again after restoring the state of the lexer when its token ends close to the end of the buffered data of streaming input.
+func (l *Lexer) stream(next func() (TokenType, []byte)) (tt TokenType, data []byte) {
+	state := *l
+	l.r.Retry(func() {
+		*l = state
+		tt, data = next()
+	})
+	return
+}
+
 // Next returns the next Token. It returns ErrorToken when an error was encountered. Using Err() one can retrieve the error message.
 func (l *Lexer) Next() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.next)
+	}
+	return l.next()
+}
+
+func (l *Lexer) next() (TokenType, []byte) {
 	l.text = nil
 	l.hasTmpl = false
 	var c byte
@@ -1,10 +1,12 @@
 package html
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/test"
@@ -325,6 +327,30 @@ func TestOffset(t *testing.T) {
 	test.T(t, z.Offset(), 26) // </div>
 }
 
+func TestStreamLexer(t *testing.T) {
+	html := "<!DOCTYPE html><div id=\"main\" class='a b' hidden>text &amp; more<!-- comment --><script>if (a</b) {}</script><SVG><path d=\"M0 0\"/></SVG><textarea><b></textarea></div>"
+	lex := func(l *Lexer, z *parse.Input) []string {
+		tokens := []string{}
+		for {
+			tt, data := l.Next()
+			if tt == ErrorToken {
+				test.T(t, l.Err(), io.EOF)
+				return tokens
+			}
+			tokens = append(tokens, fmt.Sprintf("%v %q %q %q %d", tt, data, l.Text(), l.AttrVal(), z.Offset()))
+		}
+	}
+
+	z := parse.NewInputString(html)
+	expected := lex(NewLexer(z), z)
+	for _, size := range []int{1, 2, 3, 16} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			z := parse.NewStreamInputSize(iotest.OneByteReader(strings.NewReader(html)), size)
+			test.T(t, lex(NewStreamLexer(z), &z.Input), expected)
+		})
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 var J int
@@ -384,6 +410,33 @@ func BenchmarkWhitespace3(b *testing.B) {
 	}
 }
 
+var lexerHTML = []byte(strings.Repeat("<!DOCTYPE html>\n<div class=\"main\" id=main>\n\t<p>Lorem ipsum <b>dolor</b> sit amet &amp; consectetur.</p>\n\t<!-- comment -->\n\t<script>var a = 1 < 2;</script>\n\t<input type='text' value=\"x\" disabled>\n</div>\n", 500))
+
+// BenchmarkLexer lexes in-memory input, which must not be slowed down by streaming input.
+func BenchmarkLexer(b *testing.B) {
+	b.SetBytes(int64(len(lexerHTML)))
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(parse.NewInputBytes(lexerHTML))
+		for {
+			if tt, _ := l.Next(); tt == ErrorToken {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkStreamLexer(b *testing.B) {
+	b.SetBytes(int64(len(lexerHTML)))
+	for i := 0; i < b.N; i++ {
+		l := NewStreamLexer(parse.NewStreamInput(iotest.HalfReader(bytes.NewReader(lexerHTML))))
+		for {
+			if tt, _ := l.Next(); tt == ErrorToken {
+				break
+			}
+		}
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 func ExampleNewLexer() {
@@ -6,15 +6,28 @@ import (
 
 var nullBuffer = []byte{0}
 
+// lookahead is the number of bytes past the end of a token that lexers may read for streaming input, see Retry.
+const lookahead = 64
+
 // Input is a buffered reader that allows peeking forward and shifting, taking an io.Input.
 // It keeps data in-memory until Free, taking a byte length, is called to move beyond the data.
 type Input struct {
 	buf   []byte
 	pos   int // index in buf
 	start int // index in buf
+	prev  int // index in buf of the previous selection
 	err   error
 
 	restore func()
+
+	// streaming
+	r         io.Reader // nil when all data has been read
+	end       int       // index in buf of the end of the read data, which is past the NULL at the end of buf when data is held back
+	offset    int       // offset of buf in the stream
+	held      byte      // byte in buf that is replaced by the NULL at the end of the buffered data
+	spare     []byte
+	spareEnd  int // offset of the end of the data in spare
+	line, col int // line and column at the start of buf, where the line is zero-based
 }
 
 // NewInput returns a new Input for a given io.Input and uses io.ReadAll to read it into a byte slice.
@@ -40,6 +53,37 @@ func NewInput(r io.Reader) *Input {
 	return NewInputBytes(b)
 }
 
+// StreamInput is an Input that is read from an io.Reader in chunks as needed. It is a separate type so that it can only be passed to the lexers that support it, using their NewStreamLexer, and not to parsers that keep references to earlier tokens.
+type StreamInput struct {
+	Input
+}
+
+// NewStreamInput returns a new StreamInput for a given io.Reader with a 4kB estimated buffer size, reading in chunks as needed.
+// If the io.Reader implements Bytes, that is used instead. See NewStreamInputSize.
+func NewStreamInput(r io.Reader) *StreamInput {
+	return NewStreamInputSize(r, 4096)
+}
+
+// NewStreamInputSize returns a new StreamInput for a given io.Reader and estimated buffer size, reading in chunks as needed.
+// Memory use is bounded by the size of the largest token instead of the size of the input. The css, html, js, and xml lexers support streaming input, other users must read tokens using Retry. Data before the previous selection is discarded when more data is read, so that byte slices returned by Lexeme and Shift are only valid until the next token is read, they must be copied to retain them. Bytes and Len only return the buffered data, while Offset returns the offset in the stream.
+// If the io.Reader implements Bytes, that is used instead.
+func NewStreamInputSize(r io.Reader, size int) *StreamInput {
+	if buffer, ok := r.(interface {
+		Bytes() []byte
+	}); ok {
+		return &StreamInput{*NewInputBytes(buffer.Bytes())}
+	} else if r == nil {
+		return &StreamInput{*NewInputBytes(nil)}
+	}
+	if size < 2 {
+		size = 2
+	}
+	return &StreamInput{Input{
+		buf: make([]byte, 1, size), // NULL at the end of the buffered data
+		r:   r,
+	}}
+}
+
 // NewInputString returns a new Input for a given string and appends NULL at the end.
 func NewInputString(s string) *Input {
 	return NewInputBytes([]byte(s))
@@ -88,6 +132,7 @@ func (z *Input) Err() error {
 }
 
 // PeekErr returns the error at position pos. When pos is zero, this is the same as calling Err().
+// For streaming input, io.EOF is also returned at the end of the buffered data, past which Retry reads in more data.
 func (z *Input) PeekErr(pos int) error {
 	if z.err != nil {
 		return z.err
@@ -99,6 +144,7 @@ func (z *Input) PeekErr(pos int) error {
 
 // Peek returns the ith byte relative to the end position.
 // Peek returns 0 when an error has occurred, Err returns the erroz.
+// For streaming input, Peek returns 0 at the end of the buffered data, see Retry.
 func (z *Input) Peek(pos int) byte {
 	pos += z.pos
 	return z.buf[pos]
@@ -137,6 +183,119 @@ func (z *Input) MoveRune() {
 	}
 }
 
+// Streaming returns true if the input is read from a stream that has not yet been read entirely, in which case tokens must be read using Retry.
+func (z *Input) Streaming() bool {
+	return z.r != nil
+}
+
+// Retry calls f, which reads a token, and returns when f returns. For streaming input, the buffered data ends with a NULL like in-memory input, so that f sees the end of the buffered data as the end of the input. When f ends its token within 64 bytes of the end of the buffered data, Retry reads in more data, restores the positions from before calling f, and calls f again, so that f must restore any other state it changes. Tokens are read correctly as long as f doesn't peek more than 64 bytes past the end of its token. The in-memory input is thus as fast as without streaming.
+func (z *Input) Retry(f func()) {
+	if z.r == nil {
+		f()
+		return
+	}
+
+	pos, start, prev := z.offset+z.pos, z.offset+z.start, z.offset+z.prev
+	for {
+		f()
+		if z.r == nil || z.pos < len(z.buf)-1-lookahead {
+			return
+		}
+		z.pos, z.start, z.prev = pos-z.offset, start-z.offset, prev-z.offset
+		z.read()
+	}
+}
+
+// read reads in more data from the stream, at least as much as the data that is kept and the lookahead past the position. It discards the data before the current and previous selection.
+func (z *Input) read() {
+	z.buf[len(z.buf)-1] = z.held // restore the byte replaced by the NULL
+	keep := z.start
+	if z.prev < keep {
+		keep = z.prev
+	}
+	if 0 < keep && z.buf[keep-1] == '\r' {
+		keep-- // don't split \r\n so that we count newlines correctly
+	}
+
+	n := z.end - keep
+	want := 2*n + 1
+	if ahead := z.pos - keep + lookahead + 1; want < ahead {
+		want = ahead
+	}
+	size := cap(z.buf)
+	if size < want+1 {
+		// if the kept data is larger than half the buffer, increase buffer size
+		size = 2*size + want
+	}
+
+	// the spare buffer may only be reused if the selections that are kept weren't read into it
+	buf := z.spare
+	if cap(buf) < size || z.offset+keep < z.spareEnd {
+		buf = make([]byte, size)
+	} else {
+		buf = buf[:cap(buf)]
+	}
+	d := copy(buf, z.buf[keep:z.end])
+	for d < want {
+		m, err := z.r.Read(buf[d : len(buf)-1])
+		d += m
+		if err != nil {
+			if err != io.EOF {
+				z.err = err
+			}
+			z.r = nil
+			break
+		}
+	}
+	z.countLines(z.buf[:keep])
+
+	z.spare, z.spareEnd = z.buf, z.offset+z.end
+	if z.r == nil {
+		z.held = 0
+		buf[d] = 0
+		z.buf = buf[:d+1]
+	} else {
+		m := complete(buf[:d])
+		z.held = buf[m]
+		buf[m] = 0
+		z.buf = buf[:m+1]
+	}
+	z.end = d
+	z.offset += keep
+	z.pos -= keep
+	z.start -= keep
+	z.prev -= keep
+}
+
+// complete returns the length of b without an incomplete UTF-8 encoding at its end, so that MoveRune and PeekRune never split a rune at the end of the buffered data. Bytes that are held back are read again after reading in more data.
+func complete(b []byte) int {
+	n := len(b)
+	for i := n - 1; 0 <= i && n-5 < i; i-- {
+		if c := b[i]; 0xF0 <= c && n < i+4 || 0xE0 <= c && n < i+3 || 0xC0 <= c && n < i+2 {
+			n = i
+		}
+	}
+	return n
+}
+
+// countLines keeps track of the line and column of the discarded data, so that positions of errors are correct.
+func (z *Input) countLines(b []byte) {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c == '\n' || c == '\r' && (len(b) <= i+1 || b[i+1] != '\n') {
+			z.line++
+			z.col = 0
+		} else if c == 0xE2 && i+2 < len(b) && b[i+1] == 0x80 && (b[i+2] == 0xA8 || b[i+2] == 0xA9) {
+			// \u2028 and \u2029
+			z.line++
+			z.col = 0
+			i += 2
+		} else if c&0xC0 != 0x80 {
+			z.col++
+		}
+	}
+}
+
 // Pos returns a mark to which can be rewinded.
 func (z *Input) Pos() int {
 	return z.pos - z.start
@@ -154,19 +313,21 @@ func (z *Input) Lexeme() []byte {
 
 // Skip collapses the position to the end of the selection.
 func (z *Input) Skip() {
+	z.prev = z.start
 	z.start = z.pos
 }
 
 // Shift returns the bytes of the current selection and collapses the position to the end of the selection.
 func (z *Input) Shift() []byte {
 	b := z.buf[z.start:z.pos:z.pos]
+	z.prev = z.start
 	z.start = z.pos
 	return b
 }
 
 // Offset returns the character position in the buffez.
 func (z *Input) Offset() int {
-	return z.pos
+	return z.offset + z.pos
 }
 
 // Bytes returns the underlying buffez.
@@ -182,5 +343,6 @@ func (z *Input) Len() int {
 // Reset resets position to the underlying buffez.
 func (z *Input) Reset() {
 	z.start = 0
+	z.prev = 0
 	z.pos = 0
 }
@@ -3,7 +3,9 @@ package parse
 import (
 	"bytes"
 	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/test"
 )
@@ -111,3 +113,112 @@ func TestInputRestore(t *testing.T) {
 	z.Restore()
 	test.Bytes(t, b, []byte{'a', 'b', 'c', 'd'}, "terminating NULL has been restored")
 }
+
+func TestStreamInput(t *testing.T) {
+	s := strings.Repeat(`Lorem ipsum dolor sit amet, consectetur adipiscing elit. `, 16)
+	z := NewStreamInputSize(iotest.OneByteReader(bytes.NewBufferString(s)), 4)
+	test.That(t, z.Streaming(), "must be streaming")
+
+	words := []string{}
+	for {
+		var word []byte
+		z.Retry(func() {
+			for c := z.Peek(0); c != ' ' && (c != 0 || z.Err() == nil); c = z.Peek(0) {
+				z.Move(1)
+			}
+			word = z.Shift()
+			if z.Peek(0) == ' ' {
+				z.Move(1)
+				test.That(t, z.Peek(-2) == word[len(word)-1], "must keep the previous selection")
+				z.Skip()
+			}
+		})
+		if len(word) == 0 {
+			break
+		}
+		words = append(words, string(word))
+		test.That(t, z.Len() < 8*lookahead, "buffer must discard the data before the previous selection")
+	}
+	test.T(t, words, strings.Fields(s))
+	test.T(t, z.Offset(), len(s), "offset")
+	test.T(t, z.Err(), io.EOF)
+	test.That(t, !z.Streaming(), "must not be streaming after reading all data")
+}
+
+func TestStreamInputPeek(t *testing.T) {
+	z := NewStreamInput(iotest.OneByteReader(bytes.NewBufferString("abc")))
+	test.T(t, z.Peek(0), byte(0), "must return NULL at the end of the buffered data")
+	test.T(t, z.Err(), io.EOF, "must return EOF at the end of the buffered data")
+
+	var b []byte
+	z.Retry(func() {
+		for z.Peek(0) != 0 {
+			z.Move(1)
+		}
+		b = z.Shift()
+	})
+	test.Bytes(t, b, []byte("abc"))
+	test.That(t, !z.Streaming(), "must not be streaming after reading all data")
+}
+
+func TestStreamInputLong(t *testing.T) {
+	// tokens larger than the buffer
+	s := strings.Repeat("a", 1000) + " " + strings.Repeat("b", 3000)
+	z := NewStreamInputSize(iotest.HalfReader(strings.NewReader(s)), 16)
+	var a, b []byte
+	z.Retry(func() {
+		for z.Peek(0) == 'a' {
+			z.Move(1)
+		}
+		a = z.Shift()
+	})
+	test.Bytes(t, a, []byte(s[:1000]))
+	z.Retry(func() {
+		z.Move(1)
+		z.Skip()
+		for z.Peek(0) == 'b' {
+			z.Move(1)
+		}
+		b = z.Shift()
+	})
+	test.Bytes(t, b, []byte(s[1001:]))
+	test.T(t, z.Err(), io.EOF)
+	test.T(t, z.Offset(), len(s))
+}
+
+func TestStreamInputRunes(t *testing.T) {
+	s := "a\u00e6\u2020\U00100000b\u00e6"
+	runes := []rune{}
+	for size := 2; size < 8; size++ {
+		z := NewStreamInputSize(iotest.OneByteReader(bytes.NewBufferString(s)), size)
+		runes = runes[:0]
+		for {
+			var r rune
+			z.Retry(func() {
+				var n int
+				r, n = z.PeekRune(0)
+				z.MoveRune()
+				test.T(t, z.Pos(), n)
+				z.Skip()
+			})
+			if r == 0 {
+				break
+			}
+			runes = append(runes, r)
+		}
+		test.T(t, string(runes), s)
+	}
+}
+
+func TestStreamInputErrorInput(t *testing.T) {
+	z := NewStreamInput(test.NewErrorReader(0))
+	var c byte
+	z.Retry(func() {
+		c = z.Peek(0)
+	})
+	test.That(t, c == 0, "first character must yield error")
+	test.T(t, z.Err(), test.ErrPlain, "error must be ErrPlain")
+
+	z = NewStreamInput(bytes.NewBufferString("abc"))
+	test.Bytes(t, z.Bytes(), []byte("abc"), "buffer with bytes must be used directly")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,6 +20,8 @@ type JSONer interface {
 // AST is the full ECMAScript abstract syntax tree.
 type AST struct {
 	BlockStmt // module
+
+	lossless *lossless // source and snapshot when parsed with Options.Lossless
 }
 
 func (ast AST) String() string {
@@ -38,10 +41,12 @@ func (ast AST) JS(w io.Writer) {
 		if i != 0 {
 			w.Write([]byte("\n"))
 		}
+		writeLeadingComments(w, item, false)
 		item.JS(w)
 		if _, ok := item.(*VarDecl); ok {
 			w.Write([]byte(";"))
 		}
+		writeTrailingComments(w, item, false)
 	}
 }
 
@@ -84,6 +89,58 @@ func (ast AST) JSONString() (string, error) {
 
 ////////////////////////////////////////////////////////////////
 
+// Loc is the location of a node in the source as byte offsets, where End is exclusive. It is the zero value for nodes that were not created by the parser.
+type Loc struct {
+	Start, End int
+}
+
+// IsSet returns true if the location was set by the parser.
+func (loc Loc) IsSet() bool {
+	return loc.Start < loc.End
+}
+
+// Position returns the line and column number (both starting at 1) of the start of the location, and the line as context. See parse.Position.
+func (loc Loc) Position(r io.Reader) (int, int, string) {
+	return parse.Position(r, loc.Start)
+}
+
+// EndPosition returns the line and column number (both starting at 1) of the end of the location, and the line as context. See parse.Position.
+func (loc Loc) EndPosition(r io.Reader) (int, int, string) {
+	return parse.Position(r, loc.End)
+}
+
+func (loc Loc) loc() Loc {
+	return loc
+}
+
+func (loc *Loc) setLoc(l Loc) {
+	*loc = l
+}
+
+// NodeLoc returns the location of a node in the source, or the zero Loc if the node does not keep its location. Variables are shared between all their uses and keep a list of locations in Var.Locs instead.
+func NodeLoc(n INode) Loc {
+	if n, ok := n.(interface{ loc() Loc }); ok {
+		return n.loc()
+	}
+	return Loc{}
+}
+
+// nextLoc returns the first location of a variable that starts at or after offset. Since variables are shared between all their uses, this finds the location of a use when visiting the nodes in source order with offset just after the previous node. Variables without locations use those of the variable they link to.
+func nextLoc(v *Var, offset int) (Loc, bool) {
+	for len(v.Locs) == 0 && v.Link != nil {
+		v = v.Link
+	}
+	i := sort.Search(len(v.Locs), func(i int) bool {
+		return offset <= v.Locs[i].Start
+	})
+	if i < len(v.Locs) {
+		return v.Locs[i], true
+	}
+	return Loc{}, false
+}
+
+////////////////////////////////////////////////////////////////
+
 // DeclType specifies the kind of declaration.
 type DeclType uint16
 
@@ -97,6 +154,7 @@ const (
 	LexicalDecl                  // let, const, class
 	CatchDecl                    // catch statement argument
 	ExprDecl                     // function expression name or class expression name
+	UsingDecl                    // using, await using
 )
 
 func (decl DeclType) String() string {
@@ -117,6 +175,8 @@ func (decl DeclType) String() string {
 		return "CatchDecl"
 	case ExprDecl:
 		return "ExprDecl"
+	case UsingDecl:
+		return "UsingDecl"
 	}
 	return "Invalid(" + strconv.Itoa(int(decl)) + ")"
 }
@@ -127,6 +187,7 @@ type Var struct {
 	Link *Var // is set when merging variable uses, as in:  {a} {var a}  where the first links to the second, only used for undeclared variables
 	Uses uint16
 	Decl DeclType
+	Locs []Loc // locations of all declarations and uses in source order, also of those that link to this variable
 }
 
 // Name returns the variable name.
@@ -261,7 +322,7 @@ func (s *Scope) Declare(decl DeclType, name []byte) (*Var, bool) {
 	}
 	if v == nil {
 		// add variable to the context list and to the scope
-		v = &Var{name, nil, 0, decl}
+		v = &Var{name, nil, 0, decl, nil}
 	} else {
 		v.Decl = decl
 	}
@@ -283,7 +344,7 @@ func (s *Scope) Use(name []byte) *Var {
 		v = s.findUndeclared(name)
 		if v == nil {
 			// add variable to the context list and to the scope's undeclared
-			v = &Var{name, nil, 0, NoDecl}
+			v = &Var{name, nil, 0, NoDecl, nil}
 			s.Undeclared = append(s.Undeclared, v)
 		}
 	}
@@ -351,11 +412,13 @@ func (s *Scope) HoistUndeclared() {
 			if v := s.Parent.findDeclared(vorig.Data, false); v != nil {
 				// check if variable is declared in parent scope
 				v.Uses += vorig.Uses
+				v.Locs = mergeLocs(v.Locs, vorig.Locs)
 				vorig.Link = v
 				s.Undeclared[i] = v // point reference to existing var (to avoid many Link chains)
 			} else if v := s.Parent.findUndeclared(vorig.Data); v != nil {
 				// check if variable is already used before in parent scope
 				v.Uses += vorig.Uses
+				v.Locs = mergeLocs(v.Locs, vorig.Locs)
 				vorig.Link = v
 				s.Undeclared[i] = v // point reference to existing var (to avoid many Link chains)
 			} else {
@@ -376,10 +439,12 @@ func (s *Scope) UndeclareScope() {
 		if v := s.Parent.findDeclared(vorig.Data, false); v != nil {
 			// check if variable has been declared in this scope
 			v.Uses += vorig.Uses
+			v.Locs = mergeLocs(v.Locs, vorig.Locs)
 			vorig.Link = v
 		} else if v := s.Parent.findUndeclared(vorig.Data); v != nil {
 			// check if variable is already used before in the current or lower scopes
 			v.Uses += vorig.Uses
+			v.Locs = mergeLocs(v.Locs, vorig.Locs)
 			vorig.Link = v
 		} else {
 			// add variable to the context list and to the scope's undeclared
@@ -391,6 +456,27 @@ func (s *Scope) UndeclareScope() {
 	s.Undeclared = s.Undeclared[:0]
 }
 
+// mergeLocs merges two lists of locations that are in source order.
+func mergeLocs(a, b []Loc) []Loc {
+	if len(b) == 0 {
+		return a
+	} else if len(a) == 0 || a[len(a)-1].Start < b[0].Start {
+		return append(a, b...)
+	}
+	locs := make([]Loc, 0, len(a)+len(b))
+	for 0 < len(a) && 0 < len(b) {
+		if a[0].Start < b[0].Start {
+			locs = append(locs, a[0])
+			a = a[1:]
+		} else {
+			locs = append(locs, b[0])
+			b = b[1:]
+		}
+	}
+	locs = append(locs, a...)
+	return append(locs, b...)
+}
+
 // Unscope moves all declared variables of the current scope to the parent scope. Undeclared variables are already in the parent scope.
 func (s *Scope) Unscope() {
 	for _, vorig := range s.Declared {
@@ -433,6 +519,7 @@ type IExpr interface {
 // Comment block or line, usually a bang comment.
 type Comment struct {
 	Value []byte
+	Loc
 }
 
 func (n Comment) String() string {
@@ -448,10 +535,70 @@ func (n Comment) JS(w io.Writer) {
 	}
 }
 
+// Comments are the comments attached to a declaration, method, or property when parsing with Options.Comments.
+type Comments struct {
+	Leading  []*Comment // comments on the lines before
+	Trailing []*Comment // comments after it on the same line
+}
+
+// nodeComments returns the attached comments of a node, or nil if the node does not accept comments.
+func nodeComments(n INode) *Comments {
+	switch n := n.(type) {
+	case *VarDecl:
+		return &n.Comments
+	case *FuncDecl:
+		return &n.Comments
+	case *ClassDecl:
+		return &n.Comments
+	case *MethodDecl:
+		return &n.Comments
+	case *Field:
+		return &n.Comments
+	case *Property:
+		return &n.Comments
+	case *ExportStmt:
+		if n.Decl != nil {
+			return nodeComments(n.Decl)
+		}
+	}
+	return nil
+}
+
+// writeLeadingComments writes the leading comments of a node, each followed by a newline. When inline is set, block comments are followed by a space instead.
+func writeLeadingComments(w io.Writer, n INode, inline bool) {
+	if comments := nodeComments(n); comments != nil {
+		for _, comment := range comments.Leading {
+			comment.JS(w)
+			if inline && comment.Value[1] == '*' {
+				w.Write([]byte(" "))
+			} else {
+				w.Write([]byte("\n"))
+			}
+		}
+	}
+}
+
+// writeTrailingComments writes the trailing comments of a node, each preceded by a space. When inline is set, line comments are followed by a newline and it returns true if it wrote a newline last.
+func writeTrailingComments(w io.Writer, n INode, inline bool) bool {
+	newline := false
+	if comments := nodeComments(n); comments != nil {
+		for _, comment := range comments.Trailing {
+			w.Write([]byte(" "))
+			comment.JS(w)
+			newline = inline && comment.Value[1] == '/'
+			if newline {
+				w.Write([]byte("\n"))
+			}
+		}
+	}
+	return newline
+}
+
 // BlockStmt is a block statement.
 type BlockStmt struct {
 	List []IStmt
 	Scope
+	Loc
 }
 
 func (n BlockStmt) String() string {
@@ -474,16 +621,20 @@ func (n BlockStmt) JS(w io.Writer) {
 	wi := parse.NewIndenter(w, 4)
 	for _, item := range n.List {
 		wi.Write([]byte("\n"))
+		writeLeadingComments(wi, item, false)
 		item.JS(wi)
 		if _, ok := item.(*VarDecl); ok {
 			w.Write([]byte(";"))
 		}
+		writeTrailingComments(wi, item, false)
 	}
 	w.Write([]byte("\n}"))
 }
 
 // EmptyStmt is an empty statement.
-type EmptyStmt struct{}
+type EmptyStmt struct {
+	Loc
+}
 
 func (n EmptyStmt) String() string {
 	return "Stmt()"
@@ -494,9 +645,28 @@ func (n EmptyStmt) JS(w io.Writer) {
 	w.Write([]byte(";"))
 }
 
+// BadStmt is a placeholder for erroneous source when parsing with Options.Tolerant.
+type BadStmt struct {
+	Value []byte
+	Loc
+}
+
+func (n BadStmt) String() string {
+	return "Stmt(bad)"
+}
+
+// JS writes the erroneous source to writer.
+func (n BadStmt) JS(w io.Writer) {
+	if wi, ok := w.(parse.Indenter); ok {
+		w = wi.Writer
+	}
+	w.Write(n.Value)
+}
+
 // ExprStmt is an expression statement.
 type ExprStmt struct {
 	Value IExpr
+	Loc
 }
 
 func (n ExprStmt) String() string {
@@ -536,6 +706,7 @@ type IfStmt struct {
 	Cond IExpr
 	Body IStmt
 	Else IStmt // can be nil
+	Loc
 }
 
 func (n IfStmt) String() string {
@@ -574,6 +745,7 @@ func (n IfStmt) JS(w io.Writer) {
 type DoWhileStmt struct {
 	Cond IExpr
 	Body IStmt
+	Loc
 }
 
 func (n DoWhileStmt) String() string {
@@ -601,6 +773,7 @@ func (n DoWhileStmt) JS(w io.Writer) {
 type WhileStmt struct {
 	Cond IExpr
 	Body IStmt
+	Loc
 }
 
 func (n WhileStmt) String() string {
@@ -629,6 +802,7 @@ type ForStmt struct {
 	Cond IExpr // can be nil
 	Post IExpr // can be nil
 	Body *BlockStmt
+	Loc
 }
 
 func (n ForStmt) String() string {
@@ -672,6 +846,7 @@ type ForInStmt struct {
 	Init  IExpr
 	Value IExpr
 	Body  *BlockStmt
+	Loc
 }
 
 func (n ForInStmt) String() string {
@@ -694,6 +869,7 @@ type ForOfStmt struct {
 	Init  IExpr
 	Value IExpr
 	Body  *BlockStmt
+	Loc
 }
 
 func (n ForOfStmt) String() string {
@@ -723,6 +899,7 @@ type CaseClause struct {
 	TokenType
 	Cond IExpr // can be nil
 	List []IStmt
+	Loc
 }
 
 func (n CaseClause) String() string {
@@ -761,6 +938,7 @@ type SwitchStmt struct {
 	Init IExpr
 	List []CaseClause
 	Scope
+	Loc
 }
 
 func (n SwitchStmt) String() string {
@@ -792,6 +970,7 @@ func (n SwitchStmt) JS(w io.Writer) {
 type BranchStmt struct {
 	Type  TokenType
 	Label []byte // can be nil
+	Loc
 }
 
 func (n BranchStmt) String() string {
@@ -815,6 +994,7 @@ func (n BranchStmt) JS(w io.Writer) {
 // ReturnStmt is a return statement.
 type ReturnStmt struct {
 	Value IExpr // can be nil
+	Loc
 }
 
 func (n ReturnStmt) String() string {
@@ -839,6 +1019,7 @@ func (n ReturnStmt) JS(w io.Writer) {
 type WithStmt struct {
 	Cond IExpr
 	Body IStmt
+	Loc
 }
 
 func (n WithStmt) String() string {
@@ -863,6 +1044,7 @@ func (n WithStmt) JS(w io.Writer) {
 type LabelledStmt struct {
 	Label []byte
 	Value IStmt
+	Loc
 }
 
 func (n LabelledStmt) String() string {
@@ -885,6 +1067,7 @@ func (n LabelledStmt) JS(w io.Writer) {
 // ThrowStmt is a throw statement.
 type ThrowStmt struct {
 	Value IExpr
+	Loc
 }
 
 func (n ThrowStmt) String() string {
@@ -904,6 +1087,7 @@ type TryStmt struct {
 	Binding IBinding   // can be nil
 	Catch   *BlockStmt // can be nil
 	Finally *BlockStmt // can be nil
+	Loc
 }
 
 func (n TryStmt) String() string {
@@ -942,7 +1126,9 @@ func (n TryStmt) JS(w io.Writer) {
 }
 
 // DebuggerStmt is a debugger statement.
-type DebuggerStmt struct{}
+type DebuggerStmt struct {
+	Loc
+}
 
 func (n DebuggerStmt) String() string {
 	return "Stmt(debugger)"
@@ -976,11 +1162,62 @@ func (alias Alias) JS(w io.Writer) {
 	w.Write(alias.Binding)
 }
 
+// ImportAttribute is a key and value pair of the with clause of import/export statements.
+type ImportAttribute struct {
+	Key   []byte // identifier or string
+	Value []byte // string
+}
+
+func (attribute ImportAttribute) String() string {
+	return string(attribute.Key) + ": " + string(attribute.Value)
+}
+
+// JS writes JavaScript to writer.
+func (attribute ImportAttribute) JS(w io.Writer) {
+	w.Write(attribute.Key)
+	w.Write([]byte(": "))
+	w.Write(attribute.Value)
+}
+
+func importAttributesString(attributes []ImportAttribute) string {
+	if attributes == nil {
+		return ""
+	}
+	s := " with {"
+	for i, item := range attributes {
+		if i != 0 {
+			s += " ,"
+		}
+		s += " " + item.String()
+	}
+	return s + " }"
+}
+
+func writeImportAttributes(w io.Writer, attributes []ImportAttribute) {
+	if attributes == nil {
+		return
+	} else if len(attributes) == 0 {
+		w.Write([]byte(" with {}"))
+		return
+	}
+	w.Write([]byte(" with {"))
+	for j, item := range attributes {
+		if j != 0 {
+			w.Write([]byte(","))
+		}
+		w.Write([]byte(" "))
+		item.JS(w)
+	}
+	w.Write([]byte(" }"))
+}
+
 // ImportStmt is an import statement.
 type ImportStmt struct {
-	List    []Alias
-	Default []byte // can be nil
-	Module  []byte
+	List       []Alias
+	Default    []byte // can be nil
+	Module     []byte
+	Attributes []ImportAttribute // can be nil
+	Loc
 }
 
 func (n ImportStmt) String() string {
@@ -1009,7 +1246,7 @@ func (n ImportStmt) String() string {
 	if n.Default != nil || n.List != nil {
 		s.WriteString(" from")
 	}
-	return s.String() + " " + string(n.Module) + ")"
+	return s.String() + " " + string(n.Module) + importAttributesString(n.Attributes) + ")"
 }
 
 // JS writes JavaScript to writer.
@@ -1050,15 +1287,18 @@ func (n ImportStmt) JS(w io.Writer) {
 	}
 	w.Write([]byte(" "))
 	w.Write(n.Module)
+	writeImportAttributes(w, n.Attributes)
 	w.Write([]byte(";"))
 }
 
 // ExportStmt is an export statement.
 type ExportStmt struct {
-	List    []Alias
-	Module  []byte // can be nil
-	Default bool
-	Decl    IExpr
+	List       []Alias
+	Module     []byte            // can be nil
+	Attributes []ImportAttribute // can be nil
+	Default    bool
+	Decl       IExpr
+	Loc
 }
 
 func (n ExportStmt) String() string {
@@ -1083,7 +1323,7 @@ func (n ExportStmt) String() string {
 		s += " }"
 	}
 	if n.Module != nil {
-		s += " from " + string(n.Module)
+		s += " from " + string(n.Module) + importAttributesString(n.Attributes)
 	}
 	return s + ")"
 }
@@ -1123,6 +1363,7 @@ func (n ExportStmt) JS(w io.Writer) {
 	if n.Module != nil {
 		w.Write([]byte(" from "))
 		w.Write(n.Module)
+		writeImportAttributes(w, n.Attributes)
 	}
 	w.Write([]byte(";"))
 }
@@ -1130,6 +1371,7 @@ func (n ExportStmt) JS(w io.Writer) {
 // DirectivePrologueStmt is a string literal at the beginning of a function or module (usually "use strict").
 type DirectivePrologueStmt struct {
 	Value []byte
+	Loc
 }
 
 func (n DirectivePrologueStmt) String() string {
@@ -1148,6 +1390,7 @@ func (n DirectivePrologueStmt) JS(w io.Writer) {
 func (n Comment) stmtNode()               {}
 func (n BlockStmt) stmtNode()             {}
 func (n EmptyStmt) stmtNode()             {}
+func (n BadStmt) stmtNode()               {}
 func (n ExprStmt) stmtNode()              {}
 func (n IfStmt) stmtNode()                {}
 func (n DoWhileStmt) stmtNode()           {}
@@ -1219,6 +1462,7 @@ func (n PropertyName) JS(w io.Writer) {
 type BindingArray struct {
 	List []BindingElement
 	Rest IBinding // can be nil
+	Loc
 }
 
 func (n BindingArray) String() string {
@@ -1298,6 +1542,7 @@ func (n BindingObjectItem) JS(w io.Writer) {
 type BindingObject struct {
 	List []BindingObjectItem
 	Rest *Var // can be nil
+	Loc
 }
 
 func (n BindingObject) String() string {
@@ -1341,6 +1586,7 @@ func (n BindingObject) JS(w io.Writer) {
 type BindingElement struct {
 	Binding IBinding // can be nil (in case of ellision)
 	Default IExpr    // can be nil
+	Loc
 }
 
 func (n BindingElement) String() string {
@@ -1376,14 +1622,21 @@ func (n BindingObject) bindingNode() {}
 // VarDecl is a variable statement or lexical declaration.
 type VarDecl struct {
 	TokenType
+	Await            bool // await using
 	List             []BindingElement
 	Scope            *Scope
 	InFor, InForInOf bool
+	Comments         Comments
+	Loc
 }
 
 func (n VarDecl) String() string {
 	var s strings.Builder
-	s.WriteString("Decl(" + n.TokenType.String())
+	s.WriteString("Decl(")
+	if n.Await {
+		s.WriteString("await ")
+	}
+	s.WriteString(n.TokenType.String())
 	for _, item := range n.List {
 		s.WriteString(" " + item.String())
 	}
@@ -1392,6 +1645,9 @@ func (n VarDecl) String() string {
 
 // JS writes JavaScript to writer.
 func (n VarDecl) JS(w io.Writer) {
+	if n.Await {
+		w.Write([]byte("await "))
+	}
 	w.Write(n.TokenType.Bytes())
 	for j, item := range n.List {
 		if j != 0 {
@@ -1452,6 +1708,8 @@ type FuncDecl struct {
 	Name      *Var // can be nil
 	Params    Params
 	Body      BlockStmt
+	Comments  Comments
+	Loc
 }
 
 func (n FuncDecl) String() string {
@@ -1515,18 +1773,21 @@ func (n ClassElementName) JS(w io.Writer) {
 
 // MethodDecl is a method definition in a class declaration.
 type MethodDecl struct {
-	Static    bool
-	Async     bool
-	Generator bool
-	Get       bool
-	Set       bool
-	Name      ClassElementName
-	Params    Params
-	Body      BlockStmt
+	Decorators []IExpr
+	Static     bool
+	Async      bool
+	Generator  bool
+	Get        bool
+	Set        bool
+	Name       ClassElementName
+	Params     Params
+	Body       BlockStmt
+	Comments   Comments
+	Loc
 }
 
 func (n MethodDecl) String() string {
-	s := ""
+	s := decoratorsString(n.Decorators)
 	if n.Static {
 		s += " static"
 	}
@@ -1548,6 +1809,7 @@ func (n MethodDecl) String() string {
 
 // JS writes JavaScript to writer.
 func (n MethodDecl) JS(w io.Writer) {
+	writeDecorators(w, n.Decorators)
 	writen := false
 	if n.Static {
 		w.Write([]byte("static"))
@@ -1593,16 +1855,26 @@ func (n MethodDecl) JS(w io.Writer) {
 
 // Field is a field definition in a class declaration.
 type Field struct {
-	Static bool
-	Name   ClassElementName
-	Init   IExpr // can be nil
+	Decorators []IExpr
+	Static     bool
+	Accessor   bool
+	Name       ClassElementName
+	Init       IExpr // can be nil
+	Comments   Comments
+	Loc
 }
 
 func (n Field) String() string {
 	s := "Field("
+	if 0 < len(n.Decorators) {
+		s += decoratorsString(n.Decorators)[1:] + " "
+	}
 	if n.Static {
 		s += "static "
 	}
+	if n.Accessor {
+		s += "accessor "
+	}
 	s += n.Name.String()
 	if n.Init != nil {
 		s += " = " + n.Init.String()
@@ -1612,9 +1884,13 @@ func (n Field) String() string {
 
 // JS writes JavaScript to writer.
 func (n Field) JS(w io.Writer) {
+	writeDecorators(w, n.Decorators)
 	if n.Static {
 		w.Write([]byte("static "))
 	}
+	if n.Accessor {
+		w.Write([]byte("accessor "))
+	}
 	n.Name.JS(w)
 	if n.Init != nil {
 		w.Write([]byte(" = "))
@@ -1627,6 +1903,7 @@ type ClassElement struct {
 	StaticBlock *BlockStmt  // can be nil
 	Method      *MethodDecl // can be nil
 	Field
+	Loc
 }
 
 func (n ClassElement) String() string {
@@ -1654,15 +1931,22 @@ func (n ClassElement) JS(w io.Writer) {
 
 // ClassDecl is a class declaration.
 type ClassDecl struct {
-	Name    *Var  // can be nil
-	Extends IExpr // can be nil
-	List    []ClassElement
-	Scope   // for private elements
+	Decorators []IExpr
+	Name       *Var  // can be nil
+	Extends    IExpr // can be nil
+	List       []ClassElement
+	Scope      // for private elements
+	Comments   Comments
+	Loc
 }
 
 func (n ClassDecl) String() string {
 	var s strings.Builder
-	s.WriteString("Decl(class")
+	s.WriteString("Decl(")
+	if 0 < len(n.Decorators) {
+		s.WriteString(decoratorsString(n.Decorators)[1:] + " ")
+	}
+	s.WriteString("class")
 	if n.Name != nil {
 		s.WriteString(" " + string(n.Name.Data))
 	}
@@ -1677,6 +1961,7 @@ func (n ClassDecl) String() string {
 
 // JS writes JavaScript to writer.
 func (n ClassDecl) JS(w io.Writer) {
+	writeDecorators(w, n.Decorators)
 	w.Write([]byte("class"))
 	if n.Name != nil {
 		w.Write([]byte(" "))
@@ -1694,11 +1979,37 @@ func (n ClassDecl) JS(w io.Writer) {
 	wi := parse.NewIndenter(w, 4)
 	for _, item := range n.List {
 		wi.Write([]byte("\n"))
+		if item.Method != nil {
+			writeLeadingComments(wi, item.Method, false)
+		} else if item.StaticBlock == nil {
+			writeLeadingComments(wi, &item.Field, false)
+		}
 		item.JS(wi)
+		if item.Method != nil {
+			writeTrailingComments(wi, item.Method, false)
+		} else if item.StaticBlock == nil {
+			writeTrailingComments(wi, &item.Field, false)
+		}
 	}
 	w.Write([]byte("\n}"))
 }
 
+func decoratorsString(decorators []IExpr) string {
+	s := ""
+	for _, decorator := range decorators {
+		s += " @" + decorator.String()
+	}
+	return s
+}
+
+func writeDecorators(w io.Writer, decorators []IExpr) {
+	for _, decorator := range decorators {
+		w.Write([]byte("@"))
+		decorator.JS(w)
+		w.Write([]byte(" "))
+	}
+}
+
 func (n VarDecl) stmtNode()   {}
 func (n FuncDecl) stmtNode()  {}
 func (n ClassDecl) stmtNode() {}
@@ -1714,6 +2025,7 @@ func (n MethodDecl) exprNode() {} // not a real IExpr, used for ObjectExpression
 type LiteralExpr struct {
 	TokenType
 	Data []byte
+	Loc
 }
 
 func (n LiteralExpr) String() string {
@@ -1783,6 +2095,7 @@ func (n Element) JS(w io.Writer) {
 // ArrayExpr is an array literal.
 type ArrayExpr struct {
 	List []Element
+	Loc
 }
 
 func (n ArrayExpr) String() string {
@@ -1853,10 +2166,12 @@ func (n ArrayExpr) JSON(w io.Writer) error {
 type Property struct {
 	// either Name or Spread are set. When Spread is set then Value is AssignmentExpression
 	// if Init is set then Value is IdentifierReference, otherwise it can also be MethodDefinition
-	Name   *PropertyName // can be nil
-	Spread bool
-	Value  IExpr // Var, MethodDecl, AssignExpr, or IdentExpr
-	Init   IExpr // can be nil
+	Name     *PropertyName // can be nil
+	Spread   bool
+	Value    IExpr // Var, MethodDecl, AssignExpr, or IdentExpr
+	Init     IExpr // can be nil
+	Comments Comments
+	Loc
 }
 
 func (n Property) String() string {
@@ -1924,6 +2239,7 @@ func (n Property) JSON(w io.Writer) error {
 // ObjectExpr is an object literal.
 type ObjectExpr struct {
 	List []Property
+	Loc
 }
 
 func (n ObjectExpr) String() string {
@@ -1941,11 +2257,16 @@ func (n ObjectExpr) String() string {
 // JS writes JavaScript to writer.
 func (n ObjectExpr) JS(w io.Writer) {
 	w.Write([]byte("{"))
-	for j, item := range n.List {
-		if j != 0 {
-			w.Write([]byte(", "))
-		}
+	for j := range n.List {
+		item := &n.List[j]
+		writeLeadingComments(w, item, true)
 		item.JS(w)
+		if j+1 < len(n.List) {
+			w.Write([]byte(","))
+		}
+		if !writeTrailingComments(w, item, true) && j+1 < len(n.List) {
+			w.Write([]byte(" "))
+		}
 	}
 	w.Write([]byte("}"))
 }
@@ -1988,6 +2309,7 @@ type TemplateExpr struct {
 	Tail     []byte
 	Prec     OpPrec
 	Optional bool
+	Loc
 }
 
 func (n TemplateExpr) String() string {
@@ -2048,6 +2370,7 @@ func (n TemplateExpr) JSON(w io.Writer) error {
 // GroupExpr is a parenthesized expression.
 type GroupExpr struct {
 	X IExpr
+	Loc
 }
 
 func (n GroupExpr) String() string {
@@ -2067,6 +2390,7 @@ type IndexExpr struct {
 	Y        IExpr
 	Prec     OpPrec
 	Optional bool
+	Loc
 }
 
 func (n IndexExpr) String() string {
@@ -2094,6 +2418,7 @@ type DotExpr struct {
 	Y        IExpr // LiteralExpr or Var
 	Prec     OpPrec
 	Optional bool
+	Loc
 }
 
 func (n DotExpr) String() string {
@@ -2123,7 +2448,9 @@ func (n DotExpr) JS(w io.Writer) {
 }
 
 // NewTargetExpr is a new target meta property.
-type NewTargetExpr struct{}
+type NewTargetExpr struct {
+	Loc
+}
 
 func (n NewTargetExpr) String() string {
 	return "(new.target)"
@@ -2135,7 +2462,9 @@ func (n NewTargetExpr) JS(w io.Writer) {
 }
 
 // ImportMetaExpr is a import meta meta property.
-type ImportMetaExpr struct{}
+type ImportMetaExpr struct {
+	Loc
+}
 
 func (n ImportMetaExpr) String() string {
 	return "(import.meta)"
@@ -2198,6 +2527,7 @@ func (n Args) JS(w io.Writer) {
 type NewExpr struct {
 	X    IExpr
 	Args *Args // can be nil
+	Loc
 }
 
 func (n NewExpr) String() string {
@@ -2226,6 +2556,7 @@ type CallExpr struct {
 	Args     Args
 	Prec     OpPrec
 	Optional bool
+	Loc
 }
 
 func (n CallExpr) String() string {
@@ -2251,6 +2582,7 @@ func (n CallExpr) JS(w io.Writer) {
 type UnaryExpr struct {
 	Op TokenType
 	X  IExpr
+	Loc
 }
 
 func (n UnaryExpr) String() string {
@@ -2301,6 +2633,7 @@ func (n UnaryExpr) JSON(w io.Writer) error {
 type BinaryExpr struct {
 	Op   TokenType
 	X, Y IExpr
+	Loc
 }
 
 func (n BinaryExpr) String() string {
@@ -2322,6 +2655,7 @@ func (n BinaryExpr) JS(w io.Writer) {
 // CondExpr is a conditional expression.
 type CondExpr struct {
 	Cond, X, Y IExpr
+	Loc
 }
 
 func (n CondExpr) String() string {
@@ -2341,6 +2675,7 @@ func (n CondExpr) JS(w io.Writer) {
 type YieldExpr struct {
 	Generator bool
 	X         IExpr // can be nil
+	Loc
 }
 
 func (n YieldExpr) String() string {
@@ -2372,6 +2707,7 @@ type ArrowFunc struct {
 	Async  bool
 	Params Params
 	Body   BlockStmt
+	Loc
 }
 
 func (n ArrowFunc) String() string {
@@ -2395,6 +2731,7 @@ func (n ArrowFunc) JS(w io.Writer) {
 // CommaExpr is a series of comma expressions.
 type CommaExpr struct {
 	List []IExpr
+	Loc
 }
 
 func (n CommaExpr) String() string {
@@ -2419,21 +2756,161 @@ func (n CommaExpr) JS(w io.Writer) {
 	}
 }
 
-func (v *Var) exprNode()           {}
-func (n LiteralExpr) exprNode()    {}
-func (n ArrayExpr) exprNode()      {}
-func (n ObjectExpr) exprNode()     {}
-func (n TemplateExpr) exprNode()   {}
-func (n GroupExpr) exprNode()      {}
-func (n DotExpr) exprNode()        {}
-func (n IndexExpr) exprNode()      {}
-func (n NewTargetExpr) exprNode()  {}
-func (n ImportMetaExpr) exprNode() {}
-func (n NewExpr) exprNode()        {}
-func (n CallExpr) exprNode()       {}
-func (n UnaryExpr) exprNode()      {}
-func (n BinaryExpr) exprNode()     {}
-func (n CondExpr) exprNode()       {}
-func (n YieldExpr) exprNode()      {}
-func (n ArrowFunc) exprNode()      {}
-func (n CommaExpr) exprNode()      {}
+////////////////////////////////////////////////////////////////
+
+// JSXElement is a JSX element. Name is a LiteralExpr with JSXIdentifierToken for intrinsic elements such as div or svg:rect, a Var for components, or a DotExpr for member expressions.
+type JSXElement struct {
+	Name     IExpr
+	Attrs    []JSXAttribute
+	Children []IExpr // LiteralExpr with JSXTextToken, JSXExprContainer, JSXElement, or JSXFragment
+	Loc
+}
+
+func (n JSXElement) String() string {
+	var s strings.Builder
+	s.WriteString("<" + n.Name.String())
+	for _, attr := range n.Attrs {
+		s.WriteString(" " + attr.String())
+	}
+	if len(n.Children) == 0 {
+		return s.String() + "/>"
+	}
+	s.WriteString(">")
+	for _, child := range n.Children {
+		s.WriteString(child.String())
+	}
+	return s.String() + "</" + n.Name.String() + ">"
+}
+
+// JS writes JavaScript to writer.
+func (n JSXElement) JS(w io.Writer) {
+	if wi, ok := w.(parse.Indenter); ok {
+		w = wi.Writer
+	}
+	w.Write([]byte("<"))
+	n.Name.JS(w)
+	for _, attr := range n.Attrs {
+		w.Write([]byte(" "))
+		attr.JS(w)
+	}
+	if len(n.Children) == 0 {
+		w.Write([]byte("/>"))
+		return
+	}
+	w.Write([]byte(">"))
+	for _, child := range n.Children {
+		child.JS(w)
+	}
+	w.Write([]byte("</"))
+	n.Name.JS(w)
+	w.Write([]byte(">"))
+}
+
+// JSXFragment is a JSX fragment.
+type JSXFragment struct {
+	Children []IExpr // LiteralExpr with JSXTextToken, JSXExprContainer, JSXElement, or JSXFragment
+	Loc
+}
+
+func (n JSXFragment) String() string {
+	var s strings.Builder
+	s.WriteString("<>")
+	for _, child := range n.Children {
+		s.WriteString(child.String())
+	}
+	return s.String() + "</>"
+}
+
+// JS writes JavaScript to writer.
+func (n JSXFragment) JS(w io.Writer) {
+	if wi, ok := w.(parse.Indenter); ok {
+		w = wi.Writer
+	}
+	w.Write([]byte("<>"))
+	for _, child := range n.Children {
+		child.JS(w)
+	}
+	w.Write([]byte("</>"))
+}
+
+// JSXAttribute is an attribute or a spread attribute of a JSX element.
+type JSXAttribute struct {
+	Name   []byte // can be nil when Spread is set, may be a namespaced name such as xlink:href
+	Value  IExpr  // can be nil, LiteralExpr with JSXStringToken, JSXExprContainer, JSXElement, or JSXFragment. When Spread is set then Value is AssignmentExpression
+	Spread bool
+	Loc
+}
+
+func (n JSXAttribute) String() string {
+	if n.Spread {
+		return "{..." + n.Value.String() + "}"
+	} else if n.Value == nil {
+		return string(n.Name)
+	}
+	return string(n.Name) + "=" + n.Value.String()
+}
+
+// JS writes JavaScript to writer.
+func (n JSXAttribute) JS(w io.Writer) {
+	if n.Spread {
+		w.Write([]byte("{..."))
+		n.Value.JS(w)
+		w.Write([]byte("}"))
+		return
+	}
+	w.Write(n.Name)
+	if n.Value != nil {
+		w.Write([]byte("="))
+		n.Value.JS(w)
+	}
+}
+
+// JSXExprContainer is an expression in braces used as a JSX attribute value or child.
+type JSXExprContainer struct {
+	X      IExpr // can be nil for children such as {} or {/* comment */}
+	Spread bool  // only for children
+	Loc
+}
+
+func (n JSXExprContainer) String() string {
+	if n.X == nil {
+		return "{}"
+	} else if n.Spread {
+		return "{..." + n.X.String() + "}"
+	}
+	return "{" + n.X.String() + "}"
+}
+
+// JS writes JavaScript to writer.
+func (n JSXExprContainer) JS(w io.Writer) {
+	w.Write([]byte("{"))
+	if n.X != nil {
+		if n.Spread {
+			w.Write([]byte("..."))
+		}
+		n.X.JS(w)
+	}
+	w.Write([]byte("}"))
+}
+
+func (v *Var) exprNode()             {}
+func (n LiteralExpr) exprNode()      {}
+func (n ArrayExpr) exprNode()        {}
+func (n ObjectExpr) exprNode()       {}
+func (n TemplateExpr) exprNode()     {}
+func (n GroupExpr) exprNode()        {}
+func (n DotExpr) exprNode()          {}
+func (n IndexExpr) exprNode()        {}
+func (n NewTargetExpr) exprNode()    {}
+func (n ImportMetaExpr) exprNode()   {}
+func (n NewExpr) exprNode()          {}
+func (n CallExpr) exprNode()         {}
+func (n UnaryExpr) exprNode()        {}
+func (n BinaryExpr) exprNode()       {}
+func (n CondExpr) exprNode()         {}
+func (n YieldExpr) exprNode()        {}
+func (n ArrowFunc) exprNode()        {}
+func (n CommaExpr) exprNode()        {}
+func (n JSXElement) exprNode()       {}
+func (n JSXFragment) exprNode()      {}
+func (n JSXExprContainer) exprNode() {} // not a real IExpr, used for JSX attribute values and children
@@ -55,6 +55,9 @@ func TestJS(t *testing.T) {
 		{"import { foo , bar } from 'module-name/path/to/specific/un-exported/file';", "import { foo, bar } from 'module-name/path/to/specific/un-exported/file';"},
 		{"import defaultExport, * as name from 'module-name';", "import defaultExport, * as name from 'module-name';"},
 		{"import 'module-name';", "import 'module-name';"},
+		{"import data from './data.json' with { type: 'json' };", "import data from './data.json' with { type: 'json' };"},
+		{"export * from './data.json' with { type: 'json', 'a': 'b' };", "export * from './data.json' with { type: 'json', 'a': 'b' };"},
+		{"await using a = b, c = d;", "await using a = b, c = d;"},
 		{"var promise = import('module-name');", "var promise = import('module-name');"},
 		{"export { myFunction as default }", "export { myFunction as default };"},
 		{"export default k = 12;", "export default k = 12;"},
@@ -76,6 +79,7 @@ func TestJS(t *testing.T) {
 		{"class A { field = 5; }", "class A { field = 5; }"},
 		{"class A { field; static get method () { } }", "class A { field; static get method () {} }"},
 		{"class B extends A { field; static get method () { } }", "class B extends A { field; static get method () {} }"},
+		{"@a @b.c(d) class A { @e m () { } @f static accessor g = 1; }", "@a @b.c(d) class A { @e m () {} @f static accessor g = 1; }"},
 
 		{"x = 1;", "x = 1;"},
 		{"'test';", "'test';"},
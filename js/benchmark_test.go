@@ -299,7 +299,7 @@ var listVars []*Var
 func BenchmarkInterfaceAddPtr(b *testing.B) {
 	listInterface = listInterface[:0:0]
 	for k := 0; k < b.N; k++ {
-		v := &Var{nil, nil, 0, 0}
+		v := &Var{nil, nil, 0, 0, nil}
 		listInterface = append(listInterface, v)
 	}
 }
@@ -321,7 +321,7 @@ func BenchmarkInterfaceAddPtr(b *testing.B) {
 //}
 
 func BenchmarkInterfaceCheckPtr(b *testing.B) {
-	v := &Var{nil, nil, 0, 0}
+	v := &Var{nil, nil, 0, 0, nil}
 	i := interface{}(v)
 	for k := 0; k < b.N; k++ {
 		if r, ok := i.(*Var); ok {
@@ -0,0 +1,558 @@
+// Package builder constructs JavaScript ASTs of the js package from Go. Constructors validate identifiers and operators, quote string literals, add parentheses where required by operator precedence, and declare and resolve identifiers in the scopes as the parser would, so that the AST can be written using AST.JS or js.Print.
+package builder
+
+import (
+	"fmt"
+
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// Builder builds an AST. Statements are added to the current block, and identifiers are declared in and resolved against the current scope, which are those of the function or block body being built. The first error is kept and returned by AST, later calls still return nodes but these should not be used.
+type Builder struct {
+	ast   *js.AST
+	block *js.BlockStmt // block that statements are added to
+	scope *js.Scope     // scope of the block
+	fn    bool          // inside a function
+	err   error
+}
+
+// New returns a new builder for a script or module.
+func New() *Builder {
+	ast := &js.AST{}
+	ast.BlockStmt.Scope.Func = &ast.BlockStmt.Scope
+	return &Builder{
+		ast:   ast,
+		block: &ast.BlockStmt,
+		scope: &ast.BlockStmt.Scope,
+	}
+}
+
+// AST returns the built AST and the first error.
+func (b *Builder) AST() (*js.AST, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.ast, nil
+}
+
+// Err returns the first error.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+func (b *Builder) fail(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+}
+
+// Add adds statements to the current block.
+func (b *Builder) Add(stmts ...js.IStmt) {
+	for _, stmt := range stmts {
+		if stmt == nil {
+			b.fail("statement cannot be nil")
+			return
+		}
+		b.block.List = append(b.block.List, stmt)
+	}
+}
+
+// enter starts a new scope that is a function scope if fn is set, and returns the state to restore using exit.
+func (b *Builder) enter(block *js.BlockStmt, fn bool) Builder {
+	prev := *b
+	block.Scope = js.Scope{Parent: b.scope, Func: b.scope.Func}
+	if fn {
+		block.Scope.Func = &block.Scope
+		b.fn = true
+	}
+	b.block, b.scope = block, &block.Scope
+	return prev
+}
+
+func (b *Builder) exit(prev Builder) {
+	b.scope.HoistUndeclared()
+	b.block, b.scope, b.fn = prev.block, prev.scope, prev.fn
+}
+
+func (b *Builder) declare(decl js.DeclType, name string) *js.Var {
+	if !isIdentifier(name) {
+		b.fail("invalid identifier %q", name)
+		return &js.Var{Data: []byte(name)}
+	}
+	v, ok := b.scope.Declare(decl, []byte(name))
+	if !ok {
+		b.fail("identifier %s has already been declared", name)
+		return &js.Var{Data: []byte(name)}
+	}
+	return v
+}
+
+func (b *Builder) expr(x js.IExpr) js.IExpr {
+	switch x.(type) {
+	case nil:
+		b.fail("expression cannot be nil")
+		return b.Undefined()
+	case *js.VarDecl, *js.MethodDecl:
+		b.fail("%s is not an expression", x)
+		return b.Undefined()
+	}
+	return x
+}
+
+func isIdentifier(name string) bool {
+	if !js.AsIdentifierName([]byte(name)) {
+		return false
+	}
+	tt, ok := js.Keywords[name]
+	return !ok || !js.IsReservedWord(tt) || tt == js.AwaitToken || tt == js.YieldToken
+}
+
+////////////////////////////////////////////////////////////////
+
+// Ident returns a use of a variable, which refers to the declaration in the current or a parent scope, or to a global variable.
+func (b *Builder) Ident(name string) *js.Var {
+	if !isIdentifier(name) {
+		b.fail("invalid identifier %q", name)
+		return &js.Var{Data: []byte(name)}
+	}
+	return b.scope.Use([]byte(name))
+}
+
+// This returns the this keyword.
+func (b *Builder) This() *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.ThisToken, Data: []byte("this")}
+}
+
+// Str returns a string literal, quoted and escaped as required.
+func (b *Builder) Str(s string) *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.StringToken, Data: js.QuoteString(s)}
+}
+
+// Num returns a numeric literal, or an expression for negative numbers, NaN, and Infinity.
+func (b *Builder) Num(f float64) js.IExpr {
+	return js.Value{Type: js.NumberValue, Num: f}.Expr()
+}
+
+// Bool returns true or false.
+func (b *Builder) Bool(v bool) *js.LiteralExpr {
+	return js.Value{Type: js.BooleanValue, Bool: v}.Expr().(*js.LiteralExpr)
+}
+
+// Null returns null.
+func (b *Builder) Null() *js.LiteralExpr {
+	return &js.LiteralExpr{TokenType: js.NullToken, Data: []byte("null")}
+}
+
+// Undefined returns void 0.
+func (b *Builder) Undefined() js.IExpr {
+	return js.Value{Type: js.UndefinedValue}.Expr()
+}
+
+// Array returns an array literal.
+func (b *Builder) Array(elems ...js.IExpr) *js.ArrayExpr {
+	array := &js.ArrayExpr{}
+	for _, elem := range elems {
+		array.List = append(array.List, js.Element{Value: group(b.expr(elem), js.OpAssign)})
+	}
+	return array
+}
+
+// Object returns an object literal.
+func (b *Builder) Object(props ...js.Property) *js.ObjectExpr {
+	return &js.ObjectExpr{List: props}
+}
+
+// Prop returns a property for an object literal, where the name is quoted when it is not an identifier name.
+func (b *Builder) Prop(name string, value js.IExpr) js.Property {
+	lit := js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte(name)}
+	if !js.AsIdentifierName(lit.Data) {
+		lit = *b.Str(name)
+	}
+	return js.Property{Name: &js.PropertyName{Literal: lit}, Value: group(b.expr(value), js.OpAssign)}
+}
+
+////////////////////////////////////////////////////////////////
+
+// Dot returns a member expression x.name.
+func (b *Builder) Dot(x js.IExpr, name string) *js.DotExpr {
+	if !js.AsIdentifierName([]byte(name)) {
+		b.fail("invalid property name %q", name)
+	}
+	x = member(b.expr(x))
+	return &js.DotExpr{X: x, Y: js.LiteralExpr{TokenType: js.IdentifierToken, Data: []byte(name)}, Prec: chainPrec(x)}
+}
+
+// Index returns a member expression x[y].
+func (b *Builder) Index(x, y js.IExpr) *js.IndexExpr {
+	x = member(b.expr(x))
+	return &js.IndexExpr{X: x, Y: b.expr(y), Prec: chainPrec(x)}
+}
+
+// Call returns a call expression x(args...).
+func (b *Builder) Call(x js.IExpr, args ...js.IExpr) *js.CallExpr {
+	x = member(b.expr(x))
+	return &js.CallExpr{X: x, Args: b.args(args), Prec: js.OpCall}
+}
+
+// New returns a new expression new x(args...).
+func (b *Builder) New(x js.IExpr, args ...js.IExpr) *js.NewExpr {
+	x = b.expr(x)
+	if prec(x) < js.OpMember || hasCall(x) {
+		x = &js.GroupExpr{X: x}
+	}
+	list := b.args(args)
+	return &js.NewExpr{X: x, Args: &list}
+}
+
+func (b *Builder) args(args []js.IExpr) js.Args {
+	list := js.Args{List: make([]js.Arg, len(args))}
+	for i, arg := range args {
+		list.List[i].Value = group(b.expr(arg), js.OpAssign)
+	}
+	return list
+}
+
+// Unary returns a unary expression, where op is one of NotToken, BitNotToken, PosToken, NegToken, TypeofToken, VoidToken, DeleteToken, AwaitToken, PreIncrToken, PreDecrToken, PostIncrToken, or PostDecrToken.
+func (b *Builder) Unary(op js.TokenType, x js.IExpr) *js.UnaryExpr {
+	x = b.expr(x)
+	switch op {
+	case js.NotToken, js.BitNotToken, js.PosToken, js.NegToken, js.TypeofToken, js.VoidToken, js.DeleteToken, js.AwaitToken:
+		x = group(x, js.OpUnary)
+	case js.PreIncrToken, js.PreDecrToken, js.PostIncrToken, js.PostDecrToken:
+		if !isTarget(x) {
+			b.fail("invalid update target %s", x)
+		}
+	default:
+		b.fail("invalid unary operator %s", op)
+	}
+	return &js.UnaryExpr{Op: op, X: x}
+}
+
+// Binary returns a binary expression, where op is a binary operator such as AddToken, AndToken, or InstanceofToken. Operands are parenthesized when needed.
+func (b *Builder) Binary(op js.TokenType, x, y js.IExpr) *js.BinaryExpr {
+	x, y = b.expr(x), b.expr(y)
+	p, ok := binaryPrec[op]
+	if !ok {
+		b.fail("invalid binary operator %s", op)
+		return &js.BinaryExpr{Op: op, X: x, Y: y}
+	} else if op == js.ExpToken {
+		// right associative and the base cannot be a unary expression
+		if prec(x) <= js.OpUnary {
+			x = &js.GroupExpr{X: x}
+		}
+		return &js.BinaryExpr{Op: op, X: x, Y: group(y, js.OpExp)}
+	}
+
+	x, y = group(x, p), group(y, p+1)
+	if op == js.NullishToken || op == js.AndToken || op == js.OrToken {
+		// ?? cannot be mixed with && or || without parentheses
+		for _, z := range []*js.IExpr{&x, &y} {
+			if bin, ok := (*z).(*js.BinaryExpr); ok && (op == js.NullishToken) != (bin.Op == js.NullishToken) && (bin.Op == js.NullishToken || bin.Op == js.AndToken || bin.Op == js.OrToken) {
+				*z = &js.GroupExpr{X: bin}
+			}
+		}
+	}
+	return &js.BinaryExpr{Op: op, X: x, Y: y}
+}
+
+// Assign returns an assignment expression, where op is EqToken or a compound assignment operator such as AddEqToken. The target must be a variable or member expression.
+func (b *Builder) Assign(op js.TokenType, x, y js.IExpr) *js.BinaryExpr {
+	x, y = b.expr(x), b.expr(y)
+	if !isAssignOp(op) {
+		b.fail("invalid assignment operator %s", op)
+	} else if !isTarget(x) {
+		b.fail("invalid assignment target %s", x)
+	}
+	return &js.BinaryExpr{Op: op, X: x, Y: group(y, js.OpAssign)}
+}
+
+// Cond returns a conditional expression cond ? x : y.
+func (b *Builder) Cond(cond, x, y js.IExpr) *js.CondExpr {
+	return &js.CondExpr{Cond: group(b.expr(cond), js.OpCoalesce), X: group(b.expr(x), js.OpAssign), Y: group(b.expr(y), js.OpAssign)}
+}
+
+// Arrow returns an arrow function with the given parameters, and body adds the statements of its body. A body of a single return statement is written as a concise body.
+func (b *Builder) Arrow(params []string, body func()) *js.ArrowFunc {
+	arrow := &js.ArrowFunc{}
+	prev := b.enter(&arrow.Body, true)
+	arrow.Params = b.params(params)
+	body()
+	b.exit(prev)
+	return arrow
+}
+
+// Func returns a function declaration that declares name in the current scope, with the given parameters, and body adds the statements of its body.
+func (b *Builder) Func(name string, params []string, body func()) *js.FuncDecl {
+	fn := &js.FuncDecl{Name: b.declare(js.FunctionDecl, name)}
+	prev := b.enter(&fn.Body, true)
+	fn.Params = b.params(params)
+	body()
+	b.exit(prev)
+	return fn
+}
+
+func (b *Builder) params(names []string) js.Params {
+	params := js.Params{List: make([]js.BindingElement, len(names))}
+	for i, name := range names {
+		params.List[i].Binding = b.declare(js.ArgumentDecl, name)
+	}
+	b.scope.MarkFuncArgs()
+	return params
+}
+
+////////////////////////////////////////////////////////////////
+
+// Expr returns an expression statement, where expressions starting with an object literal, function, class, or let are parenthesized.
+func (b *Builder) Expr(x js.IExpr) *js.ExprStmt {
+	x = b.expr(x)
+	switch n := leftmost(x).(type) {
+	case *js.ObjectExpr, *js.FuncDecl, *js.ClassDecl:
+		x = &js.GroupExpr{X: x}
+	case *js.Var:
+		if string(n.Data) == "let" {
+			x = &js.GroupExpr{X: x}
+		}
+	}
+	return &js.ExprStmt{Value: x}
+}
+
+// Var returns a variable declaration of name with an optional initializer, where tt is VarToken, LetToken, or ConstToken. The name is declared in the current scope, or in the function scope for var.
+func (b *Builder) Var(tt js.TokenType, name string, init js.IExpr) *js.VarDecl {
+	decl := js.LexicalDecl
+	if tt == js.VarToken {
+		decl = js.VariableDecl
+	} else if tt != js.LetToken && tt != js.ConstToken {
+		b.fail("invalid declaration %s", tt)
+	} else if tt == js.ConstToken && init == nil {
+		b.fail("missing initializer in const declaration of %s", name)
+	}
+	if init != nil {
+		init = group(init, js.OpAssign)
+	}
+	varDecl := &js.VarDecl{TokenType: tt, Scope: b.scope}
+	varDecl.List = []js.BindingElement{{Binding: b.declare(decl, name), Default: init}}
+	if tt == js.VarToken {
+		b.scope.Func.VarDecls = append(b.scope.Func.VarDecls, varDecl)
+	}
+	return varDecl
+}
+
+// Return returns a return statement with an optional value. It must be used inside a function.
+func (b *Builder) Return(x js.IExpr) *js.ReturnStmt {
+	if !b.fn {
+		b.fail("return statement outside function")
+	}
+	if x != nil {
+		x = b.expr(x)
+	}
+	return &js.ReturnStmt{Value: x}
+}
+
+// Throw returns a throw statement.
+func (b *Builder) Throw(x js.IExpr) *js.ThrowStmt {
+	return &js.ThrowStmt{Value: b.expr(x)}
+}
+
+// If returns an if statement with an optional else statement. When there is an else statement and the body ends with an if statement without else, the body is wrapped in a block so that the else belongs to this if statement.
+func (b *Builder) If(cond js.IExpr, body, els js.IStmt) *js.IfStmt {
+	if body == nil {
+		b.fail("statement cannot be nil")
+		body = &js.EmptyStmt{}
+	} else if els != nil && danglingIf(body) {
+		body = &js.BlockStmt{List: []js.IStmt{body}, Scope: js.Scope{Parent: b.scope, Func: b.scope.Func}}
+	}
+	return &js.IfStmt{Cond: b.expr(cond), Body: body, Else: els}
+}
+
+// danglingIf returns true if the statement ends with an if statement without else, which would take the else of an enclosing if statement.
+func danglingIf(stmt js.IStmt) bool {
+	switch stmt := stmt.(type) {
+	case *js.IfStmt:
+		return stmt.Else == nil || danglingIf(stmt.Else)
+	case *js.WhileStmt:
+		return danglingIf(stmt.Body)
+	case *js.WithStmt:
+		return danglingIf(stmt.Body)
+	case *js.LabelledStmt:
+		return danglingIf(stmt.Value)
+	}
+	return false
+}
+
+// Block returns a block statement with its own scope, and body adds its statements.
+func (b *Builder) Block(body func()) *js.BlockStmt {
+	block := &js.BlockStmt{}
+	prev := b.enter(block, false)
+	body()
+	b.exit(prev)
+	return block
+}
+
+////////////////////////////////////////////////////////////////
+
+var binaryPrec = map[js.TokenType]js.OpPrec{
+	js.NullishToken:    js.OpCoalesce,
+	js.OrToken:         js.OpOr,
+	js.AndToken:        js.OpAnd,
+	js.BitOrToken:      js.OpBitOr,
+	js.BitXorToken:     js.OpBitXor,
+	js.BitAndToken:     js.OpBitAnd,
+	js.EqEqToken:       js.OpEquals,
+	js.NotEqToken:      js.OpEquals,
+	js.EqEqEqToken:     js.OpEquals,
+	js.NotEqEqToken:    js.OpEquals,
+	js.LtToken:         js.OpCompare,
+	js.GtToken:         js.OpCompare,
+	js.LtEqToken:       js.OpCompare,
+	js.GtEqToken:       js.OpCompare,
+	js.InstanceofToken: js.OpCompare,
+	js.InToken:         js.OpCompare,
+	js.LtLtToken:       js.OpShift,
+	js.GtGtToken:       js.OpShift,
+	js.GtGtGtToken:     js.OpShift,
+	js.AddToken:        js.OpAdd,
+	js.SubToken:        js.OpAdd,
+	js.MulToken:        js.OpMul,
+	js.DivToken:        js.OpMul,
+	js.ModToken:        js.OpMul,
+	js.ExpToken:        js.OpExp,
+}
+
+func isAssignOp(op js.TokenType) bool {
+	switch op {
+	case js.EqToken, js.AddEqToken, js.SubEqToken, js.MulEqToken, js.DivEqToken, js.ModEqToken, js.ExpEqToken, js.LtLtEqToken, js.GtGtEqToken, js.GtGtGtEqToken, js.BitAndEqToken, js.BitOrEqToken, js.BitXorEqToken, js.AndEqToken, js.OrEqToken, js.NullishEqToken:
+		return true
+	}
+	return false
+}
+
+// isTarget returns true for simple assignment targets.
+func isTarget(x js.IExpr) bool {
+	switch n := x.(type) {
+	case *js.Var:
+		return true
+	case *js.DotExpr:
+		return !n.Optional && n.Prec != js.OpOpt
+	case *js.IndexExpr:
+		return !n.Optional && n.Prec != js.OpOpt
+	case *js.GroupExpr:
+		return isTarget(n.X)
+	}
+	return false
+}
+
+// prec returns the precedence of an expression.
+func prec(x js.IExpr) js.OpPrec {
+	switch n := x.(type) {
+	case *js.Var, *js.LiteralExpr, *js.ArrayExpr, *js.ObjectExpr, *js.GroupExpr, *js.FuncDecl, *js.ClassDecl:
+		return js.OpPrimary
+	case *js.DotExpr:
+		return n.Prec
+	case *js.IndexExpr:
+		return n.Prec
+	case *js.CallExpr:
+		return n.Prec
+	case *js.TemplateExpr:
+		if n.Tag == nil {
+			return js.OpPrimary
+		}
+		return n.Prec
+	case *js.NewExpr:
+		if n.Args == nil {
+			return js.OpNew
+		}
+		return js.OpMember
+	case *js.UnaryExpr:
+		if n.Op == js.PostIncrToken || n.Op == js.PostDecrToken {
+			return js.OpUpdate
+		}
+		return js.OpUnary
+	case *js.BinaryExpr:
+		if p, ok := binaryPrec[n.Op]; ok {
+			return p
+		}
+		return js.OpAssign
+	case *js.CondExpr, *js.ArrowFunc, *js.YieldExpr:
+		return js.OpAssign
+	}
+	return js.OpExpr
+}
+
+// group parenthesizes an expression when its precedence is lower than p.
+func group(x js.IExpr, p js.OpPrec) js.IExpr {
+	if prec(x) < p {
+		return &js.GroupExpr{X: x}
+	}
+	return x
+}
+
+// member parenthesizes an expression to be the object of a member or call expression.
+func member(x js.IExpr) js.IExpr {
+	if lit, ok := x.(*js.LiteralExpr); ok && js.IsNumeric(lit.TokenType) {
+		return &js.GroupExpr{X: x} // prevent 1.toString()
+	} else if n, ok := x.(*js.NewExpr); ok && n.Args == nil || prec(x) < js.OpOpt {
+		return &js.GroupExpr{X: x}
+	}
+	return x
+}
+
+// chainPrec returns the precedence of a member expression of x, which is that of a call expression when x contains a call.
+func chainPrec(x js.IExpr) js.OpPrec {
+	if p := prec(x); p < js.OpMember {
+		return p // OpCall or OpOpt
+	}
+	return js.OpMember
+}
+
+// hasCall returns true if a member expression contains a call, which must be parenthesized in a new expression.
+func hasCall(x js.IExpr) bool {
+	for {
+		switch n := x.(type) {
+		case *js.CallExpr:
+			return true
+		case *js.DotExpr:
+			x = n.X
+		case *js.IndexExpr:
+			x = n.X
+		case *js.TemplateExpr:
+			if n.Tag == nil {
+				return false
+			}
+			x = n.Tag
+		default:
+			return false
+		}
+	}
+}
+
+// leftmost returns the leftmost expression, which starts an expression statement.
+func leftmost(x js.IExpr) js.IExpr {
+	for {
+		switch n := x.(type) {
+		case *js.DotExpr:
+			x = n.X
+		case *js.IndexExpr:
+			x = n.X
+		case *js.CallExpr:
+			x = n.X
+		case *js.BinaryExpr:
+			x = n.X
+		case *js.CondExpr:
+			x = n.Cond
+		case *js.CommaExpr:
+			x = n.List[0]
+		case *js.UnaryExpr:
+			if n.Op != js.PostIncrToken && n.Op != js.PostDecrToken {
+				return n
+			}
+			x = n.X
+		case *js.TemplateExpr:
+			if n.Tag == nil {
+				return n
+			}
+			x = n.Tag
+		default:
+			return x
+		}
+	}
+}
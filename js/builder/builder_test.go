@@ -0,0 +1,128 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/js"
+	"github.com/tdewolff/test"
+)
+
+func TestBuilder(t *testing.T) {
+	var tests = []struct {
+		build    func(b *Builder)
+		expected string
+	}{
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Call(b.Dot(b.Ident("console"), "log"), b.Str("it's \"quoted\"\n"), b.Num(-1.5), b.Num(1e21))))
+		}, `console.log('it\'s "quoted"\n', -1.5, 1e+21);`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Binary(js.MulToken, b.Binary(js.AddToken, b.Ident("a"), b.Num(1)), b.Binary(js.SubToken, b.Ident("b"), b.Ident("c")))))
+		}, `(a + 1) * (b - c);`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Binary(js.SubToken, b.Ident("a"), b.Binary(js.SubToken, b.Ident("b"), b.Ident("c")))))
+		}, `a - (b - c);`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Binary(js.ExpToken, b.Unary(js.NegToken, b.Ident("a")), b.Binary(js.ExpToken, b.Ident("b"), b.Ident("c")))))
+		}, `(-a) ** b ** c;`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Binary(js.NullishToken, b.Binary(js.OrToken, b.Ident("a"), b.Ident("b")), b.Ident("c"))))
+		}, `(a || b) ?? c;`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Unary(js.NegToken, b.Num(-1))))
+		}, `- -1;`},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Call(b.Dot(b.Num(1), "toString"))))
+			b.Add(b.Expr(b.Dot(b.New(b.Ident("A")), "b")))
+			b.Add(b.Expr(b.New(b.Call(b.Ident("f")))))
+		}, "(1).toString();\nnew A().b;\nnew (f())();"},
+		{func(b *Builder) {
+			b.Add(b.Expr(b.Dot(b.Object(b.Prop("a", b.Null()), b.Prop("b-c", b.Bool(true))), "a")))
+		}, `({a: null, "b-c": true}.a);`},
+		{func(b *Builder) {
+			b.Add(b.Var(js.ConstToken, "x", b.Cond(b.Ident("a"), b.Array(b.Num(1), b.Undefined()), b.Assign(js.EqToken, b.Ident("y"), b.Num(2)))))
+		}, `const x = a ? [1, void 0] : y = 2;`},
+		{func(b *Builder) {
+			b.Add(b.Func("f", []string{"a", "b"}, func() {
+				b.Add(b.If(b.Ident("a"), b.Return(b.Ident("b")), nil))
+				b.Add(b.Return(b.Call(b.Arrow([]string{"c"}, func() {
+					b.Add(b.Return(b.Binary(js.AddToken, b.Ident("c"), b.This())))
+				}), b.Ident("a"))))
+			}))
+		}, "function f(a, b) {\n    if (a) return b;\n    return ((c) => {\n        return c + this;\n    })(a);\n}"},
+		{func(b *Builder) {
+			b.Add(b.If(b.Ident("a"), b.If(b.Ident("b"), b.Expr(b.Ident("c")), nil), b.Expr(b.Ident("d"))))
+			b.Add(b.If(b.Ident("a"), b.If(b.Ident("b"), b.Expr(b.Ident("c")), b.Expr(b.Ident("d"))), b.Expr(b.Ident("e"))))
+		}, "if (a) {\n    if (b) c;\n} else d;\nif (a) if (b) c; else d; else e;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			b := New()
+			tt.build(b)
+			ast, err := b.AST()
+			test.Error(t, err)
+			src := ast.JSString()
+			test.String(t, src, tt.expected)
+
+			_, err = js.Parse(parse.NewInputString(src), js.Options{})
+			test.Error(t, err)
+		})
+	}
+}
+
+func TestBuilderScope(t *testing.T) {
+	b := New()
+	b.Add(b.Var(js.LetToken, "a", b.Num(1)))
+	var inner, outer, global *js.Var
+	b.Add(b.Func("f", []string{"x"}, func() {
+		b.Add(b.Block(func() {
+			b.Add(b.Var(js.VarToken, "y", b.Ident("x")))
+			inner = b.Ident("y")
+		}))
+		outer = b.Ident("a")
+		global = b.Ident("g")
+	}))
+	ast, err := b.AST()
+	test.Error(t, err)
+
+	f := ast.List[1].(*js.FuncDecl)
+	test.T(t, inner.Decl, js.VariableDecl)
+	test.T(t, len(f.Body.Scope.Declared), 2) // x and y
+	test.T(t, f.Body.Scope.VarDecls[0].List[0].Binding, js.IBinding(inner))
+	test.T(t, outer.Link, ast.BlockStmt.Scope.Declared[0])
+	test.T(t, global.Decl, js.NoDecl)
+	test.T(t, len(ast.BlockStmt.Scope.Undeclared), 1)
+	test.T(t, f.Name, ast.BlockStmt.Scope.Declared[1])
+}
+
+func TestBuilderErrors(t *testing.T) {
+	var tests = []struct {
+		build func(b *Builder)
+		err   string
+	}{
+		{func(b *Builder) { b.Ident("class") }, `invalid identifier "class"`},
+		{func(b *Builder) { b.Ident("a-b") }, `invalid identifier "a-b"`},
+		{func(b *Builder) { b.Dot(b.Ident("a"), "1") }, `invalid property name "1"`},
+		{func(b *Builder) { b.Binary(js.EqToken, b.Ident("a"), b.Ident("b")) }, `invalid binary operator =`},
+		{func(b *Builder) { b.Unary(js.AddToken, b.Ident("a")) }, `invalid unary operator +`},
+		{func(b *Builder) { b.Unary(js.PreIncrToken, b.Num(1)) }, `invalid update target 1`},
+		{func(b *Builder) { b.Assign(js.EqToken, b.Call(b.Ident("f")), b.Num(1)) }, `invalid assignment target (f())`},
+		{func(b *Builder) { b.Assign(js.AddToken, b.Ident("a"), b.Num(1)) }, `invalid assignment operator +`},
+		{func(b *Builder) { b.Var(js.ConstToken, "a", nil) }, `missing initializer in const declaration of a`},
+		{func(b *Builder) { b.Var(js.LetToken, "a", nil); b.Var(js.VarToken, "a", nil) }, `identifier a has already been declared`},
+		{func(b *Builder) { b.Return(nil) }, `return statement outside function`},
+		{func(b *Builder) { b.Add(nil) }, `statement cannot be nil`},
+		{func(b *Builder) { b.Call(nil) }, `expression cannot be nil`},
+		{func(b *Builder) { b.Func("f", nil, func() { b.Return(b.Var(js.LetToken, "a", nil)) }) }, `Decl(let Binding(a)) is not an expression`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			b := New()
+			tt.build(b)
+			_, err := b.AST()
+			test.That(t, err != nil)
+			test.String(t, err.Error(), tt.err)
+			test.T(t, b.Err(), err)
+		})
+	}
+}
@@ -0,0 +1,710 @@
+package js
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// CommonJSIssue is a use of require, module, exports, __dirname, or __filename that ConvertCommonJS left unchanged, as it cannot be converted safely.
+type CommonJSIssue struct {
+	Message string
+	Loc     Loc
+}
+
+// ConvertCommonJS converts a CommonJS module into an ES module. Top-level statements and declarations that call require with a string literal are converted into import statements, where const a = require('m') imports the default export, const {a, b: c} = require('m') imports named exports, and const a = require('m').b imports b. A top-level assignment to module.exports is converted into a default export, and the properties of an object literal that refer to top-level variables are exported by name as well. Top-level assignments to exports.a or module.exports.a are converted into named exports, reads of exports.a are replaced by the exported variable, and an object with all named exports is exported as default. That is, the default export is the value of module.exports, which is also how Node and bundlers import CommonJS modules. Modules that are marked by Object.defineProperty(exports, '__esModule', {value: true}) or exports.__esModule = true, as compilers do for ES modules, are converted back instead: the marker is removed, exports.default becomes the default export, and no object with all named exports is exported. Note that imports are hoisted so that required modules may be evaluated earlier.
+//
+// It returns all uses of require, module, exports, __dirname, and __filename that have not been converted in source order, such as require calls inside functions or with other arguments, bindings of require calls that are reassigned, and properties of exports that are assigned more than once. As the exports object does not exist in ES modules, the exports are converted only if all uses of exports and module.exports can be converted, and the other assignments and reads are returned as well. When module or exports is used as a value, such as in f(exports), the exports object may be modified anywhere and the module is not converted at all.
+func ConvertCommonJS(ast *AST) []CommonJSIssue {
+	c := &commonJSConverter{
+		ast:     ast,
+		planned: map[IStmt]bool{},
+		assigns: map[string][]*ExprStmt{},
+		writes:  map[string]bool{},
+		reads:   map[string][]commonJSRead{},
+	}
+	for _, v := range ast.Scope.Undeclared {
+		if v = v.Resolve(); v.Decl != NoDecl {
+			continue
+		}
+		switch string(v.Data) {
+		case "require":
+			c.require = v
+		case "module":
+			c.module = v
+		case "exports":
+			c.exports = v
+		case "__dirname", "__filename":
+			c.globals = append(c.globals, v)
+		}
+	}
+	if c.require == nil && c.module == nil && c.exports == nil && c.globals == nil {
+		return nil
+	}
+
+	c.sa = AnalyzeScopes(ast)
+	c.plan()
+	Walk(c, ast)
+	c.convert()
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		return c.issues[i].Loc.Start < c.issues[j].Loc.Start
+	})
+	return c.issues
+}
+
+// commonJSRead is a read of exports.name in a scope.
+type commonJSRead struct {
+	dot   *DotExpr
+	scope *Scope
+}
+
+// commonJSConverter finds the top-level statements that can be converted, and visits the AST to find all other uses of the CommonJS variables.
+type commonJSConverter struct {
+	ast                      *AST
+	require, module, exports *Var   // undeclared variables, nil if not used
+	globals                  []*Var // __dirname and __filename
+	sa                       *ScopeAnalysis
+	issues                   []CommonJSIssue
+
+	planned  map[IStmt]bool         // top-level statements that are converted if possible
+	escaped  bool                   // module, exports, or module.exports is used as a value
+	markers  []*ExprStmt            // statements that set exports.__esModule
+	defaults []*ExprStmt            // assignments to module.exports
+	names    []string               // names of exports in order of their first assignment
+	assigns  map[string][]*ExprStmt // top-level assignments to exports.name
+	writes   map[string]bool        // names of exports that are written to elsewhere
+	reads    map[string][]commonJSRead
+	declared []*Var // variables of the converted named exports, declared in the module scope only if all exports are converted
+
+	locs   []Loc // locations of the visited nodes
+	scopes []*Scope
+}
+
+func (c *commonJSConverter) issue(loc Loc, msg string, a ...interface{}) {
+	c.issues = append(c.issues, CommonJSIssue{fmt.Sprintf(msg, a...), loc})
+}
+
+// isVar returns true if expr is the variable v.
+func isVar(expr IExpr, v *Var) bool {
+	x, ok := expr.(*Var)
+	return ok && v != nil && x.Resolve() == v
+}
+
+// propertyName returns the name of a property such as in a.name.
+func propertyName(dot *DotExpr) ([]byte, bool) {
+	if dot.Optional {
+		return nil, false
+	}
+	y, ok := dot.Y.(LiteralExpr)
+	return y.Data, ok
+}
+
+// isModuleExports returns true if expr is module.exports.
+func (c *commonJSConverter) isModuleExports(expr IExpr) bool {
+	dot, ok := expr.(*DotExpr)
+	if !ok || !isVar(dot.X, c.module) {
+		return false
+	}
+	name, ok := propertyName(dot)
+	return ok && string(name) == "exports"
+}
+
+// exportsProperty returns the name if expr is exports.name or module.exports.name.
+func (c *commonJSConverter) exportsProperty(expr IExpr) (string, bool) {
+	dot, ok := expr.(*DotExpr)
+	if !ok || !isVar(dot.X, c.exports) && !c.isModuleExports(dot.X) {
+		return "", false
+	}
+	name, ok := propertyName(dot)
+	return string(name), ok
+}
+
+// requireCall returns the module specifier if expr is a call to require with a string literal.
+func (c *commonJSConverter) requireCall(expr IExpr) ([]byte, bool) {
+	call, ok := expr.(*CallExpr)
+	if !ok || call.Optional || !isVar(call.X, c.require) || len(call.Args.List) != 1 || call.Args.List[0].Rest {
+		return nil, false
+	}
+	switch arg := call.Args.List[0].Value.(type) {
+	case *LiteralExpr:
+		if arg.TokenType == StringToken {
+			return arg.Data, true
+		}
+	case *TemplateExpr:
+		if arg.Tag == nil && len(arg.List) == 0 {
+			return QuoteString(stringValue(arg.Tail)), true
+		}
+	}
+	return nil, false
+}
+
+// requireExpr returns the module specifier if expr is require('m') or require('m').name, and the name if present.
+func (c *commonJSConverter) requireExpr(expr IExpr) ([]byte, []byte, bool) {
+	if dot, ok := expr.(*DotExpr); ok {
+		if name, ok := propertyName(dot); ok {
+			if specifier, ok := c.requireCall(dot.X); ok {
+				return specifier, name, true
+			}
+		}
+		return nil, nil, false
+	}
+	specifier, ok := c.requireCall(expr)
+	return specifier, nil, ok
+}
+
+// isESModuleMarker returns true if expr is Object.defineProperty(exports, '__esModule', {...}) or exports.__esModule = true, which compilers add to CommonJS modules that were ES modules.
+func (c *commonJSConverter) isESModuleMarker(expr IExpr) bool {
+	switch n := expr.(type) {
+	case *BinaryExpr:
+		name, ok := c.exportsProperty(n.X)
+		lit, isLit := n.Y.(*LiteralExpr)
+		return n.Op == EqToken && ok && name == "__esModule" && isLit && lit.TokenType == TrueToken
+	case *CallExpr:
+		dot, ok := n.X.(*DotExpr)
+		if !ok || n.Optional || len(n.Args.List) != 3 || n.Args.List[2].Rest {
+			return false
+		}
+		object, ok := dot.X.(*Var)
+		if !ok || object.Resolve().Decl != NoDecl || string(object.Data) != "Object" {
+			return false
+		} else if name, ok := propertyName(dot); !ok || string(name) != "defineProperty" {
+			return false
+		}
+		key, ok := n.Args.List[1].Value.(*LiteralExpr)
+		_, isObject := n.Args.List[2].Value.(*ObjectExpr)
+		return isVar(n.Args.List[0].Value, c.exports) && ok && key.TokenType == StringToken && stringValue(key.Data) == "__esModule" && isObject
+	}
+	return false
+}
+
+// exportName returns the name of an import or export for a property key, which is quoted only if it is not an identifier name.
+func exportName(key LiteralExpr) []byte {
+	if key.TokenType == StringToken {
+		if name := stringValue(key.Data); AsIdentifierName([]byte(name)) {
+			return []byte(name)
+		}
+	}
+	return key.Data
+}
+
+// plan finds the top-level statements that may be converted.
+func (c *commonJSConverter) plan() {
+	for _, item := range c.ast.List {
+		switch stmt := item.(type) {
+		case *ExprStmt:
+			if _, ok := c.requireCall(stmt.Value); ok {
+				c.planned[stmt] = true
+			} else if c.isESModuleMarker(stmt.Value) {
+				c.markers = append(c.markers, stmt)
+				c.planned[stmt] = true
+			} else if assign, ok := stmt.Value.(*BinaryExpr); ok && assign.Op == EqToken {
+				if c.isModuleExports(assign.X) {
+					c.defaults = append(c.defaults, stmt)
+					c.planned[stmt] = true
+				} else if name, ok := c.exportsProperty(assign.X); ok {
+					if _, ok := c.assigns[name]; !ok {
+						c.names = append(c.names, name)
+					}
+					c.assigns[name] = append(c.assigns[name], stmt)
+					c.planned[stmt] = true
+				}
+			}
+		case *VarDecl:
+			for _, item := range stmt.List {
+				if _, _, ok := c.requireExpr(item.Default); ok {
+					c.planned[stmt] = true
+				}
+			}
+		}
+	}
+}
+
+func (c *commonJSConverter) Enter(n INode) IVisitor {
+	loc := NodeLoc(n)
+	if !loc.IsSet() && 0 < len(c.locs) {
+		loc = c.locs[len(c.locs)-1]
+	}
+
+	switch n := n.(type) {
+	case *ExprStmt:
+		if c.planned[n] {
+			if assign, ok := n.Value.(*BinaryExpr); ok {
+				if _, ok := c.requireCall(assign.Y); !ok || !c.isModuleExports(assign.X) {
+					Walk(c, assign.Y)
+				}
+			}
+			return nil
+		}
+	case *VarDecl:
+		if c.planned[n] {
+			for i, item := range n.List {
+				if _, _, ok := c.requireExpr(item.Default); !ok {
+					Walk(c, &n.List[i])
+				}
+			}
+			return nil
+		}
+	case *CallExpr:
+		if isVar(n.X, c.require) {
+			if _, ok := c.requireCall(n); ok {
+				c.issue(loc, "require call is not in a top-level statement or declaration")
+			} else {
+				c.issue(loc, "require is not called with a string literal")
+			}
+			Walk(c, &n.Args)
+			return nil
+		}
+	case *BinaryExpr:
+		if isAssignment(n.Op) {
+			if name, ok := c.exportsProperty(n.X); ok {
+				c.writes[name] = true
+				c.issue(loc, "exports.%s is assigned outside of a top-level statement", name)
+				Walk(c, n.Y)
+				return nil
+			}
+		}
+	case *UnaryExpr:
+		if n.Op == PreIncrToken || n.Op == PreDecrToken || n.Op == PostIncrToken || n.Op == PostDecrToken || n.Op == DeleteToken {
+			if name, ok := c.exportsProperty(n.X); ok {
+				c.writes[name] = true
+				c.issue(loc, "exports.%s is assigned outside of a top-level statement", name)
+				return nil
+			}
+		}
+	case *DotExpr:
+		if name, ok := c.exportsProperty(n); ok {
+			c.reads[name] = append(c.reads[name], commonJSRead{n, c.scopes[len(c.scopes)-1]})
+			return nil
+		} else if c.isModuleExports(n) {
+			c.escaped = true
+			c.issue(loc, "module.exports is used other than by a top-level assignment")
+			return nil
+		}
+	case *Var:
+		switch v := n.Resolve(); {
+		case v == c.require:
+			c.issue(loc, "require is used other than by a call")
+		case v == c.module || v == c.exports:
+			c.escaped = true
+			c.issue(loc, "%s is used other than by assigning to its properties", v.Data)
+		case v.Decl == NoDecl && VarArray(c.globals).contains(v):
+			c.issue(loc, "%s is not available in ES modules", v.Data)
+		}
+	case *BlockStmt:
+		c.scopes = append(c.scopes, &n.Scope)
+	case *SwitchStmt:
+		c.scopes = append(c.scopes, &n.Scope)
+	case *ClassDecl:
+		c.scopes = append(c.scopes, &n.Scope)
+	}
+	c.locs = append(c.locs, loc)
+	return c
+}
+
+func (c *commonJSConverter) Exit(n INode) {
+	switch n.(type) {
+	case *BlockStmt, *SwitchStmt, *ClassDecl:
+		c.scopes = c.scopes[:len(c.scopes)-1]
+	}
+	c.locs = c.locs[:len(c.locs)-1]
+}
+
+// commonJSExport is a converted named export and the variable that holds its value.
+type commonJSExport struct {
+	name string
+	v    *Var
+}
+
+// commonJSConversion is a statement that sets the exports object, and the statements that replace it.
+type commonJSConversion struct {
+	stmt  *ExprStmt
+	name  string
+	stmts []IStmt
+}
+
+// convert converts the planned statements that are safe to convert, and reports the others.
+func (c *commonJSConverter) convert() {
+	if c.escaped {
+		return
+	}
+	replacements := map[IStmt][]IStmt{}
+	olds := map[IStmt]INode{} // declarations before their items were converted
+	imports := []*Var{}
+
+	// imports
+	for _, item := range c.ast.List {
+		if decl, ok := item.(*VarDecl); ok && c.planned[decl] {
+			old := *decl
+			stmts, vars := c.convertDecl(decl)
+			if vars != nil {
+				replacements[decl] = stmts
+				if stmts[len(stmts)-1] == decl {
+					olds[decl] = &old
+				}
+				imports = append(imports, vars...)
+			}
+		} else if stmt, ok := item.(*ExprStmt); ok && c.planned[stmt] {
+			if specifier, ok := c.requireCall(stmt.Value); ok {
+				replacements[stmt] = []IStmt{&ImportStmt{Module: specifier, Loc: stmt.Loc}}
+			}
+		}
+	}
+
+	// exports
+	hasDefault := false
+	for _, item := range c.ast.List {
+		if exportStmt, ok := item.(*ExportStmt); ok && exportStmt.Default {
+			hasDefault = true
+		}
+	}
+	exports := []commonJSExport{}
+	converts := []commonJSConversion{}
+	complete := len(c.writes) == 0
+	if 0 < len(c.defaults) && 0 < len(c.names) {
+		for _, stmt := range c.defaults {
+			c.issue(stmt.Loc, "module.exports is assigned while exports.%s is assigned as well", c.names[0])
+		}
+		for _, name := range c.names {
+			for _, stmt := range c.assigns[name] {
+				c.issue(stmt.Loc, "exports.%s is assigned while module.exports is assigned as well", name)
+			}
+		}
+		complete = false
+	} else if 1 < len(c.defaults) {
+		for _, stmt := range c.defaults {
+			c.issue(stmt.Loc, "module.exports is assigned more than once")
+		}
+		complete = false
+	} else if hasDefault && len(c.defaults) == 1 {
+		c.issue(c.defaults[0].Loc, "module.exports is assigned while the module has a default export")
+		complete = false
+	} else if len(c.defaults) == 1 {
+		stmt := c.defaults[0]
+		for _, marker := range c.markers {
+			c.issue(marker.Loc, "exports.__esModule is set while module.exports is assigned")
+			complete = false
+		}
+		converts = append(converts, commonJSConversion{stmt, "module.exports", c.convertDefault(stmt)})
+	} else {
+		for _, stmt := range c.markers {
+			converts = append(converts, commonJSConversion{stmt, "exports.__esModule", []IStmt{}})
+		}
+		for _, name := range c.names {
+			stmt := c.assigns[name][0]
+			if exportStmt, v := c.convertNamed(name, hasDefault); exportStmt != nil {
+				converts = append(converts, commonJSConversion{stmt, "exports." + name, []IStmt{exportStmt}})
+				if v != nil {
+					exports = append(exports, commonJSExport{name, v})
+				}
+			} else {
+				complete = false
+			}
+		}
+	}
+
+	// reads of exports that are not converted
+	converted := map[string]*Var{}
+	for _, export := range exports {
+		converted[export.name] = export.v
+	}
+	reads := map[*DotExpr]*Var{}
+	for name, list := range c.reads {
+		for _, read := range list {
+			if v := converted[name]; v == nil {
+				c.issue(read.dot.Loc, "exports.%s is read but not converted", name)
+				complete = false
+			} else if read.scope.shadows(&c.ast.Scope, v.Data) {
+				c.issue(read.dot.Loc, "exports.%s is read where %s refers to another variable", name, v.Data)
+				complete = false
+			} else {
+				reads[read.dot] = v
+			}
+		}
+	}
+
+	if complete {
+		for _, convert := range converts {
+			replacements[convert.stmt] = convert.stmts
+		}
+		c.ast.Scope.Declared = append(c.ast.Scope.Declared, c.declared...) // exported declarations are not in VarDecls, as they cannot be hoisted
+	} else {
+		for _, convert := range converts {
+			c.issue(convert.stmt.Loc, "%s is not converted, as other uses of exports are not converted", convert.name)
+		}
+		for read := range reads {
+			name, _ := propertyName(read)
+			c.issue(read.Loc, "exports.%s is not converted, as other uses of exports are not converted", name)
+		}
+		exports, reads = nil, nil
+	}
+
+	// replace the statements and update the variables of the module scope
+	if len(replacements) == 0 && len(reads) == 0 {
+		return
+	}
+	w := &rewriter{scopes: []*Scope{&c.ast.Scope}}
+	list := make([]IStmt, 0, len(c.ast.List))
+	for _, item := range c.ast.List {
+		stmts, ok := replacements[item]
+		if !ok {
+			list = append(list, item)
+			continue
+		}
+		old, ok := olds[item]
+		if !ok {
+			old = item
+		}
+		w.replace(old, &BlockStmt{List: stmts})
+		list = append(list, stmts...)
+	}
+	if 0 < len(exports) && !hasDefault && len(c.markers) == 0 {
+		// export all named exports as default, like the exports object
+		object := &ObjectExpr{}
+		for _, export := range exports {
+			if string(export.v.Data) == export.name {
+				object.List = append(object.List, Property{Value: export.v})
+			} else {
+				name := &PropertyName{Literal: LiteralExpr{TokenType: IdentifierToken, Data: []byte(export.name)}}
+				object.List = append(object.List, Property{Name: name, Value: export.v})
+			}
+		}
+		exportStmt := &ExportStmt{Default: true, Decl: object}
+		w.replace(&EmptyStmt{}, exportStmt)
+		list = append(list, exportStmt)
+	}
+	c.ast.List = list
+
+	// import bindings are undeclared in the module scope, as when parsed
+	scope := &c.ast.Scope
+	for _, v := range imports {
+		for i, w := range scope.Declared {
+			if w == v {
+				scope.Declared = append(scope.Declared[:i], scope.Declared[i+1:]...)
+				v.Decl = NoDecl
+				scope.Undeclared = append(scope.Undeclared, v)
+				break
+			}
+		}
+	}
+
+	if 0 < len(reads) {
+		Rewrite(&commonJSRewriter{reads}, c.ast)
+	}
+}
+
+// convertDecl converts the items of a declaration that call require into imports, and returns the statements that replace the declaration and the variables that are imported, or nil if no item can be converted. The declaration is modified to hold the other items.
+func (c *commonJSConverter) convertDecl(decl *VarDecl) ([]IStmt, []*Var) {
+	stmts := []IStmt{}
+	vars := []*Var{}
+	list := []BindingElement{}
+	for _, item := range decl.List {
+		specifier, name, ok := c.requireExpr(item.Default)
+		if !ok {
+			list = append(list, item)
+			continue
+		}
+
+		importStmt := &ImportStmt{Module: specifier, Loc: decl.Loc}
+		switch binding := item.Binding.(type) {
+		case *Var:
+			if c.sa.IsReassigned(binding) {
+				c.issue(item.Loc, "binding %s of require call is reassigned", binding.Data)
+				list = append(list, item)
+				continue
+			} else if name == nil {
+				importStmt.Default = binding.Data
+			} else if string(name) == "default" {
+				c.issue(item.Loc, "default property of require call cannot be imported by name")
+				list = append(list, item)
+				continue
+			} else if bytes.Equal(name, binding.Data) {
+				importStmt.List = []Alias{{Binding: binding.Data}}
+			} else {
+				importStmt.List = []Alias{{Name: name, Binding: binding.Data}}
+			}
+			vars = append(vars, binding)
+		case *BindingObject:
+			aliases, bindings, msg := c.importAliases(binding)
+			if name != nil {
+				msg = "destructuring pattern of a property of require call cannot be imported"
+			}
+			if msg != "" {
+				c.issue(item.Loc, msg)
+				list = append(list, item)
+				continue
+			}
+			importStmt.List = aliases
+			vars = append(vars, bindings...)
+		default:
+			c.issue(item.Loc, "destructuring pattern of require call cannot be imported")
+			list = append(list, item)
+			continue
+		}
+		stmts = append(stmts, importStmt)
+	}
+	if len(vars) == 0 {
+		return nil, nil
+	} else if 0 < len(list) {
+		decl.List = list
+		stmts = append(stmts, decl)
+	}
+	return stmts, vars
+}
+
+// importAliases returns the aliases of named imports for an object destructuring pattern, or a message why it cannot be converted.
+func (c *commonJSConverter) importAliases(binding *BindingObject) ([]Alias, []*Var, string) {
+	if binding.Rest != nil {
+		return nil, nil, "destructuring pattern of require call has a rest element"
+	}
+	aliases := []Alias{}
+	vars := []*Var{}
+	for _, item := range binding.List {
+		v, ok := item.Value.Binding.(*Var)
+		if !ok || item.Value.Default != nil || item.Key != nil && item.Key.IsComputed() {
+			return nil, nil, "destructuring pattern of require call has defaults, nested patterns, or computed keys"
+		} else if c.sa.IsReassigned(v) {
+			return nil, nil, fmt.Sprintf("binding %s of require call is reassigned", v.Data)
+		}
+
+		var name []byte
+		if item.Key != nil {
+			name = exportName(item.Key.Literal)
+		}
+		if string(name) == "default" || name == nil && string(v.Data) == "default" {
+			return nil, nil, "default property of require call cannot be imported by name"
+		} else if bytes.Equal(name, v.Data) {
+			name = nil
+		}
+		aliases = append(aliases, Alias{Name: name, Binding: v.Data})
+		vars = append(vars, v)
+	}
+	return aliases, vars, ""
+}
+
+// convertDefault converts an assignment to module.exports into a default export. Properties of an object literal that refer to top-level variables are exported by name, and module.exports = require('m') is converted into a re-export.
+func (c *commonJSConverter) convertDefault(stmt *ExprStmt) []IStmt {
+	value := stmt.Value.(*BinaryExpr).Y
+	if specifier, ok := c.requireCall(value); ok {
+		return []IStmt{
+			&ExportStmt{List: []Alias{{Binding: []byte("*")}}, Module: specifier, Loc: stmt.Loc},
+			&ExportStmt{List: []Alias{{Binding: []byte("default")}}, Module: specifier, Loc: stmt.Loc},
+		}
+	}
+
+	stmts := []IStmt{&ExportStmt{Default: true, Decl: groupExpr(value, OpAssign), Loc: stmt.Loc}}
+	if object, ok := value.(*ObjectExpr); ok {
+		aliases := []Alias{}
+		for _, property := range object.List {
+			if property.Spread || property.Name != nil && (property.Name.IsComputed() || IsNumeric(property.Name.Literal.TokenType)) {
+				continue
+			}
+			v, ok := property.Value.(*Var)
+			if !ok || property.Init != nil || !c.isTopLevel(v) {
+				continue
+			}
+			var name []byte
+			if property.Name != nil {
+				name = exportName(property.Name.Literal)
+			}
+			if string(name) == "default" || name == nil && string(v.Data) == "default" {
+				continue
+			} else if name == nil || bytes.Equal(name, v.Data) {
+				aliases = append(aliases, Alias{Binding: v.Data})
+			} else {
+				aliases = append(aliases, Alias{Name: v.Data, Binding: name})
+			}
+		}
+		if 0 < len(aliases) {
+			stmts = append(stmts, &ExportStmt{List: aliases, Loc: stmt.Loc})
+		}
+	}
+	return stmts
+}
+
+// isTopLevel returns true if the variable is declared in the module scope and never reassigned, so that it can be exported by name.
+func (c *commonJSConverter) isTopLevel(v *Var) bool {
+	v = v.Resolve()
+	for _, w := range c.ast.Scope.Declared {
+		if w == v {
+			return v.Decl != NoDecl && !c.sa.IsReassigned(v)
+		}
+	}
+	return false
+}
+
+// convertNamed converts the assignment to exports.name into a named export, and returns the export statement and the variable that holds the value, or nil if it cannot be converted. For modules marked by __esModule, exports.default is converted into a default export without a variable.
+func (c *commonJSConverter) convertNamed(name string, hasDefault bool) (*ExportStmt, *Var) {
+	assigns := c.assigns[name]
+	stmt := assigns[0]
+	value := stmt.Value.(*BinaryExpr).Y
+	if 1 < len(assigns) || c.writes[name] {
+		for _, stmt := range assigns {
+			c.issue(stmt.Loc, "exports.%s is assigned more than once", name)
+		}
+		return nil, nil
+	} else if name == "default" && len(c.markers) == 0 {
+		c.issue(stmt.Loc, "exports.default cannot be exported by name, as the default export is module.exports")
+		return nil, nil
+	} else if name == "default" && hasDefault {
+		c.issue(stmt.Loc, "exports.default is assigned while the module has a default export")
+		return nil, nil
+	} else if name == "default" {
+		return &ExportStmt{Default: true, Decl: groupExpr(value, OpAssign), Loc: stmt.Loc}, nil
+	}
+
+	if v, ok := value.(*Var); ok && c.isTopLevel(v) {
+		v = v.Resolve()
+		if string(v.Data) == name {
+			return &ExportStmt{List: []Alias{{Binding: v.Data}}, Loc: stmt.Loc}, v
+		}
+		return &ExportStmt{List: []Alias{{Name: v.Data, Binding: []byte(name)}}, Loc: stmt.Loc}, v
+	} else if !isBindingName([]byte(name)) {
+		c.issue(stmt.Loc, "exports.%s cannot be declared as %s is not a valid identifier", name, name)
+		return nil, nil
+	}
+	// undeclared variables of inner scopes are undeclared in the module scope as well, and inner declarations only shadow the reads of exports.name
+	for _, vs := range []VarArray{c.ast.Scope.Declared, c.ast.Scope.Undeclared} {
+		for _, v := range vs {
+			if string(v.Data) == name {
+				c.issue(stmt.Loc, "exports.%s cannot be declared as %s is already used", name, name)
+				return nil, nil
+			}
+		}
+	}
+
+	// a var declaration has no temporal dead zone, so that reads before the assignment are undefined as for CommonJS
+	v := &Var{Data: []byte(name), Decl: VariableDecl}
+	decl := &VarDecl{TokenType: VarToken, List: []BindingElement{{Binding: v, Default: value}}, Scope: &c.ast.Scope, Loc: stmt.Loc}
+	c.declared = append(c.declared, v)
+	return &ExportStmt{Decl: decl, Loc: stmt.Loc}, v
+}
+
+// shadows returns true if a scope between s and the given ancestor declares a variable with the given name.
+func (s *Scope) shadows(ancestor *Scope, name []byte) bool {
+	for ; s != nil && s != ancestor; s = s.Parent {
+		for _, v := range s.Declared {
+			if bytes.Equal(v.Data, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commonJSRewriter replaces reads of exports.name by the exported variables.
+type commonJSRewriter struct {
+	reads map[*DotExpr]*Var
+}
+
+func (r *commonJSRewriter) Enter(n INode) IRewriter {
+	return r
+}
+
+func (r *commonJSRewriter) Exit(n INode) INode {
+	if dot, ok := n.(*DotExpr); ok {
+		if v, ok := r.reads[dot]; ok {
+			return v
+		}
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+package js
+
+import (
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestConvertCommonJS(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"a = 1", "a = 1;"},
+		{"require('a')", "import 'a';"},
+		{"const a = require('a'); a()", "import a from 'a';\na();"},
+		{"const a = require(`a`)", "import a from \"a\";"},
+		{"const {a, b: c} = require('m')", "import { a, b as c } from 'm';"},
+		{"const {'a': b, 'c-d': e} = require('m')", "import { a as b, 'c-d' as e } from 'm';"},
+		{"const a = require('m').b", "import { b as a } from 'm';"},
+		{"const a = require('m').a", "import { a } from 'm';"},
+		{"const a = require('a'), b = 1", "import a from 'a';\nconst b = 1;"},
+		{"let a = f(), b = require('b')", "import b from 'b';\nlet a = f();"},
+		{"module.exports = 1", "export default 1;"},
+		{"module.exports = (a, b)", "export default (a,b);"},
+		{"module.exports = require('a')", "export * from 'a';\nexport { default } from 'a';"},
+		{"function f() {} module.exports = {f, g: f, 'h': f, 1: f, i: 2}", "function f() {}\nexport default {f, g: f, h: f, 1: f, i: 2};\nexport { f, f as g, f as h };"},
+		{"let a = 1; a++; module.exports = {a}", "let a = 1;\na++;\nexport default {a};"},
+		{"exports.a = 1", "export var a = 1;\nexport default {a};"},
+		{"exports.a = 1; module.exports.b = function() { return exports.a }", "export var a = 1;\nexport var b = function() {\n    return a;\n};\nexport default {a, b};"},
+		{"function f() {} exports.f = f; exports.g = f", "function f() {}\nexport { f };\nexport { f as g };\nexport default {f, g: f};"},
+		{"exports.a = 1; export default 2", "export var a = 1;\nexport default 2;"},
+		{"const a = require('a'); exports.b = a.b", "import a from 'a';\nexport var b = a.b;\nexport default {b};"},
+		{"Object.defineProperty(exports, '__esModule', {value: true}); exports.a = 1; exports.default = f()", "export var a = 1;\nexport default f();"},
+		{"exports.__esModule = true; exports.default = (1, 2)", "export default (1,2);"},
+		{"exports.__esModule = true; exports.a = 1", "export var a = 1;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			test.Error(t, err)
+			issues := ConvertCommonJS(ast)
+			test.T(t, len(issues), 0, "issues")
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+func TestConvertCommonJSIssues(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+		issues   []string
+	}{
+		{"function f() { return require('a') }", "function f() {\n    return require('a');\n}", []string{"require call is not in a top-level statement or declaration"}},
+		{"require(a)", "require(a);", []string{"require is not called with a string literal"}},
+		{"const r = require", "const r = require;", []string{"require is used other than by a call"}},
+		{"let a = require('a'); a = 1", "let a = require('a');\na = 1;", []string{"binding a of require call is reassigned"}},
+		{"const {a = 1} = require('a')", "const {a = 1} = require('a');", []string{"destructuring pattern of require call has defaults, nested patterns, or computed keys"}},
+		{"const {...a} = require('a')", "const {...a} = require('a');", []string{"destructuring pattern of require call has a rest element"}},
+		{"const [a] = require('a')", "const [a] = require('a');", []string{"destructuring pattern of require call cannot be imported"}},
+		{"const {default: a} = require('a')", "const {default: a} = require('a');", []string{"default property of require call cannot be imported by name"}},
+		{"const a = require('a').default", "const a = require('a').default;", []string{"default property of require call cannot be imported by name"}},
+		{"module.exports = 1; module.exports = 2", "module.exports = 1;\nmodule.exports = 2;", []string{"module.exports is assigned more than once", "module.exports is assigned more than once"}},
+		{"module.exports = 1; exports.a = 2", "module.exports = 1;\nexports.a = 2;", []string{"module.exports is assigned while exports.a is assigned as well", "exports.a is assigned while module.exports is assigned as well"}},
+		{"exports.a = 1; exports.a = 2", "exports.a = 1;\nexports.a = 2;", []string{"exports.a is assigned more than once", "exports.a is assigned more than once"}},
+		{"exports.a = 1; function f() { exports.a++ }", "exports.a = 1;\nfunction f() {\n    exports.a++;\n}", []string{"exports.a is assigned more than once", "exports.a is assigned outside of a top-level statement"}},
+		{"exports.default = 1", "exports.default = 1;", []string{"exports.default cannot be exported by name, as the default export is module.exports"}},
+		{"exports.a = 1; a", "exports.a = 1;\na;", []string{"exports.a cannot be declared as a is already used"}},
+		{"exports.a = 1; exports.b = exports.c", "exports.a = 1;\nexports.b = exports.c;", []string{"exports.a is not converted, as other uses of exports are not converted", "exports.b is not converted, as other uses of exports are not converted", "exports.c is read but not converted"}},
+		{"exports.a = 1; function f(a) { return exports.a }", "exports.a = 1;\nfunction f(a) {\n    return exports.a;\n}", []string{"exports.a is not converted, as other uses of exports are not converted", "exports.a is read where a refers to another variable"}},
+		{"exports.x = 1; exports.y = 1; exports.y = 2", "exports.x = 1;\nexports.y = 1;\nexports.y = 2;", []string{"exports.x is not converted, as other uses of exports are not converted", "exports.y is assigned more than once", "exports.y is assigned more than once"}},
+		{"exports.count = 0; exports.inc = function(){ exports.count++ }", "exports.count = 0;\nexports.inc = function() {\n    exports.count++;\n};", []string{"exports.count is assigned more than once", "exports.inc is not converted, as other uses of exports are not converted", "exports.count is assigned outside of a top-level statement"}},
+		{"exports.a = exports.b = 1", "exports.a = exports.b = 1;", []string{"exports.a is not converted, as other uses of exports are not converted", "exports.b is assigned outside of a top-level statement"}},
+		{"const a = require('a'); exports.b = a; exports.c = exports.d", "import a from 'a';\nexports.b = a;\nexports.c = exports.d;", []string{"exports.b is not converted, as other uses of exports are not converted", "exports.c is not converted, as other uses of exports are not converted", "exports.d is read but not converted"}},
+		{"f(module)", "f(module);", []string{"module is used other than by assigning to its properties"}},
+		{"f(module.exports)", "f(module.exports);", []string{"module.exports is used other than by a top-level assignment"}},
+		{"const a = require('a'); exports.b = 1; f(exports)", "const a = require('a');\nexports.b = 1;\nf(exports);", []string{"exports is used other than by assigning to its properties"}},
+		{"Object.defineProperty(exports, 'a', {value: 1}); exports.b = 1", "Object.defineProperty(exports, 'a', {value: 1});\nexports.b = 1;", []string{"exports is used other than by assigning to its properties"}},
+		{"exports.__esModule = true; exports.default = 1; export default 2", "exports.__esModule = true;\nexports.default = 1;\nexport default 2;", []string{"exports.__esModule is not converted, as other uses of exports are not converted", "exports.default is assigned while the module has a default export"}},
+		{"f(__dirname)", "f(__dirname);", []string{"__dirname is not available in ES modules"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			test.Error(t, err)
+			issues := ConvertCommonJS(ast)
+			messages := []string{}
+			for _, issue := range issues {
+				test.That(t, issue.Loc.IsSet(), "issue must have a location")
+				messages = append(messages, issue.Message)
+			}
+			test.T(t, messages, tt.issues, "issues")
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,540 @@
+package js
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"io"
+)
+
+// Equal returns true if both nodes are structurally equal. Locations, comments, parentheses, and scopes are ignored, as well as the spelling of literals that have the same value, such as 'a' and "a", or 0x10 and 16, and property names such as a and 'a'. Variables are compared by name.
+func Equal(a, b INode) bool {
+	bufA, bufB := &bytes.Buffer{}, &bytes.Buffer{}
+	(&canonicalWriter{w: bufA}).node(a)
+	(&canonicalWriter{w: bufB}).node(b)
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+// Hash returns a structural hash of the node that is equal for nodes that are Equal. The hash is stable across builds and platforms, so that it can be used to detect duplicate code or as a cache key.
+func Hash(n INode) uint64 {
+	h := fnv.New64a()
+	(&canonicalWriter{w: h}).node(n)
+	return h.Sum64()
+}
+
+// canonicalWriter writes an unambiguous encoding of a node, which contains only what is compared by Equal.
+type canonicalWriter struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *canonicalWriter) uint(i uint64) {
+	n := binary.PutUvarint(w.buf[:], i)
+	w.w.Write(w.buf[:n])
+}
+
+func (w *canonicalWriter) bool(b bool) {
+	if b {
+		w.uint(1)
+	} else {
+		w.uint(0)
+	}
+}
+
+func (w *canonicalWriter) bytes(b []byte) {
+	w.uint(uint64(len(b)))
+	w.w.Write(b)
+}
+
+func (w *canonicalWriter) string(s string) {
+	w.uint(uint64(len(s)))
+	io.WriteString(w.w, s)
+}
+
+// tag starts a node, the tag string of nil is empty.
+func (w *canonicalWriter) tag(s string) {
+	w.string(s)
+}
+
+func (w *canonicalWriter) node(n INode) {
+	switch n := n.(type) {
+	case nil:
+		w.tag("")
+	case *AST:
+		w.tag("AST")
+		w.stmts(n.List)
+	case IStmt:
+		w.stmt(n)
+	case IExpr:
+		w.expr(n)
+	case IBinding:
+		w.binding(n)
+	default:
+		w.tag(n.String())
+	}
+}
+
+func (w *canonicalWriter) stmts(list []IStmt) {
+	num := 0
+	for _, item := range list {
+		if _, ok := item.(*Comment); !ok {
+			num++
+		}
+	}
+	w.uint(uint64(num))
+	for _, item := range list {
+		if _, ok := item.(*Comment); !ok {
+			w.stmt(item)
+		}
+	}
+}
+
+func (w *canonicalWriter) block(n *BlockStmt) {
+	if n == nil {
+		w.tag("")
+		return
+	}
+	w.tag("BlockStmt")
+	w.stmts(n.List)
+}
+
+func (w *canonicalWriter) stmt(n IStmt) {
+	switch n := n.(type) {
+	case nil:
+		w.tag("")
+	case *BlockStmt:
+		w.block(n)
+	case *EmptyStmt:
+		w.tag("EmptyStmt")
+	case *BadStmt:
+		w.tag("BadStmt")
+		w.bytes(n.Value)
+	case *ExprStmt:
+		w.tag("ExprStmt")
+		w.expr(n.Value)
+	case *IfStmt:
+		w.tag("IfStmt")
+		w.expr(n.Cond)
+		w.stmt(n.Body)
+		w.stmt(n.Else)
+	case *DoWhileStmt:
+		w.tag("DoWhileStmt")
+		w.expr(n.Cond)
+		w.stmt(n.Body)
+	case *WhileStmt:
+		w.tag("WhileStmt")
+		w.expr(n.Cond)
+		w.stmt(n.Body)
+	case *ForStmt:
+		w.tag("ForStmt")
+		if varDecl, ok := n.Init.(*VarDecl); ok && len(varDecl.List) == 0 {
+			w.expr(nil) // for init without declarations
+		} else {
+			w.expr(n.Init)
+		}
+		w.expr(n.Cond)
+		w.expr(n.Post)
+		w.block(n.Body)
+	case *ForInStmt:
+		w.tag("ForInStmt")
+		w.expr(n.Init)
+		w.expr(n.Value)
+		w.block(n.Body)
+	case *ForOfStmt:
+		w.tag("ForOfStmt")
+		w.bool(n.Await)
+		w.expr(n.Init)
+		w.expr(n.Value)
+		w.block(n.Body)
+	case *SwitchStmt:
+		w.tag("SwitchStmt")
+		w.expr(n.Init)
+		w.uint(uint64(len(n.List)))
+		for _, clause := range n.List {
+			w.uint(uint64(clause.TokenType))
+			w.expr(clause.Cond)
+			w.stmts(clause.List)
+		}
+	case *BranchStmt:
+		w.tag("BranchStmt")
+		w.uint(uint64(n.Type))
+		w.bytes(n.Label)
+	case *ReturnStmt:
+		w.tag("ReturnStmt")
+		w.expr(n.Value)
+	case *WithStmt:
+		w.tag("WithStmt")
+		w.expr(n.Cond)
+		w.stmt(n.Body)
+	case *LabelledStmt:
+		w.tag("LabelledStmt")
+		w.bytes(n.Label)
+		w.stmt(n.Value)
+	case *ThrowStmt:
+		w.tag("ThrowStmt")
+		w.expr(n.Value)
+	case *TryStmt:
+		w.tag("TryStmt")
+		w.block(n.Body)
+		w.binding(n.Binding)
+		w.block(n.Catch)
+		w.block(n.Finally)
+	case *DebuggerStmt:
+		w.tag("DebuggerStmt")
+	case *ImportStmt:
+		w.tag("ImportStmt")
+		w.aliases(n.List)
+		w.bytes(n.Default)
+		w.moduleName(n.Module)
+		w.attributes(n.Attributes)
+	case *ExportStmt:
+		w.tag("ExportStmt")
+		w.aliases(n.List)
+		w.moduleName(n.Module)
+		w.attributes(n.Attributes)
+		w.bool(n.Default)
+		w.expr(n.Decl)
+	case *DirectivePrologueStmt:
+		w.tag("DirectivePrologueStmt")
+		w.string(stringValue(n.Value))
+	case *VarDecl:
+		w.varDecl(n)
+	case *FuncDecl:
+		w.funcDecl(n)
+	case *ClassDecl:
+		w.classDecl(n)
+	default:
+		w.tag(n.String())
+	}
+}
+
+// moduleName writes an identifier, or the value of a string used as an import or export name or module specifier.
+func (w *canonicalWriter) moduleName(b []byte) {
+	if 0 < len(b) && (b[0] == '"' || b[0] == '\'') {
+		w.string(stringValue(b))
+	} else {
+		w.bytes(b)
+	}
+}
+
+func (w *canonicalWriter) aliases(list []Alias) {
+	num := 0
+	for _, alias := range list {
+		if alias.Name != nil || alias.Binding != nil {
+			num++
+		}
+	}
+	w.uint(uint64(num))
+	for _, alias := range list {
+		if alias.Name != nil || alias.Binding != nil {
+			w.moduleName(alias.Name)
+			w.moduleName(alias.Binding)
+		}
+	}
+}
+
+func (w *canonicalWriter) attributes(list []ImportAttribute) {
+	w.uint(uint64(len(list)))
+	for _, attribute := range list {
+		w.moduleName(attribute.Key)
+		w.moduleName(attribute.Value)
+	}
+}
+
+func (w *canonicalWriter) varDecl(n *VarDecl) {
+	w.tag("VarDecl")
+	w.uint(uint64(n.TokenType))
+	w.bool(n.Await)
+	w.bindingElements(n.List)
+}
+
+func (w *canonicalWriter) params(n Params) {
+	w.bindingElements(n.List)
+	w.binding(n.Rest)
+}
+
+func (w *canonicalWriter) funcDecl(n *FuncDecl) {
+	w.tag("FuncDecl")
+	w.bool(n.Async)
+	w.bool(n.Generator)
+	w.variable(n.Name)
+	w.params(n.Params)
+	w.stmts(n.Body.List)
+}
+
+func (w *canonicalWriter) methodDecl(n *MethodDecl) {
+	w.tag("MethodDecl")
+	w.exprs(n.Decorators)
+	w.bool(n.Static)
+	w.bool(n.Async)
+	w.bool(n.Generator)
+	w.bool(n.Get)
+	w.bool(n.Set)
+	w.classElementName(n.Name)
+	w.params(n.Params)
+	w.stmts(n.Body.List)
+}
+
+func (w *canonicalWriter) classElementName(n ClassElementName) {
+	if n.Private != nil {
+		w.variable(n.Private)
+	} else {
+		w.propertyName(&n.PropertyName)
+	}
+}
+
+func (w *canonicalWriter) classDecl(n *ClassDecl) {
+	w.tag("ClassDecl")
+	w.exprs(n.Decorators)
+	w.variable(n.Name)
+	w.expr(n.Extends)
+	w.uint(uint64(len(n.List)))
+	for _, item := range n.List {
+		if item.StaticBlock != nil {
+			w.block(item.StaticBlock)
+		} else if item.Method != nil {
+			w.methodDecl(item.Method)
+		} else {
+			w.tag("Field")
+			w.exprs(item.Field.Decorators)
+			w.bool(item.Field.Static)
+			w.bool(item.Field.Accessor)
+			w.classElementName(item.Field.Name)
+			w.expr(item.Field.Init)
+		}
+	}
+}
+
+// propertyName writes the key of a property name, so that a, 'a', and "a" are equal, as well as 1, 1.0, and '1'.
+func (w *canonicalWriter) propertyName(n *PropertyName) {
+	if n == nil {
+		w.tag("")
+	} else if n.Computed != nil {
+		w.tag("Computed")
+		w.expr(n.Computed)
+	} else {
+		w.tag("PropertyName")
+		switch tt := n.Literal.TokenType; {
+		case tt == StringToken:
+			w.string(stringValue(n.Literal.Data))
+		case IsNumeric(tt):
+			if f, ok := numericValue(n.Literal.Data); ok {
+				w.string(numberString(f))
+				return
+			}
+			w.bytes(n.Literal.Data)
+		default:
+			w.bytes(n.Literal.Data)
+		}
+	}
+}
+
+func (w *canonicalWriter) variable(v *Var) {
+	if v == nil {
+		w.tag("")
+		return
+	}
+	w.tag("Var")
+	w.bytes(v.Data)
+}
+
+func (w *canonicalWriter) binding(n IBinding) {
+	switch n := n.(type) {
+	case nil:
+		w.tag("")
+	case *Var:
+		w.variable(n)
+	case *BindingArray:
+		w.tag("BindingArray")
+		w.bindingElements(n.List)
+		w.binding(n.Rest)
+	case *BindingObject:
+		w.tag("BindingObject")
+		w.uint(uint64(len(n.List)))
+		for _, item := range n.List {
+			w.propertyName(item.Key)
+			w.bindingElement(item.Value)
+		}
+		w.variable(n.Rest)
+	default:
+		w.tag(n.String())
+	}
+}
+
+func (w *canonicalWriter) bindingElement(n BindingElement) {
+	w.binding(n.Binding)
+	w.expr(n.Default)
+}
+
+func (w *canonicalWriter) bindingElements(list []BindingElement) {
+	w.uint(uint64(len(list)))
+	for _, item := range list {
+		w.bindingElement(item)
+	}
+}
+
+func (w *canonicalWriter) exprs(list []IExpr) {
+	w.uint(uint64(len(list)))
+	for _, item := range list {
+		w.expr(item)
+	}
+}
+
+func (w *canonicalWriter) args(n Args) {
+	w.uint(uint64(len(n.List)))
+	for _, arg := range n.List {
+		w.bool(arg.Rest)
+		w.expr(arg.Value)
+	}
+}
+
+func (w *canonicalWriter) literal(n *LiteralExpr) {
+	w.tag("LiteralExpr")
+	switch tt := n.TokenType; {
+	case tt == StringToken:
+		w.uint(uint64(StringToken))
+		w.string(stringValue(n.Data))
+	case IsNumeric(tt):
+		if f, ok := numericValue(n.Data); ok {
+			w.uint(uint64(DecimalToken))
+			w.string(numberString(f))
+		} else if i, err := ParseBigInt(n.Data); err == nil {
+			w.uint(uint64(IntegerToken))
+			w.string(i.String() + "n")
+		} else {
+			w.uint(uint64(tt))
+			w.bytes(n.Data)
+		}
+	default:
+		w.uint(uint64(tt))
+		w.bytes(n.Data)
+	}
+}
+
+func (w *canonicalWriter) expr(n IExpr) {
+	switch n := n.(type) {
+	case nil:
+		w.tag("")
+	case *GroupExpr:
+		w.expr(n.X)
+	case *Var:
+		w.variable(n)
+	case *LiteralExpr:
+		w.literal(n)
+	case *ArrayExpr:
+		w.tag("ArrayExpr")
+		w.uint(uint64(len(n.List)))
+		for _, item := range n.List {
+			w.bool(item.Spread)
+			w.expr(item.Value)
+		}
+	case *ObjectExpr:
+		w.tag("ObjectExpr")
+		w.uint(uint64(len(n.List)))
+		for _, item := range n.List {
+			w.propertyName(item.Name)
+			w.bool(item.Spread)
+			w.expr(item.Value)
+			w.expr(item.Init)
+		}
+	case *TemplateExpr:
+		w.tag("TemplateExpr")
+		w.expr(n.Tag)
+		w.bool(n.Optional)
+		w.bool(n.Prec == OpOpt)
+		w.uint(uint64(len(n.List)))
+		for _, item := range n.List {
+			w.bytes(item.Value)
+			w.expr(item.Expr)
+		}
+		w.bytes(n.Tail)
+	case *IndexExpr:
+		w.tag("IndexExpr")
+		w.expr(n.X)
+		w.expr(n.Y)
+		w.bool(n.Optional)
+		w.bool(n.Prec == OpOpt) // continues an optional chain
+	case *DotExpr:
+		w.tag("DotExpr")
+		w.expr(n.X)
+		if y, ok := n.Y.(LiteralExpr); ok {
+			w.literal(&y)
+		} else {
+			w.expr(n.Y)
+		}
+		w.bool(n.Optional)
+		w.bool(n.Prec == OpOpt)
+	case *NewTargetExpr:
+		w.tag("NewTargetExpr")
+	case *ImportMetaExpr:
+		w.tag("ImportMetaExpr")
+	case *NewExpr:
+		w.tag("NewExpr")
+		w.expr(n.X)
+		if n.Args == nil {
+			w.args(Args{}) // new a is equal to new a()
+		} else {
+			w.args(*n.Args)
+		}
+	case *CallExpr:
+		w.tag("CallExpr")
+		w.expr(n.X)
+		w.args(n.Args)
+		w.bool(n.Optional)
+		w.bool(n.Prec == OpOpt)
+	case *UnaryExpr:
+		w.tag("UnaryExpr")
+		w.uint(uint64(n.Op))
+		w.expr(n.X)
+	case *BinaryExpr:
+		w.tag("BinaryExpr")
+		w.uint(uint64(n.Op))
+		w.expr(n.X)
+		w.expr(n.Y)
+	case *CondExpr:
+		w.tag("CondExpr")
+		w.expr(n.Cond)
+		w.expr(n.X)
+		w.expr(n.Y)
+	case *YieldExpr:
+		w.tag("YieldExpr")
+		w.bool(n.Generator)
+		w.expr(n.X)
+	case *ArrowFunc:
+		w.tag("ArrowFunc")
+		w.bool(n.Async)
+		w.params(n.Params)
+		w.stmts(n.Body.List)
+	case *CommaExpr:
+		w.tag("CommaExpr")
+		w.exprs(n.List)
+	case *MethodDecl:
+		w.methodDecl(n)
+	case *VarDecl:
+		w.varDecl(n)
+	case *FuncDecl:
+		w.funcDecl(n)
+	case *ClassDecl:
+		w.classDecl(n)
+	case *JSXElement:
+		w.tag("JSXElement")
+		w.expr(n.Name)
+		w.uint(uint64(len(n.Attrs)))
+		for _, attr := range n.Attrs {
+			w.bytes(attr.Name)
+			w.expr(attr.Value)
+			w.bool(attr.Spread)
+		}
+		w.bool(n.Children == nil)
+		w.exprs(n.Children)
+	case *JSXFragment:
+		w.tag("JSXFragment")
+		w.exprs(n.Children)
+	case *JSXExprContainer:
+		w.tag("JSXExprContainer")
+		w.expr(n.X)
+		w.bool(n.Spread)
+	default:
+		w.tag(n.String())
+	}
+}
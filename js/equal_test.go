@@ -0,0 +1,71 @@
+package js
+
+import (
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestEqual(t *testing.T) {
+	var tests = []struct {
+		a, b  string
+		equal bool
+	}{
+		{"a + b", "a+b", true},
+		{"a + b // comment", "/* comment */ a + b", true},
+		{"(a + b) * c", "((a + b)) * (c)", true},
+		{"a + b * c", "(a + b) * c", false},
+		{"'a'", `"a"`, true},
+		{"'\\x61'", "'a'", true},
+		{"0x10", "16", true},
+		{"1_000", "1e3", true},
+		{"0x10n", "16n", true},
+		{"1", "'1'", false},
+		{"x = {a: 1, 'b': 2, 3: c}", "x = {'a': 1, b: 2, '3': c}", true},
+		{"x = {a}", "x = {a: a}", true},
+		{"x = {a: 1}", "x = {b: 1}", false},
+		{"var a = 1", "let a = 1", false},
+		{"a.b", "a['b']", false},
+		{"a?.b.c", "(a?.b).c", false},
+		{"new A", "new A()", true},
+		{"for (;;) a", "for (;;) { a }", true},
+		{"import a from 'b'", `import a from "b"`, true},
+		{"export {a as 'b'}", `export {a as "b"}`, true},
+		{"'use strict'; a", `"use strict"; a`, true},
+		{"class A { #a; m() { return this.#a } }", "class A{#a;m(){return this.#a}}", true},
+		{"x => x", "x => { return x }", true},
+		{"`a${b}`", "`a${ b }`", true},
+		{"`a`", "'a'", false},
+		{"a; b", "a; c", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" == "+tt.b, func(t *testing.T) {
+			a, err := Parse(parse.NewInputString(tt.a), Options{})
+			test.Error(t, err)
+			b, err := Parse(parse.NewInputString(tt.b), Options{})
+			test.Error(t, err)
+			test.T(t, Equal(a, b), tt.equal)
+			test.T(t, Equal(b, a), tt.equal)
+			if tt.equal {
+				test.T(t, Hash(a), Hash(b))
+			} else {
+				test.That(t, Hash(a) != Hash(b), "hashes must differ")
+			}
+		})
+	}
+}
+
+func TestEqualNodes(t *testing.T) {
+	ast, err := Parse(parse.NewInputString("f(a + 1); g(a + 0x1)"), Options{})
+	test.Error(t, err)
+	x := ast.List[0].(*ExprStmt).Value.(*CallExpr).Args.List[0].Value
+	y := ast.List[1].(*ExprStmt).Value.(*CallExpr).Args.List[0].Value
+	test.That(t, Equal(x, y))
+	test.That(t, !Equal(ast.List[0], ast.List[1]))
+	test.That(t, !Equal(x, nil))
+	test.That(t, Equal(nil, nil))
+
+	// the hash is stable across builds
+	test.T(t, Hash(&Var{Data: []byte("a")}), uint64(0x45e023a2801b4741))
+}
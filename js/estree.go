@@ -0,0 +1,2301 @@
+package js
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidESTree is returned by ParseESTree when the JSON is not a valid ESTree program.
+var ErrInvalidESTree = errors.New("invalid ESTree")
+
+// ESTreeOptions are the options for ESTree.
+type ESTreeOptions struct {
+	Src []byte // source of the AST, when set the nodes get a loc field with line and column positions
+}
+
+// ESTree writes the node as JSON in the ESTree format (https://github.com/estree/estree), as used by tools such as ESLint. All nodes that keep their location in the source get a range field with the start and end offsets, and a loc field with the line (starting at 1) and column (starting at 0, in UTF-16 code units) of the start and end when ESTreeOptions.Src is set. Parentheses are not part of ESTree and are omitted, and comments and BadStmt are skipped. Identifiers only get a location when the AST was parsed with Options.VarLocs.
+func ESTree(w io.Writer, n INode, o ESTreeOptions) error {
+	e := &estreeEncoder{o: o}
+	if o.Src != nil {
+		e.lines = lineStarts(o.Src)
+	}
+
+	var obj *estreeObject
+	switch n := n.(type) {
+	case *AST:
+		obj = e.node("Program", n.BlockStmt.Loc).set("sourceType", "module").set("body", e.stmts(n.List))
+	case IStmt:
+		obj = e.stmt(n)
+	case IExpr:
+		obj = e.expr(n)
+	case IBinding:
+		obj = e.binding(n)
+	default:
+		return fmt.Errorf("cannot write %T as ESTree", n)
+	}
+	if e.err != nil {
+		return e.err
+	}
+
+	buf := &bytes.Buffer{}
+	e.enc = json.NewEncoder(buf)
+	e.enc.SetEscapeHTML(false)
+	e.write(buf, obj)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// estreeObject is a JSON object that keeps the order of its keys.
+type estreeObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (obj *estreeObject) set(key string, value interface{}) *estreeObject {
+	obj.keys = append(obj.keys, key)
+	obj.values = append(obj.values, value)
+	return obj
+}
+
+type estreeEncoder struct {
+	o      ESTreeOptions
+	lines  []int // offsets of the line starts in the source
+	cursor int   // start of the last node with a location, used to find the location of variables
+	enc    *json.Encoder
+	err    error
+}
+
+func (e *estreeEncoder) write(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int:
+		buf.WriteString(strconv.Itoa(v))
+	case float64:
+		b, _ := json.Marshal(v)
+		buf.Write(b)
+	case string:
+		e.enc.Encode(v)
+		buf.Truncate(buf.Len() - 1) // remove newline
+	case *estreeObject:
+		if v == nil {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			e.write(buf, key)
+			buf.WriteByte(':')
+			e.write(buf, v.values[i])
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			e.write(buf, item)
+		}
+		buf.WriteByte(']')
+	}
+}
+
+// lineStarts returns the offsets of the start of each line, where lines are terminated by \n, \r\n, \r, \u2028, or \u2029.
+func lineStarts(src []byte) []int {
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' || src[i] == '\r' && (i+1 == len(src) || src[i+1] != '\n') {
+			lines = append(lines, i+1)
+		} else if src[i] == 0xE2 && i+2 < len(src) && src[i+1] == 0x80 && (src[i+2] == 0xA8 || src[i+2] == 0xA9) {
+			lines = append(lines, i+3)
+			i += 2
+		}
+	}
+	return lines
+}
+
+func (e *estreeEncoder) position(offset int) *estreeObject {
+	if len(e.o.Src) < offset {
+		offset = len(e.o.Src)
+	}
+	line := sort.SearchInts(e.lines, offset+1) - 1
+	column := utf16Len(e.o.Src[e.lines[line]:offset])
+	return (&estreeObject{}).set("line", line+1).set("column", column)
+}
+
+// node returns a new ESTree node with its location.
+func (e *estreeEncoder) node(typ string, loc Loc) *estreeObject {
+	obj := (&estreeObject{}).set("type", typ)
+	if loc.IsSet() {
+		e.cursor = loc.Start
+		obj.set("range", []interface{}{loc.Start, loc.End})
+		if e.o.Src != nil {
+			obj.set("loc", (&estreeObject{}).set("start", e.position(loc.Start)).set("end", e.position(loc.End)))
+		}
+	}
+	return obj
+}
+
+// varLoc returns the location of the next occurrence of the variable in the source.
+func (e *estreeEncoder) varLoc(v *Var) Loc {
+	for _, locs := range [][]Loc{v.Locs, v.Resolve().Locs} {
+		for _, loc := range locs {
+			if e.cursor <= loc.Start {
+				return loc
+			}
+		}
+	}
+	return Loc{}
+}
+
+func (e *estreeEncoder) ident(v *Var) *estreeObject {
+	if v == nil {
+		return nil
+	}
+	loc := e.varLoc(v)
+	if 0 < len(v.Data) && v.Data[0] == '#' {
+		return e.node("PrivateIdentifier", loc).set("name", string(v.Data[1:]))
+	}
+	return e.node("Identifier", loc).set("name", string(v.Data))
+}
+
+func (e *estreeEncoder) name(name []byte, loc Loc) *estreeObject {
+	if name == nil {
+		return nil
+	}
+	return e.node("Identifier", loc).set("name", string(name))
+}
+
+// moduleExportName returns an identifier or string literal of an import or export name.
+func (e *estreeEncoder) moduleExportName(name []byte) *estreeObject {
+	if 0 < len(name) && (name[0] == '"' || name[0] == '\'') {
+		return e.literal(&LiteralExpr{TokenType: StringToken, Data: name})
+	}
+	return e.name(name, Loc{})
+}
+
+func (e *estreeEncoder) stmts(list []IStmt) []interface{} {
+	objs := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		if obj := e.stmt(item); obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+func (e *estreeEncoder) block(n *BlockStmt) *estreeObject {
+	if n == nil {
+		return nil
+	}
+	return e.node("BlockStatement", n.Loc).set("body", e.stmts(n.List))
+}
+
+// forBody returns the body of a for statement, which is a block statement when it has braces.
+func (e *estreeEncoder) forBody(n *BlockStmt) *estreeObject {
+	if len(n.List) == 1 && n.Loc.IsSet() && NodeLoc(n.List[0]) == n.Loc {
+		return e.stmt(n.List[0])
+	} else if len(n.List) == 0 && n.Loc.IsSet() && n.End-n.Start == 1 {
+		return e.node("EmptyStatement", n.Loc)
+	}
+	return e.block(n)
+}
+
+func (e *estreeEncoder) stmt(n IStmt) *estreeObject {
+	switch n := n.(type) {
+	case nil, *Comment:
+		return nil
+	case *BlockStmt:
+		return e.block(n)
+	case *EmptyStmt:
+		return e.node("EmptyStatement", n.Loc)
+	case *ExprStmt:
+		return e.node("ExpressionStatement", n.Loc).set("expression", e.expr(n.Value))
+	case *DirectivePrologueStmt:
+		obj := e.node("ExpressionStatement", n.Loc)
+		lit := &LiteralExpr{TokenType: StringToken, Data: n.Value}
+		if n.Loc.IsSet() {
+			lit.Loc = Loc{n.Start, n.Start + len(n.Value)}
+		}
+		return obj.set("expression", e.literal(lit)).set("directive", string(n.Value[1:len(n.Value)-1]))
+	case *IfStmt:
+		return e.node("IfStatement", n.Loc).set("test", e.expr(n.Cond)).set("consequent", e.stmt(n.Body)).set("alternate", e.stmt(n.Else))
+	case *DoWhileStmt:
+		return e.node("DoWhileStatement", n.Loc).set("body", e.stmt(n.Body)).set("test", e.expr(n.Cond))
+	case *WhileStmt:
+		return e.node("WhileStatement", n.Loc).set("test", e.expr(n.Cond)).set("body", e.stmt(n.Body))
+	case *ForStmt:
+		obj := e.node("ForStatement", n.Loc)
+		if varDecl, ok := n.Init.(*VarDecl); ok && len(varDecl.List) == 0 {
+			obj.set("init", nil)
+		} else {
+			obj.set("init", e.expr(n.Init))
+		}
+		return obj.set("test", e.expr(n.Cond)).set("update", e.expr(n.Post)).set("body", e.forBody(n.Body))
+	case *ForInStmt:
+		return e.node("ForInStatement", n.Loc).set("left", e.target(n.Init)).set("right", e.expr(n.Value)).set("body", e.forBody(n.Body))
+	case *ForOfStmt:
+		return e.node("ForOfStatement", n.Loc).set("await", n.Await).set("left", e.target(n.Init)).set("right", e.expr(n.Value)).set("body", e.forBody(n.Body))
+	case *SwitchStmt:
+		obj := e.node("SwitchStatement", n.Loc).set("discriminant", e.expr(n.Init))
+		cases := make([]interface{}, 0, len(n.List))
+		for _, clause := range n.List {
+			cases = append(cases, e.node("SwitchCase", clause.Loc).set("test", e.expr(clause.Cond)).set("consequent", e.stmts(clause.List)))
+		}
+		return obj.set("cases", cases)
+	case *BranchStmt:
+		typ := "BreakStatement"
+		if n.Type == ContinueToken {
+			typ = "ContinueStatement"
+		}
+		return e.node(typ, n.Loc).set("label", e.name(n.Label, Loc{}))
+	case *ReturnStmt:
+		return e.node("ReturnStatement", n.Loc).set("argument", e.expr(n.Value))
+	case *WithStmt:
+		return e.node("WithStatement", n.Loc).set("object", e.expr(n.Cond)).set("body", e.stmt(n.Body))
+	case *LabelledStmt:
+		return e.node("LabeledStatement", n.Loc).set("label", e.name(n.Label, Loc{})).set("body", e.stmt(n.Value))
+	case *ThrowStmt:
+		return e.node("ThrowStatement", n.Loc).set("argument", e.expr(n.Value))
+	case *TryStmt:
+		obj := e.node("TryStatement", n.Loc).set("block", e.block(n.Body))
+		if n.Catch != nil {
+			handler := e.node("CatchClause", Loc{}).set("param", e.binding(n.Binding)).set("body", e.block(n.Catch))
+			obj.set("handler", handler)
+		} else {
+			obj.set("handler", nil)
+		}
+		return obj.set("finalizer", e.block(n.Finally))
+	case *DebuggerStmt:
+		return e.node("DebuggerStatement", n.Loc)
+	case *ImportStmt:
+		obj := e.node("ImportDeclaration", n.Loc)
+		specifiers := []interface{}{}
+		if n.Default != nil {
+			specifiers = append(specifiers, e.node("ImportDefaultSpecifier", Loc{}).set("local", e.name(n.Default, Loc{})))
+		}
+		if len(n.List) == 1 && len(n.List[0].Name) == 1 && n.List[0].Name[0] == '*' {
+			specifiers = append(specifiers, e.node("ImportNamespaceSpecifier", Loc{}).set("local", e.name(n.List[0].Binding, Loc{})))
+		} else {
+			for _, alias := range n.List {
+				if alias.Binding == nil {
+					continue
+				}
+				imported := alias.Name
+				if imported == nil {
+					imported = alias.Binding
+				}
+				specifiers = append(specifiers, e.node("ImportSpecifier", Loc{}).set("imported", e.moduleExportName(imported)).set("local", e.name(alias.Binding, Loc{})))
+			}
+		}
+		return obj.set("specifiers", specifiers).set("source", e.moduleExportName(n.Module)).set("attributes", e.attributes(n.Attributes))
+	case *ExportStmt:
+		if n.Default {
+			obj := e.node("ExportDefaultDeclaration", n.Loc)
+			switch decl := n.Decl.(type) {
+			case *FuncDecl:
+				return obj.set("declaration", e.function("FunctionDeclaration", decl))
+			case *ClassDecl:
+				return obj.set("declaration", e.class("ClassDeclaration", decl))
+			}
+			return obj.set("declaration", e.expr(n.Decl))
+		} else if n.Decl != nil {
+			obj := e.node("ExportNamedDeclaration", n.Loc)
+			switch decl := n.Decl.(type) {
+			case *FuncDecl:
+				obj.set("declaration", e.function("FunctionDeclaration", decl))
+			case *ClassDecl:
+				obj.set("declaration", e.class("ClassDeclaration", decl))
+			default:
+				obj.set("declaration", e.expr(n.Decl))
+			}
+			return obj.set("specifiers", []interface{}{}).set("source", nil)
+		} else if len(n.List) == 1 && (len(n.List[0].Name) == 1 && n.List[0].Name[0] == '*' || n.List[0].Name == nil && len(n.List[0].Binding) == 1 && n.List[0].Binding[0] == '*') {
+			obj := e.node("ExportAllDeclaration", n.Loc)
+			if n.List[0].Name != nil {
+				obj.set("exported", e.moduleExportName(n.List[0].Binding))
+			} else {
+				obj.set("exported", nil)
+			}
+			return obj.set("source", e.moduleExportName(n.Module)).set("attributes", e.attributes(n.Attributes))
+		}
+		obj := e.node("ExportNamedDeclaration", n.Loc).set("declaration", nil)
+		specifiers := []interface{}{}
+		for _, alias := range n.List {
+			if alias.Binding == nil {
+				continue
+			}
+			local := alias.Name
+			if local == nil {
+				local = alias.Binding
+			}
+			specifiers = append(specifiers, e.node("ExportSpecifier", Loc{}).set("local", e.moduleExportName(local)).set("exported", e.moduleExportName(alias.Binding)))
+		}
+		obj.set("specifiers", specifiers)
+		if n.Module != nil {
+			return obj.set("source", e.moduleExportName(n.Module)).set("attributes", e.attributes(n.Attributes))
+		}
+		return obj.set("source", nil)
+	case *VarDecl:
+		return e.varDecl(n)
+	case *FuncDecl:
+		return e.function("FunctionDeclaration", n)
+	case *ClassDecl:
+		return e.class("ClassDeclaration", n)
+	case *BadStmt:
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot write BadStmt as ESTree")
+		}
+		return nil
+	}
+	if e.err == nil {
+		e.err = fmt.Errorf("cannot write %T as ESTree", n)
+	}
+	return nil
+}
+
+func (e *estreeEncoder) attributes(attributes []ImportAttribute) []interface{} {
+	objs := make([]interface{}, 0, len(attributes))
+	for _, attribute := range attributes {
+		objs = append(objs, e.node("ImportAttribute", Loc{}).set("key", e.moduleExportName(attribute.Key)).set("value", e.moduleExportName(attribute.Value)))
+	}
+	return objs
+}
+
+func (e *estreeEncoder) varDecl(n *VarDecl) *estreeObject {
+	kind := string(n.TokenType.Bytes())
+	if n.Await {
+		kind = "await " + kind
+	}
+	declarations := make([]interface{}, 0, len(n.List))
+	for _, item := range n.List {
+		declarations = append(declarations, e.node("VariableDeclarator", item.Loc).set("id", e.binding(item.Binding)).set("init", e.expr(item.Default)))
+	}
+	return e.node("VariableDeclaration", n.Loc).set("kind", kind).set("declarations", declarations)
+}
+
+func (e *estreeEncoder) params(params Params) []interface{} {
+	objs := make([]interface{}, 0, len(params.List)+1)
+	for _, item := range params.List {
+		objs = append(objs, e.bindingElement(item))
+	}
+	if params.Rest != nil {
+		objs = append(objs, e.node("RestElement", Loc{}).set("argument", e.binding(params.Rest)))
+	}
+	return objs
+}
+
+func (e *estreeEncoder) function(typ string, n *FuncDecl) *estreeObject {
+	obj := e.node(typ, n.Loc).set("id", e.ident(n.Name)).set("expression", false).set("generator", n.Generator).set("async", n.Async)
+	return obj.set("params", e.params(n.Params)).set("body", e.block(&n.Body))
+}
+
+func (e *estreeEncoder) method(n *MethodDecl) *estreeObject {
+	obj := e.node("FunctionExpression", Loc{}).set("id", nil).set("expression", false).set("generator", n.Generator).set("async", n.Async)
+	return obj.set("params", e.params(n.Params)).set("body", e.block(&n.Body))
+}
+
+func (e *estreeEncoder) decorators(obj *estreeObject, decorators []IExpr) {
+	if len(decorators) == 0 {
+		return
+	}
+	objs := make([]interface{}, 0, len(decorators))
+	for _, decorator := range decorators {
+		objs = append(objs, e.node("Decorator", Loc{}).set("expression", e.expr(decorator)))
+	}
+	obj.set("decorators", objs)
+}
+
+func (e *estreeEncoder) class(typ string, n *ClassDecl) *estreeObject {
+	obj := e.node(typ, n.Loc)
+	e.decorators(obj, n.Decorators)
+	obj.set("id", e.ident(n.Name)).set("superClass", e.expr(n.Extends))
+	elements := make([]interface{}, 0, len(n.List))
+	for _, item := range n.List {
+		if item.StaticBlock != nil {
+			elements = append(elements, e.node("StaticBlock", item.Loc).set("body", e.stmts(item.StaticBlock.List)))
+		} else if item.Method != nil {
+			method := e.node("MethodDefinition", item.Loc)
+			e.decorators(method, item.Method.Decorators)
+			kind := "method"
+			if item.Method.Get {
+				kind = "get"
+			} else if item.Method.Set {
+				kind = "set"
+			} else if !item.Method.Static && item.Method.Name.IsIdent([]byte("constructor")) {
+				kind = "constructor"
+			}
+			method.set("static", item.Method.Static).set("computed", item.Method.Name.IsComputed()).set("key", e.elementName(item.Method.Name)).set("kind", kind)
+			elements = append(elements, method.set("value", e.method(item.Method)))
+		} else {
+			typ := "PropertyDefinition"
+			if item.Field.Accessor {
+				typ = "AccessorProperty"
+			}
+			field := e.node(typ, item.Loc)
+			e.decorators(field, item.Field.Decorators)
+			field.set("static", item.Field.Static).set("computed", item.Field.Name.IsComputed()).set("key", e.elementName(item.Field.Name))
+			elements = append(elements, field.set("value", e.expr(item.Field.Init)))
+		}
+	}
+	return obj.set("body", e.node("ClassBody", Loc{}).set("body", elements))
+}
+
+func (e *estreeEncoder) elementName(n ClassElementName) *estreeObject {
+	if n.Private != nil {
+		return e.ident(n.Private)
+	}
+	return e.propertyName(n.PropertyName)
+}
+
+func (e *estreeEncoder) propertyName(n PropertyName) *estreeObject {
+	if n.Computed != nil {
+		return e.expr(n.Computed)
+	} else if n.Literal.TokenType == IdentifierToken {
+		return e.name(n.Literal.Data, n.Literal.Loc)
+	}
+	return e.literal(&n.Literal)
+}
+
+func (e *estreeEncoder) property(key, value *estreeObject, kind string, method, shorthand, computed bool, loc Loc) *estreeObject {
+	obj := e.node("Property", loc).set("key", key).set("value", value).set("kind", kind)
+	return obj.set("method", method).set("shorthand", shorthand).set("computed", computed)
+}
+
+func (e *estreeEncoder) binding(n IBinding) *estreeObject {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case *Var:
+		return e.ident(n)
+	case *BindingArray:
+		obj := e.node("ArrayPattern", n.Loc)
+		elements := make([]interface{}, 0, len(n.List)+1)
+		for _, item := range n.List {
+			elements = append(elements, e.bindingElement(item))
+		}
+		if n.Rest != nil {
+			elements = append(elements, e.node("RestElement", Loc{}).set("argument", e.binding(n.Rest)))
+		}
+		return obj.set("elements", elements)
+	case *BindingObject:
+		obj := e.node("ObjectPattern", n.Loc)
+		properties := make([]interface{}, 0, len(n.List)+1)
+		for _, item := range n.List {
+			v, _ := item.Value.Binding.(*Var)
+			shorthand := v != nil && item.Key.IsIdent(v.Data) && (!item.Key.Literal.Loc.IsSet() || item.Value.Loc.Start == item.Key.Literal.Start)
+			loc := item.Value.Loc
+			if !shorthand {
+				loc = Loc{}
+				if !item.Key.IsComputed() && item.Key.Literal.Loc.IsSet() && item.Value.Loc.IsSet() {
+					loc = Loc{item.Key.Literal.Start, item.Value.End}
+				}
+			}
+			properties = append(properties, e.property(e.propertyName(*item.Key), e.bindingElement(item.Value), "init", false, shorthand, item.Key.IsComputed(), loc))
+		}
+		if n.Rest != nil {
+			properties = append(properties, e.node("RestElement", Loc{}).set("argument", e.ident(n.Rest)))
+		}
+		return obj.set("properties", properties)
+	}
+	if e.err == nil {
+		e.err = fmt.Errorf("cannot write %T as ESTree", n)
+	}
+	return nil
+}
+
+func (e *estreeEncoder) bindingElement(n BindingElement) *estreeObject {
+	if n.Binding == nil {
+		return nil
+	} else if n.Default != nil {
+		return e.node("AssignmentPattern", n.Loc).set("left", e.binding(n.Binding)).set("right", e.expr(n.Default))
+	}
+	return e.binding(n.Binding)
+}
+
+// target returns the pattern of an assignment target or the left-hand side of a for-in or for-of statement.
+func (e *estreeEncoder) target(n IExpr) *estreeObject {
+	switch n := n.(type) {
+	case *GroupExpr:
+		return e.target(n.X)
+	case *ArrayExpr:
+		obj := e.node("ArrayPattern", n.Loc)
+		elements := make([]interface{}, 0, len(n.List))
+		for _, item := range n.List {
+			if item.Spread {
+				elements = append(elements, e.node("RestElement", Loc{}).set("argument", e.target(item.Value)))
+			} else {
+				elements = append(elements, e.targetElement(item.Value))
+			}
+		}
+		return obj.set("elements", elements)
+	case *ObjectExpr:
+		obj := e.node("ObjectPattern", n.Loc)
+		properties := make([]interface{}, 0, len(n.List))
+		for _, item := range n.List {
+			if item.Spread {
+				properties = append(properties, e.node("RestElement", item.Loc).set("argument", e.target(item.Value)))
+			} else if v, ok := item.Value.(*Var); ok && item.Init != nil {
+				key := e.propertyName(*item.Name)
+				value := e.node("AssignmentPattern", item.Loc).set("left", e.ident(v)).set("right", e.expr(item.Init))
+				properties = append(properties, e.property(key, value, "init", false, true, false, item.Loc))
+			} else if item.Name != nil {
+				shorthand := ok && item.Name.IsIdent(v.Data) && (!item.Loc.IsSet() || !item.Name.Literal.Loc.IsSet() || item.Loc.End == item.Name.Literal.End)
+				properties = append(properties, e.property(e.propertyName(*item.Name), e.targetElement(item.Value), "init", false, shorthand, item.Name.IsComputed(), item.Loc))
+			}
+		}
+		return obj.set("properties", properties)
+	case *VarDecl:
+		return e.varDecl(n)
+	}
+	return e.expr(n)
+}
+
+func (e *estreeEncoder) targetElement(n IExpr) *estreeObject {
+	if n == nil {
+		return nil
+	} else if assign, ok := n.(*BinaryExpr); ok && assign.Op == EqToken {
+		return e.node("AssignmentPattern", assign.Loc).set("left", e.target(assign.X)).set("right", e.expr(assign.Y))
+	}
+	return e.target(n)
+}
+
+func (e *estreeEncoder) args(args []Arg) []interface{} {
+	objs := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if arg.Rest {
+			objs = append(objs, e.node("SpreadElement", Loc{}).set("argument", e.expr(arg.Value)))
+		} else {
+			objs = append(objs, e.expr(arg.Value))
+		}
+	}
+	return objs
+}
+
+func (e *estreeEncoder) literal(n *LiteralExpr) *estreeObject {
+	switch n.TokenType {
+	case ThisToken:
+		return e.node("ThisExpression", n.Loc)
+	case SuperToken:
+		return e.node("Super", n.Loc)
+	case IdentifierToken:
+		return e.name(n.Data, n.Loc)
+	case JSXTextToken:
+		return e.node("JSXText", n.Loc).set("value", html.UnescapeString(string(n.Data))).set("raw", string(n.Data))
+	}
+
+	obj := e.node("Literal", n.Loc)
+	switch n.TokenType {
+	case NullToken:
+		obj.set("value", nil)
+	case TrueToken, FalseToken:
+		obj.set("value", n.TokenType == TrueToken)
+	case StringToken:
+		obj.set("value", stringValue(n.Data))
+	case JSXStringToken:
+		obj.set("value", html.UnescapeString(string(n.Data[1:len(n.Data)-1])))
+	case RegExpToken:
+		i := bytes.LastIndexByte(n.Data, '/')
+		regex := (&estreeObject{}).set("pattern", string(n.Data[1:i])).set("flags", string(n.Data[i+1:]))
+		obj.set("value", nil).set("raw", string(n.Data)).set("regex", regex)
+		return obj
+	default:
+		if !IsNumeric(n.TokenType) {
+			if e.err == nil {
+				e.err = fmt.Errorf("cannot write %s literal as ESTree", n.TokenType)
+			}
+			return nil
+		} else if f, ok := numericValue(n.Data); ok {
+			if math.IsInf(f, 0) {
+				obj.set("value", nil)
+			} else {
+				obj.set("value", f)
+			}
+		} else {
+			bigint := strings.ReplaceAll(string(n.Data[:len(n.Data)-1]), "_", "")
+			obj.set("value", nil).set("raw", string(n.Data)).set("bigint", bigint)
+			return obj
+		}
+	}
+	return obj.set("raw", string(n.Data))
+}
+
+// templateElement returns the raw and cooked values of a template part including its delimiters, where the cooked value is null for invalid escapes.
+func templateElement(b []byte) *estreeObject {
+	end := len(b) - 1
+	if b[end] == '{' {
+		end--
+	}
+	value := (&estreeObject{}).set("raw", string(b[1:end]))
+	if cooked, err := UnquoteTemplate(b); err == nil {
+		return value.set("cooked", cooked)
+	}
+	return value.set("cooked", nil)
+}
+
+func (e *estreeEncoder) template(n *TemplateExpr, loc Loc) *estreeObject {
+	quasis := make([]interface{}, 0, len(n.List)+1)
+	expressions := make([]interface{}, 0, len(n.List))
+	for _, item := range n.List {
+		quasis = append(quasis, e.node("TemplateElement", Loc{}).set("value", templateElement(item.Value)).set("tail", false))
+		expressions = append(expressions, e.expr(item.Expr))
+	}
+	quasis = append(quasis, e.node("TemplateElement", Loc{}).set("value", templateElement(n.Tail)).set("tail", true))
+	return e.node("TemplateLiteral", loc).set("quasis", quasis).set("expressions", expressions)
+}
+
+// isOptionalChain returns true for member, call, and tagged template expressions that are part of an optional chain.
+func isOptionalChain(n IExpr) bool {
+	switch n := n.(type) {
+	case *DotExpr:
+		return n.Prec == OpOpt
+	case *IndexExpr:
+		return n.Prec == OpOpt
+	case *CallExpr:
+		return n.Prec == OpOpt
+	case *TemplateExpr:
+		return n.Tag != nil && n.Prec == OpOpt
+	}
+	return false
+}
+
+// chainObject returns the object of a member, call, or tagged template expression, where an optional chain continues without a ChainExpression.
+func (e *estreeEncoder) chainObject(n IExpr) *estreeObject {
+	if isOptionalChain(n) {
+		return e.chainElement(n)
+	}
+	return e.expr(n)
+}
+
+func (e *estreeEncoder) chainElement(n IExpr) *estreeObject {
+	switch n := n.(type) {
+	case *DotExpr:
+		obj := e.node("MemberExpression", n.Loc).set("object", e.chainObject(n.X))
+		switch y := n.Y.(type) {
+		case *Var:
+			obj.set("property", e.ident(y))
+		case LiteralExpr:
+			obj.set("property", e.name(y.Data, y.Loc))
+		default:
+			obj.set("property", e.expr(n.Y))
+		}
+		return obj.set("computed", false).set("optional", n.Optional)
+	case *IndexExpr:
+		obj := e.node("MemberExpression", n.Loc).set("object", e.chainObject(n.X)).set("property", e.expr(n.Y))
+		return obj.set("computed", true).set("optional", n.Optional)
+	case *CallExpr:
+		if lit, ok := n.X.(*LiteralExpr); ok && lit.TokenType == ImportToken && 0 < len(n.Args.List) {
+			obj := e.node("ImportExpression", n.Loc).set("source", e.expr(n.Args.List[0].Value))
+			if 1 < len(n.Args.List) {
+				return obj.set("options", e.expr(n.Args.List[1].Value))
+			}
+			return obj.set("options", nil)
+		}
+		obj := e.node("CallExpression", n.Loc).set("callee", e.chainObject(n.X)).set("arguments", e.args(n.Args.List))
+		return obj.set("optional", n.Optional)
+	case *TemplateExpr:
+		obj := e.node("TaggedTemplateExpression", n.Loc).set("tag", e.chainObject(n.Tag))
+		return obj.set("quasi", e.template(n, Loc{}))
+	}
+	return e.expr(n)
+}
+
+func (e *estreeEncoder) expr(n IExpr) *estreeObject {
+	switch n := n.(type) {
+	case nil:
+		return nil
+	case *Var:
+		return e.ident(n)
+	case *LiteralExpr:
+		return e.literal(n)
+	case *ArrayExpr:
+		elements := make([]interface{}, 0, len(n.List))
+		for _, item := range n.List {
+			if item.Spread {
+				elements = append(elements, e.node("SpreadElement", Loc{}).set("argument", e.expr(item.Value)))
+			} else {
+				elements = append(elements, e.expr(item.Value))
+			}
+		}
+		return e.node("ArrayExpression", n.Loc).set("elements", elements)
+	case *ObjectExpr:
+		obj := e.node("ObjectExpression", n.Loc)
+		properties := make([]interface{}, 0, len(n.List))
+		for _, item := range n.List {
+			if item.Spread {
+				properties = append(properties, e.node("SpreadElement", item.Loc).set("argument", e.expr(item.Value)))
+			} else if method, ok := item.Value.(*MethodDecl); ok {
+				kind := "init"
+				if method.Get {
+					kind = "get"
+				} else if method.Set {
+					kind = "set"
+				}
+				key := e.propertyName(method.Name.PropertyName)
+				properties = append(properties, e.property(key, e.method(method), kind, kind == "init", false, method.Name.IsComputed(), item.Loc))
+			} else if v, ok := item.Value.(*Var); ok && (item.Name == nil || item.Init != nil) {
+				var key *estreeObject
+				if item.Name != nil {
+					key = e.propertyName(*item.Name)
+				} else {
+					key = e.name(v.Data, Loc{})
+				}
+				value := e.ident(v)
+				if item.Init != nil {
+					value = e.node("AssignmentPattern", item.Loc).set("left", value).set("right", e.expr(item.Init))
+				}
+				properties = append(properties, e.property(key, value, "init", false, true, false, item.Loc))
+			} else {
+				shorthand := ok && item.Name.IsIdent(v.Data) && (!item.Loc.IsSet() || !item.Name.Literal.Loc.IsSet() || item.Loc.End == item.Name.Literal.End)
+				properties = append(properties, e.property(e.propertyName(*item.Name), e.expr(item.Value), "init", false, shorthand, item.Name.IsComputed(), item.Loc))
+			}
+		}
+		return obj.set("properties", properties)
+	case *TemplateExpr:
+		if n.Tag == nil {
+			return e.template(n, n.Loc)
+		}
+	case *GroupExpr:
+		return e.expr(n.X)
+	case *NewTargetExpr:
+		obj := e.node("MetaProperty", n.Loc)
+		meta, property := Loc{}, Loc{}
+		if n.Loc.IsSet() {
+			meta, property = Loc{n.Start, n.Start + 3}, Loc{n.End - 6, n.End}
+		}
+		return obj.set("meta", e.name([]byte("new"), meta)).set("property", e.name([]byte("target"), property))
+	case *ImportMetaExpr:
+		obj := e.node("MetaProperty", n.Loc)
+		meta, property := Loc{}, Loc{}
+		if n.Loc.IsSet() {
+			meta, property = Loc{n.Start, n.Start + 6}, Loc{n.End - 4, n.End}
+		}
+		return obj.set("meta", e.name([]byte("import"), meta)).set("property", e.name([]byte("meta"), property))
+	case *NewExpr:
+		obj := e.node("NewExpression", n.Loc).set("callee", e.expr(n.X))
+		if n.Args == nil {
+			return obj.set("arguments", []interface{}{})
+		}
+		return obj.set("arguments", e.args(n.Args.List))
+	case *UnaryExpr:
+		switch n.Op {
+		case PreIncrToken, PreDecrToken, PostIncrToken, PostDecrToken:
+			prefix := n.Op == PreIncrToken || n.Op == PreDecrToken
+			return e.node("UpdateExpression", n.Loc).set("operator", string(n.Op.Bytes())).set("prefix", prefix).set("argument", e.expr(n.X))
+		case AwaitToken:
+			return e.node("AwaitExpression", n.Loc).set("argument", e.expr(n.X))
+		}
+		return e.node("UnaryExpression", n.Loc).set("operator", string(n.Op.Bytes())).set("prefix", true).set("argument", e.expr(n.X))
+	case *BinaryExpr:
+		if _, ok := assignOps[string(n.Op.Bytes())]; ok {
+			left := e.expr(n.X)
+			if n.Op == EqToken {
+				left = e.target(n.X)
+			}
+			return e.node("AssignmentExpression", n.Loc).set("operator", string(n.Op.Bytes())).set("left", left).set("right", e.expr(n.Y))
+		}
+		typ := "BinaryExpression"
+		if n.Op == AndToken || n.Op == OrToken || n.Op == NullishToken {
+			typ = "LogicalExpression"
+		}
+		return e.node(typ, n.Loc).set("operator", string(n.Op.Bytes())).set("left", e.expr(n.X)).set("right", e.expr(n.Y))
+	case *CondExpr:
+		return e.node("ConditionalExpression", n.Loc).set("test", e.expr(n.Cond)).set("consequent", e.expr(n.X)).set("alternate", e.expr(n.Y))
+	case *YieldExpr:
+		return e.node("YieldExpression", n.Loc).set("delegate", n.Generator).set("argument", e.expr(n.X))
+	case *ArrowFunc:
+		obj := e.node("ArrowFunctionExpression", n.Loc).set("id", nil)
+		if ret, ok := arrowConciseBody(n); ok {
+			obj.set("expression", true).set("generator", false).set("async", n.Async).set("params", e.params(n.Params))
+			return obj.set("body", e.expr(ret.Value))
+		}
+		obj.set("expression", false).set("generator", false).set("async", n.Async).set("params", e.params(n.Params))
+		return obj.set("body", e.block(&n.Body))
+	case *CommaExpr:
+		expressions := make([]interface{}, 0, len(n.List))
+		for _, item := range n.List {
+			expressions = append(expressions, e.expr(item))
+		}
+		return e.node("SequenceExpression", n.Loc).set("expressions", expressions)
+	case *FuncDecl:
+		return e.function("FunctionExpression", n)
+	case *ClassDecl:
+		return e.class("ClassExpression", n)
+	case *VarDecl:
+		return e.varDecl(n)
+	case *JSXElement:
+		return e.jsxElement(n)
+	case *JSXFragment:
+		obj := e.node("JSXFragment", n.Loc).set("openingFragment", e.node("JSXOpeningFragment", Loc{})).set("children", e.jsxChildren(n.Children))
+		return obj.set("closingFragment", e.node("JSXClosingFragment", Loc{}))
+	case *JSXExprContainer:
+		return e.jsxExprContainer(n)
+	}
+	if isOptionalChain(n) {
+		return e.node("ChainExpression", NodeLoc(n)).set("expression", e.chainElement(n))
+	}
+	switch n.(type) {
+	case *DotExpr, *IndexExpr, *CallExpr, *TemplateExpr:
+		return e.chainElement(n)
+	}
+	if e.err == nil {
+		e.err = fmt.Errorf("cannot write %T as ESTree", n)
+	}
+	return nil
+}
+
+// arrowConciseBody returns the return statement of an arrow function with a concise body, as in x => x.
+func arrowConciseBody(n *ArrowFunc) (*ReturnStmt, bool) {
+	if len(n.Body.List) == 1 {
+		if ret, ok := n.Body.List[0].(*ReturnStmt); ok && ret.Value != nil && (!n.Body.Loc.IsSet() || ret.Loc == n.Body.Loc) {
+			return ret, true
+		}
+	}
+	return nil, false
+}
+
+func (e *estreeEncoder) jsxName(n IExpr, loc bool) *estreeObject {
+	switch n := n.(type) {
+	case *LiteralExpr:
+		nameLoc := Loc{}
+		if loc {
+			nameLoc = n.Loc
+		}
+		if i := bytes.IndexByte(n.Data, ':'); i != -1 && n.TokenType == JSXIdentifierToken {
+			namespace, name := Loc{}, Loc{}
+			if nameLoc.IsSet() {
+				namespace, name = Loc{nameLoc.Start, nameLoc.Start + i}, Loc{nameLoc.End - len(n.Data) + i + 1, nameLoc.End}
+			}
+			obj := e.node("JSXNamespacedName", nameLoc)
+			return obj.set("namespace", e.node("JSXIdentifier", namespace).set("name", string(n.Data[:i]))).set("name", e.node("JSXIdentifier", name).set("name", string(n.Data[i+1:])))
+		}
+		return e.node("JSXIdentifier", nameLoc).set("name", string(n.Data))
+	case *Var:
+		nameLoc := Loc{}
+		if loc {
+			nameLoc = e.varLoc(n)
+		}
+		return e.node("JSXIdentifier", nameLoc).set("name", string(n.Data))
+	case *DotExpr:
+		nameLoc := Loc{}
+		if loc {
+			nameLoc = n.Loc
+		}
+		obj := e.node("JSXMemberExpression", nameLoc).set("object", e.jsxName(n.X, loc))
+		y, _ := n.Y.(LiteralExpr)
+		if !loc {
+			y.Loc = Loc{}
+		}
+		return obj.set("property", e.node("JSXIdentifier", y.Loc).set("name", string(y.Data)))
+	}
+	if e.err == nil {
+		e.err = fmt.Errorf("cannot write JSX element name %T as ESTree", n)
+	}
+	return nil
+}
+
+func (e *estreeEncoder) jsxElement(n *JSXElement) *estreeObject {
+	obj := e.node("JSXElement", n.Loc)
+	opening := e.node("JSXOpeningElement", Loc{}).set("name", e.jsxName(n.Name, true))
+	attributes := make([]interface{}, 0, len(n.Attrs))
+	for _, attr := range n.Attrs {
+		if attr.Spread {
+			attributes = append(attributes, e.node("JSXSpreadAttribute", attr.Loc).set("argument", e.expr(attr.Value)))
+			continue
+		}
+		item := e.node("JSXAttribute", attr.Loc)
+		name := &LiteralExpr{TokenType: JSXIdentifierToken, Data: attr.Name}
+		if attr.Loc.IsSet() {
+			name.Loc = Loc{attr.Start, attr.Start + len(attr.Name)}
+		}
+		item.set("name", e.jsxName(name, true))
+		if attr.Value == nil {
+			item.set("value", nil)
+		} else {
+			item.set("value", e.expr(attr.Value))
+		}
+		attributes = append(attributes, item)
+	}
+	opening.set("attributes", attributes).set("selfClosing", n.Children == nil)
+	obj.set("openingElement", opening).set("children", e.jsxChildren(n.Children))
+	if n.Children == nil {
+		return obj.set("closingElement", nil)
+	}
+	return obj.set("closingElement", e.node("JSXClosingElement", Loc{}).set("name", e.jsxName(n.Name, false)))
+}
+
+func (e *estreeEncoder) jsxChildren(children []IExpr) []interface{} {
+	objs := make([]interface{}, 0, len(children))
+	for _, child := range children {
+		objs = append(objs, e.expr(child))
+	}
+	return objs
+}
+
+func (e *estreeEncoder) jsxExprContainer(n *JSXExprContainer) *estreeObject {
+	if n.Spread {
+		return e.node("JSXSpreadChild", n.Loc).set("expression", e.expr(n.X))
+	}
+	obj := e.node("JSXExpressionContainer", n.Loc)
+	if n.X == nil {
+		empty := Loc{}
+		if n.Loc.IsSet() {
+			empty = Loc{n.Start + 1, n.End - 1}
+		}
+		return obj.set("expression", e.node("JSXEmptyExpression", empty))
+	}
+	return obj.set("expression", e.expr(n.X))
+}
+
+////////////////////////////////////////////////////////////////
+
+var binaryOps = map[string]TokenType{}
+var assignOps = map[string]TokenType{}
+
+func init() {
+	for _, tt := range []TokenType{EqEqToken, NotEqToken, EqEqEqToken, NotEqEqToken, LtToken, LtEqToken, GtToken, GtEqToken, InToken, InstanceofToken, LtLtToken, GtGtToken, GtGtGtToken, AddToken, SubToken, MulToken, DivToken, ModToken, ExpToken, BitOrToken, BitXorToken, BitAndToken, AndToken, OrToken, NullishToken} {
+		binaryOps[string(tt.Bytes())] = tt
+	}
+	for _, tt := range []TokenType{EqToken, MulEqToken, DivEqToken, ModEqToken, ExpEqToken, AddEqToken, SubEqToken, LtLtEqToken, GtGtEqToken, GtGtGtEqToken, BitAndEqToken, BitXorEqToken, BitOrEqToken, AndEqToken, OrEqToken, NullishEqToken} {
+		assignOps[string(tt.Bytes())] = tt
+	}
+}
+
+var unaryOps = map[string]TokenType{
+	"+":      PosToken,
+	"-":      NegToken,
+	"!":      NotToken,
+	"~":      BitNotToken,
+	"typeof": TypeofToken,
+	"void":   VoidToken,
+	"delete": DeleteToken,
+}
+
+var binaryPrecs = map[TokenType]OpPrec{
+	NullishToken:    OpCoalesce,
+	OrToken:         OpOr,
+	AndToken:        OpAnd,
+	BitOrToken:      OpBitOr,
+	BitXorToken:     OpBitXor,
+	BitAndToken:     OpBitAnd,
+	EqEqToken:       OpEquals,
+	NotEqToken:      OpEquals,
+	EqEqEqToken:     OpEquals,
+	NotEqEqToken:    OpEquals,
+	LtToken:         OpCompare,
+	GtToken:         OpCompare,
+	LtEqToken:       OpCompare,
+	GtEqToken:       OpCompare,
+	InstanceofToken: OpCompare,
+	InToken:         OpCompare,
+	LtLtToken:       OpShift,
+	GtGtToken:       OpShift,
+	GtGtGtToken:     OpShift,
+	AddToken:        OpAdd,
+	SubToken:        OpAdd,
+	MulToken:        OpMul,
+	DivToken:        OpMul,
+	ModToken:        OpMul,
+	ExpToken:        OpExp,
+}
+
+// exprPrec returns the precedence of an expression.
+func exprPrec(x IExpr) OpPrec {
+	switch n := x.(type) {
+	case *DotExpr:
+		return n.Prec
+	case *IndexExpr:
+		return n.Prec
+	case *CallExpr:
+		return n.Prec
+	case *TemplateExpr:
+		if n.Tag == nil {
+			return OpPrimary
+		}
+		return n.Prec
+	case *NewExpr:
+		if n.Args == nil {
+			return OpNew
+		}
+		return OpMember
+	case *UnaryExpr:
+		if n.Op == PostIncrToken || n.Op == PostDecrToken {
+			return OpUpdate
+		}
+		return OpUnary
+	case *BinaryExpr:
+		if prec, ok := binaryPrecs[n.Op]; ok {
+			return prec
+		}
+		return OpAssign
+	case *CondExpr, *ArrowFunc, *YieldExpr:
+		return OpAssign
+	case *CommaExpr:
+		return OpExpr
+	}
+	return OpPrimary
+}
+
+// groupExpr parenthesizes an expression when its precedence is lower than prec.
+func groupExpr(x IExpr, prec OpPrec) IExpr {
+	if x != nil && exprPrec(x) < prec {
+		return &GroupExpr{X: x, Loc: NodeLoc(x)}
+	}
+	return x
+}
+
+// ParseESTree parses a program in the ESTree JSON format, as written by ESTree, and returns its AST. Variables are declared in and resolved against their scopes as by the parser, and parentheses are added where needed by the precedence of operators. The range or start and end fields of the nodes are used as their locations when present. It returns an error that wraps ErrInvalidESTree if the JSON is not an ESTree program.
+func ParseESTree(b []byte) (*AST, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	d := &estreeDecoder{}
+	o, _ := v.(map[string]interface{})
+	if typ, _ := o["type"].(string); typ != "Program" {
+		return nil, fmt.Errorf("%w: expected Program instead of %s", ErrInvalidESTree, estreeType(o))
+	}
+	ast := &AST{}
+	d.enterScope(&ast.BlockStmt.Scope, true)
+	ast.List = d.stmts(o, "body", true)
+	ast.BlockStmt.Loc = d.loc(o)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return ast, nil
+}
+
+type estreeDecoder struct {
+	scope *Scope
+	err   error
+}
+
+func (d *estreeDecoder) fail(msg string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrInvalidESTree, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (d *estreeDecoder) enterScope(scope *Scope, isFunc bool) *Scope {
+	parent := d.scope
+	d.scope = scope
+	*scope = Scope{
+		Parent: parent,
+	}
+	if isFunc {
+		scope.Func = scope
+	} else if parent != nil {
+		scope.Func = parent.Func
+	}
+	return parent
+}
+
+func (d *estreeDecoder) exitScope(parent *Scope) {
+	d.scope.HoistUndeclared()
+	d.scope = parent
+}
+
+func (d *estreeDecoder) use(name []byte, loc Loc) *Var {
+	v := d.scope.Use(name)
+	if loc.IsSet() {
+		v.Locs = append(v.Locs, loc)
+	}
+	return v
+}
+
+func (d *estreeDecoder) declare(decl DeclType, name []byte, loc Loc) *Var {
+	v, ok := d.scope.Declare(decl, name)
+	if !ok {
+		d.fail("identifier %s has already been declared", name)
+	} else if loc.IsSet() {
+		v.Locs = append(v.Locs, loc)
+	}
+	return v
+}
+
+// estreeType returns the type of a node for error messages.
+func estreeType(o map[string]interface{}) string {
+	if typ, ok := o["type"].(string); ok {
+		return typ
+	} else if o == nil {
+		return "null"
+	}
+	return "object without type"
+}
+
+func child(o map[string]interface{}, key string) map[string]interface{} {
+	v, _ := o[key].(map[string]interface{})
+	return v
+}
+
+func children(o map[string]interface{}, key string) []map[string]interface{} {
+	list, _ := o[key].([]interface{})
+	objs := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		objs[i], _ = item.(map[string]interface{})
+	}
+	return objs
+}
+
+func boolean(o map[string]interface{}, key string) bool {
+	b, _ := o[key].(bool)
+	return b
+}
+
+func str(o map[string]interface{}, key string) string {
+	s, _ := o[key].(string)
+	return s
+}
+
+func (d *estreeDecoder) loc(o map[string]interface{}) Loc {
+	var start, end json.Number
+	if r, ok := o["range"].([]interface{}); ok && len(r) == 2 {
+		start, _ = r[0].(json.Number)
+		end, _ = r[1].(json.Number)
+	} else {
+		start, _ = o["start"].(json.Number)
+		end, _ = o["end"].(json.Number)
+	}
+	s, err := start.Int64()
+	if err != nil {
+		return Loc{}
+	}
+	e, err := end.Int64()
+	if err != nil {
+		return Loc{}
+	}
+	return Loc{int(s), int(e)}
+}
+
+// name returns the name of an identifier, or the raw string of a string literal for import and export names.
+func (d *estreeDecoder) name(o map[string]interface{}) []byte {
+	switch typ := estreeType(o); typ {
+	case "Identifier", "JSXIdentifier":
+		return []byte(str(o, "name"))
+	case "PrivateIdentifier":
+		return []byte("#" + str(o, "name"))
+	case "Literal":
+		lit := d.literal(o)
+		if lit != nil && lit.TokenType == StringToken {
+			return lit.Data
+		}
+	}
+	d.fail("expected identifier instead of %s", estreeType(o))
+	return nil
+}
+
+func (d *estreeDecoder) stmts(o map[string]interface{}, key string, directives bool) []IStmt {
+	objs := children(o, key)
+	list := make([]IStmt, 0, len(objs))
+	for _, item := range objs {
+		if directives && str(item, "directive") != "" {
+			if lit := d.literal(child(item, "expression")); lit != nil {
+				list = append(list, &DirectivePrologueStmt{lit.Data, d.loc(item)})
+			}
+			continue
+		}
+		directives = false
+		if stmt := d.stmt(item); stmt != nil {
+			list = append(list, stmt)
+		}
+	}
+	return list
+}
+
+func (d *estreeDecoder) block(o map[string]interface{}) *BlockStmt {
+	if o == nil {
+		return nil
+	} else if typ := estreeType(o); typ != "BlockStatement" {
+		d.fail("expected BlockStatement instead of %s", typ)
+		return nil
+	}
+	block := &BlockStmt{}
+	parent := d.enterScope(&block.Scope, false)
+	block.List = d.stmts(o, "body", false)
+	d.exitScope(parent)
+	block.Loc = d.loc(o)
+	return block
+}
+
+// forBody sets the statements of the body of a for statement, whose scope has already been entered.
+func (d *estreeDecoder) forBody(body *BlockStmt, o map[string]interface{}) {
+	d.scope.MarkForStmt()
+	switch estreeType(o) {
+	case "BlockStatement":
+		body.List = d.stmts(o, "body", false)
+	case "EmptyStatement":
+	default:
+		body.List = []IStmt{d.stmt(o)}
+	}
+	body.Loc = d.loc(o)
+}
+
+// forInit parenthesizes the in operators in the initializer of a for statement, which would otherwise make it a for-in statement.
+func forInit(init IExpr) IExpr {
+	switch n := init.(type) {
+	case *VarDecl:
+		for i := range n.List {
+			n.List[i].Default = forInit(n.List[i].Default)
+		}
+	case *CommaExpr:
+		for i := range n.List {
+			n.List[i] = forInit(n.List[i])
+		}
+	case *BinaryExpr:
+		if isAssignment(n.Op) {
+			n.Y = forInit(n.Y)
+		} else if hasInOp(n) {
+			return &GroupExpr{X: n, Loc: n.Loc}
+		}
+	default:
+		if hasInOp(init) {
+			return &GroupExpr{X: init, Loc: NodeLoc(init)}
+		}
+	}
+	return init
+}
+
+// hasInOp returns true if an expression has an in operator that is not enclosed by parentheses, brackets, or braces.
+func hasInOp(x IExpr) bool {
+	switch n := x.(type) {
+	case *BinaryExpr:
+		return n.Op == InToken || hasInOp(n.X) || hasInOp(n.Y)
+	case *UnaryExpr:
+		return hasInOp(n.X)
+	case *CondExpr:
+		return hasInOp(n.Cond) || hasInOp(n.X) || hasInOp(n.Y)
+	case *YieldExpr:
+		return hasInOp(n.X)
+	case *CommaExpr:
+		for _, item := range n.List {
+			if hasInOp(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exprStmt parenthesizes an expression that would otherwise start a declaration.
+func exprStmt(x IExpr) IExpr {
+	switch n := leftmostExpr(x).(type) {
+	case *ObjectExpr, *FuncDecl, *ClassDecl:
+		return &GroupExpr{X: x, Loc: NodeLoc(x)}
+	case *Var:
+		if bytes.Equal(n.Data, []byte("let")) {
+			return &GroupExpr{X: x, Loc: NodeLoc(x)}
+		}
+	}
+	return x
+}
+
+func (d *estreeDecoder) stmt(o map[string]interface{}) IStmt {
+	if d.err != nil {
+		return nil
+	}
+	loc := d.loc(o)
+	switch typ := estreeType(o); typ {
+	case "BlockStatement":
+		return d.block(o)
+	case "EmptyStatement":
+		return &EmptyStmt{loc}
+	case "ExpressionStatement":
+		return &ExprStmt{exprStmt(d.expr(child(o, "expression"))), loc}
+	case "IfStatement":
+		cond := d.expr(child(o, "test"))
+		body := d.stmt(child(o, "consequent"))
+		var elseBody IStmt
+		if alternate := child(o, "alternate"); alternate != nil {
+			elseBody = d.stmt(alternate)
+		}
+		return &IfStmt{cond, body, elseBody, loc}
+	case "DoWhileStatement":
+		body := d.stmt(child(o, "body"))
+		return &DoWhileStmt{d.expr(child(o, "test")), body, loc}
+	case "WhileStatement":
+		cond := d.expr(child(o, "test"))
+		return &WhileStmt{cond, d.stmt(child(o, "body")), loc}
+	case "ForStatement":
+		body := &BlockStmt{}
+		parent := d.enterScope(&body.Scope, false)
+		var init IExpr
+		if varDecl := child(o, "init"); estreeType(varDecl) == "VariableDeclaration" {
+			init = d.varDecl(varDecl, true)
+			init.(*VarDecl).InFor = true
+		} else if varDecl != nil {
+			init = d.expr(varDecl)
+		}
+		init = forInit(init)
+		var cond, post IExpr
+		if test := child(o, "test"); test != nil {
+			cond = d.expr(test)
+		}
+		if update := child(o, "update"); update != nil {
+			post = d.expr(update)
+		}
+		d.forBody(body, child(o, "body"))
+		if init == nil {
+			varDecl := &VarDecl{TokenType: VarToken, Scope: d.scope, InFor: true}
+			d.scope.Func.VarDecls = append(d.scope.Func.VarDecls, varDecl)
+			init = varDecl
+		}
+		d.exitScope(parent)
+		return &ForStmt{init, cond, post, body, loc}
+	case "ForInStatement", "ForOfStatement":
+		body := &BlockStmt{}
+		parent := d.enterScope(&body.Scope, false)
+		var init IExpr
+		if varDecl := child(o, "left"); estreeType(varDecl) == "VariableDeclaration" {
+			init = d.varDecl(varDecl, true)
+			init.(*VarDecl).InForInOf = true
+		} else {
+			init = d.target(varDecl)
+		}
+		value := d.expr(child(o, "right"))
+		d.forBody(body, child(o, "body"))
+		d.exitScope(parent)
+		if typ == "ForInStatement" {
+			return &ForInStmt{init, value, body, loc}
+		}
+		return &ForOfStmt{boolean(o, "await"), init, groupExpr(value, OpAssign), body, loc}
+	case "SwitchStatement":
+		switchStmt := &SwitchStmt{Init: d.expr(child(o, "discriminant"))}
+		parent := d.enterScope(&switchStmt.Scope, false)
+		for _, item := range children(o, "cases") {
+			clause := CaseClause{TokenType: DefaultToken, Loc: d.loc(item)}
+			if test := child(item, "test"); test != nil {
+				clause.TokenType = CaseToken
+				clause.Cond = d.expr(test)
+			}
+			clause.List = d.stmts(item, "consequent", false)
+			switchStmt.List = append(switchStmt.List, clause)
+		}
+		d.exitScope(parent)
+		switchStmt.Loc = loc
+		return switchStmt
+	case "BreakStatement", "ContinueStatement":
+		branchStmt := &BranchStmt{Type: BreakToken, Loc: loc}
+		if typ == "ContinueStatement" {
+			branchStmt.Type = ContinueToken
+		}
+		if label := child(o, "label"); label != nil {
+			branchStmt.Label = d.name(label)
+		}
+		return branchStmt
+	case "ReturnStatement":
+		var value IExpr
+		if argument := child(o, "argument"); argument != nil {
+			value = d.expr(argument)
+		}
+		return &ReturnStmt{value, loc}
+	case "WithStatement":
+		cond := d.expr(child(o, "object"))
+		d.scope.Func.HasWith = true
+		return &WithStmt{cond, d.stmt(child(o, "body")), loc}
+	case "LabeledStatement":
+		label := d.name(child(o, "label"))
+		return &LabelledStmt{label, d.stmt(child(o, "body")), loc}
+	case "ThrowStatement":
+		return &ThrowStmt{d.expr(child(o, "argument")), loc}
+	case "TryStatement":
+		tryStmt := &TryStmt{Body: d.block(child(o, "block")), Loc: loc}
+		if handler := child(o, "handler"); handler != nil {
+			tryStmt.Catch = &BlockStmt{}
+			parent := d.enterScope(&tryStmt.Catch.Scope, false)
+			if param := child(handler, "param"); param != nil {
+				tryStmt.Binding = d.binding(param, CatchDecl)
+			}
+			body := child(handler, "body")
+			tryStmt.Catch.List = d.stmts(body, "body", false)
+			tryStmt.Catch.Loc = d.loc(body)
+			d.exitScope(parent)
+		}
+		if finalizer := child(o, "finalizer"); finalizer != nil {
+			tryStmt.Finally = d.block(finalizer)
+		}
+		return tryStmt
+	case "DebuggerStatement":
+		return &DebuggerStmt{loc}
+	case "VariableDeclaration":
+		return d.varDecl(o, true)
+	case "FunctionDeclaration":
+		return d.function(o, false)
+	case "ClassDeclaration":
+		return d.class(o, false)
+	case "ImportDeclaration":
+		importStmt := &ImportStmt{Loc: loc}
+		for _, item := range children(o, "specifiers") {
+			switch typ := estreeType(item); typ {
+			case "ImportDefaultSpecifier":
+				importStmt.Default = d.name(child(item, "local"))
+			case "ImportNamespaceSpecifier":
+				importStmt.List = []Alias{{[]byte("*"), d.name(child(item, "local"))}}
+			case "ImportSpecifier":
+				name, binding := d.name(child(item, "imported")), d.name(child(item, "local"))
+				if bytes.Equal(name, binding) {
+					name = nil
+				}
+				importStmt.List = append(importStmt.List, Alias{name, binding})
+			default:
+				d.fail("unexpected %s in ImportDeclaration", typ)
+			}
+		}
+		importStmt.Module = d.name(child(o, "source"))
+		importStmt.Attributes = d.attributes(o)
+		return importStmt
+	case "ExportNamedDeclaration":
+		exportStmt := &ExportStmt{Loc: loc}
+		if decl := child(o, "declaration"); decl != nil {
+			switch typ := estreeType(decl); typ {
+			case "VariableDeclaration":
+				exportStmt.Decl = d.varDecl(decl, false)
+			case "FunctionDeclaration":
+				exportStmt.Decl = d.function(decl, false)
+			case "ClassDeclaration":
+				exportStmt.Decl = d.class(decl, false)
+			default:
+				d.fail("unexpected %s in ExportNamedDeclaration", typ)
+			}
+			return exportStmt
+		}
+		exportStmt.List = []Alias{}
+		for _, item := range children(o, "specifiers") {
+			name, binding := d.name(child(item, "local")), d.name(child(item, "exported"))
+			if bytes.Equal(name, binding) {
+				name = nil
+			}
+			exportStmt.List = append(exportStmt.List, Alias{name, binding})
+		}
+		if source := child(o, "source"); source != nil {
+			exportStmt.Module = d.name(source)
+			exportStmt.Attributes = d.attributes(o)
+		}
+		return exportStmt
+	case "ExportDefaultDeclaration":
+		exportStmt := &ExportStmt{Default: true, Loc: loc}
+		switch decl := child(o, "declaration"); estreeType(decl) {
+		case "FunctionDeclaration":
+			exportStmt.Decl = d.function(decl, false)
+		case "ClassDeclaration":
+			exportStmt.Decl = d.class(decl, false)
+		default:
+			x := groupExpr(d.expr(decl), OpAssign)
+			switch leftmostExpr(x).(type) {
+			case *FuncDecl, *ClassDecl:
+				x = &GroupExpr{X: x, Loc: NodeLoc(x)}
+			}
+			exportStmt.Decl = x
+		}
+		return exportStmt
+	case "ExportAllDeclaration":
+		exportStmt := &ExportStmt{Loc: loc}
+		if exported := child(o, "exported"); exported != nil {
+			exportStmt.List = []Alias{{[]byte("*"), d.name(exported)}}
+		} else {
+			exportStmt.List = []Alias{{nil, []byte("*")}}
+		}
+		exportStmt.Module = d.name(child(o, "source"))
+		exportStmt.Attributes = d.attributes(o)
+		return exportStmt
+	default:
+		d.fail("unexpected %s statement", typ)
+	}
+	return nil
+}
+
+func (d *estreeDecoder) attributes(o map[string]interface{}) []ImportAttribute {
+	items := children(o, "attributes")
+	if len(items) == 0 {
+		return nil
+	}
+	attributes := make([]ImportAttribute, 0, len(items))
+	for _, item := range items {
+		attributes = append(attributes, ImportAttribute{d.name(child(item, "key")), d.name(child(item, "value"))})
+	}
+	return attributes
+}
+
+func (d *estreeDecoder) varDecl(o map[string]interface{}, canBeHoisted bool) *VarDecl {
+	varDecl := &VarDecl{Scope: d.scope}
+	declType := LexicalDecl
+	switch kind := str(o, "kind"); kind {
+	case "var":
+		varDecl.TokenType = VarToken
+		declType = VariableDecl
+		if canBeHoisted {
+			d.scope.Func.VarDecls = append(d.scope.Func.VarDecls, varDecl)
+		}
+	case "let":
+		varDecl.TokenType = LetToken
+	case "const":
+		varDecl.TokenType = ConstToken
+	case "using", "await using":
+		varDecl.TokenType = UsingToken
+		varDecl.Await = kind == "await using"
+		declType = UsingDecl
+	default:
+		d.fail("unexpected %s declaration", kind)
+	}
+	for _, item := range children(o, "declarations") {
+		bindingElement := BindingElement{Binding: d.binding(child(item, "id"), declType), Loc: d.loc(item)}
+		if init := child(item, "init"); init != nil {
+			bindingElement.Default = groupExpr(d.expr(init), OpAssign)
+		}
+		varDecl.List = append(varDecl.List, bindingElement)
+	}
+	varDecl.Loc = d.loc(o)
+	return varDecl
+}
+
+func (d *estreeDecoder) binding(o map[string]interface{}, decl DeclType) IBinding {
+	switch typ := estreeType(o); typ {
+	case "Identifier":
+		return d.declare(decl, d.name(o), d.loc(o))
+	case "ArrayPattern":
+		array := &BindingArray{}
+		for _, item := range children(o, "elements") {
+			if estreeType(item) == "RestElement" {
+				array.Rest = d.binding(child(item, "argument"), decl)
+			} else if item == nil {
+				array.List = append(array.List, BindingElement{})
+			} else {
+				array.List = append(array.List, d.bindingElement(item, decl))
+			}
+		}
+		array.Loc = d.loc(o)
+		return array
+	case "ObjectPattern":
+		object := &BindingObject{}
+		for _, item := range children(o, "properties") {
+			if estreeType(item) == "RestElement" {
+				if rest, ok := d.binding(child(item, "argument"), decl).(*Var); ok {
+					object.Rest = rest
+				} else {
+					d.fail("expected Identifier in object rest element")
+				}
+				continue
+			}
+			key := d.propertyName(item)
+			if !key.IsComputed() {
+				key.Literal.Data = append([]byte{}, key.Literal.Data...) // copy so that renaming doesn't rename the key
+			}
+			object.List = append(object.List, BindingObjectItem{Key: &key, Value: d.bindingElement(child(item, "value"), decl)})
+		}
+		object.Loc = d.loc(o)
+		return object
+	default:
+		d.fail("unexpected %s in binding", typ)
+	}
+	return nil
+}
+
+func (d *estreeDecoder) bindingElement(o map[string]interface{}, decl DeclType) BindingElement {
+	if estreeType(o) == "AssignmentPattern" {
+		binding := d.binding(child(o, "left"), decl)
+		return BindingElement{binding, groupExpr(d.expr(child(o, "right")), OpAssign), d.loc(o)}
+	}
+	return BindingElement{Binding: d.binding(o, decl), Loc: d.loc(o)}
+}
+
+func (d *estreeDecoder) params(o map[string]interface{}) (params Params) {
+	for _, item := range children(o, "params") {
+		if estreeType(item) == "RestElement" {
+			params.Rest = d.binding(child(item, "argument"), ArgumentDecl)
+		} else {
+			params.List = append(params.List, d.bindingElement(item, ArgumentDecl))
+		}
+	}
+
+	// mark undeclared vars as arguments in `function f(a=b){var b}` where the b's are different vars
+	d.scope.MarkFuncArgs()
+	return
+}
+
+// funcBody sets the parameters and body of a function, whose scope has already been entered.
+func (d *estreeDecoder) funcBody(params *Params, body *BlockStmt, o map[string]interface{}) {
+	*params = d.params(o)
+	block := child(o, "body")
+	body.List = d.stmts(block, "body", true)
+	body.Loc = d.loc(block)
+}
+
+func (d *estreeDecoder) function(o map[string]interface{}, expr bool) *FuncDecl {
+	funcDecl := &FuncDecl{Async: boolean(o, "async"), Generator: boolean(o, "generator")}
+	id := child(o, "id")
+	if id != nil && !expr {
+		funcDecl.Name = d.declare(FunctionDecl, d.name(id), d.loc(id))
+	}
+	parent := d.enterScope(&funcDecl.Body.Scope, true)
+	if id != nil && expr {
+		funcDecl.Name = d.declare(ExprDecl, d.name(id), d.loc(id))
+	}
+	d.funcBody(&funcDecl.Params, &funcDecl.Body, o)
+	d.exitScope(parent)
+	funcDecl.Loc = d.loc(o)
+	return funcDecl
+}
+
+func (d *estreeDecoder) arrow(o map[string]interface{}) *ArrowFunc {
+	arrowFunc := &ArrowFunc{Async: boolean(o, "async")}
+	parent := d.enterScope(&arrowFunc.Body.Scope, true)
+	if body := child(o, "body"); boolean(o, "expression") || estreeType(body) != "BlockStatement" {
+		arrowFunc.Params = d.params(o)
+		x := groupExpr(d.expr(body), OpAssign)
+		if _, ok := leftmostExpr(x).(*ObjectExpr); ok {
+			x = &GroupExpr{X: x, Loc: NodeLoc(x)}
+		}
+		arrowFunc.Body.Loc = d.loc(body)
+		arrowFunc.Body.List = []IStmt{&ReturnStmt{x, arrowFunc.Body.Loc}}
+	} else {
+		d.funcBody(&arrowFunc.Params, &arrowFunc.Body, o)
+	}
+	d.exitScope(parent)
+	arrowFunc.Loc = d.loc(o)
+	return arrowFunc
+}
+
+func (d *estreeDecoder) decorators(o map[string]interface{}) []IExpr {
+	var decorators []IExpr
+	for _, item := range children(o, "decorators") {
+		x := d.expr(child(item, "expression"))
+		if !isDecorator(x) {
+			x = &GroupExpr{X: x, Loc: NodeLoc(x)}
+		}
+		decorators = append(decorators, x)
+	}
+	return decorators
+}
+
+// isDecorator returns true if the expression can be a decorator without parentheses, which is a dotted name optionally followed by arguments.
+func isDecorator(x IExpr) bool {
+	if call, ok := x.(*CallExpr); ok && !call.Optional {
+		x = call.X
+	}
+	for {
+		switch n := x.(type) {
+		case *Var:
+			return true
+		case *DotExpr:
+			if n.Optional || n.Prec == OpOpt {
+				return false
+			}
+			x = n.X
+		default:
+			return false
+		}
+	}
+}
+
+func (d *estreeDecoder) class(o map[string]interface{}, expr bool) *ClassDecl {
+	classDecl := &ClassDecl{Decorators: d.decorators(o)}
+	if id := child(o, "id"); id != nil {
+		if !expr {
+			classDecl.Name = d.declare(LexicalDecl, d.name(id), d.loc(id))
+		} else {
+			// classes do not register vars
+			classDecl.Name = &Var{d.name(id), nil, 1, ExprDecl, nil}
+			if loc := d.loc(id); loc.IsSet() {
+				classDecl.Name.Locs = []Loc{loc}
+			}
+		}
+	}
+	if superClass := child(o, "superClass"); superClass != nil {
+		classDecl.Extends = groupExpr(d.expr(superClass), OpLHS)
+	}
+	parent := d.enterScope(&classDecl.Scope, false)
+	for _, item := range children(child(o, "body"), "body") {
+		elem := ClassElement{Loc: d.loc(item)}
+		switch typ := estreeType(item); typ {
+		case "StaticBlock":
+			elem.StaticBlock = &BlockStmt{}
+			blockParent := d.enterScope(&elem.StaticBlock.Scope, false)
+			elem.StaticBlock.List = d.stmts(item, "body", false)
+			d.exitScope(blockParent)
+		case "MethodDefinition":
+			method := &MethodDecl{Decorators: d.decorators(item), Static: boolean(item, "static")}
+			method.Get = str(item, "kind") == "get"
+			method.Set = str(item, "kind") == "set"
+			method.Name = d.elementName(item)
+			value := child(item, "value")
+			method.Async, method.Generator = boolean(value, "async"), boolean(value, "generator")
+			methodParent := d.enterScope(&method.Body.Scope, true)
+			d.funcBody(&method.Params, &method.Body, value)
+			d.exitScope(methodParent)
+			method.Loc = elem.Loc
+			elem.Method = method
+		case "PropertyDefinition", "AccessorProperty":
+			elem.Field = Field{Decorators: d.decorators(item), Static: boolean(item, "static"), Accessor: typ == "AccessorProperty"}
+			elem.Field.Name = d.elementName(item)
+			if value := child(item, "value"); value != nil {
+				elem.Field.Init = groupExpr(d.expr(value), OpAssign)
+			}
+			elem.Field.Loc = elem.Loc
+		default:
+			d.fail("unexpected %s in class body", typ)
+		}
+		classDecl.List = append(classDecl.List, elem)
+	}
+	d.exitScope(parent)
+	classDecl.Loc = d.loc(o)
+	return classDecl
+}
+
+func (d *estreeDecoder) elementName(o map[string]interface{}) ClassElementName {
+	if key := child(o, "key"); estreeType(key) == "PrivateIdentifier" {
+		return ClassElementName{Private: d.declare(PrivateDecl, d.name(key), d.loc(key))}
+	}
+	return ClassElementName{PropertyName: d.propertyName(o)}
+}
+
+func (d *estreeDecoder) propertyName(o map[string]interface{}) PropertyName {
+	key := child(o, "key")
+	if boolean(o, "computed") {
+		return PropertyName{Computed: groupExpr(d.expr(key), OpAssign)}
+	} else if estreeType(key) == "Identifier" {
+		return PropertyName{Literal: LiteralExpr{IdentifierToken, d.name(key), d.loc(key)}}
+	} else if estreeType(key) == "Literal" {
+		if lit := d.literal(key); lit != nil {
+			return PropertyName{Literal: *lit}
+		}
+		return PropertyName{}
+	}
+	d.fail("unexpected %s in property name", estreeType(key))
+	return PropertyName{}
+}
+
+func (d *estreeDecoder) literal(o map[string]interface{}) *LiteralExpr {
+	if estreeType(o) != "Literal" {
+		d.fail("expected Literal instead of %s", estreeType(o))
+		return nil
+	}
+	loc := d.loc(o)
+	raw, hasRaw := o["raw"].(string)
+	if regex := child(o, "regex"); regex != nil {
+		if !hasRaw {
+			raw = "/" + str(regex, "pattern") + "/" + str(regex, "flags")
+		}
+		return &LiteralExpr{RegExpToken, []byte(raw), loc}
+	} else if bigint, ok := o["bigint"].(string); ok {
+		if !hasRaw {
+			raw = bigint + "n"
+		}
+		return &LiteralExpr{IntegerToken, []byte(raw), loc}
+	}
+	switch value := o["value"].(type) {
+	case nil:
+		return &LiteralExpr{NullToken, []byte("null"), loc}
+	case bool:
+		if value {
+			return &LiteralExpr{TrueToken, []byte("true"), loc}
+		}
+		return &LiteralExpr{FalseToken, []byte("false"), loc}
+	case string:
+		if !hasRaw || len(raw) < 2 || raw[0] != '"' && raw[0] != '\'' {
+			return &LiteralExpr{StringToken, QuoteString(value), loc}
+		}
+		return &LiteralExpr{StringToken, []byte(raw), loc}
+	case json.Number:
+		if !hasRaw {
+			f, err := value.Float64()
+			if err != nil {
+				d.fail("invalid number %s", value)
+				return nil
+			}
+			raw = numberString(f)
+		}
+		return &LiteralExpr{numericToken(raw), []byte(raw), loc}
+	}
+	d.fail("unexpected Literal value %v", o["value"])
+	return nil
+}
+
+// numericToken returns the token type of a numeric literal.
+func numericToken(s string) TokenType {
+	if 2 < len(s) && s[0] == '0' && !strings.HasSuffix(s, "n") {
+		switch s[1] {
+		case 'x', 'X':
+			return HexadecimalToken
+		case 'b', 'B':
+			return BinaryToken
+		case 'o', 'O':
+			return OctalToken
+		}
+	}
+	if strings.ContainsAny(s, ".eE") && !strings.HasSuffix(s, "n") {
+		return DecimalToken
+	}
+	return IntegerToken
+}
+
+func (d *estreeDecoder) args(list []map[string]interface{}) Args {
+	args := Args{List: make([]Arg, 0, len(list))}
+	for _, item := range list {
+		if estreeType(item) == "SpreadElement" {
+			args.List = append(args.List, Arg{groupExpr(d.expr(child(item, "argument")), OpAssign), true})
+		} else {
+			args.List = append(args.List, Arg{groupExpr(d.expr(item), OpAssign), false})
+		}
+	}
+	return args
+}
+
+func (d *estreeDecoder) template(o map[string]interface{}) *TemplateExpr {
+	template := &TemplateExpr{Prec: OpMember, Loc: d.loc(o)}
+	quasis, expressions := children(o, "quasis"), children(o, "expressions")
+	if len(quasis) != len(expressions)+1 {
+		d.fail("expected one more quasi than expressions in TemplateLiteral")
+		return template
+	}
+	for i, quasi := range quasis {
+		raw, ok := child(quasi, "value")["raw"].(string)
+		if !ok {
+			raw = strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(str(child(quasi, "value"), "cooked"))
+		}
+		open := "`"
+		if i != 0 {
+			open = "}"
+		}
+		if i == len(expressions) {
+			template.Tail = []byte(open + raw + "`")
+		} else {
+			template.List = append(template.List, TemplatePart{[]byte(open + raw + "${"), d.expr(expressions[i])})
+		}
+	}
+	return template
+}
+
+// chainObject returns the object of a member, call, or tagged template expression. Within an optional chain, the object continues the chain, otherwise it is parenthesized when needed.
+func (d *estreeDecoder) chainObject(o map[string]interface{}, chain bool) IExpr {
+	switch typ := estreeType(o); typ {
+	case "MemberExpression", "CallExpression", "TaggedTemplateExpression":
+		if chain {
+			return d.chainElement(o, true)
+		}
+	case "Super":
+		return &LiteralExpr{SuperToken, []byte("super"), d.loc(o)}
+	}
+	x := d.expr(o)
+	if lit, ok := x.(*LiteralExpr); ok && IsNumeric(lit.TokenType) {
+		return &GroupExpr{X: x, Loc: NodeLoc(x)} // prevent 1.toString()
+	} else if prec := exprPrec(x); prec <= OpOpt || prec == OpNew {
+		return &GroupExpr{X: x, Loc: NodeLoc(x)}
+	}
+	return x
+}
+
+// chainElement returns a member, call, or tagged template expression, where chain is set within a ChainExpression.
+func (d *estreeDecoder) chainElement(o map[string]interface{}, chain bool) IExpr {
+	loc := d.loc(o)
+	optional := boolean(o, "optional")
+	precOf := func(x IExpr, prec OpPrec) OpPrec {
+		if optional || exprPrec(x) == OpOpt {
+			return OpOpt
+		} else if exprPrec(x) == OpCall {
+			return OpCall
+		}
+		return prec
+	}
+	switch typ := estreeType(o); typ {
+	case "MemberExpression":
+		x := d.chainObject(child(o, "object"), chain)
+		property := child(o, "property")
+		if boolean(o, "computed") {
+			return &IndexExpr{x, d.expr(property), precOf(x, OpMember), optional, loc}
+		} else if estreeType(property) == "PrivateIdentifier" {
+			return &DotExpr{x, d.use(d.name(property), d.loc(property)), precOf(x, OpMember), optional, loc}
+		}
+		return &DotExpr{x, LiteralExpr{IdentifierToken, d.name(property), d.loc(property)}, precOf(x, OpMember), optional, loc}
+	case "CallExpression":
+		x := d.chainObject(child(o, "callee"), chain)
+		args := d.args(children(o, "arguments"))
+		return &CallExpr{x, args, precOf(x, OpCall), optional, loc}
+	case "TaggedTemplateExpression":
+		x := d.chainObject(child(o, "tag"), chain)
+		template := d.template(child(o, "quasi"))
+		template.Tag = x
+		template.Prec = precOf(x, OpMember)
+		template.Loc = loc
+		return template
+	}
+	return d.expr(o)
+}
+
+// binaryExpr returns a binary expression, where the operands are parenthesized when needed.
+func binaryExpr(op TokenType, x, y IExpr, loc Loc) *BinaryExpr {
+	prec := binaryPrecs[op]
+	if op == ExpToken {
+		// right associative and the base cannot be a unary expression
+		if exprPrec(x) <= OpUnary {
+			x = &GroupExpr{X: x, Loc: NodeLoc(x)}
+		}
+		return &BinaryExpr{op, x, groupExpr(y, OpExp), loc}
+	}
+
+	x, y = groupExpr(x, prec), groupExpr(y, prec+1)
+	if op == NullishToken || op == AndToken || op == OrToken {
+		// ?? cannot be mixed with && or || without parentheses
+		for _, z := range []*IExpr{&x, &y} {
+			if bin, ok := (*z).(*BinaryExpr); ok && (op == NullishToken) != (bin.Op == NullishToken) && (bin.Op == NullishToken || bin.Op == AndToken || bin.Op == OrToken) {
+				*z = &GroupExpr{X: bin, Loc: bin.Loc}
+			}
+		}
+	}
+	return &BinaryExpr{op, x, y, loc}
+}
+
+// unaryArg parenthesizes the argument of a unary expression when needed.
+func unaryArg(x IExpr) IExpr {
+	if bin, ok := x.(*BinaryExpr); ok && bin.Op == ExpToken {
+		return &GroupExpr{X: x, Loc: bin.Loc}
+	}
+	return groupExpr(x, OpUnary)
+}
+
+func (d *estreeDecoder) expr(o map[string]interface{}) IExpr {
+	if d.err != nil {
+		return nil
+	}
+	loc := d.loc(o)
+	switch typ := estreeType(o); typ {
+	case "Identifier", "PrivateIdentifier":
+		return d.use(d.name(o), loc)
+	case "Literal":
+		if lit := d.literal(o); lit != nil {
+			return lit
+		}
+	case "ThisExpression":
+		return &LiteralExpr{ThisToken, []byte("this"), loc}
+	case "ArrayExpression":
+		array := &ArrayExpr{Loc: loc}
+		for _, item := range children(o, "elements") {
+			if item == nil {
+				array.List = append(array.List, Element{})
+			} else if estreeType(item) == "SpreadElement" {
+				array.List = append(array.List, Element{groupExpr(d.expr(child(item, "argument")), OpAssign), true})
+			} else {
+				array.List = append(array.List, Element{groupExpr(d.expr(item), OpAssign), false})
+			}
+		}
+		return array
+	case "ObjectExpression":
+		object := &ObjectExpr{Loc: loc}
+		for _, item := range children(o, "properties") {
+			object.List = append(object.List, d.property(item))
+		}
+		return object
+	case "FunctionExpression":
+		return d.function(o, true)
+	case "ArrowFunctionExpression":
+		return d.arrow(o)
+	case "ClassExpression":
+		return d.class(o, true)
+	case "TemplateLiteral":
+		return d.template(o)
+	case "MemberExpression", "CallExpression", "TaggedTemplateExpression":
+		return d.chainElement(o, false)
+	case "ChainExpression":
+		return d.chainElement(child(o, "expression"), true)
+	case "ImportExpression":
+		x := &LiteralExpr{ImportToken, []byte("import"), Loc{}}
+		if loc.IsSet() {
+			x.Loc = Loc{loc.Start, loc.Start + 6}
+		}
+		args := d.args([]map[string]interface{}{child(o, "source")})
+		if options := child(o, "options"); options != nil {
+			args.List = append(args.List, Arg{groupExpr(d.expr(options), OpAssign), false})
+		}
+		return &CallExpr{x, args, OpCall, false, loc}
+	case "NewExpression":
+		x := d.expr(child(o, "callee"))
+		if prec := exprPrec(x); prec < OpMember || hasCall(x) {
+			x = &GroupExpr{X: x, Loc: NodeLoc(x)}
+		}
+		newExpr := &NewExpr{X: x, Loc: loc}
+		if args := children(o, "arguments"); len(args) != 0 {
+			list := d.args(args)
+			newExpr.Args = &list
+		}
+		return newExpr
+	case "MetaProperty":
+		if meta := str(child(o, "meta"), "name"); meta == "new" {
+			return &NewTargetExpr{loc}
+		} else if meta == "import" {
+			return &ImportMetaExpr{loc}
+		}
+		d.fail("unexpected MetaProperty %s", str(child(o, "meta"), "name"))
+	case "UnaryExpression":
+		op, ok := unaryOps[str(o, "operator")]
+		if !ok {
+			d.fail("unexpected unary operator %s", str(o, "operator"))
+			return nil
+		}
+		return &UnaryExpr{op, unaryArg(d.expr(child(o, "argument"))), loc}
+	case "UpdateExpression":
+		op := PreIncrToken
+		if str(o, "operator") == "--" {
+			op = PreDecrToken
+		} else if str(o, "operator") != "++" {
+			d.fail("unexpected update operator %s", str(o, "operator"))
+			return nil
+		}
+		if !boolean(o, "prefix") {
+			op += PostIncrToken - PreIncrToken
+		}
+		return &UnaryExpr{op, groupExpr(d.expr(child(o, "argument")), OpLHS), loc}
+	case "AwaitExpression":
+		return &UnaryExpr{AwaitToken, unaryArg(d.expr(child(o, "argument"))), loc}
+	case "BinaryExpression", "LogicalExpression":
+		op, ok := binaryOps[str(o, "operator")]
+		if !ok {
+			d.fail("unexpected binary operator %s", str(o, "operator"))
+			return nil
+		}
+		x := d.expr(child(o, "left"))
+		return binaryExpr(op, x, d.expr(child(o, "right")), loc)
+	case "AssignmentExpression":
+		op, ok := assignOps[str(o, "operator")]
+		if !ok {
+			d.fail("unexpected assignment operator %s", str(o, "operator"))
+			return nil
+		}
+		x := d.target(child(o, "left"))
+		return &BinaryExpr{op, x, groupExpr(d.expr(child(o, "right")), OpAssign), loc}
+	case "ConditionalExpression":
+		cond := groupExpr(d.expr(child(o, "test")), OpCoalesce)
+		x := groupExpr(d.expr(child(o, "consequent")), OpAssign)
+		return &CondExpr{cond, x, groupExpr(d.expr(child(o, "alternate")), OpAssign), loc}
+	case "YieldExpression":
+		yieldExpr := &YieldExpr{Generator: boolean(o, "delegate"), Loc: loc}
+		if argument := child(o, "argument"); argument != nil {
+			yieldExpr.X = groupExpr(d.expr(argument), OpAssign)
+		}
+		return yieldExpr
+	case "SequenceExpression":
+		commaExpr := &CommaExpr{Loc: loc}
+		for _, item := range children(o, "expressions") {
+			commaExpr.List = append(commaExpr.List, groupExpr(d.expr(item), OpAssign))
+		}
+		return commaExpr
+	case "ParenthesizedExpression":
+		return &GroupExpr{d.expr(child(o, "expression")), loc}
+	case "JSXElement":
+		return d.jsxElement(o)
+	case "JSXFragment":
+		return &JSXFragment{d.jsxChildren(o), loc}
+	default:
+		d.fail("unexpected %s expression", typ)
+	}
+	return nil
+}
+
+// hasCall returns true if a member expression contains a call, which must be parenthesized in a new expression.
+func hasCall(x IExpr) bool {
+	for {
+		switch n := x.(type) {
+		case *CallExpr:
+			return true
+		case *DotExpr:
+			x = n.X
+		case *IndexExpr:
+			x = n.X
+		case *TemplateExpr:
+			if n.Tag == nil {
+				return false
+			}
+			x = n.Tag
+		default:
+			return false
+		}
+	}
+}
+
+func (d *estreeDecoder) property(o map[string]interface{}) Property {
+	loc := d.loc(o)
+	if estreeType(o) == "SpreadElement" {
+		return Property{Spread: true, Value: groupExpr(d.expr(child(o, "argument")), OpAssign), Loc: loc}
+	} else if estreeType(o) != "Property" {
+		d.fail("unexpected %s in object", estreeType(o))
+		return Property{}
+	}
+
+	kind := str(o, "kind")
+	if kind == "get" || kind == "set" || boolean(o, "method") {
+		value := child(o, "value")
+		method := &MethodDecl{Get: kind == "get", Set: kind == "set", Async: boolean(value, "async"), Generator: boolean(value, "generator")}
+		method.Name.PropertyName = d.propertyName(o)
+		parent := d.enterScope(&method.Body.Scope, true)
+		d.funcBody(&method.Params, &method.Body, value)
+		d.exitScope(parent)
+		method.Loc = loc
+		return Property{Value: method, Loc: loc}
+	}
+
+	name := d.propertyName(o)
+	if boolean(o, "shorthand") {
+		name.Literal.Data = append([]byte{}, name.Literal.Data...) // copy so that renaming doesn't rename the key
+		property := Property{Name: &name, Loc: loc}
+		if value := child(o, "value"); estreeType(value) == "AssignmentPattern" {
+			left := child(value, "left")
+			property.Value = d.use(d.name(left), d.loc(left))
+			property.Init = groupExpr(d.expr(child(value, "right")), OpAssign)
+		} else {
+			property.Value = d.use(d.name(value), d.loc(value))
+		}
+		return property
+	}
+	return Property{Name: &name, Value: groupExpr(d.expr(child(o, "value")), OpAssign), Loc: loc}
+}
+
+// target returns the expression of a pattern that is an assignment target.
+func (d *estreeDecoder) target(o map[string]interface{}) IExpr {
+	loc := d.loc(o)
+	switch estreeType(o) {
+	case "ArrayPattern":
+		array := &ArrayExpr{Loc: loc}
+		for _, item := range children(o, "elements") {
+			if item == nil {
+				array.List = append(array.List, Element{})
+			} else if estreeType(item) == "RestElement" {
+				array.List = append(array.List, Element{d.target(child(item, "argument")), true})
+			} else {
+				array.List = append(array.List, Element{d.targetElement(item), false})
+			}
+		}
+		return array
+	case "ObjectPattern":
+		object := &ObjectExpr{Loc: loc}
+		for _, item := range children(o, "properties") {
+			if estreeType(item) == "RestElement" {
+				object.List = append(object.List, Property{Spread: true, Value: d.target(child(item, "argument")), Loc: d.loc(item)})
+			} else if boolean(item, "shorthand") {
+				object.List = append(object.List, d.property(item))
+			} else {
+				name := d.propertyName(item)
+				object.List = append(object.List, Property{Name: &name, Value: d.targetElement(child(item, "value")), Loc: d.loc(item)})
+			}
+		}
+		return object
+	}
+	return d.expr(o)
+}
+
+func (d *estreeDecoder) targetElement(o map[string]interface{}) IExpr {
+	if estreeType(o) == "AssignmentPattern" {
+		x := d.target(child(o, "left"))
+		return &BinaryExpr{EqToken, x, groupExpr(d.expr(child(o, "right")), OpAssign), d.loc(o)}
+	}
+	return d.target(o)
+}
+
+func (d *estreeDecoder) jsxName(o map[string]interface{}, member bool) IExpr {
+	loc := d.loc(o)
+	switch typ := estreeType(o); typ {
+	case "JSXIdentifier":
+		name := []byte(str(o, "name"))
+		if bytes.Equal(name, []byte("this")) {
+			return &LiteralExpr{ThisToken, name, loc}
+		} else if !member && (bytes.IndexByte(name, '-') != -1 || 0 < len(name) && 'a' <= name[0] && name[0] <= 'z') {
+			// intrinsic element, such as div
+			return &LiteralExpr{JSXIdentifierToken, name, loc}
+		}
+		return d.use(name, loc)
+	case "JSXNamespacedName":
+		name := str(child(o, "namespace"), "name") + ":" + str(child(o, "name"), "name")
+		return &LiteralExpr{JSXIdentifierToken, []byte(name), loc}
+	case "JSXMemberExpression":
+		x := d.jsxName(child(o, "object"), true)
+		property := child(o, "property")
+		return &DotExpr{x, LiteralExpr{IdentifierToken, []byte(str(property, "name")), d.loc(property)}, OpMember, false, loc}
+	default:
+		d.fail("unexpected %s in JSX element name", typ)
+	}
+	return nil
+}
+
+func (d *estreeDecoder) jsxElement(o map[string]interface{}) IExpr {
+	opening := child(o, "openingElement")
+	element := &JSXElement{Name: d.jsxName(child(opening, "name"), false), Loc: d.loc(o)}
+	for _, item := range children(opening, "attributes") {
+		loc := d.loc(item)
+		if estreeType(item) == "JSXSpreadAttribute" {
+			element.Attrs = append(element.Attrs, JSXAttribute{nil, groupExpr(d.expr(child(item, "argument")), OpAssign), true, loc})
+			continue
+		} else if estreeType(item) != "JSXAttribute" {
+			d.fail("unexpected %s in JSX attributes", estreeType(item))
+			return nil
+		}
+
+		attr := JSXAttribute{Loc: loc}
+		if name := child(item, "name"); estreeType(name) == "JSXNamespacedName" {
+			attr.Name = []byte(str(child(name, "namespace"), "name") + ":" + str(child(name, "name"), "name"))
+		} else {
+			attr.Name = []byte(str(name, "name"))
+		}
+		switch value := child(item, "value"); estreeType(value) {
+		case "null":
+		case "Literal":
+			raw, ok := value["raw"].(string)
+			if !ok {
+				raw = `"` + html.EscapeString(str(value, "value")) + `"`
+			}
+			attr.Value = &LiteralExpr{JSXStringToken, []byte(raw), d.loc(value)}
+		case "JSXExpressionContainer":
+			attr.Value = &JSXExprContainer{X: groupExpr(d.expr(child(value, "expression")), OpAssign), Loc: d.loc(value)}
+		default:
+			attr.Value = d.expr(value)
+		}
+		element.Attrs = append(element.Attrs, attr)
+	}
+	if !boolean(opening, "selfClosing") {
+		element.Children = d.jsxChildren(o)
+		if closing := child(o, "closingElement"); closing != nil {
+			d.jsxName(child(closing, "name"), false) // uses the variables of the closing tag
+		}
+	}
+	return element
+}
+
+func (d *estreeDecoder) jsxChildren(o map[string]interface{}) []IExpr {
+	var list []IExpr
+	for _, item := range children(o, "children") {
+		loc := d.loc(item)
+		switch typ := estreeType(item); typ {
+		case "JSXText":
+			raw, ok := item["raw"].(string)
+			if !ok {
+				raw = html.EscapeString(str(item, "value"))
+			}
+			list = append(list, &LiteralExpr{JSXTextToken, []byte(raw), loc})
+		case "JSXExpressionContainer":
+			container := &JSXExprContainer{Loc: loc}
+			if expression := child(item, "expression"); estreeType(expression) != "JSXEmptyExpression" {
+				container.X = groupExpr(d.expr(expression), OpAssign)
+			}
+			list = append(list, container)
+		case "JSXSpreadChild":
+			list = append(list, &JSXExprContainer{groupExpr(d.expr(child(item, "expression")), OpAssign), true, loc})
+		case "JSXElement", "JSXFragment":
+			list = append(list, d.expr(item))
+		default:
+			d.fail("unexpected %s in JSX children", typ)
+		}
+	}
+	return list
+}
@@ -0,0 +1,147 @@
+package js
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestESTree(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"a = 1", `{"type":"Program","range":[0,5],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,5],"expression":{"type":"AssignmentExpression","range":[0,5],"operator":"=","left":{"type":"Identifier","range":[0,1],"name":"a"},"right":{"type":"Literal","range":[4,5],"value":1,"raw":"1"}}}]}`},
+		{"a?.b", `{"type":"Program","range":[0,4],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,4],"expression":{"type":"ChainExpression","range":[0,4],"expression":{"type":"MemberExpression","range":[0,4],"object":{"type":"Identifier","range":[0,1],"name":"a"},"property":{"type":"Identifier","range":[3,4],"name":"b"},"computed":false,"optional":true}}}]}`},
+		{"x => 1n", `{"type":"Program","range":[0,7],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,7],"expression":{"type":"ArrowFunctionExpression","range":[0,7],"id":null,"expression":true,"generator":false,"async":false,"params":[{"type":"Identifier","range":[0,1],"name":"x"}],"body":{"type":"Literal","range":[5,7],"value":null,"raw":"1n","bigint":"1"}}}]}`},
+		{"'use strict'", `{"type":"Program","range":[0,12],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,12],"expression":{"type":"Literal","range":[0,12],"value":"use strict","raw":"'use strict'"},"directive":"use strict"}]}`},
+		{"/a/g", `{"type":"Program","range":[0,4],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,4],"expression":{"type":"Literal","range":[0,4],"value":null,"raw":"/a/g","regex":{"pattern":"a","flags":"g"}}}]}`},
+		{"f`\\u`", `{"type":"Program","range":[0,5],"sourceType":"module","body":[{"type":"ExpressionStatement","range":[0,5],"expression":{"type":"TaggedTemplateExpression","range":[0,5],"tag":{"type":"Identifier","range":[0,1],"name":"f"},"quasi":{"type":"TemplateLiteral","quasis":[{"type":"TemplateElement","value":{"raw":"\\u","cooked":null},"tail":true}],"expressions":[]}}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			test.Error(t, err)
+			buf := &bytes.Buffer{}
+			test.Error(t, ESTree(buf, ast, ESTreeOptions{}))
+			test.String(t, buf.String(), tt.expected)
+		})
+	}
+}
+
+func TestESTreeLoc(t *testing.T) {
+	src := []byte("x\r\n\"é😀\" + y")
+	ast, err := Parse(parse.NewInput(bytes.NewReader(src)), Options{VarLocs: true})
+	test.Error(t, err)
+	buf := &bytes.Buffer{}
+	test.Error(t, ESTree(buf, ast.List[1].(*ExprStmt).Value.(*BinaryExpr).Y, ESTreeOptions{Src: src}))
+	test.String(t, buf.String(), `{"type":"Identifier","range":[14,15],"loc":{"start":{"line":2,"column":8},"end":{"line":2,"column":9}},"name":"y"}`)
+}
+
+func TestESTreeRoundTrip(t *testing.T) {
+	var tests = []string{
+		"var a = 1, b; let {c, d: [e = 2, ...f], ...g} = h; const i = 5n",
+		"(a + b) * c; a ** -b; (-a) ** b; a ?? (b || c); a, b",
+		"a?.b.c(d)?.[e]; (a?.b).c; new (a())(); new a; new a.b(c); (1).toString()",
+		"x = `a${b}c${d}e`; tag`x${y}`",
+		"class A extends (B, C) { #x = 1; static y; get z() { return this.#x } static { a } constructor(a, ...b) { super(a) } accessor w }",
+		"@dec class B { @a.b() m() {} }",
+		"function* f(a = 1, {b}, [c]) { yield* a; yield; 'use strict' }",
+		"async function g() { await a; for await (const x of y) {} }",
+		"for (;;); for (var i = 0; i < 5; i++) a(); for (a in b) { c } for (const [a, b] of c) d",
+		"for (let i = 0, j = (a in b); ;) {} for ((a in b);;); for (x = (a ? b : c in d), y;;);",
+		"switch (a) { case 1: b; break; default: c }",
+		"try { a } catch ({e}) { b } finally { c } try {} catch {}",
+		"l: while (a) { if (b) continue l; else break l }",
+		"do a; while (b); with (a) b; throw a; debugger",
+		"x = {a, b: c, [d]: e, f() {}, get g() {}, set h(v) {}, ...i, async *j() {}, 'k': 1, 2: 3}",
+		"({a, b = 1, c: [d]} = e); [a, , b = 2, ...c] = d",
+		"a => a; async (a, b) => { return a }; () => ({}); (a = 1) => a",
+		"import a, {b, c as d, 'e' as f} from 'g' with {type: 'json'}; import * as h from 'i'; import 'j'",
+		"export {a, b as c}; export * from 'd'; export * as e from 'f'; export default function () {}; export const g = 1; export {h} from 'i'",
+		"export default (function () {})",
+		"import('a'); import.meta.url; function f() { new.target }",
+		"/a+b/gi.test(c); typeof a; void 0; delete a.b; !a; ~a",
+		"a ? b : c ? d : e; (a ? b : c) ? d : e; a = b = c; a += 1; a ||= b; a.b++; --a",
+		"'use strict'; x = 0x1F + 0b10 + 0o7 + 1e3 + .5 + 1_000",
+		"if (a) b; else if (c) d; else {}",
+		"using a = b; async function f() { await using c = d }",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt), Options{VarLocs: true})
+			test.Error(t, err)
+			buf := &bytes.Buffer{}
+			test.Error(t, ESTree(buf, ast, ESTreeOptions{Src: []byte(tt)}))
+			ast2, err := ParseESTree(buf.Bytes())
+			test.Error(t, err)
+			test.String(t, ast2.JSString(), ast.JSString())
+		})
+	}
+
+	var jsxTests = []string{
+		`<div a="b &amp; c" d={e} {...f}>text {g} {/* x */} <A.B.C /> <h:i j:k="1" /></div>`,
+		"<><Foo>x</Foo></>",
+		"<this.x></this.x>",
+	}
+	for _, tt := range jsxTests {
+		t.Run(tt, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt), Options{JSX: true})
+			test.Error(t, err)
+			buf := &bytes.Buffer{}
+			test.Error(t, ESTree(buf, ast, ESTreeOptions{}))
+			ast2, err := ParseESTree(buf.Bytes())
+			test.Error(t, err)
+			test.String(t, ast2.JSString(), ast.JSString())
+		})
+	}
+}
+
+func TestParseESTree(t *testing.T) {
+	var tests = []struct {
+		estree   string
+		expected string
+	}{
+		{`{"type":"Program","body":[{"type":"ExpressionStatement","expression":{"type":"BinaryExpression","operator":"*","left":{"type":"BinaryExpression","operator":"+","left":{"type":"Identifier","name":"a"},"right":{"type":"Identifier","name":"b"}},"right":{"type":"Identifier","name":"c"}}}]}`, "(a + b) * c;"},
+		{`{"type":"Program","body":[{"type":"ExpressionStatement","expression":{"type":"MemberExpression","object":{"type":"ChainExpression","expression":{"type":"MemberExpression","object":{"type":"Identifier","name":"a"},"property":{"type":"Identifier","name":"b"},"computed":false,"optional":true}},"property":{"type":"Identifier","name":"c"},"computed":false,"optional":false}}]}`, "(a?.b).c;"},
+		{`{"type":"Program","body":[{"type":"ExpressionStatement","expression":{"type":"ObjectExpression","properties":[]}}]}`, "({});"},
+		{`{"type":"Program","body":[{"type":"ExpressionStatement","expression":{"type":"Literal","value":"a\"b"}}]}`, `'a"b';`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			ast, err := ParseESTree([]byte(tt.estree))
+			test.Error(t, err)
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+func TestParseESTreeScope(t *testing.T) {
+	ast, err := ParseESTree([]byte(`{"type":"Program","body":[{"type":"VariableDeclaration","kind":"let","declarations":[{"type":"VariableDeclarator","id":{"type":"Identifier","name":"a","range":[4,5]},"init":null}]},{"type":"ExpressionStatement","expression":{"type":"Identifier","name":"a","range":[7,8]}}]}`))
+	test.Error(t, err)
+	decl := ast.List[0].(*VarDecl).List[0].Binding.(*Var)
+	use := ast.List[1].(*ExprStmt).Value.(*Var)
+	test.That(t, decl == use, "declaration and use must be the same variable")
+	test.T(t, decl.Decl, LexicalDecl)
+	test.T(t, decl.Uses, uint16(2))
+	test.T(t, decl.Locs, []Loc{{4, 5}, {7, 8}})
+	test.T(t, len(ast.Scope.Undeclared), 0)
+}
+
+func TestParseESTreeError(t *testing.T) {
+	var tests = []string{
+		`{"type":"File"}`,
+		`{"type":"Program","body":[{"type":"Foo"}]}`,
+		`{"type":"Program","body":[{"type":"ExpressionStatement","expression":{"type":"UnaryExpression","operator":"?","argument":{"type":"Identifier","name":"a"}}}]}`,
+		`{"type":"Program","body":[{"type":"VariableDeclaration","kind":"let","declarations":[{"type":"VariableDeclarator","id":{"type":"Identifier","name":"a"}},{"type":"VariableDeclarator","id":{"type":"Identifier","name":"a"}}]}]}`,
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			_, err := ParseESTree([]byte(tt))
+			test.That(t, errors.Is(err, ErrInvalidESTree))
+		})
+	}
+}
@@ -0,0 +1,510 @@
+package js
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ValueType is the type of a constant value.
+type ValueType int
+
+// ValueType values.
+const (
+	UndefinedValue ValueType = iota
+	NullValue
+	BooleanValue
+	NumberValue
+	StringValue
+)
+
+func (typ ValueType) String() string {
+	switch typ {
+	case UndefinedValue:
+		return "undefined"
+	case NullValue:
+		return "null"
+	case BooleanValue:
+		return "boolean"
+	case NumberValue:
+		return "number"
+	case StringValue:
+		return "string"
+	}
+	return "Invalid(" + strconv.Itoa(int(typ)) + ")"
+}
+
+// Value is a constant value of a primitive type, where Bool, Num, or Str is set depending on its type.
+type Value struct {
+	Type ValueType
+	Bool bool
+	Num  float64
+	Str  string
+}
+
+func newBoolean(b bool) Value   { return Value{Type: BooleanValue, Bool: b} }
+func newNumber(f float64) Value { return Value{Type: NumberValue, Num: f} }
+func newString(s string) Value  { return Value{Type: StringValue, Str: s} }
+
+func (v Value) isNullish() bool {
+	return v.Type == UndefinedValue || v.Type == NullValue
+}
+
+// String returns the value converted to a string following ToString.
+func (v Value) String() string {
+	switch v.Type {
+	case UndefinedValue:
+		return "undefined"
+	case NullValue:
+		return "null"
+	case BooleanValue:
+		if v.Bool {
+			return "true"
+		}
+		return "false"
+	case NumberValue:
+		return numberString(v.Num)
+	}
+	return v.Str
+}
+
+// ToBoolean returns the value converted to a boolean.
+func (v Value) ToBoolean() bool {
+	switch v.Type {
+	case BooleanValue:
+		return v.Bool
+	case NumberValue:
+		return v.Num != 0 && !math.IsNaN(v.Num)
+	case StringValue:
+		return v.Str != ""
+	}
+	return false
+}
+
+// ToNumber returns the value converted to a number.
+func (v Value) ToNumber() float64 {
+	switch v.Type {
+	case UndefinedValue:
+		return math.NaN()
+	case NullValue:
+		return 0
+	case BooleanValue:
+		if v.Bool {
+			return 1
+		}
+		return 0
+	case StringValue:
+		return stringNumber(v.Str)
+	}
+	return v.Num
+}
+
+// Expr returns an expression for the value. This is a LiteralExpr except for undefined as void 0, for negative numbers as a negation, and for NaN and Infinity as divisions.
+func (v Value) Expr() IExpr {
+	switch v.Type {
+	case UndefinedValue:
+		return &UnaryExpr{Op: VoidToken, X: &LiteralExpr{TokenType: DecimalToken, Data: []byte("0")}}
+	case NumberValue:
+		if math.IsNaN(v.Num) {
+			return &BinaryExpr{Op: DivToken, X: &LiteralExpr{TokenType: DecimalToken, Data: []byte("0")}, Y: &LiteralExpr{TokenType: DecimalToken, Data: []byte("0")}}
+		} else if math.IsInf(v.Num, 0) {
+			return &BinaryExpr{Op: DivToken, X: newNumber(math.Copysign(1, v.Num)).Expr(), Y: &LiteralExpr{TokenType: DecimalToken, Data: []byte("0")}}
+		} else if math.Signbit(v.Num) {
+			return &UnaryExpr{Op: NegToken, X: &LiteralExpr{TokenType: DecimalToken, Data: []byte(numberString(-v.Num))}}
+		}
+	}
+	lit, _ := v.literal()
+	return lit
+}
+
+// literal returns the value as a literal, or false for undefined, negative numbers, NaN, and Infinity.
+func (v Value) literal() (*LiteralExpr, bool) {
+	switch v.Type {
+	case NullValue:
+		return &LiteralExpr{TokenType: NullToken, Data: []byte("null")}, true
+	case BooleanValue:
+		if v.Bool {
+			return &LiteralExpr{TokenType: TrueToken, Data: []byte("true")}, true
+		}
+		return &LiteralExpr{TokenType: FalseToken, Data: []byte("false")}, true
+	case NumberValue:
+		if !math.IsNaN(v.Num) && !math.IsInf(v.Num, 0) && !math.Signbit(v.Num) {
+			return numberLiteral(v.Num), true
+		}
+	case StringValue:
+		return &LiteralExpr{TokenType: StringToken, Data: QuoteString(v.Str)}, true
+	}
+	return nil, false
+}
+
+////////////////////////////////////////////////////////////////
+
+// Fold evaluates a constant expression and returns its value as a literal. It returns false if the expression is not constant, or if its value cannot be written as a literal, see Evaluate and Value.Expr for those values.
+func Fold(expr IExpr) (*LiteralExpr, bool) {
+	if v, ok := Evaluate(expr); ok {
+		return v.literal()
+	}
+	return nil, false
+}
+
+// Evaluate returns the value of a constant expression following the semantics of JavaScript, or false if the expression is not constant. Constant expressions consist of literals, the global variables undefined, NaN, and Infinity, templates without tags, and the unary, binary, conditional, and comma operators except for assignments, delete, in, and instanceof. BigInt literals are not constant. The logical and conditional operators are constant when their result is, as in false && x.
+func Evaluate(expr IExpr) (Value, bool) {
+	switch expr := expr.(type) {
+	case *LiteralExpr:
+		switch expr.TokenType {
+		case NullToken:
+			return Value{Type: NullValue}, true
+		case TrueToken:
+			return newBoolean(true), true
+		case FalseToken:
+			return newBoolean(false), true
+		case StringToken:
+			return newString(stringValue(expr.Data)), true
+		case DecimalToken, IntegerToken, BinaryToken, OctalToken, HexadecimalToken:
+			if f, ok := numericValue(expr.Data); ok {
+				return newNumber(f), true
+			}
+		}
+	case *Var:
+		if v := expr.Resolve(); v.Decl == NoDecl {
+			switch string(v.Data) {
+			case "undefined":
+				return Value{Type: UndefinedValue}, true
+			case "NaN":
+				return newNumber(math.NaN()), true
+			case "Infinity":
+				return newNumber(math.Inf(1)), true
+			}
+		}
+	case *GroupExpr:
+		return Evaluate(expr.X)
+	case *TemplateExpr:
+		if expr.Tag != nil {
+			break
+		}
+		var sb strings.Builder
+		for _, item := range expr.List {
+			sb.WriteString(stringValue(item.Value))
+			v, ok := Evaluate(item.Expr)
+			if !ok {
+				return Value{}, false
+			}
+			sb.WriteString(v.String())
+		}
+		sb.WriteString(stringValue(expr.Tail))
+		return newString(sb.String()), true
+	case *CommaExpr:
+		var v Value
+		for _, item := range expr.List {
+			var ok bool
+			if v, ok = Evaluate(item); !ok {
+				return Value{}, false
+			}
+		}
+		return v, true
+	case *CondExpr:
+		if cond, ok := Evaluate(expr.Cond); !ok {
+			return Value{}, false
+		} else if cond.ToBoolean() {
+			return Evaluate(expr.X)
+		}
+		return Evaluate(expr.Y)
+	case *UnaryExpr:
+		x, ok := Evaluate(expr.X)
+		if !ok {
+			return Value{}, false
+		}
+		switch expr.Op {
+		case NotToken:
+			return newBoolean(!x.ToBoolean()), true
+		case NegToken:
+			return newNumber(-x.ToNumber()), true
+		case PosToken:
+			return newNumber(x.ToNumber()), true
+		case BitNotToken:
+			return newNumber(float64(^toInt32(x.ToNumber()))), true
+		case TypeofToken:
+			if x.Type == NullValue {
+				return newString("object"), true
+			}
+			return newString(x.Type.String()), true
+		case VoidToken:
+			return Value{Type: UndefinedValue}, true
+		}
+	case *BinaryExpr:
+		return evaluateBinary(expr)
+	}
+	return Value{}, false
+}
+
+func evaluateBinary(expr *BinaryExpr) (Value, bool) {
+	x, ok := Evaluate(expr.X)
+	if !ok {
+		return Value{}, false
+	}
+
+	// short-circuiting operators
+	switch expr.Op {
+	case AndToken:
+		if !x.ToBoolean() {
+			return x, true
+		}
+		return Evaluate(expr.Y)
+	case OrToken:
+		if x.ToBoolean() {
+			return x, true
+		}
+		return Evaluate(expr.Y)
+	case NullishToken:
+		if !x.isNullish() {
+			return x, true
+		}
+		return Evaluate(expr.Y)
+	}
+
+	y, ok := Evaluate(expr.Y)
+	if !ok {
+		return Value{}, false
+	}
+	switch expr.Op {
+	case AddToken:
+		if x.Type == StringValue || y.Type == StringValue {
+			return newString(x.String() + y.String()), true
+		}
+		return newNumber(x.ToNumber() + y.ToNumber()), true
+	case SubToken:
+		return newNumber(x.ToNumber() - y.ToNumber()), true
+	case MulToken:
+		return newNumber(x.ToNumber() * y.ToNumber()), true
+	case DivToken:
+		return newNumber(x.ToNumber() / y.ToNumber()), true
+	case ModToken:
+		return newNumber(math.Mod(x.ToNumber(), y.ToNumber())), true
+	case ExpToken:
+		base, exp := x.ToNumber(), y.ToNumber()
+		if math.IsNaN(exp) || (base == 1 || base == -1) && math.IsInf(exp, 0) {
+			return newNumber(math.NaN()), true
+		}
+		return newNumber(math.Pow(base, exp)), true
+	case BitAndToken:
+		return newNumber(float64(toInt32(x.ToNumber()) & toInt32(y.ToNumber()))), true
+	case BitOrToken:
+		return newNumber(float64(toInt32(x.ToNumber()) | toInt32(y.ToNumber()))), true
+	case BitXorToken:
+		return newNumber(float64(toInt32(x.ToNumber()) ^ toInt32(y.ToNumber()))), true
+	case LtLtToken:
+		return newNumber(float64(toInt32(x.ToNumber()) << (uint32(toInt32(y.ToNumber())) & 31))), true
+	case GtGtToken:
+		return newNumber(float64(toInt32(x.ToNumber()) >> (uint32(toInt32(y.ToNumber())) & 31))), true
+	case GtGtGtToken:
+		return newNumber(float64(uint32(toInt32(x.ToNumber())) >> (uint32(toInt32(y.ToNumber())) & 31))), true
+	case EqEqEqToken:
+		return newBoolean(strictEquals(x, y)), true
+	case NotEqEqToken:
+		return newBoolean(!strictEquals(x, y)), true
+	case EqEqToken:
+		return newBoolean(looseEquals(x, y)), true
+	case NotEqToken:
+		return newBoolean(!looseEquals(x, y)), true
+	case LtToken:
+		return newBoolean(lessThan(x, y) == 1), true
+	case GtToken:
+		return newBoolean(lessThan(y, x) == 1), true
+	case LtEqToken:
+		return newBoolean(lessThan(y, x) == 0), true
+	case GtEqToken:
+		return newBoolean(lessThan(x, y) == 0), true
+	}
+	return Value{}, false
+}
+
+func strictEquals(x, y Value) bool {
+	if x.Type != y.Type {
+		return false
+	}
+	switch x.Type {
+	case BooleanValue:
+		return x.Bool == y.Bool
+	case NumberValue:
+		return x.Num == y.Num
+	case StringValue:
+		return x.Str == y.Str
+	}
+	return true
+}
+
+func looseEquals(x, y Value) bool {
+	if x.Type == y.Type {
+		return strictEquals(x, y)
+	} else if x.isNullish() || y.isNullish() {
+		return x.isNullish() && y.isNullish()
+	}
+	return x.ToNumber() == y.ToNumber()
+}
+
+// lessThan returns 1 if x < y, 0 if not, and -1 if either is NaN.
+func lessThan(x, y Value) int {
+	if x.Type == StringValue && y.Type == StringValue {
+		// compare UTF-16 code units
+		a, b := utf16.Encode([]rune(x.Str)), utf16.Encode([]rune(y.Str))
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if a[i] != b[i] {
+				if a[i] < b[i] {
+					return 1
+				}
+				return 0
+			}
+		}
+		if len(a) < len(b) {
+			return 1
+		}
+		return 0
+	}
+	a, b := x.ToNumber(), y.ToNumber()
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return -1
+	} else if a < b {
+		return 1
+	}
+	return 0
+}
+
+// numberLiteral returns the literal of a finite number that is not negative.
+func numberLiteral(f float64) *LiteralExpr {
+	data := []byte(numberString(f))
+	for _, c := range data {
+		if c == '.' || c == 'e' {
+			return &LiteralExpr{TokenType: DecimalToken, Data: data}
+		}
+	}
+	return &LiteralExpr{TokenType: IntegerToken, Data: data}
+}
+
+// toInt32 converts a number to a 32-bit integer following ToInt32.
+func toInt32(f float64) int32 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	f = math.Mod(math.Trunc(f), 1<<32)
+	if f < 0 {
+		f += 1 << 32
+	}
+	return int32(uint32(f))
+}
+
+// numericValue returns the value of a numeric literal, or false for BigInt literals.
+func numericValue(b []byte) (float64, bool) {
+	f, err := ParseNumber(b)
+	return f, err == nil
+}
+
+// integerValue returns the value of a hexadecimal, octal, or binary integer with prefix, rounded to the nearest number.
+func integerValue(s string) (float64, bool) {
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return 0, false
+	}
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f, true
+}
+
+// stringNumber converts a string to a number following StringToNumber.
+func stringNumber(s string) float64 {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.Is(unicode.Zs, r) || r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r' || r == '\u2028' || r == '\u2029' || r == '\ufeff'
+	})
+	if s == "" {
+		return 0
+	} else if 2 < len(s) && s[0] == '0' && strings.IndexByte("xXoObB", s[1]) != -1 {
+		if strings.IndexByte(s, '_') == -1 {
+			if f, ok := integerValue(s); ok {
+				return f
+			}
+		}
+		return math.NaN()
+	}
+
+	// StrDecimalLiteral
+	i := 0
+	if s[0] == '+' || s[0] == '-' {
+		i++
+	}
+	if s[i:] == "Infinity" {
+		if s[0] == '-' {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	}
+	digits := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return math.NaN()
+	} else if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if i == len(s) {
+			return math.NaN()
+		}
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+	}
+	if i != len(s) {
+		return math.NaN()
+	}
+	f, _ := strconv.ParseFloat(s, 64) // out of range returns Infinity
+	return f
+}
+
+// numberString converts a number to a string following Number::toString.
+func numberString(f float64) string {
+	if math.IsNaN(f) {
+		return "NaN"
+	} else if f == 0 {
+		return "0"
+	} else if math.IsInf(f, 1) {
+		return "Infinity"
+	} else if math.IsInf(f, -1) {
+		return "-Infinity"
+	} else if f < 0 {
+		return "-" + numberString(-f)
+	}
+
+	// shortest digits and exponent n such that f = 0.digits * 10^n
+	b := strconv.AppendFloat(nil, f, 'e', -1, 64)
+	e := strings.IndexByte(string(b), 'e')
+	exp, _ := strconv.Atoi(string(b[e+1:]))
+	digits := string(b[:1]) + strings.TrimPrefix(string(b[1:e]), ".")
+	k, n := len(digits), exp+1
+
+	if k <= n && n <= 21 {
+		return digits + strings.Repeat("0", n-k)
+	} else if 0 < n && n <= 21 {
+		return digits[:n] + "." + digits[n:]
+	} else if -6 < n && n <= 0 {
+		return "0." + strings.Repeat("0", -n) + digits
+	}
+	s := digits[:1]
+	if 1 < k {
+		s += "." + digits[1:]
+	}
+	if 0 < n-1 {
+		return s + "e+" + strconv.Itoa(n-1)
+	}
+	return s + "e" + strconv.Itoa(n-1)
+}
@@ -0,0 +1,147 @@
+package js
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestEvaluate(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"'production' === \"production\"", "true"},
+		{"'a' + 1 + 2", `"a12"`},
+		{"1 + 2 + 'a'", `"3a"`},
+		{"1 + null + true", "2"},
+		{"1 + undefined", "0 / 0"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"-(2 ** 3)", "-8"},
+		{"1 / 0", "1 / 0"},
+		{"-1 / 0", "-1 / 0"},
+		{"1e21 + ' ' + 1e-7 + ' ' + 123e-20 + ' ' + 1.5e300 * 1e10", `"1e+21 1e-7 1.23e-18 Infinity"`},
+		{"100 + ' ' + 0.000001 + ' ' + -0 + ' ' + 0x10 + ' ' + 0b11 + ' ' + 0o17 + ' ' + 1_000", `"100 0.000001 0 16 3 15 1000"`},
+		{"7 % -3 + ' ' + -7 % 3", `"1 -1"`},
+		{"1 ** NaN + ' ' + (-1) ** Infinity + ' ' + 2 ** -1", `"NaN NaN 0.5"`},
+		{"~5 + ' ' + (5 & 3) + ' ' + (5 | 3) + ' ' + (5 ^ 3) + ' ' + (1 << 33) + ' ' + (-8 >> 1) + ' ' + (-1 >>> 0)", `"-6 1 7 6 2 -4 4294967295"`},
+		{"2147483648 | 0", "-2147483648"},
+		{"null == undefined", "true"},
+		{"null == 0", "false"},
+		{"'1' == 1", "true"},
+		{"true == '1'", "true"},
+		{"NaN == NaN", "false"},
+		{"NaN !== NaN", "true"},
+		{"0 === -0", "true"},
+		{"'b' > 'a'", "true"},
+		{"'10' < '9'", "true"},
+		{"'10' < 9", "false"},
+		{"NaN <= 1", "false"},
+		{"null >= 0", "true"},
+		{"'\\uFFFF' < '\\u{10000}'", "false"},
+		{"+'' + +' 12 ' + +'0x1F' + +'1e3'", "1043"},
+		{"+'1_000' + ' ' + +'12px' + ' ' + +'-Infinity' + ' ' + +'.5' + ' ' + +'5.' + ' ' + +'.'", `"NaN NaN -Infinity 0.5 5 NaN"`},
+		{"!'' + ' ' + !'0' + ' ' + !NaN + ' ' + !null", `"true false true true"`},
+		{"typeof null + typeof undefined + typeof 1 + typeof '' + typeof !1", `"objectundefinednumberstringboolean"`},
+		{"void 1", "void 0"},
+		{"0 || 'a'", `"a"`},
+		{"1 && 'a'", `"a"`},
+		{"null ?? 2", "2"},
+		{"false && x", "false"},
+		{"true || x()", "true"},
+		{"0 ?? x", "0"},
+		{"true ? 1 : x", "1"},
+		{"false ? x : 'b'", `"b"`},
+		{"(1, 2)", "2"},
+		{"`a${1 + 1}b${null}\\n${`c`}`", `"a2bnull\nc"`},
+		{"'\\x41\\u0042\\u{43}\\uD83D\\uDE00\\'\"'", `"ABC😀'\""`},
+		{"'\\u2028\\0'", `"\u2028\0"`},
+		{"undefined + ''", `"undefined"`},
+		{"[] + 1", ""},
+		{"x === 'production'", ""},
+		{"x && false", ""},
+		{"1n + 1n", ""},
+		{"tag`a`", ""},
+		{"`${x}`", ""},
+		{"typeof x", ""},
+		{"'a' in b", ""},
+		{"a = 1", ""},
+		{"delete 1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil {
+				test.Error(t, err)
+			}
+			v, ok := Evaluate(ast.List[0].(*ExprStmt).Value)
+			if tt.expected == "" {
+				test.That(t, !ok, "not constant")
+				return
+			}
+			test.That(t, ok, "constant")
+			sb := &strings.Builder{}
+			v.Expr().JS(sb)
+			test.String(t, sb.String(), tt.expected)
+		})
+	}
+}
+
+func TestEvaluateShadowed(t *testing.T) {
+	ast, err := Parse(parse.NewInputString("function f(undefined) { return undefined }"), Options{})
+	test.Error(t, err)
+	ret := ast.List[0].(*FuncDecl).Body.List[0].(*ReturnStmt)
+	_, ok := Evaluate(ret.Value)
+	test.That(t, !ok)
+}
+
+func TestFold(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"process.env.NODE_ENV === 'production'", ""},
+		{"'production' === 'production' ? 'a' : 'b'", `"a"`},
+		{`'a"' + 'b'`, `'a"b'`},
+		{"1 + 0.5", "1.5"},
+		{"2 ** 60", "1152921504606847000"},
+		{"2 ** 70", "1.1805916207174113e+21"},
+		{"2 ** 80", "1.2089258196146292e+24"},
+		{"!0", "true"},
+		{"-1", ""},
+		{"void 0", ""},
+		{"0 / 0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil {
+				test.Error(t, err)
+			}
+			lit, ok := Fold(ast.List[0].(*ExprStmt).Value)
+			if tt.expected == "" {
+				test.That(t, !ok, "not constant")
+				return
+			}
+			test.That(t, ok, "constant")
+			test.String(t, string(lit.Data), tt.expected)
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	test.String(t, Value{Type: NumberValue, Num: 5e-324}.String(), "5e-324")
+	test.String(t, Value{Type: NumberValue, Num: 123456789012345680000}.String(), "123456789012345680000")
+	test.String(t, Value{Type: NumberValue, Num: -1.5}.String(), "-1.5")
+	test.String(t, Value{Type: NumberValue, Num: math.Inf(-1)}.String(), "-Infinity")
+	test.String(t, Value{Type: NullValue}.String(), "null")
+	test.T(t, Value{Type: StringValue, Str: " \ufeff7\u2028"}.ToNumber(), 7.0)
+	test.T(t, Value{Type: BooleanValue, Bool: true}.ToNumber(), 1.0)
+	test.That(t, math.IsNaN(Value{Type: UndefinedValue}.ToNumber()))
+	test.That(t, !Value{Type: NumberValue, Num: math.NaN()}.ToBoolean())
+	test.String(t, ValueType(10).String(), "Invalid(10)")
+}
@@ -51,6 +51,11 @@ func NewLexer(r *parse.Input) *Lexer {
 	}
 }
 
+// NewStreamLexer returns a new Lexer for streaming input, which is read in chunks with bounded memory. The returned byte slices are only valid until the next call to Next.
+func NewStreamLexer(r *parse.StreamInput) *Lexer {
+	return NewLexer(&r.Input)
+}
+
 // Err returns the error encountered during lexing, this is often io.EOF but also other errors can be returned.
 func (l *Lexer) Err() error {
 	if l.err != nil {
@@ -59,8 +64,25 @@ func (l *Lexer) Err() error {
 	return l.r.Err()
 }
 
+// stream calls next, and calls it again after restoring the state of the lexer when its token ends close to the end of the buffered data of streaming input.
+func (l *Lexer) stream(next func() (TokenType, []byte)) (tt TokenType, data []byte) {
+	state := *l
+	l.r.Retry(func() {
+		*l = state
+		tt, data = next()
+	})
+	return
+}
+
 // RegExp reparses the input stream for a regular expression. It is assumed that we just received DivToken or DivEqToken with Next(). This function will go back and read that as a regular expression.
 func (l *Lexer) RegExp() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.regExp)
+	}
+	return l.regExp()
+}
+
+func (l *Lexer) regExp() (TokenType, []byte) {
 	if 0 < l.r.Offset() && l.r.Peek(-1) == '/' {
 		l.r.Move(-1)
 	} else if 1 < l.r.Offset() && l.r.Peek(-1) == '=' && l.r.Peek(-2) == '/' {
@@ -78,8 +100,149 @@ func (l *Lexer) RegExp() (TokenType, []byte) {
 	return ErrorToken, nil
 }
 
+// NextJSXTag returns the next token inside a JSX opening or closing tag. It returns JSXIdentifierToken for (dashed) names, JSXStringToken for attribute strings, and punctuators and operators for < > / = . : and {. Whitespace and comments are returned as usual. It is assumed that the caller tracks when we are inside a JSX tag.
+func (l *Lexer) NextJSXTag() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.nextJSXTag)
+	}
+	return l.nextJSXTag()
+}
+
+func (l *Lexer) nextJSXTag() (TokenType, []byte) {
+	l.err = nil // clear error from previous ErrorToken
+	l.prevNumericLiteral = false
+
+	c := l.r.Peek(0)
+	switch c {
+	case ' ', '\t', '\v', '\f':
+		l.r.Move(1)
+		for l.consumeWhitespace() {
+		}
+		return WhitespaceToken, l.r.Shift()
+	case '\n', '\r':
+		l.r.Move(1)
+		for l.consumeLineTerminator() {
+		}
+		l.prevLineTerminator = true
+		return LineTerminatorToken, l.r.Shift()
+	case '/':
+		if tt := l.consumeCommentToken(); tt != ErrorToken || l.err != nil {
+			if l.err != nil {
+				return ErrorToken, nil
+			}
+			return tt, l.r.Shift()
+		}
+		l.r.Move(1)
+		return DivToken, l.r.Shift()
+	case '<':
+		l.r.Move(1)
+		return LtToken, l.r.Shift()
+	case '>':
+		l.r.Move(1)
+		return GtToken, l.r.Shift()
+	case '=':
+		l.r.Move(1)
+		return EqToken, l.r.Shift()
+	case '.':
+		l.r.Move(1)
+		return DotToken, l.r.Shift()
+	case ':':
+		l.r.Move(1)
+		return ColonToken, l.r.Shift()
+	case '{':
+		l.level++
+		l.r.Move(1)
+		return OpenBraceToken, l.r.Shift()
+	case '\'', '"':
+		l.r.Move(1)
+		for {
+			if c2 := l.r.Peek(0); c2 == c {
+				l.r.Move(1)
+				return JSXStringToken, l.r.Shift()
+			} else if c2 == 0 && l.r.Err() != nil {
+				l.err = parse.NewErrorLexer(l.r, "unterminated string literal")
+				return ErrorToken, nil
+			}
+			l.r.Move(1)
+		}
+	default:
+		if l.consumeIdentifierToken() {
+			for l.r.Peek(0) == '-' {
+				l.r.Move(1)
+				l.consumeIdentifierToken()
+			}
+			return JSXIdentifierToken, l.r.Shift()
+		}
+		if 0xC0 <= c {
+			if l.consumeWhitespace() {
+				for l.consumeWhitespace() {
+				}
+				return WhitespaceToken, l.r.Shift()
+			} else if l.consumeLineTerminator() {
+				for l.consumeLineTerminator() {
+				}
+				l.prevLineTerminator = true
+				return LineTerminatorToken, l.r.Shift()
+			}
+		} else if c == 0 && l.r.Err() != nil {
+			return ErrorToken, nil
+		}
+	}
+
+	r, _ := l.r.PeekRune(0)
+	l.err = parse.NewErrorLexer(l.r, "unexpected %s", parse.Printable(r))
+	l.r.MoveRune() // allow to continue after error
+	return ErrorToken, l.r.Shift()
+}
+
+// NextJSXChild returns the next token inside the children of a JSX element. It returns either JSXTextToken, OpenBraceToken for an expression container, or LtToken for an element or closing tag. It is assumed that the caller tracks when we are inside JSX children.
+func (l *Lexer) NextJSXChild() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.nextJSXChild)
+	}
+	return l.nextJSXChild()
+}
+
+func (l *Lexer) nextJSXChild() (TokenType, []byte) {
+	l.err = nil // clear error from previous ErrorToken
+	l.prevNumericLiteral = false
+
+	switch l.r.Peek(0) {
+	case '{':
+		l.level++
+		l.r.Move(1)
+		return OpenBraceToken, l.r.Shift()
+	case '<':
+		l.r.Move(1)
+		return LtToken, l.r.Shift()
+	}
+	for {
+		c := l.r.Peek(0)
+		if c == '{' || c == '<' {
+			break
+		} else if c == '>' || c == '}' {
+			l.err = parse.NewErrorLexer(l.r, "unexpected %s in JSX text", string(c))
+			return ErrorToken, nil
+		} else if c == 0 && l.r.Err() != nil {
+			if l.r.Pos() == 0 {
+				return ErrorToken, nil
+			}
+			break
+		}
+		l.r.Move(1)
+	}
+	return JSXTextToken, l.r.Shift()
+}
+
 // Next returns the next Token. It returns ErrorToken when an error was encountered. Using Err() one can retrieve the error message.
 func (l *Lexer) Next() (TokenType, []byte) {
+	if l.r.Streaming() {
+		return l.stream(l.next)
+	}
+	return l.next()
+}
+
+func (l *Lexer) next() (TokenType, []byte) {
 	l.err = nil // clear error from previous ErrorToken
 	prevLineTerminator := l.prevLineTerminator
 	l.prevLineTerminator = false
@@ -187,6 +350,9 @@ func (l *Lexer) Next() (TokenType, []byte) {
 	case '`':
 		l.templateLevels = append(l.templateLevels, l.level)
 		return l.consumeTemplateToken(), l.r.Shift()
+	case '@':
+		l.r.Move(1)
+		return AtToken, l.r.Shift()
 	case '#':
 		l.r.Move(1)
 		if l.consumeIdentifierToken() {
@@ -5,6 +5,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/test"
@@ -26,7 +27,7 @@ func TestTokens(t *testing.T) {
 		{"a = 'string'", TTs{IdentifierToken, EqToken, StringToken}},
 		{"/*comment*/ //comment", TTs{CommentToken, CommentToken}},
 		{"{ } ( ) [ ]", TTs{OpenBraceToken, CloseBraceToken, OpenParenToken, CloseParenToken, OpenBracketToken, CloseBracketToken}},
-		{". ; , < > <= ...", TTs{DotToken, SemicolonToken, CommaToken, LtToken, GtToken, LtEqToken, EllipsisToken}},
+		{". ; , < > <= ... @", TTs{DotToken, SemicolonToken, CommaToken, LtToken, GtToken, LtEqToken, EllipsisToken, AtToken}},
 		{">= == != === !==", TTs{GtEqToken, EqEqToken, NotEqToken, EqEqEqToken, NotEqEqToken}},
 		{"+ - * / % ** ++ --", TTs{AddToken, SubToken, MulToken, DivToken, ModToken, ExpToken, IncrToken, DecrToken}},
 		{"<< >> >>> & | ^", TTs{LtLtToken, GtGtToken, GtGtGtToken, BitAndToken, BitOrToken, BitXorToken}},
@@ -42,7 +43,7 @@ func TestTokens(t *testing.T) {
 		{"new null return super switch this throw true", TTs{NewToken, NullToken, ReturnToken, SuperToken, SwitchToken, ThisToken, ThrowToken, TrueToken}},
 		{"try typeof var void while with yield", TTs{TryToken, TypeofToken, VarToken, VoidToken, WhileToken, WithToken, YieldToken}},
 		{"implements interface let package private protected public static", TTs{ImplementsToken, InterfaceToken, LetToken, PackageToken, PrivateToken, ProtectedToken, PublicToken, StaticToken}},
-		{"as async from get meta of set target", TTs{AsToken, AsyncToken, FromToken, GetToken, MetaToken, OfToken, SetToken, TargetToken}},
+		{"accessor as async from get meta of set target using", TTs{AccessorToken, AsToken, AsyncToken, FromToken, GetToken, MetaToken, OfToken, SetToken, TargetToken, UsingToken}},
 		{"#ident", TTs{PrivateIdentifierToken}},
 
 		{"/*co\nm\u2028m/*ent*/ //co//mment\u2029//comment", TTs{CommentLineTerminatorToken, CommentToken, LineTerminatorToken, CommentToken}},
@@ -185,6 +186,8 @@ func TestRegExp(t *testing.T) {
 		{"a=/=/g1", TTs{IdentifierToken, EqToken, RegExpToken}},
 		{"a = /'\\\\/\n", TTs{IdentifierToken, EqToken, RegExpToken, LineTerminatorToken}},
 		{"a=/\\//g1", TTs{IdentifierToken, EqToken, RegExpToken}},
+		{"a=/[\\p{L}--[a-z]]/v", TTs{IdentifierToken, EqToken, RegExpToken}},
+		{"a=/[\\q{abc|d}&&[/]]/dgv", TTs{IdentifierToken, EqToken, RegExpToken}},
 		{"new RegExp(a + /\\d{1,2}/.source)", TTs{NewToken, IdentifierToken, OpenParenToken, IdentifierToken, AddToken, RegExpToken, DotToken, IdentifierToken, CloseParenToken}},
 		{"a=/regexp\x00/;return", TTs{IdentifierToken, EqToken, RegExpToken, SemicolonToken, ReturnToken}},
 		{"a=/regexp\\\x00/;return", TTs{IdentifierToken, EqToken, RegExpToken, SemicolonToken, ReturnToken}},
@@ -224,6 +227,77 @@ func TestRegExp(t *testing.T) {
 	test.T(t, token, ErrorToken)
 }
 
+func TestJSX(t *testing.T) {
+	var tokenTests = []struct {
+		jsx      string
+		expected []TokenType
+	}{
+		{"div", TTs{JSXIdentifierToken}},
+		{"a-b:c-d", TTs{JSXIdentifierToken, ColonToken, JSXIdentifierToken}},
+		{"A.B", TTs{JSXIdentifierToken, DotToken, JSXIdentifierToken}},
+		{"a x=\"y\" z='w'/>", TTs{JSXIdentifierToken, JSXIdentifierToken, EqToken, JSXStringToken, JSXIdentifierToken, EqToken, JSXStringToken, DivToken, GtToken}},
+		{"a {...b}", TTs{JSXIdentifierToken, OpenBraceToken}},
+		{"a /* c */ >", TTs{JSXIdentifierToken, CommentToken, GtToken}},
+		{"a x=\"y", TTs{JSXIdentifierToken, JSXIdentifierToken, EqToken, ErrorToken}},
+		{"a #", TTs{JSXIdentifierToken, ErrorToken}},
+	}
+
+	for _, tt := range tokenTests {
+		t.Run(tt.jsx, func(t *testing.T) {
+			l := NewLexer(parse.NewInputString(tt.jsx))
+			tokens := []TokenType{}
+			for {
+				token, _ := l.NextJSXTag()
+				if token == ErrorToken {
+					if l.Err() != io.EOF {
+						tokens = append(tokens, token)
+					}
+					break
+				} else if token == WhitespaceToken {
+					continue
+				}
+				tokens = append(tokens, token)
+				if token == OpenBraceToken {
+					break
+				}
+			}
+			test.T(t, tokens, tt.expected, "token types must match")
+		})
+	}
+
+	var childTests = []struct {
+		jsx      string
+		expected []TokenType
+	}{
+		{"text", TTs{JSXTextToken}},
+		{"a {b", TTs{JSXTextToken, OpenBraceToken}},
+		{"a\n b</a>", TTs{JSXTextToken, LtToken}},
+		{"a > b", TTs{ErrorToken}},
+		{"}", TTs{ErrorToken}},
+	}
+
+	for _, tt := range childTests {
+		t.Run(tt.jsx, func(t *testing.T) {
+			l := NewLexer(parse.NewInputString(tt.jsx))
+			tokens := []TokenType{}
+			for {
+				token, _ := l.NextJSXChild()
+				if token == ErrorToken {
+					if l.Err() != io.EOF {
+						tokens = append(tokens, token)
+					}
+					break
+				}
+				tokens = append(tokens, token)
+				if token == OpenBraceToken || token == LtToken {
+					break
+				}
+			}
+			test.T(t, tokens, tt.expected, "token types must match")
+		})
+	}
+}
+
 func TestOffset(t *testing.T) {
 	z := parse.NewInputString(`var i=5;`)
 	l := NewLexer(z)
@@ -242,12 +316,53 @@ func TestOffset(t *testing.T) {
 	test.T(t, z.Offset(), 8) // ;
 }
 
+func TestStreamLexer(t *testing.T) {
+	js := "var a = /re[/]gex/g, b = c / d /= 2;\n// comment\nf(`a${b}c`, 'str\\'ing', 0x1F, 1.5e3, 12n) /* multi\nline */\n<!-- html\nlet ü = /=/.source\u2028x"
+	lex := func(l *Lexer, z *parse.Input) []string {
+		tokens := []string{}
+		prev := ErrorToken
+		for {
+			tt, data := l.Next()
+			if (tt == DivToken || tt == DivEqToken) && (prev == EqToken || prev == OpenParenToken) {
+				tt, data = l.RegExp()
+			}
+			if tt == ErrorToken {
+				test.T(t, l.Err(), io.EOF)
+				return tokens
+			} else if tt != WhitespaceToken {
+				prev = tt
+			}
+			tokens = append(tokens, fmt.Sprintf("%v %q %d", tt, data, z.Offset()))
+		}
+	}
+
+	z := parse.NewInputString(js)
+	expected := lex(NewLexer(z), z)
+	for _, size := range []int{1, 2, 3, 16} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			z := parse.NewStreamInputSize(iotest.OneByteReader(strings.NewReader(js)), size)
+			test.T(t, lex(NewStreamLexer(z), &z.Input), expected)
+		})
+	}
+
+	// error positions count the lines of the discarded data
+	l := NewStreamLexer(parse.NewStreamInputSize(iotest.OneByteReader(strings.NewReader(strings.Repeat("a\n", 40)+"c = 'd\n")), 2))
+	for {
+		if tt, _ := l.Next(); tt == ErrorToken {
+			break
+		}
+	}
+	line, col, _ := l.Err().(*parse.Error).Position()
+	test.T(t, line, 41)
+	test.T(t, col, 7)
+}
+
 func TestLexerErrors(t *testing.T) {
 	var tests = []struct {
 		js  string
 		err string
 	}{
-		{"@", "unexpected @"},
+		{"\\", "unexpected \\"},
 		{"\x00", "unexpected 0x00"},
 		{"\x7f", "unexpected 0x7F"},
 		{"\u200F", "unexpected U+200F"},
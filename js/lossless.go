@@ -0,0 +1,893 @@
+package js
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+// ErrNotLossless is returned by PrintLossless when the AST was not parsed with Options.Lossless.
+var ErrNotLossless = errors.New("AST was not parsed with Options.Lossless")
+
+// lossless keeps the source and a snapshot of the AST as it was parsed, so that unchanged nodes can be written as in the source.
+type lossless struct {
+	ast        *AST
+	src        []byte
+	trivia     []Loc // whitespace and comments between tokens in source order
+	nodes      map[INode]*snapshot
+	vars       map[*Var][]byte // variable names as parsed
+	shorthands map[Loc][]byte  // keys of shorthand properties and bindings by the location of their variable
+	exports    map[*ExportStmt]exportList
+}
+
+// snapshot is the location and contents of a node as it was parsed, where the child nodes are kept by reference and have their own snapshot.
+type snapshot struct {
+	loc      Loc
+	data     []byte      // encoding of the fields that are not child nodes
+	children []childNode // child nodes in order, absent children are nil
+}
+
+// exportList is the list of an export statement as it was parsed, with the locations of its specifiers.
+type exportList struct {
+	list []Alias
+	locs []Loc
+}
+
+type childKind int
+
+// childKind values, which determine how a replaced child node is printed.
+const (
+	exprChild    childKind = iota // printed by its type
+	stmtChild                     // printed as a statement
+	bindingChild                  // printed as a binding
+)
+
+type childNode struct {
+	n    INode
+	kind childKind
+}
+
+func newLossless(ast *AST, src []byte, trivia []Loc, exportLocs map[*ExportStmt][]Loc) *lossless {
+	l := &lossless{
+		ast:        ast,
+		src:        append([]byte{}, src...), // data in the AST may point into the source
+		trivia:     trivia,
+		nodes:      map[INode]*snapshot{},
+		vars:       map[*Var][]byte{},
+		shorthands: map[Loc][]byte{},
+		exports:    map[*ExportStmt]exportList{},
+	}
+	l.add(ast)
+	for exportStmt, locs := range exportLocs {
+		if _, ok := l.nodes[exportStmt]; ok && len(locs) == len(exportStmt.List) {
+			l.exports[exportStmt] = exportList{append([]Alias{}, exportStmt.List...), locs}
+		}
+	}
+	return l
+}
+
+// add takes snapshots of a node and its descendants.
+func (l *lossless) add(n INode) {
+	if n == nil {
+		return
+	} else if v, ok := n.(*Var); ok {
+		if l.vars[v] == nil {
+			l.vars[v] = append([]byte{}, v.Data...)
+		}
+		return
+	} else if !tracked(n) {
+		return
+	} else if _, ok := l.nodes[n]; ok {
+		return
+	}
+	s, _ := newSnapshot(n)
+	l.nodes[n] = s
+
+	switch n := n.(type) {
+	case *Property:
+		if n.Name != nil && !n.Name.IsComputed() {
+			if _, ok := n.Value.(*Var); ok {
+				l.shorthands[n.Name.Literal.Loc] = append([]byte{}, n.Name.Literal.Data...)
+			}
+		}
+	case *BindingObject:
+		for _, item := range n.List {
+			if _, ok := item.Value.Binding.(*Var); ok && item.Key != nil && !item.Key.IsComputed() {
+				l.shorthands[item.Key.Literal.Loc] = append([]byte{}, item.Key.Literal.Data...)
+			}
+		}
+	}
+	for _, c := range s.children {
+		l.add(c.n)
+	}
+}
+
+// tracked returns true for the nodes that are stored by reference and can have a snapshot. Nodes stored by value, such as the LiteralExpr of a DotExpr, are compared as part of their parent.
+func tracked(n INode) bool {
+	switch n.(type) {
+	case *AST, *Comment, *BlockStmt, *EmptyStmt, *BadStmt, *ExprStmt, *IfStmt, *DoWhileStmt, *WhileStmt, *ForStmt, *ForInStmt, *ForOfStmt, *SwitchStmt, *BranchStmt, *ReturnStmt, *WithStmt, *LabelledStmt, *ThrowStmt, *TryStmt, *DebuggerStmt, *ImportStmt, *ExportStmt, *DirectivePrologueStmt:
+		return true
+	case *BindingArray, *BindingObject, *VarDecl, *FuncDecl, *MethodDecl, *ClassDecl, *Property:
+		return true
+	case *LiteralExpr, *ArrayExpr, *ObjectExpr, *TemplateExpr, *GroupExpr, *IndexExpr, *DotExpr, *NewTargetExpr, *ImportMetaExpr, *NewExpr, *CallExpr, *UnaryExpr, *BinaryExpr, *CondExpr, *YieldExpr, *ArrowFunc, *CommaExpr, *JSXElement, *JSXFragment, *JSXExprContainer:
+		return true
+	}
+	return false
+}
+
+// newSnapshot returns the snapshot of a node in its current state, or false if the node is not tracked.
+func newSnapshot(n INode) (*snapshot, bool) {
+	if !tracked(n) {
+		return nil, false
+	}
+	s := &snapshot{loc: NodeLoc(n)}
+	switch n := n.(type) {
+	case *AST:
+		s.block(&n.BlockStmt)
+	case *Comment:
+		s.bytes(n.Value)
+	case *BlockStmt:
+		s.stmts(n.List)
+	case *BadStmt:
+		s.bytes(n.Value)
+	case *ExprStmt:
+		s.expr(n.Value)
+	case *IfStmt:
+		s.expr(n.Cond)
+		s.stmt(n.Body)
+		s.stmt(n.Else)
+	case *DoWhileStmt:
+		s.stmt(n.Body)
+		s.expr(n.Cond)
+	case *WhileStmt:
+		s.expr(n.Cond)
+		s.stmt(n.Body)
+	case *ForStmt:
+		s.expr(n.Init)
+		s.expr(n.Cond)
+		s.expr(n.Post)
+		s.block(n.Body)
+	case *ForInStmt:
+		s.expr(n.Init)
+		s.expr(n.Value)
+		s.block(n.Body)
+	case *ForOfStmt:
+		s.bool(n.Await)
+		s.expr(n.Init)
+		s.expr(n.Value)
+		s.block(n.Body)
+	case *SwitchStmt:
+		s.expr(n.Init)
+		s.uint(len(n.List))
+		for _, clause := range n.List {
+			s.uint(int(clause.TokenType))
+			s.expr(clause.Cond)
+			s.stmts(clause.List)
+		}
+	case *BranchStmt:
+		s.uint(int(n.Type))
+		s.bytes(n.Label)
+	case *ReturnStmt:
+		s.expr(n.Value)
+	case *WithStmt:
+		s.expr(n.Cond)
+		s.stmt(n.Body)
+	case *LabelledStmt:
+		s.bytes(n.Label)
+		s.stmt(n.Value)
+	case *ThrowStmt:
+		s.expr(n.Value)
+	case *TryStmt:
+		s.block(n.Body)
+		s.binding(n.Binding)
+		s.block(n.Catch)
+		s.block(n.Finally)
+	case *ImportStmt:
+		s.aliases(n.List)
+		s.bytes(n.Default)
+		s.bytes(n.Module)
+		s.attributes(n.Attributes)
+	case *ExportStmt:
+		s.uint(len(n.List)) // the specifiers are compared by diffExports
+		s.bytes(n.Module)
+		s.attributes(n.Attributes)
+		s.bool(n.Default)
+		s.expr(n.Decl)
+	case *DirectivePrologueStmt:
+		s.bytes(n.Value)
+	case *BindingArray:
+		s.bindingElements(n.List)
+		s.binding(n.Rest)
+	case *BindingObject:
+		s.uint(len(n.List))
+		for _, item := range n.List {
+			s.propertyName(item.Key)
+			s.bindingElement(item.Value)
+		}
+		s.variable(n.Rest)
+	case *VarDecl:
+		s.uint(int(n.TokenType))
+		s.bool(n.Await)
+		s.bindingElements(n.List)
+		s.comments(n.Comments)
+	case *FuncDecl:
+		s.bool(n.Async)
+		s.bool(n.Generator)
+		s.variable(n.Name)
+		s.params(n.Params)
+		s.block(&n.Body)
+		s.comments(n.Comments)
+	case *MethodDecl:
+		s.exprs(n.Decorators)
+		s.bool(n.Static)
+		s.bool(n.Async)
+		s.bool(n.Generator)
+		s.bool(n.Get)
+		s.bool(n.Set)
+		s.classElementName(n.Name)
+		s.params(n.Params)
+		s.block(&n.Body)
+		s.comments(n.Comments)
+	case *ClassDecl:
+		s.exprs(n.Decorators)
+		s.variable(n.Name)
+		s.expr(n.Extends)
+		s.uint(len(n.List))
+		for _, item := range n.List {
+			if item.StaticBlock != nil {
+				s.uint(0)
+				s.block(item.StaticBlock)
+			} else if item.Method != nil {
+				s.uint(1)
+				s.node(item.Method)
+			} else {
+				s.uint(2)
+				s.exprs(item.Field.Decorators)
+				s.bool(item.Field.Static)
+				s.bool(item.Field.Accessor)
+				s.classElementName(item.Field.Name)
+				s.expr(item.Field.Init)
+				s.comments(item.Field.Comments)
+			}
+		}
+		s.comments(n.Comments)
+	case *Property:
+		s.propertyName(n.Name)
+		s.bool(n.Spread)
+		s.expr(n.Value)
+		s.expr(n.Init)
+		s.comments(n.Comments)
+	case *LiteralExpr:
+		s.literal(*n)
+	case *ArrayExpr:
+		s.uint(len(n.List))
+		for _, item := range n.List {
+			s.bool(item.Spread)
+			s.expr(item.Value)
+		}
+	case *ObjectExpr:
+		s.uint(len(n.List))
+		for i := range n.List {
+			s.node(&n.List[i])
+		}
+	case *TemplateExpr:
+		s.expr(n.Tag)
+		s.uint(len(n.List))
+		for _, item := range n.List {
+			s.bytes(item.Value)
+			s.expr(item.Expr)
+		}
+		s.bytes(n.Tail)
+		s.uint(int(n.Prec))
+		s.bool(n.Optional)
+	case *GroupExpr:
+		s.expr(n.X)
+	case *IndexExpr:
+		s.expr(n.X)
+		s.expr(n.Y)
+		s.uint(int(n.Prec))
+		s.bool(n.Optional)
+	case *DotExpr:
+		s.expr(n.X)
+		if y, ok := n.Y.(LiteralExpr); ok {
+			s.bool(true)
+			s.literal(y)
+		} else {
+			s.bool(false)
+			s.expr(n.Y)
+		}
+		s.uint(int(n.Prec))
+		s.bool(n.Optional)
+	case *NewExpr:
+		s.expr(n.X)
+		s.bool(n.Args != nil)
+		if n.Args != nil {
+			s.args(*n.Args)
+		}
+	case *CallExpr:
+		s.expr(n.X)
+		s.args(n.Args)
+		s.uint(int(n.Prec))
+		s.bool(n.Optional)
+	case *UnaryExpr:
+		s.uint(int(n.Op))
+		s.expr(n.X)
+	case *BinaryExpr:
+		s.uint(int(n.Op))
+		s.expr(n.X)
+		s.expr(n.Y)
+	case *CondExpr:
+		s.expr(n.Cond)
+		s.expr(n.X)
+		s.expr(n.Y)
+	case *YieldExpr:
+		s.bool(n.Generator)
+		s.expr(n.X)
+	case *ArrowFunc:
+		s.bool(n.Async)
+		s.params(n.Params)
+		s.block(&n.Body)
+	case *CommaExpr:
+		s.exprs(n.List)
+	case *JSXElement:
+		s.expr(n.Name)
+		s.uint(len(n.Attrs))
+		for _, attr := range n.Attrs {
+			s.bytes(attr.Name)
+			s.expr(attr.Value)
+			s.bool(attr.Spread)
+		}
+		s.bool(n.Children == nil)
+		s.exprs(n.Children)
+	case *JSXFragment:
+		s.exprs(n.Children)
+	case *JSXExprContainer:
+		s.expr(n.X)
+		s.bool(n.Spread)
+	}
+	return s, true
+}
+
+func (s *snapshot) uint(i int) {
+	s.data = binary.AppendUvarint(s.data, uint64(i))
+}
+
+func (s *snapshot) bool(b bool) {
+	if b {
+		s.uint(1)
+	} else {
+		s.uint(0)
+	}
+}
+
+func (s *snapshot) bytes(b []byte) {
+	s.bool(b == nil)
+	s.uint(len(b))
+	s.data = append(s.data, b...)
+}
+
+func (s *snapshot) add(n INode, kind childKind) {
+	s.children = append(s.children, childNode{n, kind})
+}
+
+func (s *snapshot) node(n INode) {
+	s.add(n, exprChild)
+}
+
+func (s *snapshot) expr(n IExpr) {
+	s.add(n, exprChild)
+}
+
+func (s *snapshot) exprs(list []IExpr) {
+	s.uint(len(list))
+	for _, item := range list {
+		s.expr(item)
+	}
+}
+
+func (s *snapshot) stmt(n IStmt) {
+	s.add(n, stmtChild)
+}
+
+func (s *snapshot) stmts(list []IStmt) {
+	s.uint(len(list))
+	for _, item := range list {
+		s.stmt(item)
+	}
+}
+
+func (s *snapshot) block(n *BlockStmt) {
+	if n == nil {
+		s.add(nil, stmtChild)
+	} else {
+		s.add(n, stmtChild)
+	}
+}
+
+func (s *snapshot) binding(n IBinding) {
+	s.add(n, bindingChild)
+}
+
+func (s *snapshot) variable(v *Var) {
+	if v == nil {
+		s.add(nil, bindingChild)
+	} else {
+		s.add(v, bindingChild)
+	}
+}
+
+func (s *snapshot) bindingElement(n BindingElement) {
+	s.binding(n.Binding)
+	s.expr(n.Default)
+}
+
+func (s *snapshot) bindingElements(list []BindingElement) {
+	s.uint(len(list))
+	for _, item := range list {
+		s.bindingElement(item)
+	}
+}
+
+func (s *snapshot) params(n Params) {
+	s.bindingElements(n.List)
+	s.binding(n.Rest)
+}
+
+func (s *snapshot) args(n Args) {
+	s.uint(len(n.List))
+	for _, arg := range n.List {
+		s.bool(arg.Rest)
+		s.expr(arg.Value)
+	}
+}
+
+func (s *snapshot) literal(n LiteralExpr) {
+	s.uint(int(n.TokenType))
+	s.bytes(n.Data)
+}
+
+func (s *snapshot) propertyName(n *PropertyName) {
+	s.bool(n == nil)
+	if n != nil {
+		s.literal(n.Literal)
+		s.expr(n.Computed)
+	}
+}
+
+func (s *snapshot) classElementName(n ClassElementName) {
+	s.variable(n.Private)
+	s.propertyName(&n.PropertyName)
+}
+
+func (s *snapshot) aliases(list []Alias) {
+	s.uint(len(list))
+	for _, alias := range list {
+		s.bytes(alias.Name)
+		s.bytes(alias.Binding)
+	}
+}
+
+func (s *snapshot) attributes(list []ImportAttribute) {
+	s.uint(len(list))
+	for _, attribute := range list {
+		s.bytes(attribute.Key)
+		s.bytes(attribute.Value)
+	}
+}
+
+func (s *snapshot) comments(c Comments) {
+	s.uint(len(c.Leading))
+	for _, comment := range c.Leading {
+		s.add(comment, stmtChild)
+	}
+	s.uint(len(c.Trailing))
+	for _, comment := range c.Trailing {
+		s.add(comment, stmtChild)
+	}
+}
+
+// Trivia returns the whitespace and comments before and after a node when the AST was parsed with Options.Lossless. The trivia between two tokens is split after the first line terminator that is not within a comment, so that trailing trivia runs up to the end of the line and the rest is leading trivia of the next token. Trivia at the start and end of the source is not split.
+func (ast *AST) Trivia(n INode) (leading, trailing []byte) {
+	l := ast.lossless
+	if l == nil {
+		return nil, nil
+	}
+	loc := NodeLoc(n)
+	if v, ok := n.(*Var); ok {
+		if 0 < len(v.Locs) {
+			loc = v.Locs[0]
+		}
+	} else if tracked(n) {
+		if s, ok := l.nodes[n]; ok {
+			loc = s.loc // location before any edits
+		}
+	}
+	if !loc.IsSet() {
+		return nil, nil
+	}
+
+	i := sort.Search(len(l.trivia), func(i int) bool { return loc.Start <= l.trivia[i].End })
+	if i < len(l.trivia) && l.trivia[i].End == loc.Start {
+		t := l.trivia[i]
+		if t.Start != 0 {
+			t.Start += splitTrivia(l.src[t.Start:t.End])
+		}
+		leading = l.src[t.Start:t.End]
+	}
+	i = sort.Search(len(l.trivia), func(i int) bool { return loc.End <= l.trivia[i].Start })
+	if i < len(l.trivia) && l.trivia[i].Start == loc.End {
+		t := l.trivia[i]
+		if t.End != len(l.src) {
+			t.End = t.Start + splitTrivia(l.src[t.Start:t.End])
+		}
+		trailing = l.src[t.Start:t.End]
+	}
+	return
+}
+
+// splitTrivia returns the index after the first line terminator that is not within a comment, or zero if there is none.
+func splitTrivia(b []byte) int {
+	for i := 0; i < len(b); i++ {
+		if b[i] == '/' && i+1 < len(b) && b[i+1] == '*' {
+			if j := bytes.Index(b[i+2:], []byte("*/")); j != -1 {
+				i += j + 3
+			} else {
+				return 0
+			}
+		} else if b[i] == '/' && i+1 < len(b) && b[i+1] == '/' {
+			for i+1 < len(b) && b[i+1] != '\n' && b[i+1] != '\r' && !bytes.HasPrefix(b[i+1:], []byte("\u2028")) && !bytes.HasPrefix(b[i+1:], []byte("\u2029")) {
+				i++
+			}
+		} else if b[i] == '\n' {
+			return i + 1
+		} else if b[i] == '\r' {
+			if i+1 < len(b) && b[i+1] == '\n' {
+				return i + 2
+			}
+			return i + 1
+		} else if bytes.HasPrefix(b[i:], []byte("\u2028")) || bytes.HasPrefix(b[i:], []byte("\u2029")) {
+			return i + 3
+		}
+	}
+	return 0
+}
+
+////////////////////////////////////////////////////////////////
+
+// PrintLossless writes an AST that was parsed with Options.Lossless to the writer. Nodes that were not changed after parsing are written exactly as in the source, including whitespace, comments, parentheses, quotes, and the spelling of numbers. Nodes that were changed or added are printed as by Print, while their unchanged child nodes are still written as in the source. Replacing a node or renaming a variable only affects the output at its locations, where a renamed variable keeps the key of shorthand properties and bindings and the exported name of export specifiers, and statements can be inserted into and deleted from statement lists while keeping the formatting of the other statements. It returns ErrNotLossless if the AST was not parsed with Options.Lossless.
+func PrintLossless(w io.Writer, ast *AST, o PrintOptions) error {
+	if ast.lossless == nil {
+		return ErrNotLossless
+	} else if o.Indent <= 0 {
+		o.Indent = 4
+	}
+	if o.Quote != 0 && o.Quote != '"' && o.Quote != '\'' {
+		return ErrInvalidQuote
+	}
+
+	// renamed variables are replaced at all their locations, where shorthand properties and bindings keep their key
+	lw := &losslessWriter{lossless: ast.lossless}
+	for v, data := range lw.vars {
+		if !bytes.Equal(v.Data, data) {
+			for _, loc := range v.Locs {
+				if key, ok := lw.shorthands[loc]; ok && !bytes.Equal(key, v.Data) {
+					lw.renames = append(lw.renames, rename{loc, append(append(key[:len(key):len(key)], ": "...), v.Data...)})
+				} else {
+					lw.renames = append(lw.renames, rename{loc, v.Data})
+				}
+			}
+		}
+	}
+	sort.Slice(lw.renames, func(i, j int) bool {
+		return lw.renames[i].Start < lw.renames[j].Start
+	})
+
+	p := newPrinter(w, o)
+	p.lossless = lw
+	if !lw.node(p, ast) {
+		p.printStmts(ast.List, false)
+	}
+	return p.lw.err
+}
+
+type rename struct {
+	Loc
+	data []byte
+}
+
+// edit replaces the source at its location by a node, by the statements of a list when splicing, or by data.
+type edit struct {
+	Loc
+	n    INode
+	kind childKind // how to print the node
+	old  []IStmt   // statements in the source when splicing
+	list []IStmt   // statements to write when splicing
+	data []byte
+}
+
+type losslessWriter struct {
+	*lossless
+	renames []rename
+}
+
+// node writes a node as in the source with the changes applied to its children, and returns false if the node must be printed instead.
+func (w *losslessWriter) node(p *printer, n INode) bool {
+	if !tracked(n) {
+		return false
+	}
+	s, ok := w.nodes[n]
+	if !ok || !s.loc.IsSet() {
+		return false
+	}
+	edits := []edit{}
+	if !w.diff(&edits, n, s) {
+		return false
+	}
+	w.write(p, s.loc.Start, s.loc.End, edits)
+	return true
+}
+
+// needsSemicolon returns true if a statement that was written as in the source relied on automatic semicolon insertion.
+func (w *losslessWriter) needsSemicolon(stmt IStmt) bool {
+	if _, ok := stmt.(*Comment); ok {
+		return false
+	}
+	loc := w.nodes[stmt].loc
+	return w.src[loc.End-1] != ';' && w.src[loc.End-1] != '}'
+}
+
+// diff compares a node with its snapshot and adds the edits that are needed to write it as in the source. It returns false if the node must be printed instead.
+func (w *losslessWriter) diff(edits *[]edit, n INode, s *snapshot) bool {
+	if block, ok := n.(*BlockStmt); ok {
+		return w.diffBlock(edits, block, s)
+	}
+	cur, _ := newSnapshot(n)
+	if cur.loc != s.loc || !bytes.Equal(cur.data, s.data) || len(cur.children) != len(s.children) {
+		return false
+	} else if exportStmt, ok := n.(*ExportStmt); ok && !w.diffExports(edits, exportStmt) {
+		return false
+	}
+	for i, c := range cur.children {
+		if !w.diffChild(edits, c, s.children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffChild compares a child node with the child in the snapshot of its parent.
+func (w *losslessWriter) diffChild(edits *[]edit, c, old childNode) bool {
+	if c.n == nil || old.n == nil {
+		return c.n == nil && old.n == nil
+	} else if c.n != old.n {
+		// replaced node
+		if _, ok := old.n.(*Var); ok {
+			return false // the location of this use is unknown
+		} else if s := w.nodes[old.n]; s != nil && s.loc.IsSet() {
+			*edits = append(*edits, edit{Loc: s.loc, n: c.n, kind: c.kind})
+			return true
+		}
+		return false
+	} else if _, ok := c.n.(*Var); ok {
+		return true
+	}
+
+	s := w.nodes[c.n]
+	if s == nil {
+		return false
+	}
+	m := len(*edits)
+	if !w.diff(edits, c.n, s) {
+		if !s.loc.IsSet() {
+			return false
+		}
+		*edits = append((*edits)[:m], edit{Loc: s.loc, n: c.n, kind: c.kind})
+	}
+	return true
+}
+
+// diffBlock compares a block statement with its snapshot. When statements were inserted or deleted, it adds an edit that splices the statement list.
+func (w *losslessWriter) diffBlock(edits *[]edit, block *BlockStmt, s *snapshot) bool {
+	if block.Loc != s.loc {
+		return false
+	} else if len(block.List) == len(s.children) {
+		m := len(*edits)
+		equal := true
+		for i, stmt := range block.List {
+			if !w.diffChild(edits, childNode{stmt, stmtChild}, s.children[i]) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+		*edits = (*edits)[:m]
+	}
+	if len(s.children) == 0 || !block.Loc.IsSet() {
+		return false
+	}
+	old := make([]IStmt, len(s.children))
+	for i, c := range s.children {
+		old[i] = c.n.(IStmt)
+		if !w.nodes[old[i]].loc.IsSet() {
+			return false
+		}
+	}
+
+	loc := block.Loc
+	if block != &w.ast.BlockStmt {
+		// exclude the braces, and require statements to start on their own line
+		first := w.nodes[old[0]].loc
+		if w.src[loc.Start] != '{' || w.src[loc.End-1] != '}' || splitTrivia(w.src[loc.Start+1:first.Start]) == 0 {
+			return false
+		}
+		loc.Start++
+		loc.End--
+	}
+	*edits = append(*edits, edit{Loc: loc, old: old, list: block.List})
+	return true
+}
+
+// diffExports compares the specifiers of an export list with the list as it was parsed, and replaces the specifiers that changed, such as by renaming the local variable.
+func (w *losslessWriter) diffExports(edits *[]edit, exportStmt *ExportStmt) bool {
+	exports, ok := w.exports[exportStmt]
+	if !ok {
+		return len(exportStmt.List) == 0
+	}
+	for i, alias := range exportStmt.List {
+		if old := exports.list[i]; bytes.Equal(alias.Name, old.Name) && bytes.Equal(alias.Binding, old.Binding) {
+			continue
+		} else if !exports.locs[i].IsSet() || alias.Binding == nil {
+			return false
+		}
+		*edits = append(*edits, edit{Loc: exports.locs[i], data: []byte(alias.String())})
+	}
+	return true
+}
+
+// write writes the source between start and end with the edits applied.
+func (w *losslessWriter) write(p *printer, start, end int, edits []edit) {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	for _, e := range edits {
+		w.copy(p, start, e.Start)
+		if e.old != nil {
+			w.splice(p, e)
+		} else if e.data != nil {
+			p.writeRaw(e.data)
+		} else {
+			w.print(p, e.n, e.kind, e.Start)
+		}
+		start = e.End
+	}
+	w.copy(p, start, end)
+}
+
+// copy writes the source between start and end, replacing renamed variables.
+func (w *losslessWriter) copy(p *printer, start, end int) {
+	i := sort.Search(len(w.renames), func(i int) bool { return start <= w.renames[i].Start })
+	for ; i < len(w.renames) && w.renames[i].End <= end; i++ {
+		p.writeRaw(w.src[start:w.renames[i].Start])
+		p.writeRaw(w.renames[i].data)
+		start = w.renames[i].End
+	}
+	if start < end {
+		p.writeRaw(w.src[start:end])
+	}
+}
+
+// print prints a node that replaces the source at pos, indented as the line at pos.
+func (w *losslessWriter) print(p *printer, n INode, kind childKind, pos int) {
+	pw := p.w
+	p.w = prefixWriter{p.lw, w.indentation(pos)}
+	switch {
+	case kind == stmtChild:
+		p.printStmt(n.(IStmt))
+	case kind == bindingChild:
+		p.printBinding(n.(IBinding))
+	default:
+		switch n := n.(type) {
+		case IExpr:
+			p.printExpr(n)
+		case *Property:
+			p.printProperty(n)
+		case *MethodDecl:
+			p.printMethod(n)
+		case IStmt:
+			p.printStmt(n)
+		case IBinding:
+			p.printBinding(n)
+		default:
+			p.flush(nil)
+			n.JS(p.w)
+		}
+	}
+	p.w = pw
+}
+
+// prefixWriter writes the indentation of the source after each newline.
+type prefixWriter struct {
+	io.Writer
+	prefix []byte
+}
+
+func (w prefixWriter) Write(b []byte) (int, error) {
+	n, j := 0, 0
+	for i, c := range b {
+		if c == '\n' {
+			m, _ := w.Writer.Write(b[j : i+1])
+			n += m
+			w.Writer.Write(w.prefix)
+			j = i + 1
+		}
+	}
+	m, err := w.Writer.Write(b[j:])
+	return n + m, err
+}
+
+// indentation returns the whitespace at the start of the line at pos.
+func (w *losslessWriter) indentation(pos int) []byte {
+	start := pos
+	for 0 < start && w.src[start-1] != '\n' && w.src[start-1] != '\r' {
+		start--
+	}
+	end := start
+	for end < pos && (w.src[end] == ' ' || w.src[end] == '\t') {
+		end++
+	}
+	return w.src[start:end]
+}
+
+// splice writes a statement list where statements may have been inserted, deleted, or moved. Each statement in the source keeps its trivia up to and including the first line terminator after it, and new statements are written on separate lines with the indentation of the preceding statement.
+func (w *losslessWriter) splice(p *printer, e edit) {
+	locs := make([]Loc, len(e.old))
+	index := make(map[IStmt]int, len(e.old))
+	for i, stmt := range e.old {
+		locs[i] = w.nodes[stmt].loc
+		index[stmt] = i
+	}
+	splits := make([]int, len(e.old)+1)
+	splits[0] = e.Start
+	if e.Start != 0 {
+		splits[0] += splitTrivia(w.src[e.Start:locs[0].Start])
+	}
+	for i := 1; i < len(e.old); i++ {
+		splits[i] = locs[i-1].End + splitTrivia(w.src[locs[i-1].End:locs[i].Start])
+	}
+	splits[len(e.old)] = e.End
+	if e.End != len(w.src) {
+		end := locs[len(e.old)-1].End
+		splits[len(e.old)] = end + splitTrivia(w.src[end:e.End])
+	}
+
+	w.copy(p, e.Start, splits[0])
+	indent := w.indentation(locs[0].Start)
+	written := make([]bool, len(e.old))
+	for _, stmt := range e.list {
+		if i, ok := index[stmt]; ok && !written[i] {
+			written[i] = true
+			w.copy(p, splits[i], locs[i].Start)
+			if !w.node(p, stmt) {
+				w.print(p, stmt, stmtChild, locs[i].Start)
+			}
+			w.copy(p, locs[i].End, splits[i+1])
+			indent = w.indentation(locs[i].Start)
+		} else {
+			if p.lw.col != 0 {
+				p.writeRaw([]byte("\n"))
+			}
+			p.writeRaw(indent)
+			pw := p.w
+			p.w = prefixWriter{p.lw, indent}
+			p.printStmt(stmt)
+			p.flush(nil)
+			p.w = pw
+			p.writeRaw([]byte("\n"))
+		}
+	}
+	w.copy(p, splits[len(e.old)], e.End)
+}
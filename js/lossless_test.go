@@ -0,0 +1,131 @@
+package js
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestPrintLossless(t *testing.T) {
+	var tests = []string{
+		"",
+		" \n\t",
+		"a  =  1",
+		"#!/usr/bin/env node\n// comment\nvar  a = 'x' ;; /* b */\n",
+		"function f ( x , ...y ) {\n\treturn (x+0x10) + 1_000 + .5e3\n}\n",
+		"class A extends B { static #a = 1 ; m () { return `t${ a }` } }",
+		"let {b, c: [d = 2]} = o, [ e ] = p\r\nexport { b as 'c' }\n",
+		"if (a) { b } else c; for (;;) {} x => { y }; (async function* () {})",
+		"label: for (const x of y) continue label /* end */",
+		"/*! license */ a b",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt), Options{Lossless: true})
+			test.Error(t, err)
+			buf := &bytes.Buffer{}
+			test.Error(t, PrintLossless(buf, ast, PrintOptions{}))
+			test.String(t, buf.String(), tt)
+		})
+	}
+}
+
+func TestPrintLosslessEdit(t *testing.T) {
+	var tests = []struct {
+		js       string
+		edit     func(*AST)
+		expected string
+	}{
+		{"a = f( 1 ) // one\n", func(ast *AST) {
+			call := ast.List[0].(*ExprStmt).Value.(*BinaryExpr).Y.(*CallExpr)
+			call.Args.List[0].Value = &LiteralExpr{TokenType: StringToken, Data: []byte(`"s"`)}
+		}, "a = f( \"s\" ) // one\n"},
+		{"var a = 'x'\nfunction f(x) {\n  return x + a // a\n}\n", func(ast *AST) {
+			ast.List[0].(*VarDecl).List[0].Binding.(*Var).Data = []byte("b")
+		}, "var b = 'x'\nfunction f(x) {\n  return x + b // a\n}\n"},
+		{"x( 1 );\nlet  y = 0x2\n", func(ast *AST) {
+			ast.List[0].(*ExprStmt).Value.(*CallExpr).X = &DotExpr{X: &Var{Data: []byte("a")}, Y: LiteralExpr{TokenType: IdentifierToken, Data: []byte("b")}}
+		}, "a.b(1);\nlet  y = 0x2\n"},
+		{"x( 1 );\nlet  y = 0x2\n", func(ast *AST) {
+			ast.List[1].(*VarDecl).TokenType = ConstToken
+		}, "x( 1 );\nconst y = 0x2;\n"},
+		{"function f() {\n    a( 1 )  // a\n    b( 2 )\n}\n", func(ast *AST) {
+			f := ast.List[0].(*FuncDecl)
+			f.Body.List = []IStmt{f.Body.List[0], &ReturnStmt{Value: &Var{Data: []byte("c")}}, f.Body.List[1]}
+		}, "function f() {\n    a( 1 )  // a\n    return c;\n    b( 2 )\n}\n"},
+		{"if (a) {\n\tb( 1 )\n}\n", func(ast *AST) {
+			body := ast.List[0].(*IfStmt).Body.(*BlockStmt)
+			body.List = append(body.List, &IfStmt{Cond: &Var{Data: []byte("c")}, Body: &BlockStmt{List: []IStmt{&ExprStmt{Value: &Var{Data: []byte("d")}}}}})
+		}, "if (a) {\n\tb( 1 )\n\tif (c) {\n\t    d;\n\t}\n}\n"},
+		{"// a\na( 1 ) ;\n\n// b\nb( 2 ) ;\nc( 3 )\n", func(ast *AST) {
+			ast.List = []IStmt{ast.List[2], ast.List[0]}
+		}, "c( 3 )\n// a\na( 1 ) ;\n"},
+		{"function f() { a( 1 ) }", func(ast *AST) {
+			f := ast.List[0].(*FuncDecl)
+			f.Body.List = append(f.Body.List, &ReturnStmt{})
+		}, "function f() {\n    a( 1 );\n    return;\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{Lossless: true})
+			test.Error(t, err)
+			tt.edit(ast)
+			buf := &bytes.Buffer{}
+			test.Error(t, PrintLossless(buf, ast, PrintOptions{}))
+			test.String(t, buf.String(), tt.expected)
+		})
+	}
+
+	ast, err := Parse(parse.NewInputString("a"), Options{})
+	test.Error(t, err)
+	test.T(t, PrintLossless(&bytes.Buffer{}, ast, PrintOptions{}), ErrNotLossless)
+}
+
+func TestPrintLosslessRename(t *testing.T) {
+	var tests = []struct {
+		js       string
+		name     string
+		expected string
+	}{
+		{"let {a} = o\nf( a )\n", "z", "let {a: z} = o\nf( z )\n"},
+		{"let {a = 2, b: [a2]} = o\n", "z", "let {a: z = 2, b: [a2]} = o\n"},
+		{"let a = 1\nx = { a , b: a }\n", "z", "let z = 1\nx = { a: z , b: z }\n"},
+		{"let a = 1\nx = { a: a }\n", "z", "let z = 1\nx = { a: z }\n"},
+		{"let a = 1\nfunction f({a}) { return a }\n", "z", "let z = 1\nfunction f({a}) { return a }\n"},
+		{"let a\nexport {a}\nexport { a as 'b' , }\n", "z", "let z\nexport {z as a}\nexport { z as 'b' , }\n"},
+		{"let a, z\nexport { a as z }\n", "y", "let y, z\nexport { y as z }\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{Lossless: true})
+			test.Error(t, err)
+			test.Error(t, Rename(ast, ast.Scope.Declared[0], []byte(tt.name)))
+			buf := &bytes.Buffer{}
+			test.Error(t, PrintLossless(buf, ast, PrintOptions{}))
+			test.String(t, buf.String(), tt.expected)
+		})
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	ast, err := Parse(parse.NewInputString("// a\nx = 1 ; /* b */ // c\n  /* d */ y\n\n"), Options{Lossless: true})
+	test.Error(t, err)
+
+	leading, trailing := ast.Trivia(ast.List[0])
+	test.String(t, string(leading), "// a\n")
+	test.String(t, string(trailing), " /* b */ // c\n")
+	leading, trailing = ast.Trivia(ast.List[1])
+	test.String(t, string(leading), "  /* d */ ")
+	test.String(t, string(trailing), "\n\n")
+	leading, _ = ast.Trivia(ast.List[0].(*ExprStmt).Value.(*BinaryExpr).Y)
+	test.String(t, string(leading), " ")
+	leading, _ = ast.Trivia(ast.List[1].(*ExprStmt).Value)
+	test.String(t, string(leading), "  /* d */ ")
+
+	ast, err = Parse(parse.NewInputString("x"), Options{})
+	test.Error(t, err)
+	leading, trailing = ast.Trivia(ast.List[0])
+	test.T(t, len(leading)+len(trailing), 0)
+}
@@ -0,0 +1,545 @@
+package js
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+)
+
+// DependencyKind is the kind of reference to another module.
+type DependencyKind int
+
+// DependencyKind values.
+const (
+	StaticImport  DependencyKind = iota // import a from 'm', or import 'm'
+	ReExport                            // export { a } from 'm', or export * from 'm'
+	DynamicImport                       // import('m')
+	RequireCall                         // require('m')
+)
+
+func (kind DependencyKind) String() string {
+	switch kind {
+	case StaticImport:
+		return "StaticImport"
+	case ReExport:
+		return "ReExport"
+	case DynamicImport:
+		return "DynamicImport"
+	case RequireCall:
+		return "RequireCall"
+	}
+	return "Invalid(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// ImportName is a name imported from a module.
+type ImportName struct {
+	Imported string // name exported by the module, which is default for default imports and * for namespace imports
+	Local    string // local binding
+}
+
+// ExportName is a name exported by a module.
+type ExportName struct {
+	Exported string // exported name, which is default for default exports and * for export * from 'm'
+	Local    string // local binding, or the name exported by the module for re-exports which is * for namespace re-exports; empty for anonymous default exports
+	Loc      Loc    // location of the export statement
+}
+
+// Dependency is a reference to another module by an import or export statement, by an import call, or by a require call.
+type Dependency struct {
+	Kind      DependencyKind
+	Specifier string       // module specifier without quotes
+	Type      string       // value of the type import attribute, such as json for with { type: 'json' }, which is empty for JavaScript modules
+	Imports   []ImportName // nil for re-exports, and for side-effect imports, import calls, and require calls that do not destructure specific names
+	Exports   []ExportName // re-exported names, only for re-exports
+	Loc       Loc          // location of the statement or call
+}
+
+// ModuleInfo is the list of dependencies and exports of a module.
+type ModuleInfo struct {
+	Dependencies []Dependency // in source order
+	Exports      []ExportName // local exports in source order, re-exports are listed in Dependencies
+}
+
+// AnalyzeModule returns the dependencies and exports of a parsed module. Import and require calls are only included when their argument is a string literal or a template literal without substitutions, and require calls only when require is not declared. The imports of require calls are the names of an object pattern they initialize, such as const {a, b: c} = require('m'), when it has no computed keys or nested patterns.
+func AnalyzeModule(ast *AST) ModuleInfo {
+	info := ModuleInfo{}
+	for _, item := range ast.List {
+		switch stmt := item.(type) {
+		case *ImportStmt:
+			dep := Dependency{
+				Kind:      StaticImport,
+				Specifier: stringValue(stmt.Module),
+				Type:      attributesType(stmt.Attributes),
+				Loc:       stmt.Loc,
+			}
+			if stmt.Default != nil {
+				dep.Imports = append(dep.Imports, ImportName{"default", string(stmt.Default)})
+			}
+			for _, alias := range stmt.List {
+				if alias.Binding == nil {
+					continue // trailing comma
+				} else if alias.Name == nil {
+					dep.Imports = append(dep.Imports, ImportName{string(alias.Binding), string(alias.Binding)})
+				} else {
+					dep.Imports = append(dep.Imports, ImportName{moduleExportName(alias.Name), string(alias.Binding)})
+				}
+			}
+			info.Dependencies = append(info.Dependencies, dep)
+		case *ExportStmt:
+			if stmt.Module != nil {
+				dep := Dependency{
+					Kind:      ReExport,
+					Specifier: stringValue(stmt.Module),
+					Type:      attributesType(stmt.Attributes),
+					Loc:       stmt.Loc,
+				}
+				for _, alias := range stmt.List {
+					if alias.Binding == nil {
+						continue // trailing comma
+					} else if alias.Name == nil && len(alias.Binding) == 1 && alias.Binding[0] == '*' {
+						dep.Exports = append(dep.Exports, ExportName{"*", "*", stmt.Loc}) // export * from 'm'
+					} else if alias.Name == nil {
+						name := moduleExportName(alias.Binding)
+						dep.Exports = append(dep.Exports, ExportName{name, name, stmt.Loc})
+					} else {
+						dep.Exports = append(dep.Exports, ExportName{moduleExportName(alias.Binding), moduleExportName(alias.Name), stmt.Loc})
+					}
+				}
+				info.Dependencies = append(info.Dependencies, dep)
+			} else if stmt.Decl != nil {
+				info.Exports = append(info.Exports, exportedDecls(stmt)...)
+				Walk(&dependencyVisitor{&info}, stmt.Decl)
+			} else {
+				for _, alias := range stmt.List {
+					if alias.Binding == nil {
+						continue // trailing comma
+					} else if alias.Name == nil {
+						info.Exports = append(info.Exports, ExportName{string(alias.Binding), string(alias.Binding), stmt.Loc})
+					} else {
+						info.Exports = append(info.Exports, ExportName{moduleExportName(alias.Binding), string(alias.Name), stmt.Loc})
+					}
+				}
+			}
+		default:
+			Walk(&dependencyVisitor{&info}, item)
+		}
+	}
+	sort.SliceStable(info.Dependencies, func(i, j int) bool {
+		return info.Dependencies[i].Loc.Start < info.Dependencies[j].Loc.Start
+	})
+	return info
+}
+
+// exportedDecls returns the exported names of an export statement with a declaration.
+func exportedDecls(stmt *ExportStmt) []ExportName {
+	names := []ExportName{}
+	switch decl := stmt.Decl.(type) {
+	case *VarDecl:
+		for _, item := range decl.List {
+			for _, v := range boundNames(item.Binding) {
+				names = append(names, ExportName{string(v.Data), string(v.Data), stmt.Loc})
+			}
+		}
+		return names
+	case *FuncDecl:
+		if decl.Name != nil {
+			names = append(names, ExportName{string(decl.Name.Data), string(decl.Name.Data), stmt.Loc})
+		}
+	case *ClassDecl:
+		if decl.Name != nil {
+			names = append(names, ExportName{string(decl.Name.Data), string(decl.Name.Data), stmt.Loc})
+		}
+	}
+	if stmt.Default {
+		local := ""
+		if 0 < len(names) {
+			local = names[0].Local
+		} else if v, ok := stmt.Decl.(*Var); ok {
+			local = string(v.Name())
+		}
+		names = []ExportName{{"default", local, stmt.Loc}}
+	}
+	return names
+}
+
+// moduleExportName returns the name of an identifier or a string literal.
+func moduleExportName(b []byte) string {
+	if 0 < len(b) && (b[0] == '"' || b[0] == '\'') {
+		return stringValue(b)
+	}
+	return string(b)
+}
+
+// attributesType returns the value of the type import attribute.
+func attributesType(attributes []ImportAttribute) string {
+	for _, attribute := range attributes {
+		if moduleExportName(attribute.Key) == "type" {
+			return stringValue(attribute.Value)
+		}
+	}
+	return ""
+}
+
+// optionsType returns the value of the type import attribute of the options of an import call, such as { with: { type: 'json' } }.
+func optionsType(options IExpr) string {
+	if obj, ok := options.(*ObjectExpr); ok {
+		for _, property := range obj.List {
+			if property.Name == nil || !property.Name.IsIdent([]byte("with")) {
+				continue
+			} else if with, ok := property.Value.(*ObjectExpr); ok {
+				for _, attribute := range with.List {
+					if attribute.Name != nil && attribute.Name.IsIdent([]byte("type")) {
+						if lit, ok := attribute.Value.(*LiteralExpr); ok && lit.TokenType == StringToken {
+							return stringValue(lit.Data)
+						}
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// requireImports returns the names of an object pattern initialized by a require call, or nil when it has computed keys or nested patterns.
+func requireImports(obj *BindingObject) []ImportName {
+	imports := []ImportName{}
+	for _, item := range obj.List {
+		v, ok := item.Value.Binding.(*Var)
+		if !ok || item.Key != nil && item.Key.IsComputed() {
+			return nil
+		} else if item.Key == nil {
+			imports = append(imports, ImportName{string(v.Data), string(v.Data)})
+		} else {
+			imports = append(imports, ImportName{moduleExportName(item.Key.Literal.Data), string(v.Data)})
+		}
+	}
+	if obj.Rest != nil {
+		imports = append(imports, ImportName{"*", string(obj.Rest.Data)})
+	}
+	return imports
+}
+
+// dependencyVisitor adds the import and require calls to the dependencies.
+type dependencyVisitor struct {
+	info *ModuleInfo
+}
+
+func (v *dependencyVisitor) Enter(n INode) IVisitor {
+	switch n := n.(type) {
+	case *BindingElement:
+		// const {a, b: c} = require('m')
+		if obj, ok := n.Binding.(*BindingObject); ok {
+			if call, ok := n.Default.(*CallExpr); ok {
+				if dep, ok := callDependency(call); ok && dep.Kind == RequireCall {
+					if dep.Imports = requireImports(obj); dep.Imports == nil {
+						return v
+					}
+					v.info.Dependencies = append(v.info.Dependencies, dep)
+					Walk(v, n.Binding)
+					return nil
+				}
+			}
+		}
+	case *CallExpr:
+		if dep, ok := callDependency(n); ok {
+			v.info.Dependencies = append(v.info.Dependencies, dep)
+		}
+	}
+	return v
+}
+
+func (v *dependencyVisitor) Exit(n INode) {}
+
+// callDependency returns the dependency of an import or require call.
+func callDependency(call *CallExpr) (Dependency, bool) {
+	if len(call.Args.List) == 0 || call.Args.List[0].Rest {
+		return Dependency{}, false
+	}
+
+	kind := DynamicImport
+	if x, ok := call.X.(*LiteralExpr); ok && x.TokenType == ImportToken {
+		// import('m')
+	} else if x, ok := call.X.(*Var); ok && len(call.Args.List) == 1 && string(x.Data) == "require" && x.Resolve().Decl == NoDecl {
+		kind = RequireCall
+	} else {
+		return Dependency{}, false
+	}
+
+	var specifier []byte
+	switch arg := call.Args.List[0].Value.(type) {
+	case *LiteralExpr:
+		if arg.TokenType != StringToken {
+			return Dependency{}, false
+		}
+		specifier = arg.Data
+	case *TemplateExpr:
+		if arg.Tag != nil || len(arg.List) != 0 {
+			return Dependency{}, false
+		}
+		specifier = arg.Tail
+	default:
+		return Dependency{}, false
+	}
+	dep := Dependency{
+		Kind:      kind,
+		Specifier: stringValue(specifier),
+		Loc:       call.Loc,
+	}
+	if kind == DynamicImport && 1 < len(call.Args.List) {
+		dep.Type = optionsType(call.Args.List[1].Value)
+	}
+	return dep, true
+}
+
+////////////////////////////////////////////////////////////////
+
+// Resolver returns the path of the module that is referred to by specifier from the module at path. It returns an empty path for modules that are not part of the graph, such as built-in modules or external packages.
+type Resolver func(path, specifier string) (string, error)
+
+// NewFSResolver returns a resolver for modules in a file system. Relative specifiers, which start with ./ or ../ or are . or .., are resolved from the directory of the importing module, and absolute specifiers from the root of the file system. The specifier is tried as is, with each of the extensions appended, and as a directory with an index file with each of the extensions. Other specifiers are not resolved.
+func NewFSResolver(fsys fs.FS, extensions ...string) Resolver {
+	return func(from, specifier string) (string, error) {
+		var name string
+		if specifier == "." || specifier == ".." || strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+			name = path.Join(path.Dir(from), specifier)
+		} else if strings.HasPrefix(specifier, "/") {
+			name = path.Clean(specifier[1:])
+		} else {
+			return "", nil
+		}
+
+		candidates := []string{}
+		if name != "." {
+			// the root directory has no sibling files
+			candidates = append(candidates, name)
+			for _, ext := range extensions {
+				candidates = append(candidates, name+ext)
+			}
+		}
+		for _, ext := range extensions {
+			candidates = append(candidates, path.Join(name, "index"+ext))
+		}
+		for _, candidate := range candidates {
+			if info, err := fs.Stat(fsys, candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+		return "", fmt.Errorf("cannot resolve %s from %s", specifier, from)
+	}
+}
+
+// Module is a module in a module graph.
+type Module struct {
+	Path string
+	ModuleInfo
+	Resolved []string // resolved paths of the dependencies, which are empty for modules that are not part of the graph
+	Errors   []error  // errors of resolving the dependencies, which are nil for dependencies that were resolved
+}
+
+// ModuleGraph is the dependency graph of a set of modules.
+type ModuleGraph struct {
+	Modules map[string]*Module
+}
+
+// jsExtensions are the extensions of JavaScript and TypeScript modules.
+var jsExtensions = map[string]bool{
+	".js":  true,
+	".mjs": true,
+	".cjs": true,
+	".jsx": true,
+	".ts":  true,
+	".mts": true,
+	".cts": true,
+	".tsx": true,
+}
+
+// BuildModuleGraph parses the modules at the given paths in the file system, and all modules that they depend on. Dependencies are resolved by resolve, and modules with an empty resolved path are not added to the graph. Modules that are imported with a type import attribute, such as with { type: 'json' }, and modules with an extension that is not a JavaScript or TypeScript extension are added without being read or parsed, and have no dependencies. Errors of resolve are kept in Module.Errors for each dependency, which then has an empty resolved path, while errors of reading or parsing a module are returned.
+func BuildModuleGraph(fsys fs.FS, paths []string, resolve Resolver, o Options) (*ModuleGraph, error) {
+	g := &ModuleGraph{
+		Modules: map[string]*Module{},
+	}
+	queue := append([]string{}, paths...)
+	leaves := map[string]bool{} // modules that are imported with a type import attribute
+	for 0 < len(queue) {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := g.Modules[name]; ok {
+			continue
+		} else if ext := path.Ext(name); leaves[name] || ext != "" && !jsExtensions[ext] {
+			g.Modules[name] = &Module{Path: name}
+			continue
+		}
+
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		ast, err := Parse(parse.NewInputBytes(src), o)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		module := &Module{
+			Path:       name,
+			ModuleInfo: AnalyzeModule(ast),
+		}
+		module.Resolved = make([]string, len(module.Dependencies))
+		module.Errors = make([]error, len(module.Dependencies))
+		for i, dep := range module.Dependencies {
+			if resolved, err := resolve(name, dep.Specifier); err != nil {
+				module.Errors[i] = err
+			} else if module.Resolved[i] = resolved; resolved != "" {
+				queue = append(queue, module.Resolved[i])
+				if dep.Type != "" {
+					leaves[resolved] = true
+				}
+			}
+		}
+		g.Modules[name] = module
+	}
+	return g, nil
+}
+
+// paths returns the paths of all modules in sorted order.
+func (g *ModuleGraph) paths() []string {
+	paths := make([]string, 0, len(g.Modules))
+	for name := range g.Modules {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Cycles returns the groups of modules that depend on each other, with the paths of each group and the groups in sorted order. Import calls are not followed as they load modules lazily.
+func (g *ModuleGraph) Cycles() [][]string {
+	// Tarjan's strongly connected components algorithm
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var visit func(string)
+	visit = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		module := g.Modules[name]
+		selfLoop := false
+		for i, dep := range module.Dependencies {
+			next := module.Resolved[i]
+			if dep.Kind == DynamicImport || g.Modules[next] == nil {
+				continue
+			} else if next == name {
+				selfLoop = true
+			} else if _, ok := index[next]; !ok {
+				visit(next)
+				if lowlink[next] < lowlink[name] {
+					lowlink[name] = lowlink[next]
+				}
+			} else if onStack[next] && index[next] < lowlink[name] {
+				lowlink[name] = index[next]
+			}
+		}
+
+		if lowlink[name] == index[name] {
+			i := len(stack) - 1
+			for stack[i] != name {
+				i--
+			}
+			cycle := append([]string{}, stack[i:]...)
+			for _, item := range cycle {
+				onStack[item] = false
+			}
+			stack = stack[:i]
+			if 1 < len(cycle) || selfLoop {
+				sort.Strings(cycle)
+				cycles = append(cycles, cycle)
+			}
+		}
+	}
+	for _, name := range g.paths() {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return cycles
+}
+
+// UnusedExport is an exported name that is not imported by any module in the graph.
+type UnusedExport struct {
+	Path string
+	ExportName
+}
+
+// UnusedExports returns the exported names, including re-exports, that are not imported by any module in the graph, sorted by path and in source order. Names that are re-exported are used when the re-export is used. All names of a module are used by namespace imports, import calls, and require calls. Re-exports of all names by export * are not reported themselves. The exports of the entry points are usually also reported and need to be filtered by the caller.
+func (g *ModuleGraph) UnusedExports() []UnusedExport {
+	used := map[string]map[string]bool{} // module path => used names, where * means all names
+	var use func(string, string)
+	use = func(name, exported string) {
+		module := g.Modules[name]
+		if module == nil || used[name]["*"] || used[name][exported] {
+			return
+		} else if used[name] == nil {
+			used[name] = map[string]bool{}
+		}
+		used[name][exported] = true
+
+		// follow re-exports, where export * does not re-export default and names that are exported locally
+		local := false
+		for _, export := range module.Exports {
+			if export.Exported == exported {
+				local = true
+			}
+		}
+		for i, dep := range module.Dependencies {
+			for _, export := range dep.Exports {
+				if exported == "*" && export.Exported == "*" {
+					use(module.Resolved[i], "*")
+				} else if exported == "*" || export.Exported == exported {
+					use(module.Resolved[i], export.Local)
+				} else if export.Exported == "*" && !local && exported != "default" {
+					use(module.Resolved[i], exported)
+				}
+			}
+		}
+	}
+	for _, name := range g.paths() {
+		module := g.Modules[name]
+		for i, dep := range module.Dependencies {
+			if dep.Kind == DynamicImport || dep.Kind == RequireCall {
+				use(module.Resolved[i], "*")
+			}
+			for _, imported := range dep.Imports {
+				use(module.Resolved[i], imported.Imported)
+			}
+		}
+	}
+
+	unused := []UnusedExport{}
+	for _, name := range g.paths() {
+		module := g.Modules[name]
+		exports := append([]ExportName{}, module.Exports...)
+		for _, dep := range module.Dependencies {
+			exports = append(exports, dep.Exports...)
+		}
+		sort.SliceStable(exports, func(i, j int) bool {
+			return exports[i].Loc.Start < exports[j].Loc.Start
+		})
+		for _, export := range exports {
+			if export.Exported != "*" && !used[name]["*"] && !used[name][export.Exported] {
+				unused = append(unused, UnusedExport{name, export})
+			}
+		}
+	}
+	return unused
+}
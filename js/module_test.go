@@ -0,0 +1,130 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestAnalyzeModule(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"import 'a'", "StaticImport a 0:10"},
+		{"import a, * as b from 'a'", "StaticImport a [default:a *:b] 0:25"},
+		{"import {a, b as c, 'd e' as f,} from \"a\"", "StaticImport a [a:a b:c d e:f] 0:40"},
+		{"export {a, b as c} from 'a'", "ReExport a {a=a c=b} 0:27"},
+		{"export * from 'a'; export * as b from 'b'", "ReExport a {*=*} 0:18 | ReExport b {b=*} 19:41"},
+		{"import('a'); import(`b`, {}); import(c); import('d' + e)", "DynamicImport a 0:11 | DynamicImport b 13:28"},
+		{"const a = require('a'); f(() => require('\\x62\\u{63}\\u0064'))", "RequireCall a 10:22 | RequireCall bcd 32:59"},
+		{"function f(require) { require('a') } require(b)", ""},
+		{"export const {a, b: [c]} = require('a')", "RequireCall a 27:39 | export a=a c=c"},
+		{"const {a, b: c, 'd e': f, ...g} = require('a'); let {[h]: i} = require('b'), {j = 1} = require('c'), {k = require('d')} = l", "RequireCall a [a:a b:c d e:f *:g] 34:46 | RequireCall b 63:75 | RequireCall c [j:j] 87:99 | RequireCall d 106:118"},
+		{"import a from 'a' with { type: 'json' }; export * from 'b' with { 'type': 'css' }; import('c', { with: { type: 'json' } }); import('d', {})", "StaticImport a json [default:a] 0:40 | ReExport b css {*=*} 41:82 | DynamicImport c json 83:122 | DynamicImport d 124:139"},
+		{"export function f() { import('a') } export class A {}", "DynamicImport a 22:33 | export f=f A=A"},
+		{"export default function f() {}; export default class {}; export default a", "export default=f default= default=a"},
+		{"let a; export {a, a as b, a as 'c d'}", "export a=a b=a c d=a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil {
+				test.Error(t, err)
+			}
+			info := AnalyzeModule(ast)
+			items := []string{}
+			for _, dep := range info.Dependencies {
+				s := dep.Kind.String() + " " + dep.Specifier
+				if dep.Type != "" {
+					s += " " + dep.Type
+				}
+				if dep.Imports != nil {
+					names := []string{}
+					for _, name := range dep.Imports {
+						names = append(names, name.Imported+":"+name.Local)
+					}
+					s += " [" + strings.Join(names, " ") + "]"
+				}
+				if dep.Exports != nil {
+					names := []string{}
+					for _, name := range dep.Exports {
+						names = append(names, name.Exported+"="+name.Local)
+					}
+					s += " {" + strings.Join(names, " ") + "}"
+				}
+				items = append(items, fmt.Sprintf("%s %d:%d", s, dep.Loc.Start, dep.Loc.End))
+			}
+			if 0 < len(info.Exports) {
+				names := []string{}
+				for _, name := range info.Exports {
+					names = append(names, name.Exported+"="+name.Local)
+				}
+				items = append(items, "export "+strings.Join(names, " "))
+			}
+			test.String(t, strings.Join(items, " | "), tt.expected)
+		})
+	}
+}
+
+func TestModuleGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.js":       {Data: []byte("import { a, c } from './lib'; import 'fs'; import('./lazy.js')")},
+		"lib/index.js":  {Data: []byte("export * from './a'; export { b as c } from './b.mjs'; export const d = 1")},
+		"lib/a.js":      {Data: []byte("import { d } from './index.js'; export const a = d, e = 2; export default 3")},
+		"lib/b.mjs":     {Data: []byte("export const b = require('./c.js'), f = 4")},
+		"lib/c.js":      {Data: []byte("export const g = 5; import { b } from './b.mjs'")},
+		"lazy.js":       {Data: []byte("import * as main from './main'; export const h = 6")},
+		"self.js":       {Data: []byte("export const i = 7; import { i as j } from './self.js'")},
+		"unresolved.js": {Data: []byte("import './missing'; import '.'; import '..'")},
+		"lib/d.js":      {Data: []byte("import '.'; import '..'; import '../..'")},
+		"index.js":      {Data: []byte("")},
+	}
+	resolve := NewFSResolver(fsys, ".js", ".mjs")
+
+	g, err := BuildModuleGraph(fsys, []string{"main.js", "self.js"}, resolve, Options{})
+	test.Error(t, err)
+	test.T(t, len(g.Modules), 7)
+	test.T(t, g.Modules["main.js"].Resolved, []string{"lib/index.js", "", "lazy.js"})
+	test.T(t, g.Modules["lib/b.mjs"].Resolved, []string{"lib/c.js"})
+
+	cycles := []string{}
+	for _, cycle := range g.Cycles() {
+		cycles = append(cycles, strings.Join(cycle, ","))
+	}
+	test.String(t, strings.Join(cycles, " | "), "lib/a.js,lib/index.js | lib/b.mjs,lib/c.js | self.js")
+
+	unused := []string{}
+	for _, export := range g.UnusedExports() {
+		unused = append(unused, export.Path+":"+export.Exported)
+	}
+	test.String(t, strings.Join(unused, " "), "lib/a.js:e lib/a.js:default lib/b.mjs:f")
+
+	g, err = BuildModuleGraph(fsys, []string{"unresolved.js", "lib/d.js"}, resolve, Options{})
+	test.Error(t, err)
+	test.T(t, g.Modules["unresolved.js"].Resolved, []string{"", "index.js", ""})
+	test.String(t, g.Modules["unresolved.js"].Errors[0].Error(), "cannot resolve ./missing from unresolved.js")
+	test.T(t, g.Modules["unresolved.js"].Errors[1], nil)
+	test.String(t, g.Modules["unresolved.js"].Errors[2].Error(), "cannot resolve .. from unresolved.js")
+	test.T(t, g.Modules["lib/d.js"].Resolved, []string{"lib/index.js", "index.js", ""})
+	test.String(t, g.Modules["lib/d.js"].Errors[2].Error(), "cannot resolve ../.. from lib/d.js")
+	_, err = BuildModuleGraph(fsys, []string{"none.js"}, resolve, Options{})
+	test.That(t, err != nil)
+
+	// modules that are not JavaScript are not parsed
+	fsys = fstest.MapFS{
+		"main.js": {Data: []byte("import a from './a.json' with { type: 'json' }; import b from './b.js' with { type: 'text' }; import './c.css'")},
+		"a.json":  {Data: []byte(`{"a": 1}`)},
+		"b.js":    {Data: []byte("{ invalid")},
+		"c.css":   {Data: []byte("a { color: red }")},
+	}
+	g, err = BuildModuleGraph(fsys, []string{"main.js"}, NewFSResolver(fsys), Options{})
+	test.Error(t, err)
+	test.T(t, len(g.Modules), 4)
+	test.T(t, g.Modules["main.js"].Resolved, []string{"a.json", "b.js", "c.css"})
+	test.T(t, g.Modules["a.json"].Dependencies, []Dependency(nil))
+}
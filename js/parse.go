@@ -2,44 +2,127 @@ package js
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/parse/v2/buffer"
 )
 
+// NestedStmtLimit is the default of Options.NestedStmtLimit.
+//
+// Deprecated: set Options.NestedStmtLimit instead, changing this variable affects all parsers.
 var NestedStmtLimit = 1000
+
+// NestedExprLimit is the default of Options.NestedExprLimit.
+//
+// Deprecated: set Options.NestedExprLimit instead, changing this variable affects all parsers.
 var NestedExprLimit = 1000
 
 type Options struct {
 	WhileToFor bool
 	Inline     bool
+	JSX        bool // parse JSX elements and fragments
+	TypeScript bool // parse TypeScript and strip all type annotations and declarations
+	Tolerant   bool // continue after errors and return a partial AST, see Parse
+	Comments   bool // attach comments to declarations, methods, fields, and properties
+	Lossless   bool // keep the source and trivia to write unchanged nodes as in the source, see PrintLossless, which implies VarLocs
+	VarLocs    bool // record the locations of all declarations and uses of variables in Var.Locs
+
+	// Limits return a LimitError when exceeded, zero means no limit except for the nesting limits which default to 1000.
+	NestedStmtLimit int             // maximum nesting depth of statements
+	NestedExprLimit int             // maximum nesting depth of expressions
+	MaxTokens       int             // maximum number of tokens read, including tokens that are read again when backtracking
+	MaxNodes        int             // maximum number of statements, expressions, and bindings
+	MaxBytes        int             // maximum length of the source
+	Context         context.Context // stop parsing when the context is done
+}
+
+// LimitError is returned by Parse when a limit of Options is exceeded or when Options.Context is done. Parsing stops immediately, also in tolerant mode.
+type LimitError struct {
+	Limit string // name of the exceeded option, such as MaxTokens, or Context when the context is done
+	Err   error  // error of the context when it is done
+	err   *parse.Error
+}
+
+// Error returns the error string, containing the context and line + column number.
+func (e *LimitError) Error() string {
+	return e.err.Error()
+}
+
+// Position returns the line, column, and context at which parsing stopped.
+func (e *LimitError) Position() (int, int, string) {
+	return e.err.Position()
+}
+
+// Unwrap returns the error of the context, so that errors.Is(err, context.Canceled) works.
+func (e *LimitError) Unwrap() error {
+	return e.Err
 }
 
 // Parser is the state for the parser.
 type Parser struct {
-	l   *Lexer
-	o   Options
-	err error
+	l         *Lexer
+	o         Options
+	err       error
+	errOffset int       // offset of the token at which err occurred
+	errTT     TokenType // token type at which err occurred
+	errs      ErrorList // errors that were recovered from in tolerant mode
 
 	data                           []byte
 	tt                             TokenType
+	start, end                     int // offsets of the current token
+	prevStart, prevEnd             int // offsets of the previous token
 	prevLT                         bool
 	in, await, yield, deflt, retrn bool
 	assumeArrowFunc                bool
 	allowDirectivePrologue         bool
 	comments                       []IStmt
-
-	stmtLevel int
-	exprLevel int
+	gap                            []*Comment // comments before the current token when attaching comments
+	gapTrailing                    int        // number of comments in gap on the same line as the previous token
+	orphans                        []*Comment // comments of statements that do not accept comments, which are kept as Comment statements
+	tsTypes                        [][]byte   // names of TypeScript interfaces, type aliases, and type-only imports
+	tsParamProps                   []*Var     // TypeScript parameter properties of the current constructor
+	tsCondTrue                     bool       // at the consequent of a conditional expression, where an arrow function with a return type must be followed by a colon
+	trivia                         []Loc      // whitespace and comments between tokens in lossless mode
+
+	stmtLevel   int
+	exprLevel   int
+	tokens      int         // number of tokens read
+	skipped     int         // number of whitespace and comment tokens skipped
+	nodes       int         // number of statements, expressions, and bindings
+	limit       *LimitError // exceeded limit, after which all tokens are errors
+	limitMsg    string
+	limitOffset int
+
+	argLocs    []Loc                 // locations of the arguments of the parenthesized expressions being parsed, for the locations of arrow function parameters
+	exportLocs map[*ExportStmt][]Loc // locations of the specifiers of export lists in lossless mode
 
 	scope *Scope
 }
 
-// Parse returns a JS AST tree of.
+// Parse returns a JS AST tree of. When Options.Tolerant is set, the parser resynchronizes at the next statement after an error and replaces the erroneous source by a BadStmt. It then returns the partial AST together with an ErrorList of all errors. When a limit of Options is exceeded, it returns a LimitError.
 func Parse(r *parse.Input, o Options) (*AST, error) {
+	if o.NestedStmtLimit == 0 {
+		o.NestedStmtLimit = NestedStmtLimit
+	}
+	if o.NestedExprLimit == 0 {
+		o.NestedExprLimit = NestedExprLimit
+	}
+	if o.Lossless {
+		o.VarLocs = true
+	}
+
+	if 0 < o.MaxBytes && o.MaxBytes < r.Len() {
+		// the input is in memory, so that its length is known before lexing
+		err := &LimitError{Limit: "MaxBytes"}
+		err.err = parse.NewError(buffer.NewReader(r.Bytes()), o.MaxBytes, "source too large")
+		return nil, err
+	}
+
 	ast := &AST{}
 	p := &Parser{
 		l:     NewLexer(r),
@@ -55,10 +138,15 @@ func Parse(r *parse.Input, o Options) (*AST, error) {
 		p.allowDirectivePrologue = true
 		p.enterScope(&ast.BlockStmt.Scope, true)
 		for {
-			if p.tt == ErrorToken {
+			if p.tt == ErrorToken && (!p.o.Tolerant || p.l.Err() == io.EOF) {
+				ast.BlockStmt.List = p.endComments(ast.BlockStmt.List)
 				break
 			}
-			ast.BlockStmt.List = append(ast.BlockStmt.List, p.parseStmt(true))
+			n := len(ast.BlockStmt.List)
+			if stmt := p.parseStmtTolerant(true); stmt != nil {
+				ast.BlockStmt.List = append(ast.BlockStmt.List, stmt)
+			}
+			ast.BlockStmt.List = p.insertComments(ast.BlockStmt.List, n)
 		}
 	} else {
 		// catch shebang in first line
@@ -67,20 +155,35 @@ func Parse(r *parse.Input, o Options) (*AST, error) {
 			r.Move(2)
 			p.l.consumeSingleLineComment() // consume till end-of-line
 			shebang = r.Shift()
+			p.end = len(shebang)
 		}
 
 		// parse JS module
 		p.next()
-		ast.BlockStmt = p.parseModule()
+		p.parseModule(&ast.BlockStmt)
 
 		if 0 < len(shebang) {
-			ast.BlockStmt.List = append([]IStmt{&Comment{shebang}}, ast.BlockStmt.List...)
+			ast.BlockStmt.List = append([]IStmt{&Comment{shebang, Loc{0, len(shebang)}}}, ast.BlockStmt.List...)
 		}
 	}
+	ast.BlockStmt.Loc = Loc{0, p.end}
+	if p.o.Lossless {
+		ast.lossless = newLossless(ast, r.Bytes(), p.trivia, p.exportLocs)
+	}
 
-	if p.err != nil {
-		offset := p.l.r.Offset() - len(p.data)
-		return nil, parse.NewError(buffer.NewReader(p.l.r.Bytes()), offset, p.err.Error())
+	if p.limit != nil {
+		p.limit.err = parse.NewError(buffer.NewReader(r.Bytes()), p.limitOffset, p.limitMsg)
+		return nil, p.limit
+	} else if p.o.Tolerant {
+		if p.err != nil {
+			p.addError()
+		}
+		if 0 < len(p.errs) {
+			return ast, p.errs
+		}
+		return ast, nil
+	} else if p.err != nil {
+		return nil, p.error()
 	} else if p.l.Err() != nil && p.l.Err() != io.EOF {
 		return nil, p.l.Err()
 	}
@@ -91,6 +194,10 @@ func Parse(r *parse.Input, o Options) (*AST, error) {
 
 func (p *Parser) next() {
 	p.prevLT = false
+	p.prevStart, p.prevEnd = p.start, p.end
+	if p.o.Comments {
+		p.gap, p.gapTrailing = nil, 0
+	}
 	p.tt, p.data = p.l.Next()
 Loop:
 	for {
@@ -101,7 +208,14 @@ Loop:
 			p.prevLT = true
 		case CommentToken, CommentLineTerminatorToken:
 			if 2 < len(p.data) && p.data[2] == '!' {
-				p.comments = append(p.comments, &Comment{p.data})
+				end := p.l.r.Offset()
+				p.comments = append(p.comments, &Comment{p.data, Loc{end - len(p.data), end}})
+			} else if p.o.Comments {
+				end := p.l.r.Offset()
+				p.gap = append(p.gap, &Comment{p.data, Loc{end - len(p.data), end}})
+				if !p.prevLT {
+					p.gapTrailing++
+				}
 			}
 			if p.tt == CommentLineTerminatorToken {
 				p.prevLT = true
@@ -109,13 +223,185 @@ Loop:
 		default:
 			break Loop
 		}
+		p.skipped++
+		if !p.checkContext(p.skipped) {
+			break
+		}
 		p.tt, p.data = p.l.Next()
 	}
+	p.end = p.l.r.Offset()
+	p.start = p.end - len(p.data)
+	if p.o.Lossless && p.prevEnd < p.start {
+		p.trivia = append(p.trivia, Loc{p.prevEnd, p.start})
+	}
+	if !p.prevLT && p.tt != ErrorToken || p.prevEnd == 0 {
+		p.gapTrailing = 0 // comments on the same line as the next token or at the start of the file are leading
+	}
+	p.countToken()
+}
+
+// leadingComments returns the comments before the current token that are not trailing the previous token.
+func (p *Parser) leadingComments() []*Comment {
+	if len(p.gap) <= p.gapTrailing {
+		return nil
+	}
+	return p.gap[p.gapTrailing:]
+}
+
+// trailingComments returns the comments after the previous token on the same line.
+func (p *Parser) trailingComments() []*Comment {
+	return p.gap[:p.gapTrailing]
+}
+
+// attachComments attaches leading comments and the trailing comments of the previous token to a node, if it accepts comments. Otherwise, the comments are kept as Comment statements in the enclosing statement list.
+func (p *Parser) attachComments(n INode, leading []*Comment) {
+	if !p.o.Comments {
+		return
+	}
+	trailing := p.trailingComments()
+	p.gap, p.gapTrailing = p.gap[p.gapTrailing:], 0 // attach trailing comments only once
+	if comments := nodeComments(n); comments != nil {
+		comments.Leading = leading
+		comments.Trailing = trailing
+	} else {
+		p.orphans = append(append(p.orphans, leading...), trailing...)
+	}
+}
+
+// insertComments inserts the comments that were not attached as Comment statements into the statements list[n:], by their position.
+func (p *Parser) insertComments(list []IStmt, n int) []IStmt {
+	if len(p.orphans) == 0 {
+		return list
+	}
+	sort.SliceStable(p.orphans, func(i, j int) bool { return p.orphans[i].Start < p.orphans[j].Start })
+	stmts := append([]IStmt{}, list[n:]...)
+	list = list[:n]
+	for _, stmt := range stmts {
+		start := NodeLoc(stmt).Start
+		for 0 < len(p.orphans) && p.orphans[0].Start < start {
+			list = append(list, p.orphans[0])
+			p.orphans = p.orphans[1:]
+		}
+		list = append(list, stmt)
+	}
+	for _, comment := range p.orphans {
+		list = append(list, comment)
+	}
+	p.orphans = p.orphans[:0]
+	return list
+}
+
+// endComments returns the comments before the end of a statement list as Comment statements.
+func (p *Parser) endComments(list []IStmt) []IStmt {
+	if p.o.Comments {
+		p.orphans = append(p.orphans, p.leadingComments()...)
+		list = p.insertComments(list, len(list))
+	}
+	return list
+}
+
+// nextJSXTag moves to the next token inside a JSX tag, skipping whitespace and comments.
+func (p *Parser) nextJSXTag() {
+	p.prevLT = false
+	p.prevStart, p.prevEnd = p.start, p.end
+	p.tt, p.data = p.l.NextJSXTag()
+	for p.tt == WhitespaceToken || p.tt == LineTerminatorToken || p.tt == CommentToken || p.tt == CommentLineTerminatorToken {
+		p.skipped++
+		if !p.checkContext(p.skipped) {
+			break
+		}
+		p.tt, p.data = p.l.NextJSXTag()
+	}
+	p.end = p.l.r.Offset()
+	p.start = p.end - len(p.data)
+	if p.o.Lossless && p.prevEnd < p.start {
+		p.trivia = append(p.trivia, Loc{p.prevEnd, p.start})
+	}
+	p.countToken()
+}
+
+// nextJSXChild moves to the next token inside the children of a JSX element.
+func (p *Parser) nextJSXChild() {
+	p.prevLT = false
+	p.prevStart, p.prevEnd = p.start, p.end
+	p.tt, p.data = p.l.NextJSXChild()
+	p.end = p.l.r.Offset()
+	p.start = p.end - len(p.data)
+	p.countToken()
+}
+
+// countToken counts the current token and checks the limits of the token count and the context. The source length is checked by Parse before parsing.
+func (p *Parser) countToken() {
+	p.tokens++
+	if p.limit != nil {
+		p.failLimit(p.limit.Limit, p.limitMsg, p.limit.Err)
+	} else if 0 < p.o.MaxTokens && p.o.MaxTokens < p.tokens {
+		p.failLimit("MaxTokens", "too many tokens", nil)
+	} else {
+		p.checkContext(p.tokens)
+	}
+}
+
+// checkContext checks the context once every 1024 tokens, where n counts either the tokens or the skipped whitespace and comments. It returns false when the context is done.
+func (p *Parser) checkContext(n int) bool {
+	if p.o.Context != nil && n%1024 == 1 {
+		if err := p.o.Context.Err(); err != nil {
+			p.failLimit("Context", "parsing stopped: "+err.Error(), err)
+			return false
+		}
+	}
+	return true
+}
+
+// countNode counts a statement, expression, or binding and returns false if there are too many.
+func (p *Parser) countNode() bool {
+	p.nodes++
+	if 0 < p.o.MaxNodes && p.o.MaxNodes < p.nodes {
+		p.failLimit("MaxNodes", "too many nodes", nil)
+		return false
+	}
+	return true
+}
+
+// failLimit stops parsing when a limit is exceeded. All further tokens are errors, also after backtracking, and tolerant mode is turned off so that the parser doesn't recover.
+func (p *Parser) failLimit(limit, msg string, err error) {
+	if p.limit == nil {
+		p.limit = &LimitError{Limit: limit, Err: err}
+		p.limitMsg, p.limitOffset = msg, p.start
+		p.o.Tolerant = false
+	}
+	p.err = errors.New(p.limitMsg)
+	p.errOffset, p.errTT = p.limitOffset, p.tt
+	p.tt = ErrorToken
+}
+
+// loc returns the location from start till the end of the previous token.
+func (p *Parser) loc(start int) Loc {
+	return Loc{start, p.prevEnd}
+}
+
+// use uses the variable with the given name and records the location of the identifier when Options.VarLocs is set.
+func (p *Parser) use(name []byte, loc Loc) *Var {
+	v := p.scope.Use(name)
+	if p.o.VarLocs {
+		v.Locs = append(v.Locs, loc)
+	}
+	return v
+}
+
+// declare declares the variable with the given name and records the location of the identifier when Options.VarLocs is set.
+func (p *Parser) declare(decl DeclType, name []byte, loc Loc) (*Var, bool) {
+	v, ok := p.scope.Declare(decl, name)
+	if ok && p.o.VarLocs {
+		v.Locs = append(v.Locs, loc)
+	}
+	return v, ok
 }
 
 func (p *Parser) failMessage(msg string, args ...interface{}) {
 	if p.err == nil {
 		p.err = fmt.Errorf(msg, args...)
+		p.errOffset, p.errTT = p.l.r.Offset()-len(p.data), p.tt
 		p.tt = ErrorToken
 	}
 }
@@ -155,6 +441,7 @@ func (p *Parser) fail(in string, expected ...TokenType) {
 		}
 
 		p.err = errors.New(msg)
+		p.errOffset, p.errTT = p.l.r.Offset()-len(p.data), p.tt
 		p.tt = ErrorToken
 	}
 }
@@ -188,65 +475,101 @@ func (p *Parser) exitScope(parent *Scope) {
 	p.scope = parent
 }
 
-func (p *Parser) parseModule() (module BlockStmt) {
+func (p *Parser) parseModule(module *BlockStmt) {
 	p.enterScope(&module.Scope, true)
 	p.allowDirectivePrologue = true
 	for {
-		switch p.tt {
-		case ErrorToken:
+		if p.tt == ErrorToken && (!p.o.Tolerant || p.l.Err() == io.EOF) {
+			module.List = p.endComments(module.List)
+			if p.o.TypeScript {
+				p.elideTSImports(module)
+			}
 			if 0 < len(p.comments) {
 				module.List = append(p.comments, module.List...)
 				p.comments = p.comments[:0]
 			}
 			return
+		}
+
+		n, c := len(module.List), p.checkpoint()
+		switch p.tt {
 		case ImportToken:
+			start := p.start
 			p.next()
 			if p.tt == OpenParenToken {
 				// could be an import call expression
-				left := &LiteralExpr{ImportToken, []byte("import")}
+				left := &LiteralExpr{ImportToken, []byte("import"), p.loc(start)}
 				p.exprLevel++
-				expr := p.parseExpressionSuffix(left, OpExpr, OpCall)
+				expr := p.parseExpressionSuffix(left, start, OpExpr, OpCall)
 				p.exprLevel--
-				module.List = append(module.List, &ExprStmt{expr})
 				if !p.prevLT && p.tt == SemicolonToken {
 					p.next()
 				}
+				module.List = append(module.List, &ExprStmt{expr, p.loc(start)})
 			} else if p.tt == DotToken {
 				p.next()
 				if !p.consume("import.meta expression", MetaToken) {
-					return module
+					break
 				}
-				left := &ImportMetaExpr{}
+				left := &ImportMetaExpr{p.loc(start)}
 				p.exprLevel++
-				expr := p.parseExpressionSuffix(left, OpExpr, OpMember)
+				expr := p.parseExpressionSuffix(left, start, OpExpr, OpMember)
 				p.exprLevel--
-				module.List = append(module.List, &ExprStmt{expr})
 				if !p.prevLT && p.tt == SemicolonToken {
 					p.next()
 				}
-			} else {
-				importStmt := p.parseImportStmt()
-				module.List = append(module.List, &importStmt)
+				module.List = append(module.List, &ExprStmt{expr, p.loc(start)})
+			} else if importStmt := p.parseImportStmt(); importStmt != nil {
+				module.List = append(module.List, importStmt)
 			}
 		case ExportToken:
-			exportStmt := p.parseExportStmt()
-			module.List = append(module.List, &exportStmt)
+			if exportStmt := p.parseExportStmt(nil); exportStmt != nil {
+				module.List = append(module.List, exportStmt)
+			}
+		case AtToken:
+			// decorators of an exported class or of a class declaration
+			start := p.start
+			leading := p.leadingComments()
+			decorators := p.parseDecorators()
+			if p.tt == ExportToken {
+				if exportStmt := p.parseExportStmt(decorators); exportStmt != nil {
+					exportStmt.Loc.Start = start
+					module.List = append(module.List, exportStmt)
+				}
+			} else if p.tt == ClassToken {
+				classDecl := p.parseAnyClass(false, decorators)
+				classDecl.Loc.Start = start
+				p.attachComments(classDecl, leading)
+				module.List = append(module.List, classDecl)
+			} else {
+				p.fail("decorator", ClassToken, ExportToken)
+			}
 		default:
-			module.List = append(module.List, p.parseStmt(true))
+			if stmt := p.parseStmt(true); stmt != nil {
+				module.List = append(module.List, stmt)
+			}
+		}
+		if p.err != nil && p.o.Tolerant {
+			module.List = append(module.List[:n], p.synchronize(c))
 		}
+		module.List = p.insertComments(module.List, n)
 	}
 }
 
 func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 	p.stmtLevel++
-	if NestedStmtLimit < p.stmtLevel {
-		p.failMessage("too many nested statements")
+	if p.o.NestedStmtLimit < p.stmtLevel {
+		p.failLimit("NestedStmtLimit", "too many nested statements", nil)
+		return nil
+	} else if !p.countNode() {
 		return nil
 	}
 
 	allowDirectivePrologue := p.allowDirectivePrologue
 	p.allowDirectivePrologue = false
 
+	start := p.start
+	leading := p.leadingComments()
 	switch tt := p.tt; tt {
 	case OpenBraceToken:
 		stmt = p.parseBlockStmt("block statement")
@@ -256,6 +579,12 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			return
 		}
 		p.next()
+		if tt == ConstToken && p.o.TypeScript && p.tt == EnumToken {
+			if varDecl := p.parseTSEnum(start); varDecl != nil {
+				stmt = varDecl
+			}
+			break
+		}
 		varDecl := p.parseVarDecl(tt, true)
 		stmt = varDecl
 		if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
@@ -280,7 +609,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			return
 		} else {
 			// expression
-			stmt = &ExprStmt{p.parseIdentifierExpression(OpExpr, let)}
+			stmt = &ExprStmt{Value: p.parseIdentifierExpression(OpExpr, let)}
 			if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
 				p.fail("expression")
 				return
@@ -302,7 +631,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			p.next()
 			elseBody = p.parseStmt(false)
 		}
-		stmt = &IfStmt{cond, body, elseBody}
+		stmt = &IfStmt{Cond: cond, Body: body, Else: elseBody}
 	case ContinueToken, BreakToken:
 		tt := p.tt
 		p.next()
@@ -311,7 +640,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			label = p.data
 			p.next()
 		}
-		stmt = &BranchStmt{tt, label}
+		stmt = &BranchStmt{Type: tt, Label: label}
 	case WithToken:
 		p.next()
 		if !p.consume("with statement", OpenParenToken) {
@@ -323,7 +652,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 		}
 
 		p.scope.Func.HasWith = true
-		stmt = &WithStmt{cond, p.parseStmt(false)}
+		stmt = &WithStmt{Cond: cond, Body: p.parseStmt(false)}
 	case DoToken:
 		stmt = &DoWhileStmt{}
 		p.next()
@@ -334,7 +663,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 		if !p.consume("do-while statement", OpenParenToken) {
 			return
 		}
-		stmt = &DoWhileStmt{p.parseExpression(OpExpr), body}
+		stmt = &DoWhileStmt{Cond: p.parseExpression(OpExpr), Body: body}
 		if !p.consume("do-while statement", CloseParenToken) {
 			return
 		}
@@ -354,11 +683,11 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 
 			block, ok := body.(*BlockStmt)
 			if !ok {
-				block = &BlockStmt{List: []IStmt{body}}
+				block = &BlockStmt{List: []IStmt{body}, Loc: NodeLoc(body)}
 			}
-			stmt = &ForStmt{varDecl, cond, nil, block}
+			stmt = &ForStmt{Init: varDecl, Cond: cond, Body: block}
 		} else {
-			stmt = &WhileStmt{cond, body}
+			stmt = &WhileStmt{Cond: cond, Body: body}
 		}
 	case ForToken:
 		p.next()
@@ -375,10 +704,15 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 
 		var init IExpr
 		p.in = false
-		if p.tt == VarToken || p.tt == LetToken || p.tt == ConstToken {
-			tt := p.tt
+		if p.tt == VarToken || p.tt == LetToken || p.tt == ConstToken || p.isUsingDecl(true) {
+			tt, awaitUsing := p.tt, p.tt == AwaitToken
+			if awaitUsing {
+				tt = UsingToken
+				p.next()
+			}
 			p.next()
 			varDecl := p.parseVarDecl(tt, true)
+			varDecl.Await = awaitUsing
 			if p.err != nil {
 				return
 			} else if p.tt != SemicolonToken && (1 < len(varDecl.List) || varDecl.List[0].Default != nil) {
@@ -411,6 +745,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 				return
 			}
 			p.scope.MarkForStmt()
+			bodyStart := p.start
 			if p.tt == OpenBraceToken {
 				body.List = p.parseStmtList("")
 			} else if p.tt != SemicolonToken {
@@ -418,10 +753,11 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			} else {
 				p.next()
 			}
+			body.Loc = p.loc(bodyStart)
 			if varDecl, ok := init.(*VarDecl); ok {
 				varDecl.InForInOf = true
 			}
-			stmt = &ForInStmt{init, value, body}
+			stmt = &ForInStmt{Init: init, Value: value, Body: body}
 		} else if isLHSExpr && p.tt == OfToken {
 			p.next()
 			value := p.parseExpression(OpAssign)
@@ -429,6 +765,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 				return
 			}
 			p.scope.MarkForStmt()
+			bodyStart := p.start
 			if p.tt == OpenBraceToken {
 				body.List = p.parseStmtList("")
 			} else if p.tt != SemicolonToken {
@@ -436,10 +773,11 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			} else {
 				p.next()
 			}
+			body.Loc = p.loc(bodyStart)
 			if varDecl, ok := init.(*VarDecl); ok {
 				varDecl.InForInOf = true
 			}
-			stmt = &ForOfStmt{await, init, value, body}
+			stmt = &ForOfStmt{Await: await, Init: init, Value: value, Body: body}
 		} else if p.tt == SemicolonToken {
 			var cond, post IExpr
 			if await {
@@ -460,6 +798,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 				return
 			}
 			p.scope.MarkForStmt()
+			bodyStart := p.start
 			if p.tt == OpenBraceToken {
 				body.List = p.parseStmtList("")
 			} else if p.tt != SemicolonToken {
@@ -467,6 +806,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			} else {
 				p.next()
 			}
+			body.Loc = p.loc(bodyStart)
 			if init == nil {
 				varDecl := &VarDecl{TokenType: VarToken, Scope: p.scope, InFor: true}
 				p.scope.Func.VarDecls = append(p.scope.Func.VarDecls, varDecl)
@@ -474,7 +814,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			} else if varDecl, ok := init.(*VarDecl); ok {
 				varDecl.InFor = true
 			}
-			stmt = &ForStmt{init, cond, post, body}
+			stmt = &ForStmt{Init: init, Cond: cond, Post: post, Body: body}
 		} else if isLHSExpr {
 			p.fail("for statement", InToken, OfToken, SemicolonToken)
 			return
@@ -509,6 +849,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 				break
 			}
 
+			clauseStart := p.start
 			clause := p.tt
 			var list IExpr
 			if p.tt == CaseToken {
@@ -526,9 +867,13 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 
 			var stmts []IStmt
 			for p.tt != CaseToken && p.tt != DefaultToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
-				stmts = append(stmts, p.parseStmt(true))
+				n := len(stmts)
+				if stmt := p.parseStmt(true); stmt != nil {
+					stmts = append(stmts, stmt)
+				}
+				stmts = p.insertComments(stmts, n)
 			}
-			switchStmt.List = append(switchStmt.List, CaseClause{clause, list, stmts})
+			switchStmt.List = append(switchStmt.List, CaseClause{clause, list, stmts, p.loc(clauseStart)})
 		}
 		p.exitScope(parent)
 		stmt = switchStmt
@@ -549,13 +894,13 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			stmt = p.parseAsyncFuncDecl()
 		} else {
 			// expression
-			stmt = &ExprStmt{p.parseAsyncExpression(OpExpr, async)}
+			stmt = &ExprStmt{Value: p.parseAsyncExpression(OpExpr, async)}
 			if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
 				p.fail("expression")
 				return
 			}
 		}
-	case ClassToken:
+	case ClassToken, AtToken:
 		if !allowDeclaration {
 			p.fail("statement")
 			return
@@ -567,7 +912,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			p.failMessage("unexpected newline in throw statement")
 			return
 		}
-		stmt = &ThrowStmt{p.parseExpression(OpExpr)}
+		stmt = &ThrowStmt{Value: p.parseExpression(OpExpr)}
 	case TryToken:
 		p.next()
 		body := p.parseBlockStmt("try statement")
@@ -580,11 +925,16 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			if p.tt == OpenParenToken {
 				p.next()
 				binding = p.parseBinding(CatchDecl) // local to block scope of catch
+				if p.o.TypeScript {
+					p.parseTSTypeAnnotation()
+				}
 				if !p.consume("try-catch statement", CloseParenToken) {
 					return
 				}
 			}
+			catchStart := p.start
 			catch.List = p.parseStmtList("try-catch statement")
+			catch.Loc = p.loc(catchStart)
 			p.exitScope(parent)
 		} else if p.tt != FinallyToken {
 			p.fail("try statement", CatchToken, FinallyToken)
@@ -594,7 +944,7 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			p.next()
 			finally = p.parseBlockStmt("try-finally statement")
 		}
-		stmt = &TryStmt{body, binding, catch, finally}
+		stmt = &TryStmt{Body: body, Binding: binding, Catch: catch, Finally: finally}
 	case DebuggerToken:
 		stmt = &DebuggerStmt{}
 		p.next()
@@ -602,16 +952,35 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 		stmt = &EmptyStmt{}
 		p.next()
 	case ErrorToken:
+		if p.o.Tolerant && p.l.Err() != io.EOF {
+			p.fail("statement") // lexer error
+			return
+		}
 		stmt = &EmptyStmt{}
 		return
 	default:
-		if p.retrn && p.tt == ReturnToken {
+		if p.o.TypeScript && allowDeclaration && p.isTSDeclaration() {
+			stmt = p.parseTSDeclaration()
+		} else if allowDeclaration && p.isUsingDecl(false) {
+			awaitUsing := p.tt == AwaitToken
+			if awaitUsing {
+				p.next()
+			}
+			p.next()
+			varDecl := p.parseVarDecl(UsingToken, false)
+			varDecl.Await = awaitUsing
+			stmt = varDecl
+			if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
+				p.fail("using declaration")
+				return
+			}
+		} else if p.retrn && p.tt == ReturnToken {
 			p.next()
 			var value IExpr
 			if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
 				value = p.parseExpression(OpExpr)
 			}
-			stmt = &ReturnStmt{value}
+			stmt = &ReturnStmt{Value: value}
 		} else if p.isIdentifierReference(p.tt) {
 			// LabelledStatement, Expression
 			label := p.data
@@ -622,11 +991,15 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 				if p.tt == FunctionToken {
 					p.deflt = false
 				}
-				stmt = &LabelledStmt{label, p.parseStmt(true)} // allows illegal async function, generator function, let, const, or class declarations
+				value := p.parseStmt(true) // allows illegal async function, generator function, let, const, or class declarations
+				if value == nil {
+					value = &EmptyStmt{} // TypeScript declaration
+				}
+				stmt = &LabelledStmt{Label: label, Value: value}
 				p.deflt = prevDeflt
 			} else {
 				// expression
-				stmt = &ExprStmt{p.parseIdentifierExpression(OpExpr, label)}
+				stmt = &ExprStmt{Value: p.parseIdentifierExpression(OpExpr, label)}
 				if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
 					p.fail("expression")
 					return
@@ -634,12 +1007,12 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 			}
 		} else {
 			// expression
-			stmt = &ExprStmt{p.parseExpression(OpExpr)}
+			stmt = &ExprStmt{Value: p.parseExpression(OpExpr)}
 			if !p.prevLT && p.tt != SemicolonToken && p.tt != CloseBraceToken && p.tt != ErrorToken {
 				p.fail("expression")
 				return
 			} else if lit, ok := stmt.(*ExprStmt).Value.(*LiteralExpr); ok && allowDirectivePrologue && lit.TokenType == StringToken && len(lit.Data) == 12 && bytes.Equal(lit.Data[1:11], []byte("use strict")) {
-				stmt = &DirectivePrologueStmt{lit.Data}
+				stmt = &DirectivePrologueStmt{Value: lit.Data}
 				p.allowDirectivePrologue = true
 			}
 		}
@@ -647,6 +1020,16 @@ func (p *Parser) parseStmt(allowDeclaration bool) (stmt IStmt) {
 	if !p.prevLT && p.tt == SemicolonToken {
 		p.next()
 	}
+	if funcDecl, ok := stmt.(*FuncDecl); ok && funcDecl == nil {
+		stmt = nil // TypeScript overload signature
+	}
+	switch stmt.(type) {
+	case nil, *BlockStmt, *FuncDecl, *ClassDecl:
+		// location is already set and excludes the optional semicolon
+	default:
+		stmt.(interface{ setLoc(Loc) }).setLoc(p.loc(start))
+	}
+	p.attachComments(stmt, leading)
 	p.stmtLevel--
 	return
 }
@@ -657,14 +1040,22 @@ func (p *Parser) parseStmtList(in string) (list []IStmt) {
 		return
 	}
 	for {
-		if p.tt == ErrorToken {
+		if p.tt == ErrorToken && (!p.o.Tolerant || p.l.Err() == io.EOF) {
 			p.fail("")
+			if p.o.Tolerant {
+				p.addError() // keep the unterminated statement list
+			}
 			return
 		} else if p.tt == CloseBraceToken {
+			list = p.endComments(list)
 			p.next()
 			break
 		}
-		list = append(list, p.parseStmt(true))
+		n := len(list)
+		if stmt := p.parseStmtTolerant(true); stmt != nil {
+			list = append(list, stmt)
+		}
+		list = p.insertComments(list, n)
 	}
 	if comments < len(p.comments) {
 		list2 := make([]IStmt, 0, len(p.comments)-comments+len(list))
@@ -678,14 +1069,26 @@ func (p *Parser) parseStmtList(in string) (list []IStmt) {
 
 func (p *Parser) parseBlockStmt(in string) (blockStmt *BlockStmt) {
 	blockStmt = &BlockStmt{}
+	start := p.start
 	parent := p.enterScope(&blockStmt.Scope, false)
 	blockStmt.List = p.parseStmtList(in)
 	p.exitScope(parent)
+	blockStmt.Loc = p.loc(start)
 	return
 }
 
-func (p *Parser) parseImportStmt() (importStmt ImportStmt) {
+func (p *Parser) parseImportStmt() (importStmt *ImportStmt) {
 	// assume we're passed import
+	start := p.prevStart
+	importStmt = &ImportStmt{}
+	typeOnly := false
+	if p.o.TypeScript && p.isTSIdentifier("type") && p.peekTS(func() bool {
+		return p.tt == OpenBraceToken || p.tt == MulToken || IsIdentifier(p.tt) && p.tt != FromToken
+	}) {
+		// type-only import
+		typeOnly = true
+		p.next()
+	}
 	if p.tt == StringToken {
 		importStmt.Module = p.data
 		p.next()
@@ -710,11 +1113,21 @@ func (p *Parser) parseImportStmt() (importStmt ImportStmt) {
 				return
 			}
 			importStmt.List = []Alias{{star, p.data}}
+			if typeOnly {
+				p.tsTypes = append(p.tsTypes, p.data)
+			}
 			p.next()
 		} else if expectClause && p.tt == OpenBraceToken {
 			p.next()
 			importStmt.List = []Alias{}
+			types := 0
 			for IsIdentifierName(p.tt) || p.tt == StringToken {
+				isType := typeOnly
+				if p.o.TypeScript && p.isTSIdentifier("type") && p.peekTS(func() bool { return p.tt != CommaToken && p.tt != CloseBraceToken && p.tt != AsToken }) {
+					// type-only import specifier
+					isType = true
+					p.next()
+				}
 				tt := p.tt
 				var name, binding []byte = nil, p.data
 				p.next()
@@ -731,7 +1144,12 @@ func (p *Parser) parseImportStmt() (importStmt ImportStmt) {
 					p.fail("import statement", IdentifierToken, StringToken)
 					return
 				}
-				importStmt.List = append(importStmt.List, Alias{name, binding})
+				if isType {
+					p.tsTypes = append(p.tsTypes, binding)
+					types++
+				} else {
+					importStmt.List = append(importStmt.List, Alias{name, binding})
+				}
 				if p.tt == CommaToken {
 					p.next()
 					if p.tt == CloseBraceToken {
@@ -743,6 +1161,14 @@ func (p *Parser) parseImportStmt() (importStmt ImportStmt) {
 			if !p.consume("import statement", CloseBraceToken) {
 				return
 			}
+			if 0 < types && (len(importStmt.List) == 0 || len(importStmt.List) == 1 && importStmt.List[0].Binding == nil) {
+				// all specifiers are types
+				if importStmt.Default == nil {
+					typeOnly = true
+				} else {
+					importStmt.List = nil
+				}
+			}
 		} else if expectClause && importStmt.Default != nil {
 			p.fail("import statement", MulToken, OpenBraceToken)
 			return
@@ -761,17 +1187,84 @@ func (p *Parser) parseImportStmt() (importStmt ImportStmt) {
 		importStmt.Module = p.data
 		p.next()
 	}
+	if p.tt == WithToken {
+		importStmt.Attributes = p.parseImportAttributes("import statement")
+	}
 	if p.tt == SemicolonToken {
 		p.next()
 	}
+	importStmt.Loc = p.loc(start)
+	if typeOnly {
+		if importStmt.Default != nil {
+			p.tsTypes = append(p.tsTypes, importStmt.Default)
+		}
+		return nil
+	}
 	return
 }
 
-func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
+// parseImportAttributes parses the with clause of import/export statements.
+func (p *Parser) parseImportAttributes(in string) (attributes []ImportAttribute) {
+	// assume we're at with
+	p.next()
+	if !p.consume(in, OpenBraceToken) {
+		return nil
+	}
+	attributes = []ImportAttribute{}
+	for p.tt != CloseBraceToken {
+		if !IsIdentifierName(p.tt) && p.tt != StringToken {
+			p.fail(in, IdentifierToken, StringToken)
+			return nil
+		}
+		key := p.data
+		p.next()
+		if !p.consume(in, ColonToken) {
+			return nil
+		} else if p.tt != StringToken {
+			p.fail(in, StringToken)
+			return nil
+		}
+		attributes = append(attributes, ImportAttribute{key, p.data})
+		p.next()
+		if p.tt == CommaToken {
+			p.next()
+		} else if p.tt != CloseBraceToken {
+			p.fail(in, CommaToken, CloseBraceToken)
+			return nil
+		}
+	}
+	p.next()
+	return
+}
+
+// exportLoc records the location of the next specifier of an export list, which is unset for the empty specifier after a trailing comma.
+func (p *Parser) exportLoc(exportStmt *ExportStmt, loc Loc) {
+	if p.exportLocs == nil {
+		p.exportLocs = map[*ExportStmt][]Loc{}
+	}
+	p.exportLocs[exportStmt] = append(p.exportLocs[exportStmt], loc)
+}
+
+// parseExportStmt parses an export statement, decorators are passed when they precede the export keyword and must be followed by a class declaration.
+func (p *Parser) parseExportStmt(decorators []IExpr) (exportStmt *ExportStmt) {
 	// assume we're at export
+	start := p.start
+	leading := p.leadingComments()
+	exportStmt = &ExportStmt{}
 	p.next()
+	leading = append(leading[:len(leading):len(leading)], p.leadingComments()...) // comments after export belong to the declaration
+	if decorators != nil && p.tt != ClassToken && p.tt != DefaultToken {
+		p.fail("export statement", ClassToken)
+		return
+	}
 	prevYield, prevAwait, prevDeflt := p.yield, p.await, p.deflt
 	p.yield, p.await, p.deflt = false, true, true
+	typeOnly := false
+	if p.o.TypeScript && p.isTSIdentifier("type") && p.peekTS(func() bool { return p.tt == OpenBraceToken || p.tt == MulToken }) {
+		// type-only export
+		typeOnly = true
+		p.next()
+	}
 	if p.tt == MulToken || p.tt == OpenBraceToken {
 		if p.tt == MulToken {
 			star := p.data
@@ -794,7 +1287,14 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 		} else {
 			p.next()
 			for IsIdentifierName(p.tt) || p.tt == StringToken {
+				isType := false
+				if p.o.TypeScript && p.isTSIdentifier("type") && p.peekTS(func() bool { return p.tt != CommaToken && p.tt != CloseBraceToken && p.tt != AsToken }) {
+					// type-only export specifier
+					isType = true
+					p.next()
+				}
 				var name, binding []byte = nil, p.data
+				aliasStart := p.start
 				p.next()
 				if p.tt == AsToken {
 					p.next()
@@ -806,11 +1306,19 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 					binding = p.data
 					p.next()
 				}
-				exportStmt.List = append(exportStmt.List, Alias{name, binding})
+				if !isType {
+					exportStmt.List = append(exportStmt.List, Alias{name, binding})
+					if p.o.Lossless {
+						p.exportLoc(exportStmt, Loc{aliasStart, p.prevEnd})
+					}
+				}
 				if p.tt == CommaToken {
 					p.next()
 					if p.tt == CloseBraceToken {
 						exportStmt.List = append(exportStmt.List, Alias{})
+						if p.o.Lossless {
+							p.exportLoc(exportStmt, Loc{})
+						}
 						break
 					}
 				}
@@ -827,11 +1335,25 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 			}
 			exportStmt.Module = p.data
 			p.next()
+			if p.tt == WithToken {
+				exportStmt.Attributes = p.parseImportAttributes("export statement")
+			}
 		}
 	} else if p.tt == VarToken || p.tt == ConstToken || p.tt == LetToken {
 		tt := p.tt
 		p.next()
-		exportStmt.Decl = p.parseVarDecl(tt, false)
+		if tt == ConstToken && p.o.TypeScript && p.tt == EnumToken {
+			exportStmt.Decl = p.parseTSEnum(p.prevStart)
+		} else {
+			exportStmt.Decl = p.parseVarDecl(tt, false)
+		}
+	} else if p.o.TypeScript && p.isTSDeclaration() {
+		stmt := p.parseTSDeclaration()
+		if stmt == nil {
+			p.yield, p.await, p.deflt = prevYield, prevAwait, prevDeflt
+			return nil
+		}
+		exportStmt.Decl = stmt.(IExpr)
 	} else if p.tt == FunctionToken {
 		exportStmt.Decl = p.parseFuncDecl()
 	} else if p.tt == AsyncToken { // async function
@@ -841,12 +1363,16 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 			return
 		}
 		exportStmt.Decl = p.parseAsyncFuncDecl()
-	} else if p.tt == ClassToken {
-		exportStmt.Decl = p.parseClassDecl()
+	} else if p.tt == ClassToken || p.tt == AtToken {
+		exportStmt.Decl = p.parseAnyClass(false, decorators)
 	} else if p.tt == DefaultToken {
 		exportStmt.Default = true
 		p.next()
-		if p.tt == FunctionToken {
+		leading = append(leading[:len(leading):len(leading)], p.leadingComments()...)
+		if decorators != nil && p.tt != ClassToken {
+			p.fail("export statement", ClassToken)
+			return
+		} else if p.tt == FunctionToken {
 			// hoistable declaration
 			exportStmt.Decl = p.parseFuncDecl()
 		} else if p.tt == AsyncToken { // async function or async arrow function
@@ -859,8 +1385,15 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 				// expression
 				exportStmt.Decl = p.parseAsyncExpression(OpAssign, async)
 			}
-		} else if p.tt == ClassToken {
-			exportStmt.Decl = p.parseClassDecl()
+		} else if p.tt == ClassToken || p.tt == AtToken {
+			exportStmt.Decl = p.parseAnyClass(false, decorators)
+		} else if p.o.TypeScript && (p.tt == InterfaceToken || p.isTSIdentifier("abstract")) && p.isTSDeclaration() {
+			stmt := p.parseTSDeclaration()
+			if stmt == nil {
+				p.yield, p.await, p.deflt = prevYield, prevAwait, prevDeflt
+				return nil
+			}
+			exportStmt.Decl = stmt.(IExpr)
 		} else {
 			exportStmt.Decl = p.parseExpression(OpAssign)
 		}
@@ -872,11 +1405,27 @@ func (p *Parser) parseExportStmt() (exportStmt ExportStmt) {
 		p.next()
 	}
 	p.yield, p.await, p.deflt = prevYield, prevAwait, prevDeflt
+	exportStmt.Loc = p.loc(start)
+	p.attachComments(exportStmt, leading)
+	if funcDecl, ok := exportStmt.Decl.(*FuncDecl); typeOnly || ok && funcDecl == nil {
+		return nil // TypeScript type-only export or overload signature
+	}
 	return
 }
 
+// isUsingDecl returns true if we're at a using or await using declaration, which must be followed by an identifier on the same line. In for statements, using of is parsed as the left-hand side of a for-of statement.
+func (p *Parser) isUsingDecl(inFor bool) bool {
+	if p.tt == AwaitToken && p.await {
+		return p.peekTS(func() bool {
+			return p.tt == UsingToken && !p.prevLT && p.peekTS(func() bool { return !p.prevLT && p.isIdentifierReference(p.tt) })
+		})
+	}
+	return p.tt == UsingToken && p.peekTS(func() bool { return !p.prevLT && p.isIdentifierReference(p.tt) && (!inFor || p.tt != OfToken) })
+}
+
 func (p *Parser) parseVarDecl(tt TokenType, canBeHoisted bool) (varDecl *VarDecl) {
-	// assume we're past var, let or const
+	// assume we're past var, let, const, or using
+	start := p.prevStart
 	varDecl = &VarDecl{
 		TokenType: tt,
 		Scope:     p.scope,
@@ -887,11 +1436,24 @@ func (p *Parser) parseVarDecl(tt TokenType, canBeHoisted bool) (varDecl *VarDecl
 		if canBeHoisted {
 			p.scope.Func.VarDecls = append(p.scope.Func.VarDecls, varDecl)
 		}
+	} else if tt == UsingToken {
+		declType = UsingDecl
 	}
 	for {
 		// binding element, var declaration in for-in or for-of can never have a default
 		var bindingElement BindingElement
+		bindingStart := p.start
+		if tt == UsingToken && !p.isIdentifierReference(p.tt) {
+			p.fail("using declaration", IdentifierToken)
+			return
+		}
 		bindingElement.Binding = p.parseBinding(declType)
+		if p.o.TypeScript {
+			if p.tt == NotToken {
+				p.next() // definite assignment assertion
+			}
+			p.parseTSTypeAnnotation()
+		}
 		if p.tt == EqToken {
 			p.next()
 			bindingElement.Default = p.parseExpression(OpAssign)
@@ -900,8 +1462,11 @@ func (p *Parser) parseVarDecl(tt TokenType, canBeHoisted bool) (varDecl *VarDecl
 			return
 		} else if tt == ConstToken && (p.in || !p.in && p.tt != OfToken && p.tt != InToken) {
 			p.fail("const statement", EqToken)
+		} else if tt == UsingToken && (p.in || p.tt != OfToken) {
+			p.fail("using declaration", EqToken)
 		}
 
+		bindingElement.Loc = p.loc(bindingStart)
 		varDecl.List = append(varDecl.List, bindingElement)
 		if p.tt == CommaToken {
 			p.next()
@@ -909,24 +1474,51 @@ func (p *Parser) parseVarDecl(tt TokenType, canBeHoisted bool) (varDecl *VarDecl
 			break
 		}
 	}
+	varDecl.Loc = p.loc(start)
 	return
 }
 
 func (p *Parser) parseFuncParams(in string) (params Params) {
 	// FormalParameters
+	if p.o.TypeScript && p.tt == LtToken {
+		p.parseTSTypeParams()
+	}
 	if !p.consume(in, OpenParenToken) {
 		return
 	}
 
+	if p.o.TypeScript && p.tt == ThisToken {
+		// this parameter
+		p.next()
+		p.parseTSTypeAnnotation()
+		if p.tt == CommaToken {
+			p.next()
+		}
+	}
 	for p.tt != CloseParenToken && p.tt != ErrorToken {
 		if p.tt == EllipsisToken {
 			// binding rest element
 			p.next()
 			params.Rest = p.parseBinding(ArgumentDecl)
-			p.consume(in, CloseParenToken)
+			if p.o.TypeScript {
+				p.parseTSTypeAnnotation()
+			}
+			if !p.consume(in, CloseParenToken) {
+				return
+			}
+			if p.o.TypeScript {
+				p.parseTSTypeAnnotation() // return type
+			}
 			return
 		}
+		isProperty := false
+		if p.o.TypeScript {
+			_, _, isProperty = p.parseTSModifiers()
+		}
 		params.List = append(params.List, p.parseBindingElement(ArgumentDecl))
+		if v, ok := params.List[len(params.List)-1].Binding.(*Var); ok && isProperty {
+			p.tsParamProps = append(p.tsParamProps, v)
+		}
 		if p.tt != CommaToken {
 			break
 		}
@@ -937,6 +1529,9 @@ func (p *Parser) parseFuncParams(in string) (params Params) {
 		return
 	}
 	p.next()
+	if p.o.TypeScript {
+		p.parseTSTypeAnnotation() // return type
+	}
 
 	// mark undeclared vars as arguments in `function f(a=b){var b}` where the b's are different vars
 	p.scope.MarkFuncArgs()
@@ -961,6 +1556,10 @@ func (p *Parser) parseAsyncFuncExpr() (funcDecl *FuncDecl) {
 
 func (p *Parser) parseFunc(async, expr bool) (funcDecl *FuncDecl) {
 	// assume we're at function
+	start := p.start
+	if async {
+		start = p.prevStart
+	}
 	p.next()
 	funcDecl = &FuncDecl{}
 	funcDecl.Async = async
@@ -970,10 +1569,12 @@ func (p *Parser) parseFunc(async, expr bool) (funcDecl *FuncDecl) {
 	}
 	var ok bool
 	var name []byte
+	var nameLoc Loc
 	if expr && (IsIdentifier(p.tt) || p.tt == YieldToken || p.tt == AwaitToken) || !expr && p.isIdentifierReference(p.tt) {
 		name = p.data
-		if !expr {
-			funcDecl.Name, ok = p.scope.Declare(FunctionDecl, p.data)
+		nameLoc = Loc{p.start, p.end}
+		if !expr && !p.o.TypeScript {
+			funcDecl.Name, ok = p.declare(FunctionDecl, p.data, nameLoc)
 			if !ok {
 				p.failMessage("identifier %s has already been declared", string(p.data))
 				return
@@ -983,7 +1584,7 @@ func (p *Parser) parseFunc(async, expr bool) (funcDecl *FuncDecl) {
 	} else if !expr && !p.deflt {
 		p.fail("function declaration", IdentifierToken)
 		return
-	} else if p.tt != OpenParenToken {
+	} else if p.tt != OpenParenToken && (!p.o.TypeScript || p.tt != LtToken) {
 		p.fail("function declaration", IdentifierToken, OpenParenToken)
 		return
 	}
@@ -992,52 +1593,100 @@ func (p *Parser) parseFunc(async, expr bool) (funcDecl *FuncDecl) {
 	p.await, p.yield, p.retrn = funcDecl.Async, funcDecl.Generator, true
 
 	if expr && name != nil {
-		funcDecl.Name, _ = p.scope.Declare(ExprDecl, name) // cannot fail
+		funcDecl.Name, _ = p.declare(ExprDecl, name, nameLoc) // cannot fail
 	}
 	funcDecl.Params = p.parseFuncParams("function declaration")
+	if p.o.TypeScript && !expr && name != nil {
+		if p.tt != OpenBraceToken && p.err == nil {
+			// overload signature
+			p.await, p.yield, p.retrn = prevAwait, prevYield, prevRetrn
+			p.exitScope(parent)
+			return nil
+		}
+
+		// declare the name only after we know this is not an overload signature
+		p.scope = parent
+		funcDecl.Name, ok = p.declare(FunctionDecl, name, nameLoc)
+		p.scope = &funcDecl.Body.Scope
+		if !ok {
+			p.failMessage("identifier %s has already been declared", string(name))
+			return
+		}
+	}
 
 	prevAllowDirectivePrologue := p.allowDirectivePrologue
 	p.allowDirectivePrologue = true
+	bodyStart := p.start
 	funcDecl.Body.List = p.parseStmtList("function declaration")
+	funcDecl.Body.Loc = p.loc(bodyStart)
 	p.allowDirectivePrologue = prevAllowDirectivePrologue
 
 	p.await, p.yield, p.retrn = prevAwait, prevYield, prevRetrn
 	p.exitScope(parent)
+	funcDecl.Loc = p.loc(start)
 	return
 }
 
 func (p *Parser) parseClassDecl() (classDecl *ClassDecl) {
-	return p.parseAnyClass(false)
+	return p.parseAnyClass(false, nil)
 }
 
 func (p *Parser) parseClassExpr() (classDecl *ClassDecl) {
-	return p.parseAnyClass(true)
+	return p.parseAnyClass(true, nil)
 }
 
-func (p *Parser) parseAnyClass(expr bool) (classDecl *ClassDecl) {
-	// assume we're at class
-	p.next()
+// parseAnyClass parses a class declaration or expression, decorators are passed when they have already been parsed before an export keyword.
+func (p *Parser) parseAnyClass(expr bool, decorators []IExpr) (classDecl *ClassDecl) {
+	// assume we're at class or @
+	start := p.start
 	classDecl = &ClassDecl{}
+	if p.tt == AtToken {
+		if decorators != nil {
+			p.fail("class declaration", ClassToken)
+			return
+		}
+		decorators = p.parseDecorators()
+	}
+	classDecl.Decorators = decorators
+	if !p.consume("class declaration", ClassToken) {
+		return
+	}
 	if IsIdentifier(p.tt) || p.tt == YieldToken || p.tt == AwaitToken {
 		if !expr {
 			var ok bool
-			classDecl.Name, ok = p.scope.Declare(LexicalDecl, p.data)
+			classDecl.Name, ok = p.declare(LexicalDecl, p.data, Loc{p.start, p.end})
 			if !ok {
 				p.failMessage("identifier %s has already been declared", string(p.data))
 				return
 			}
 		} else {
 			//classDecl.Name, ok = p.scope.Declare(ExprDecl, p.data) // classes do not register vars
-			classDecl.Name = &Var{p.data, nil, 1, ExprDecl}
+			classDecl.Name = &Var{p.data, nil, 1, ExprDecl, []Loc{{p.start, p.end}}}
 		}
 		p.next()
 	} else if !expr && !p.deflt {
 		p.fail("class declaration", IdentifierToken)
 		return
 	}
+	if p.o.TypeScript && p.tt == LtToken {
+		p.parseTSTypeParams()
+	}
 	if p.tt == ExtendsToken {
 		p.next()
 		classDecl.Extends = p.parseExpression(OpLHS)
+		if p.o.TypeScript && p.tt == LtToken {
+			p.parseTSTypeArgs()
+		}
+	}
+	if p.o.TypeScript && p.tt == ImplementsToken {
+		p.next()
+		for {
+			p.parseTSTypeReference()
+			if p.tt != CommaToken {
+				break
+			}
+			p.next()
+		}
 	}
 
 	if !p.consume("class declaration", OpenBraceToken) {
@@ -1056,32 +1705,114 @@ func (p *Parser) parseAnyClass(expr bool) (classDecl *ClassDecl) {
 			break
 		}
 
-		classDecl.List = append(classDecl.List, p.parseClassElement())
+		leading := p.leadingComments()
+		if elem, ok := p.parseClassElement(classDecl.Extends != nil); ok {
+			if elem.Method != nil {
+				p.attachComments(elem.Method, leading)
+			} else if elem.StaticBlock == nil {
+				if p.tt == SemicolonToken {
+					p.next() // trailing comments follow the semicolon
+				}
+				p.attachComments(&elem.Field, leading)
+			}
+			classDecl.List = append(classDecl.List, elem)
+		}
 	}
 	p.exitScope(parent)
+	classDecl.Loc = p.loc(start)
+	return
+}
+
+// parseDecorators parses the decorators of a class or class element, which are either a dotted name optionally followed by arguments, or a parenthesized expression.
+func (p *Parser) parseDecorators() (decorators []IExpr) {
+	for p.tt == AtToken {
+		p.next()
+		start := p.start
+		var decorator IExpr
+		if p.tt == OpenParenToken {
+			p.next()
+			prevIn := p.in
+			p.in = true
+			group := &GroupExpr{X: p.parseExpression(OpExpr)}
+			p.in = prevIn
+			if !p.consume("decorator", CloseParenToken) {
+				return
+			}
+			group.Loc = p.loc(start)
+			decorator = group
+		} else if p.isIdentifierReference(p.tt) {
+			decorator = p.use(p.data, Loc{p.start, p.end})
+			p.next()
+			for p.tt == DotToken {
+				p.next()
+				if p.tt == PrivateIdentifierToken {
+					decorator = &DotExpr{decorator, p.use(p.data, Loc{p.start, p.end}), OpMember, false, Loc{start, p.end}}
+				} else if IsIdentifierName(p.tt) {
+					decorator = &DotExpr{decorator, LiteralExpr{IdentifierToken, p.data, Loc{p.start, p.end}}, OpMember, false, Loc{start, p.end}}
+				} else {
+					p.fail("decorator", IdentifierToken)
+					return
+				}
+				p.next()
+			}
+			if p.tt == OpenParenToken {
+				args := p.parseArguments()
+				decorator = &CallExpr{decorator, args, OpCall, false, p.loc(start)}
+			}
+		} else {
+			p.fail("decorator", IdentifierToken, OpenParenToken)
+			return
+		}
+		decorators = append(decorators, decorator)
+	}
 	return
 }
 
-func (p *Parser) parseClassElement() ClassElement {
+// parseClassElement parses a class element, it returns false for TypeScript elements that are removed.
+func (p *Parser) parseClassElement(extends bool) (ClassElement, bool) {
 	method := &MethodDecl{}
-	var data []byte // either static, async, get, or set
+	start := p.start
+	var data []byte // either static, async, get, set, or accessor
+	var dataLoc Loc
+	var abstract, declare, accessor bool
+	method.Decorators = p.parseDecorators()
+	if p.o.TypeScript {
+		abstract, declare, _ = p.parseTSModifiers()
+	}
 	if p.tt == StaticToken {
 		method.Static = true
 		data = p.data
+		dataLoc = Loc{p.start, p.end}
 		p.next()
 		if p.tt == OpenBraceToken {
+			if method.Decorators != nil {
+				p.fail("class static block")
+				return ClassElement{}, false
+			}
 			prevYield, prevAwait, prevRetrn := p.yield, p.await, p.retrn
 			p.yield, p.await, p.retrn = false, true, false
 			elem := ClassElement{StaticBlock: p.parseBlockStmt("class static block")}
+			elem.Loc = p.loc(start)
 			p.yield, p.await, p.retrn = prevYield, prevAwait, prevRetrn
-			return elem
+			return elem, true
 		}
+		if p.o.TypeScript {
+			abstract2, declare2, _ := p.parseTSModifiers()
+			abstract, declare = abstract || abstract2, declare || declare2
+		}
+	}
+	if p.o.TypeScript && p.tt == OpenBracketToken && p.peekTS(func() bool { return IsIdentifier(p.tt) && p.peekTS(func() bool { return p.tt == ColonToken }) }) {
+		// index signature
+		p.skipTSBalanced()
+		p.parseTSTypeAnnotation()
+		return ClassElement{}, false
 	}
 	if p.tt == MulToken {
 		method.Generator = true
 		p.next()
 	} else if p.tt == AsyncToken {
 		data = p.data
+		dataLoc = Loc{p.start, p.end}
 		p.next()
 		if !p.prevLT {
 			method.Async = true
@@ -1094,89 +1825,131 @@ func (p *Parser) parseClassElement() ClassElement {
 	} else if p.tt == GetToken {
 		method.Get = true
 		data = p.data
+		dataLoc = Loc{p.start, p.end}
 		p.next()
 	} else if p.tt == SetToken {
 		method.Set = true
 		data = p.data
+		dataLoc = Loc{p.start, p.end}
+		p.next()
+	} else if p.tt == AccessorToken {
+		accessor = true
+		data = p.data
+		dataLoc = Loc{p.start, p.end}
 		p.next()
 	}
 
 	isField := false
 	if data != nil && p.tt == OpenParenToken {
-		// (static) method name is: static, async, get, or set
-		method.Name.Literal = LiteralExpr{IdentifierToken, data}
-		if method.Async || method.Get || method.Set {
+		// (static) method name is: static, async, get, set, or accessor
+		method.Name.Literal = LiteralExpr{IdentifierToken, data, dataLoc}
+		if method.Async || method.Get || method.Set || accessor {
 			method.Async = false
 			method.Get = false
 			method.Set = false
+			accessor = false
 		} else {
 			method.Static = false
 		}
-	} else if data != nil && (p.tt == EqToken || p.tt == SemicolonToken || p.tt == CloseBraceToken) {
-		// (static) field name is: static, async, get, or set
-		method.Name.Literal = LiteralExpr{IdentifierToken, data}
-		if !method.Async && !method.Get && !method.Set {
+	} else if data != nil && (p.tt == EqToken || p.tt == SemicolonToken || p.tt == CloseBraceToken || accessor && p.prevLT || p.o.TypeScript && (p.tt == ColonToken || p.tt == QuestionToken || p.tt == NotToken)) {
+		// (static) field name is: static, async, get, set, or accessor
+		method.Name.Literal = LiteralExpr{IdentifierToken, data, dataLoc}
+		if !method.Async && !method.Get && !method.Set && !accessor {
 			method.Static = false
 		}
+		accessor = false
 		isField = true
 	} else {
 		if p.tt == PrivateIdentifierToken {
 			var ok bool
-			method.Name.Private, ok = p.scope.Declare(PrivateDecl, p.data)
+			method.Name.Private, ok = p.declare(PrivateDecl, p.data, Loc{p.start, p.end})
 			if !ok {
 				p.failMessage("identifier %s has already been declared", string(p.data))
-				return ClassElement{}
+				return ClassElement{}, false
 			}
 			p.next()
 		} else {
 			method.Name.PropertyName = p.parsePropertyName("method or field definition")
 		}
-		if (data == nil || method.Static) && p.tt != OpenParenToken {
+		if p.o.TypeScript && (p.tt == QuestionToken || p.tt == NotToken) {
+			p.next() // optional member or definite assignment assertion
+		}
+		if (data == nil || method.Static || accessor) && p.tt != OpenParenToken && (!p.o.TypeScript || p.tt != LtToken) {
 			isField = true
+		} else if accessor {
+			p.fail("accessor field")
+			return ClassElement{}, false
 		}
 	}
 
 	if isField {
+		if p.o.TypeScript {
+			if data != nil && (p.tt == QuestionToken || p.tt == NotToken) {
+				p.next()
+			}
+			p.parseTSTypeAnnotation()
+		}
 		var init IExpr
 		if p.tt == EqToken {
 			p.next()
 			init = p.parseExpression(OpAssign)
 		}
-		return ClassElement{Field: Field{Static: method.Static, Name: method.Name, Init: init}}
+		if abstract || declare {
+			return ClassElement{}, false
+		}
+		loc := p.loc(start)
+		return ClassElement{Field: Field{Decorators: method.Decorators, Static: method.Static, Accessor: accessor, Name: method.Name, Init: init, Loc: loc}, Loc: loc}, true
 	}
 
 	parent := p.enterScope(&method.Body.Scope, true)
 	prevAwait, prevYield, prevRetrn := p.await, p.yield, p.retrn
 	p.await, p.yield, p.retrn = method.Async, method.Generator, true
 
+	prevParamProps := p.tsParamProps
+	p.tsParamProps = nil
 	method.Params = p.parseFuncParams("method definition")
+	paramProps := p.tsParamProps
+	p.tsParamProps = prevParamProps
+	if p.o.TypeScript && p.tt != OpenBraceToken && p.err == nil {
+		// abstract method or overload signature
+		p.await, p.yield, p.retrn = prevAwait, prevYield, prevRetrn
+		p.exitScope(parent)
+		return ClassElement{}, false
+	}
 
 	prevAllowDirectivePrologue := p.allowDirectivePrologue
 	p.allowDirectivePrologue = true
+	bodyStart := p.start
 	method.Body.List = p.parseStmtList("method function")
+	method.Body.Loc = p.loc(bodyStart)
 	p.allowDirectivePrologue = prevAllowDirectivePrologue
+	if 0 < len(paramProps) {
+		p.addTSParamProps(&method.Body, paramProps, extends)
+	}
 
 	p.await, p.yield, p.retrn = prevAwait, prevYield, prevRetrn
 	p.exitScope(parent)
-	return ClassElement{Method: method}
+	method.Loc = p.loc(start)
+	return ClassElement{Method: method, Loc: method.Loc}, true
 }
 
 func (p *Parser) parsePropertyName(in string) (propertyName PropertyName) {
+	loc := Loc{p.start, p.end}
 	if IsIdentifierName(p.tt) {
-		propertyName.Literal = LiteralExpr{IdentifierToken, p.data}
+		propertyName.Literal = LiteralExpr{IdentifierToken, p.data, loc}
 		p.next()
 	} else if p.tt == StringToken {
 		// reinterpret string as identifier or number if we can, except for empty strings
 		if isIdent := AsIdentifierName(p.data[1 : len(p.data)-1]); isIdent {
-			propertyName.Literal = LiteralExpr{IdentifierToken, p.data[1 : len(p.data)-1]}
+			propertyName.Literal = LiteralExpr{IdentifierToken, p.data[1 : len(p.data)-1], loc}
 		} else if isNum := AsDecimalLiteral(p.data[1 : len(p.data)-1]); isNum {
-			propertyName.Literal = LiteralExpr{DecimalToken, p.data[1 : len(p.data)-1]}
+			propertyName.Literal = LiteralExpr{DecimalToken, p.data[1 : len(p.data)-1], loc}
 		} else {
-			propertyName.Literal = LiteralExpr{p.tt, p.data}
+			propertyName.Literal = LiteralExpr{p.tt, p.data, loc}
 		}
 		p.next()
 	} else if IsNumeric(p.tt) {
-		propertyName.Literal = LiteralExpr{p.tt, p.data}
+		propertyName.Literal = LiteralExpr{p.tt, p.data, loc}
 		p.next()
 	} else if p.tt == OpenBracketToken {
 		p.next()
@@ -1193,19 +1966,31 @@ func (p *Parser) parsePropertyName(in string) (propertyName PropertyName) {
 
 func (p *Parser) parseBindingElement(decl DeclType) (bindingElement BindingElement) {
 	// BindingElement
+	start := p.start
 	bindingElement.Binding = p.parseBinding(decl)
+	if p.o.TypeScript && decl == ArgumentDecl {
+		if p.tt == QuestionToken {
+			p.next() // optional parameter
+		}
+		p.parseTSTypeAnnotation()
+	}
 	if p.tt == EqToken {
 		p.next()
 		bindingElement.Default = p.parseExpression(OpAssign)
 	}
+	bindingElement.Loc = p.loc(start)
 	return
 }
 
 func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 	// BindingIdentifier, BindingPattern
+	if !p.countNode() {
+		return
+	}
+	start := p.start
 	if p.isIdentifierReference(p.tt) {
 		var ok bool
-		binding, ok = p.scope.Declare(decl, p.data)
+		binding, ok = p.declare(decl, p.data, Loc{p.start, p.end})
 		if !ok {
 			p.failMessage("identifier %s has already been declared", string(p.data))
 			return
@@ -1249,6 +2034,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 			}
 		}
 		p.next() // always CloseBracketToken
+		array.Loc = p.loc(start)
 		binding = &array
 	} else if p.tt == OpenBraceToken {
 		p.next()
@@ -1262,7 +2048,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 					return
 				}
 				var ok bool
-				object.Rest, ok = p.scope.Declare(decl, p.data)
+				object.Rest, ok = p.declare(decl, p.data, Loc{p.start, p.end})
 				if !ok {
 					p.failMessage("identifier %s has already been declared", string(p.data))
 					return
@@ -1278,7 +2064,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 			item := BindingObjectItem{}
 			if p.isIdentifierReference(p.tt) {
 				name := p.data
-				item.Key = &PropertyName{LiteralExpr{IdentifierToken, p.data}, nil}
+				item.Key = &PropertyName{LiteralExpr{IdentifierToken, p.data, Loc{p.start, p.end}}, nil}
 				p.next()
 				if p.tt == ColonToken {
 					// property name + : + binding element
@@ -1288,7 +2074,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 					// single name binding
 					var ok bool
 					item.Key.Literal.Data = parse.Copy(item.Key.Literal.Data) // copy so that renaming doesn't rename the key
-					item.Value.Binding, ok = p.scope.Declare(decl, name)
+					item.Value.Binding, ok = p.declare(decl, name, item.Key.Literal.Loc)
 					if !ok {
 						p.failMessage("identifier %s has already been declared", string(name))
 						return
@@ -1297,6 +2083,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 						p.next()
 						item.Value.Default = p.parseExpression(OpAssign)
 					}
+					item.Value.Loc = p.loc(item.Key.Literal.Start)
 				}
 			} else {
 				propertyName := p.parsePropertyName("object binding pattern")
@@ -1316,6 +2103,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 			}
 		}
 		p.next() // always CloseBracketToken
+		object.Loc = p.loc(start)
 		binding = &object
 	} else {
 		p.fail("binding")
@@ -1326,6 +2114,7 @@ func (p *Parser) parseBinding(decl DeclType) (binding IBinding) {
 
 func (p *Parser) parseArrayLiteral() (array ArrayExpr) {
 	// assume we're on [
+	start := p.start
 	p.next()
 	prevComma := true
 	for {
@@ -1334,6 +2123,7 @@ func (p *Parser) parseArrayLiteral() (array ArrayExpr) {
 			return
 		} else if p.tt == CloseBracketToken {
 			p.next()
+			array.Loc = p.loc(start)
 			break
 		} else if p.tt == CommaToken {
 			if prevComma {
@@ -1358,6 +2148,7 @@ func (p *Parser) parseArrayLiteral() (array ArrayExpr) {
 
 func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 	// assume we're on {
+	start := p.start
 	p.next()
 	for {
 		if p.tt == ErrorToken {
@@ -1365,10 +2156,13 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 			return
 		} else if p.tt == CloseBraceToken {
 			p.next()
+			object.Loc = p.loc(start)
 			break
 		}
 
 		property := Property{}
+		propertyStart := p.start
+		leading := p.leadingComments()
 		if p.tt == EllipsisToken {
 			p.next()
 			property.Spread = true
@@ -1394,7 +2188,7 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 						data = nil
 					}
 				} else {
-					method.Name.Literal = LiteralExpr{IdentifierToken, data}
+					method.Name.Literal = LiteralExpr{IdentifierToken, data, Loc{p.prevStart, p.prevEnd}}
 					data = nil
 				}
 			} else if p.tt == GetToken {
@@ -1409,7 +2203,7 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 
 			// PropertyName
 			if data != nil && !method.Generator && (p.tt == EqToken || p.tt == CommaToken || p.tt == CloseBraceToken || p.tt == ColonToken || p.tt == OpenParenToken) {
-				method.Name.Literal = LiteralExpr{IdentifierToken, data}
+				method.Name.Literal = LiteralExpr{IdentifierToken, data, Loc{p.prevStart, p.prevEnd}}
 				method.Async = false
 				method.Get = false
 				method.Set = false
@@ -1420,7 +2214,7 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 				}
 			}
 
-			if p.tt == OpenParenToken {
+			if p.tt == OpenParenToken || p.o.TypeScript && p.tt == LtToken {
 				// MethodDefinition
 				parent := p.enterScope(&method.Body.Scope, true)
 				prevAwait, prevYield, prevRetrn := p.await, p.yield, p.retrn
@@ -1428,10 +2222,13 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 				p.assumeArrowFunc = false
 
 				method.Params = p.parseFuncParams("method definition")
+				bodyStart := p.start
 				method.Body.List = p.parseStmtList("method definition")
+				method.Body.Loc = p.loc(bodyStart)
 
 				p.await, p.yield, p.retrn = prevAwait, prevYield, prevRetrn
 				p.exitScope(parent)
+				method.Loc = p.loc(propertyStart)
 				property.Value = &method
 			} else if p.tt == ColonToken {
 				// PropertyName : AssignmentExpression
@@ -1446,15 +2243,16 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 				name := method.Name.Literal.Data
 				method.Name.Literal.Data = parse.Copy(method.Name.Literal.Data) // copy so that renaming doesn't rename the key
 				property.Name = &method.Name.PropertyName                       // set key explicitly so after renaming the original is still known
+				nameLoc := method.Name.Literal.Loc
 				if p.assumeArrowFunc {
 					var ok bool
-					property.Value, ok = p.scope.Declare(ArgumentDecl, name)
+					property.Value, ok = p.declare(ArgumentDecl, name, nameLoc)
 					if !ok {
-						property.Value = p.scope.Use(name)
+						property.Value = p.use(name, nameLoc)
 						p.assumeArrowFunc = false
 					}
 				} else {
-					property.Value = p.scope.Use(name)
+					property.Value = p.use(name, nameLoc)
 				}
 				if p.tt == EqToken {
 					p.next()
@@ -1465,6 +2263,7 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 				}
 			}
 		}
+		property.Loc = p.loc(propertyStart)
 		object.List = append(object.List, property)
 		if p.tt == CommaToken {
 			p.next()
@@ -1472,12 +2271,14 @@ func (p *Parser) parseObjectLiteral() (object ObjectExpr) {
 			p.fail("object literal")
 			return
 		}
+		p.attachComments(&object.List[len(object.List)-1], leading)
 	}
 	return
 }
 
 func (p *Parser) parseTemplateLiteral(precLeft OpPrec) (template TemplateExpr) {
 	// assume we're on 'Template' or 'TemplateStart'
+	start := p.start
 	template.Prec = OpMember
 	if precLeft < OpMember {
 		template.Prec = OpCall
@@ -1493,6 +2294,7 @@ func (p *Parser) parseTemplateLiteral(precLeft OpPrec) (template TemplateExpr) {
 	}
 	template.Tail = p.data
 	p.next() // TemplateEndToken
+	template.Loc = p.loc(start)
 	return
 }
 
@@ -1524,25 +2326,28 @@ func (p *Parser) parseArguments() (args Args) {
 
 func (p *Parser) parseAsyncArrowFunc() (arrowFunc *ArrowFunc) {
 	// expect we're at Identifier or Yield or (
+	start := p.prevStart // async
 	arrowFunc = &ArrowFunc{}
 	parent := p.enterScope(&arrowFunc.Body.Scope, true)
 	prevAwait, prevYield := p.await, p.yield
 	p.await, p.yield = true, false
 
 	if IsIdentifier(p.tt) || !prevYield && p.tt == YieldToken {
-		ref, _ := p.scope.Declare(ArgumentDecl, p.data) // cannot fail
+		loc := Loc{p.start, p.end}
+		ref, _ := p.declare(ArgumentDecl, p.data, loc) // cannot fail
 		p.next()
-		arrowFunc.Params.List = []BindingElement{{Binding: ref}}
+		arrowFunc.Params.List = []BindingElement{{Binding: ref, Loc: loc}}
 	} else {
 		arrowFunc.Params = p.parseFuncParams("arrow function")
 		// CallExpression of 'async(params)' already handled
 	}
 
 	arrowFunc.Async = true
-	arrowFunc.Body.List = p.parseArrowFuncBody()
+	p.parseArrowFuncBody(&arrowFunc.Body)
 
 	p.await, p.yield = prevAwait, prevYield
 	p.exitScope(parent)
+	arrowFunc.Loc = p.loc(start)
 	return
 }
 
@@ -1553,9 +2358,16 @@ func (p *Parser) parseIdentifierArrowFunc(v *Var) (arrowFunc *ArrowFunc) {
 	prevAwait, prevYield := p.await, p.yield
 	p.await, p.yield = false, false
 
+	var loc Loc
+	if 0 < len(v.Locs) {
+		loc = v.Locs[len(v.Locs)-1] // location of the identifier, which was the last use
+	}
 	if 1 < v.Uses {
 		v.Uses--
-		v, _ = p.scope.Declare(ArgumentDecl, parse.Copy(v.Data)) // cannot fail
+		if 0 < len(v.Locs) {
+			v.Locs = v.Locs[:len(v.Locs)-1]
+		}
+		v, _ = p.declare(ArgumentDecl, parse.Copy(v.Data), loc) // cannot fail
 	} else {
 		// if v.Uses==1 it must be undeclared and be the last added
 		p.scope.Parent.Undeclared = p.scope.Parent.Undeclared[:len(p.scope.Parent.Undeclared)-1]
@@ -1563,15 +2375,16 @@ func (p *Parser) parseIdentifierArrowFunc(v *Var) (arrowFunc *ArrowFunc) {
 		p.scope.Declared = append(p.scope.Declared, v)
 	}
 
-	arrowFunc.Params.List = []BindingElement{{v, nil}}
-	arrowFunc.Body.List = p.parseArrowFuncBody()
+	arrowFunc.Params.List = []BindingElement{{v, nil, loc}}
+	p.parseArrowFuncBody(&arrowFunc.Body)
 
 	p.await, p.yield = prevAwait, prevYield
 	p.exitScope(parent)
+	arrowFunc.Loc = p.loc(loc.Start)
 	return
 }
 
-func (p *Parser) parseArrowFuncBody() (list []IStmt) {
+func (p *Parser) parseArrowFuncBody(body *BlockStmt) {
 	// expect we're at arrow
 	if p.tt != ArrowToken {
 		p.fail("arrow function", ArrowToken)
@@ -1585,59 +2398,67 @@ func (p *Parser) parseArrowFuncBody() (list []IStmt) {
 	// mark undeclared vars as arguments in `function f(a=b){var b}` where the b's are different vars
 	p.scope.MarkFuncArgs()
 
+	start := p.start
 	if p.tt == OpenBraceToken {
 		prevIn, prevRetrn := p.in, p.retrn
 		p.in, p.retrn = true, true
 
 		prevAllowDirectivePrologue := p.allowDirectivePrologue
 		p.allowDirectivePrologue = true
-		list = p.parseStmtList("arrow function")
+		body.List = p.parseStmtList("arrow function")
 		p.allowDirectivePrologue = prevAllowDirectivePrologue
 
 		p.in, p.retrn = prevIn, prevRetrn
 	} else {
-		list = []IStmt{&ReturnStmt{p.parseExpression(OpAssign)}}
+		body.List = []IStmt{&ReturnStmt{p.parseExpression(OpAssign), p.loc(start)}}
 	}
-	return
+	body.Loc = p.loc(start)
 }
 
 func (p *Parser) parseIdentifierExpression(prec OpPrec, ident []byte) IExpr {
+	// assume we're past the identifier
 	var left IExpr
-	left = p.scope.Use(ident)
-	return p.parseExpressionSuffix(left, prec, OpPrimary)
+	left = p.use(ident, Loc{p.prevStart, p.prevEnd})
+	return p.parseExpressionSuffix(left, p.prevStart, prec, OpPrimary)
 }
 
 func (p *Parser) parseAsyncExpression(prec OpPrec, async []byte) IExpr {
 	// IdentifierReference, AsyncFunctionExpression, AsyncGeneratorExpression
 	// CoverCallExpressionAndAsyncArrowHead, AsyncArrowFunction
 	// assume we're at a token after async
+	start, asyncLoc := p.prevStart, Loc{p.prevStart, p.prevEnd}
 	var left IExpr
 	precLeft := OpPrimary
 	if !p.prevLT && p.tt == FunctionToken {
 		// primary expression
 		left = p.parseAsyncFuncExpr()
+	} else if !p.prevLT && prec <= OpAssign && p.o.TypeScript && p.tt == LtToken && p.try(func() bool { p.parseTSTypeParams(); return p.tt == OpenParenToken }) {
+		// generic async arrow function or call expression with type arguments
+		return p.parseParenthesizedExpression(prec, async, asyncLoc)
 	} else if !p.prevLT && prec <= OpAssign && (p.tt == OpenParenToken || IsIdentifier(p.tt) || p.tt == YieldToken || p.tt == AwaitToken) {
 		// async arrow function expression or call expression
 		if p.tt == AwaitToken || p.yield && p.tt == YieldToken {
 			p.fail("arrow function")
 			return nil
 		} else if p.tt == OpenParenToken {
-			return p.parseParenthesizedExpression(prec, async)
+			return p.parseParenthesizedExpression(prec, async, asyncLoc)
 		}
 		left = p.parseAsyncArrowFunc()
 		precLeft = OpAssign
 	} else {
-		left = p.scope.Use(async)
+		left = p.use(async, asyncLoc)
 	}
 	// can be async(args), async => ..., or e.g. async + ...
-	return p.parseExpressionSuffix(left, prec, precLeft)
+	return p.parseExpressionSuffix(left, start, prec, precLeft)
 }
 
 // parseExpression parses an expression that has a precedence of prec or higher.
 func (p *Parser) parseExpression(prec OpPrec) IExpr {
 	p.exprLevel++
-	if NestedExprLimit < p.exprLevel {
-		p.failMessage("too many nested expressions")
+	if p.o.NestedExprLimit < p.exprLevel {
+		p.failLimit("NestedExprLimit", "too many nested expressions", nil)
+		return nil
+	} else if !p.countNode() {
 		return nil
 	}
 
@@ -1648,28 +2469,32 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			p.fail("regular expression")
 			return nil
 		}
+		p.end = p.start + len(p.data)
 	}
 
 	var left IExpr
 	precLeft := OpPrimary
+	condTrue := p.tsCondTrue
+	p.tsCondTrue = false
 
+	start := p.start
 	if IsIdentifier(p.tt) && p.tt != AsyncToken {
-		left = p.scope.Use(p.data)
+		left = p.use(p.data, Loc{p.start, p.end})
 		p.next()
-		suffix := p.parseExpressionSuffix(left, prec, precLeft)
+		suffix := p.parseExpressionSuffix(left, start, prec, precLeft)
 		p.exprLevel--
 		return suffix
 	} else if IsNumeric(p.tt) {
-		left = &LiteralExpr{p.tt, p.data}
+		left = &LiteralExpr{p.tt, p.data, Loc{p.start, p.end}}
 		p.next()
-		suffix := p.parseExpressionSuffix(left, prec, precLeft)
+		suffix := p.parseExpressionSuffix(left, start, prec, precLeft)
 		p.exprLevel--
 		return suffix
 	}
 
 	switch tt := p.tt; tt {
 	case StringToken, ThisToken, NullToken, TrueToken, FalseToken, RegExpToken:
-		left = &LiteralExpr{p.tt, p.data}
+		left = &LiteralExpr{p.tt, p.data, Loc{p.start, p.end}}
 		p.next()
 	case OpenBracketToken:
 		prevIn := p.in
@@ -1690,14 +2515,17 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			p.next()
 			prevIn := p.in
 			p.in = true
-			left = &GroupExpr{p.parseExpression(OpExpr)}
+			group := &GroupExpr{X: p.parseExpression(OpExpr)}
 			p.in = prevIn
 			if !p.consume("expression", CloseParenToken) {
 				return nil
 			}
+			group.Loc = p.loc(start)
+			left = group
 			break
 		}
-		suffix := p.parseParenthesizedExpression(prec, nil)
+		p.tsCondTrue = condTrue
+		suffix := p.parseParenthesizedExpression(prec, nil, Loc{})
 		p.exprLevel--
 		return suffix
 	case NotToken, BitNotToken, TypeofToken, VoidToken, DeleteToken:
@@ -1706,7 +2534,7 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			return nil
 		}
 		p.next()
-		left = &UnaryExpr{tt, p.parseExpression(OpUnary)}
+		left = &UnaryExpr{tt, p.parseExpression(OpUnary), p.loc(start)}
 		precLeft = OpUnary
 	case AddToken:
 		if OpUnary < prec {
@@ -1714,7 +2542,7 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			return nil
 		}
 		p.next()
-		left = &UnaryExpr{PosToken, p.parseExpression(OpUnary)}
+		left = &UnaryExpr{PosToken, p.parseExpression(OpUnary), p.loc(start)}
 		precLeft = OpUnary
 	case SubToken:
 		if OpUnary < prec {
@@ -1722,7 +2550,7 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			return nil
 		}
 		p.next()
-		left = &UnaryExpr{NegToken, p.parseExpression(OpUnary)}
+		left = &UnaryExpr{NegToken, p.parseExpression(OpUnary), p.loc(start)}
 		precLeft = OpUnary
 	case IncrToken:
 		if OpUpdate < prec {
@@ -1730,7 +2558,7 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			return nil
 		}
 		p.next()
-		left = &UnaryExpr{PreIncrToken, p.parseExpression(OpUnary)}
+		left = &UnaryExpr{PreIncrToken, p.parseExpression(OpUnary), p.loc(start)}
 		precLeft = OpUnary
 	case DecrToken:
 		if OpUpdate < prec {
@@ -1738,19 +2566,19 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			return nil
 		}
 		p.next()
-		left = &UnaryExpr{PreDecrToken, p.parseExpression(OpUnary)}
+		left = &UnaryExpr{PreDecrToken, p.parseExpression(OpUnary), p.loc(start)}
 		precLeft = OpUnary
 	case AwaitToken:
 		// either accepted as IdentifierReference or as AwaitExpression
 		if p.await && prec <= OpUnary {
 			p.next()
-			left = &UnaryExpr{tt, p.parseExpression(OpUnary)}
+			left = &UnaryExpr{tt, p.parseExpression(OpUnary), p.loc(start)}
 			precLeft = OpUnary
 		} else if p.await {
 			p.fail("expression")
 			return nil
 		} else {
-			left = p.scope.Use(p.data)
+			left = p.use(p.data, Loc{p.start, p.end})
 			p.next()
 		}
 	case NewToken:
@@ -1760,10 +2588,10 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			if !p.consume("new.target expression", TargetToken) {
 				return nil
 			}
-			left = &NewTargetExpr{}
+			left = &NewTargetExpr{p.loc(start)}
 			precLeft = OpMember
 		} else {
-			newExpr := &NewExpr{p.parseExpression(OpNew), nil}
+			newExpr := &NewExpr{X: p.parseExpression(OpNew)}
 			if p.tt == OpenParenToken {
 				args := p.parseArguments()
 				if len(args.List) != 0 {
@@ -1773,18 +2601,19 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 			} else {
 				precLeft = OpNew
 			}
+			newExpr.Loc = p.loc(start)
 			left = newExpr
 		}
 	case ImportToken:
 		// OpMember < prec does never happen
-		left = &LiteralExpr{p.tt, p.data}
+		left = &LiteralExpr{p.tt, p.data, Loc{p.start, p.end}}
 		p.next()
 		if p.tt == DotToken {
 			p.next()
 			if !p.consume("import.meta expression", MetaToken) {
 				return nil
 			}
-			left = &ImportMetaExpr{}
+			left = &ImportMetaExpr{p.loc(start)}
 			precLeft = OpMember
 		} else if p.tt != OpenParenToken {
 			p.fail("import expression", OpenParenToken)
@@ -1797,7 +2626,7 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 		}
 	case SuperToken:
 		// OpMember < prec does never happen
-		left = &LiteralExpr{p.tt, p.data}
+		left = &LiteralExpr{p.tt, p.data, Loc{p.start, p.end}}
 		p.next()
 		if OpCall < prec && p.tt != DotToken && p.tt != OpenBracketToken {
 			p.fail("super expression", OpenBracketToken, DotToken)
@@ -1826,13 +2655,14 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 					yieldExpr.X = p.parseExpression(OpAssign)
 				}
 			}
+			yieldExpr.Loc = p.loc(start)
 			left = &yieldExpr
 			precLeft = OpAssign
 		} else if p.yield {
 			p.fail("expression")
 			return nil
 		} else {
-			left = p.scope.Use(p.data)
+			left = p.use(p.data, Loc{p.start, p.end})
 			p.next()
 		}
 	case AsyncToken:
@@ -1840,9 +2670,11 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 		p.next()
 		prevIn := p.in
 		p.in = true
+		p.tsCondTrue = condTrue
 		left = p.parseAsyncExpression(prec, async)
+		p.tsCondTrue = false
 		p.in = prevIn
-	case ClassToken:
+	case ClassToken, AtToken:
 		prevIn := p.in
 		p.in = true
 		left = p.parseClassExpr()
@@ -1858,12 +2690,45 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 		template := p.parseTemplateLiteral(precLeft)
 		left = &template
 		p.in = prevIn
+	case LtToken:
+		if p.o.TypeScript && (!p.o.JSX || p.peekTS(func() bool {
+			return IsIdentifier(p.tt) && p.peekTS(func() bool { return p.tt == CommaToken || p.tt == ExtendsToken })
+		})) {
+			if p.try(func() bool { p.parseTSTypeParams(); return p.tt == OpenParenToken }) {
+				// generic arrow function
+				suffix := p.parseExpression(prec)
+				p.exprLevel--
+				return suffix
+			}
+
+			// type assertion
+			p.next()
+			p.parseTSType()
+			if !p.consumeTSGt("type assertion") {
+				return nil
+			}
+			suffix := p.parseExpression(prec)
+			p.exprLevel--
+			return suffix
+		} else if !p.o.JSX {
+			p.fail("expression")
+			return nil
+		}
+		prevIn := p.in
+		p.in = true
+		p.nextJSXTag()
+		left = p.parseJSXElement(start)
+		p.in = prevIn
+		if left == nil {
+			return nil
+		}
+		p.next()
 	case PrivateIdentifierToken:
 		if OpCompare < prec || !p.in {
 			p.fail("expression")
 			return nil
 		}
-		left = p.scope.Use(p.data)
+		left = p.use(p.data, Loc{p.start, p.end})
 		p.next()
 		if p.tt != InToken {
 			p.fail("relational expression", InToken)
@@ -1873,16 +2738,16 @@ func (p *Parser) parseExpression(prec OpPrec) IExpr {
 		p.fail("expression")
 		return nil
 	}
-	suffix := p.parseExpressionSuffix(left, prec, precLeft)
+	suffix := p.parseExpressionSuffix(left, start, prec, precLeft)
 	p.exprLevel--
 	return suffix
 }
 
-// parseExpressionSuffix parses an expression recursively by their suffixes. prec is the precedence level (or higher) that is allowed (we may return earlier when an operator has a lower precedence), while precLeft is the precedence level of the preceding (left side) of the expression at this point (may be an error if it is lower than allowed). For example: when we encounter || we parse LogicalOR: LogicalOR || LogicalAND, if prec is higher than LogicalOR we return the expression and parse || and the rest higher up, if precLeft is lower than LogicalOR (the part before ||), this is invalid syntax.
-func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr {
+// parseExpressionSuffix parses an expression recursively by their suffixes. start is the offset of left in the source. prec is the precedence level (or higher) that is allowed (we may return earlier when an operator has a lower precedence), while precLeft is the precedence level of the preceding (left side) of the expression at this point (may be an error if it is lower than allowed). For example: when we encounter || we parse LogicalOR: LogicalOR || LogicalAND, if prec is higher than LogicalOR we return the expression and parse || and the rest higher up, if precLeft is lower than LogicalOR (the part before ||), this is invalid syntax.
+func (p *Parser) parseExpressionSuffix(left IExpr, start int, prec, precLeft OpPrec) IExpr {
 	for i := 0; ; i++ {
-		if 1000 < p.exprLevel+i {
-			p.failMessage("too many nested expressions")
+		if p.o.NestedExprLimit < p.exprLevel+i {
+			p.failLimit("NestedExprLimit", "too many nested expressions", nil)
 			return nil
 		}
 
@@ -1895,10 +2760,13 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpAssign)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpAssign), p.loc(start)}
 			precLeft = OpAssign
 		case LtToken, LtEqToken, GtToken, GtEqToken, InToken, InstanceofToken:
-			if OpCompare < prec || !p.in && tt == InToken {
+			if p.o.TypeScript && tt == LtToken && OpLHS <= precLeft && p.try(p.parseTSTypeArgsInExpr) {
+				// type arguments of a call or instantiation expression
+				continue
+			} else if OpCompare < prec || !p.in && tt == InToken {
 				return left
 			} else if precLeft < OpCompare {
 				// can only fail after a yield or arrow function expression
@@ -1906,7 +2774,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpShift)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpShift), p.loc(start)}
 			precLeft = OpCompare
 		case EqEqToken, NotEqToken, EqEqEqToken, NotEqEqToken:
 			if OpEquals < prec {
@@ -1917,7 +2785,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpCompare)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpCompare), p.loc(start)}
 			precLeft = OpEquals
 		case AndToken:
 			if OpAnd < prec {
@@ -1927,7 +2795,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpBitOr)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpBitOr), p.loc(start)}
 			precLeft = OpAnd
 		case OrToken:
 			if OpOr < prec {
@@ -1937,7 +2805,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpAnd)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpAnd), p.loc(start)}
 			precLeft = OpOr
 		case NullishToken:
 			if OpCoalesce < prec {
@@ -1947,7 +2815,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpBitOr)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpBitOr), p.loc(start)}
 			precLeft = OpCoalesce
 		case DotToken:
 			// OpMember < prec does never happen
@@ -1963,9 +2831,9 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			if p.tt == PrivateIdentifierToken {
-				left = &DotExpr{left, p.scope.Use(p.data), precLeft, false}
+				left = &DotExpr{left, p.use(p.data, Loc{p.start, p.end}), precLeft, false, Loc{start, p.end}}
 			} else {
-				left = &DotExpr{left, LiteralExpr{IdentifierToken, p.data}, precLeft, false}
+				left = &DotExpr{left, LiteralExpr{IdentifierToken, p.data, Loc{p.start, p.end}}, precLeft, false, Loc{start, p.end}}
 			}
 			p.next()
 		case OpenBracketToken:
@@ -1979,11 +2847,13 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			p.next()
 			prevIn := p.in
 			p.in = true
-			left = &IndexExpr{left, p.parseExpression(OpExpr), precLeft, false}
+			indexExpr := &IndexExpr{X: left, Y: p.parseExpression(OpExpr), Prec: precLeft}
 			p.in = prevIn
 			if !p.consume("index expression", CloseBracketToken) {
 				return nil
 			}
+			indexExpr.Loc = p.loc(start)
+			left = indexExpr
 		case OpenParenToken:
 			if OpCall < prec {
 				return left
@@ -1995,7 +2865,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			}
 			prevIn := p.in
 			p.in = true
-			left = &CallExpr{left, p.parseArguments(), precLeft, false}
+			left = &CallExpr{left, p.parseArguments(), precLeft, false, p.loc(start)}
 			p.in = prevIn
 		case TemplateToken, TemplateStartToken:
 			// OpMember < prec does never happen
@@ -2009,6 +2879,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			p.in = true
 			template := p.parseTemplateLiteral(precLeft)
 			template.Tag = left
+			template.Loc.Start = start
 			left = &template
 			p.in = prevIn
 		case OptChainToken:
@@ -2020,24 +2891,30 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			}
 			p.next()
 			if p.tt == OpenParenToken {
-				left = &CallExpr{left, p.parseArguments(), OpOpt, true}
+				left = &CallExpr{left, p.parseArguments(), OpOpt, true, p.loc(start)}
+			} else if p.o.TypeScript && p.tt == LtToken && p.try(func() bool { p.parseTSTypeArgs(); return p.tt == OpenParenToken }) {
+				// type arguments of an optional call
+				left = &CallExpr{left, p.parseArguments(), OpOpt, true, p.loc(start)}
 			} else if p.tt == OpenBracketToken {
 				p.next()
-				left = &IndexExpr{left, p.parseExpression(OpExpr), OpOpt, true}
+				indexExpr := &IndexExpr{X: left, Y: p.parseExpression(OpExpr), Prec: OpOpt, Optional: true}
 				if !p.consume("optional chaining expression", CloseBracketToken) {
 					return nil
 				}
+				indexExpr.Loc = p.loc(start)
+				left = indexExpr
 			} else if p.tt == TemplateToken || p.tt == TemplateStartToken {
 				template := p.parseTemplateLiteral(precLeft)
 				template.Prec = OpOpt
 				template.Tag = left
 				template.Optional = true
+				template.Loc.Start = start
 				left = &template
 			} else if IsIdentifierName(p.tt) {
-				left = &DotExpr{left, LiteralExpr{IdentifierToken, p.data}, OpOpt, true}
+				left = &DotExpr{left, LiteralExpr{IdentifierToken, p.data, Loc{p.start, p.end}}, OpOpt, true, Loc{start, p.end}}
 				p.next()
 			} else if p.tt == PrivateIdentifierToken {
-				left = &DotExpr{left, p.scope.Use(p.data), OpOpt, true}
+				left = &DotExpr{left, p.use(p.data, Loc{p.start, p.end}), OpOpt, true, Loc{start, p.end}}
 				p.next()
 			} else {
 				p.fail("optional chaining expression", IdentifierToken, OpenParenToken, OpenBracketToken, TemplateToken)
@@ -2052,7 +2929,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &UnaryExpr{PostIncrToken, left}
+			left = &UnaryExpr{PostIncrToken, left, p.loc(start)}
 			precLeft = OpUpdate
 		case DecrToken:
 			if p.prevLT || OpUpdate < prec {
@@ -2062,7 +2939,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &UnaryExpr{PostDecrToken, left}
+			left = &UnaryExpr{PostDecrToken, left, p.loc(start)}
 			precLeft = OpUpdate
 		case ExpToken:
 			if OpExp < prec {
@@ -2072,7 +2949,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpExp)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpExp), p.loc(start)}
 			precLeft = OpExp
 		case MulToken, DivToken, ModToken:
 			if OpMul < prec {
@@ -2082,7 +2959,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpExp)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpExp), p.loc(start)}
 			precLeft = OpMul
 		case AddToken, SubToken:
 			if OpAdd < prec {
@@ -2092,7 +2969,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpMul)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpMul), p.loc(start)}
 			precLeft = OpAdd
 		case LtLtToken, GtGtToken, GtGtGtToken:
 			if OpShift < prec {
@@ -2102,7 +2979,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpAdd)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpAdd), p.loc(start)}
 			precLeft = OpShift
 		case BitAndToken:
 			if OpBitAnd < prec {
@@ -2112,7 +2989,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpEquals)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpEquals), p.loc(start)}
 			precLeft = OpBitAnd
 		case BitXorToken:
 			if OpBitXor < prec {
@@ -2122,7 +2999,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpBitAnd)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpBitAnd), p.loc(start)}
 			precLeft = OpBitXor
 		case BitOrToken:
 			if OpBitOr < prec {
@@ -2132,7 +3009,7 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 				return nil
 			}
 			p.next()
-			left = &BinaryExpr{tt, left, p.parseExpression(OpBitXor)}
+			left = &BinaryExpr{tt, left, p.parseExpression(OpBitXor), p.loc(start)}
 			precLeft = OpBitOr
 		case QuestionToken:
 			if OpAssign < prec {
@@ -2144,13 +3021,14 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			p.next()
 			prevIn := p.in
 			p.in = true
+			p.tsCondTrue = p.o.TypeScript
 			ifExpr := p.parseExpression(OpAssign)
 			p.in = prevIn
 			if !p.consume("conditional expression", ColonToken) {
 				return nil
 			}
 			elseExpr := p.parseExpression(OpAssign)
-			left = &CondExpr{left, ifExpr, elseExpr}
+			left = &CondExpr{left, ifExpr, elseExpr, p.loc(start)}
 			precLeft = OpAssign
 		case CommaToken:
 			if OpExpr < prec {
@@ -2159,11 +3037,33 @@ func (p *Parser) parseExpressionSuffix(left IExpr, prec, precLeft OpPrec) IExpr
 			p.next()
 			if commaExpr, ok := left.(*CommaExpr); ok {
 				commaExpr.List = append(commaExpr.List, p.parseExpression(OpAssign))
+				commaExpr.Loc = p.loc(start)
 				i-- // adjust expression nesting limit
 			} else {
-				left = &CommaExpr{[]IExpr{left, p.parseExpression(OpAssign)}}
+				left = &CommaExpr{[]IExpr{left, p.parseExpression(OpAssign)}, p.loc(start)}
 			}
 			precLeft = OpExpr
+		case NotToken:
+			// TypeScript non-null assertion
+			if !p.o.TypeScript || p.prevLT || precLeft < OpLHS {
+				return left
+			}
+			p.next()
+		case AsToken, IdentifierToken:
+			// TypeScript as and satisfies expressions
+			if !p.o.TypeScript || p.prevLT || tt == IdentifierToken && !p.isTSIdentifier("satisfies") || OpCompare < prec {
+				return left
+			} else if precLeft < OpCompare {
+				p.fail("expression")
+				return nil
+			}
+			p.next()
+			if p.tt == ConstToken {
+				p.next()
+			} else {
+				p.parseTSType()
+			}
+			precLeft = OpCompare
 		case ArrowToken:
 			// handle identifier => ..., where identifier could also be yield or await
 			if OpAssign < prec {
@@ -2193,13 +3093,22 @@ func (p *Parser) parseAssignExprOrParam() IExpr {
 		tt := p.tt
 		data := p.data
 		p.next()
+		if p.o.TypeScript {
+			// optional parameter and type annotation
+			if p.tt == QuestionToken && p.peekTS(func() bool {
+				return p.tt == ColonToken || p.tt == CommaToken || p.tt == CloseParenToken || p.tt == EqToken
+			}) {
+				p.next()
+			}
+			p.parseTSTypeAnnotation()
+		}
 		if p.tt == EqToken || p.tt == CommaToken || p.tt == CloseParenToken || p.tt == CloseBraceToken || p.tt == CloseBracketToken {
 			var ok bool
 			var left IExpr
-			left, ok = p.scope.Declare(ArgumentDecl, data)
+			left, ok = p.declare(ArgumentDecl, data, Loc{p.prevStart, p.prevEnd})
 			if ok {
 				p.assumeArrowFunc = false
-				left = p.parseExpressionSuffix(left, OpAssign, OpPrimary)
+				left = p.parseExpressionSuffix(left, p.prevStart, OpAssign, OpPrimary)
 				p.assumeArrowFunc = true
 				return left
 			}
@@ -2215,12 +3124,18 @@ func (p *Parser) parseAssignExprOrParam() IExpr {
 	return p.parseExpression(OpAssign)
 }
 
-func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
+func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte, asyncLoc Loc) IExpr {
 	// parse ArrowFunc, AsyncArrowFunc, AsyncCallExpr, ParenthesizedExpr
 	var left IExpr
 	precLeft := OpPrimary
+	condTrue := p.tsCondTrue
+	p.tsCondTrue = false
 
 	// expect to be at (
+	start := p.start
+	if async != nil {
+		start = asyncLoc.Start
+	}
 	p.next()
 
 	isAsync := async != nil // prevLT is false before open parenthesis
@@ -2233,6 +3148,11 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 
 	rests := 0
 	var args Args
+	argLocs := len(p.argLocs)
+	defer func() {
+		p.argLocs = p.argLocs[:argLocs]
+	}()
+	argsStart, argsEnd := p.start, p.start
 	for p.tt != CloseParenToken && p.tt != ErrorToken {
 		if 0 < len(args.List) && args.List[len(args.List)-1].Rest {
 			// only last parameter can have ellipsis
@@ -2248,7 +3168,21 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 			rests++
 		}
 
-		args.List = append(args.List, Arg{p.parseAssignExprOrParam(), rest})
+		argStart := p.start
+		arg := Arg{p.parseAssignExprOrParam(), rest}
+		p.argLocs = append(p.argLocs[:argLocs+len(args.List)], p.loc(argStart))
+		if p.o.TypeScript && p.assumeArrowFunc && p.tt == ColonToken {
+			// type annotation of a binding pattern
+			p.parseTSTypeAnnotation()
+			if p.tt == EqToken && !rest {
+				p.next()
+				p.assumeArrowFunc = false
+				arg.Value = &BinaryExpr{EqToken, arg.Value, p.parseExpression(OpAssign), p.loc(argStart)}
+				p.assumeArrowFunc = true
+			}
+		}
+		args.List = append(args.List, arg)
+		argsEnd = p.prevEnd
 		if p.tt != CommaToken {
 			break
 		}
@@ -2259,6 +3193,13 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 		return nil
 	}
 	p.next()
+	if p.o.TypeScript && p.assumeArrowFunc && p.tt == ColonToken {
+		// return type of an arrow function, which in the consequent of a conditional expression such as a ? (b) : c => d is only one if a colon follows the arrow function
+		p.try(func() bool {
+			p.parseTSTypeAnnotation()
+			return p.tt == ArrowToken && !p.prevLT && (!condTrue || p.isTSArrowFuncBeforeColon())
+		})
+	}
 	isArrowFunc := !p.prevLT && p.tt == ArrowToken && p.assumeArrowFunc
 	hasLastRest := 0 < rests && p.assumeArrowFunc
 	p.assumeArrowFunc, p.in = prevAssumeArrowFunc, prevIn
@@ -2270,18 +3211,19 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 		// arrow function
 		arrowFunc.Async = isAsync
 		arrowFunc.Params = Params{List: make([]BindingElement, 0, len(args.List)-rests)}
-		for _, arg := range args.List {
+		for i, arg := range args.List {
 			if arg.Rest {
 				arrowFunc.Params.Rest = p.exprToBinding(arg.Value)
 			} else {
-				arrowFunc.Params.List = append(arrowFunc.Params.List, p.exprToBindingElement(arg.Value)) // can not fail when assumArrowFunc is set
+				arrowFunc.Params.List = append(arrowFunc.Params.List, p.exprToBindingElement(arg.Value, p.argLocs[argLocs+i])) // can not fail when assumArrowFunc is set
 			}
 		}
-		arrowFunc.Body.List = p.parseArrowFuncBody()
+		p.parseArrowFuncBody(&arrowFunc.Body)
 
 		p.await, p.yield = prevAwait, prevYield
 		p.exitScope(parent)
 
+		arrowFunc.Loc = p.loc(start)
 		left = arrowFunc
 		precLeft = OpAssign
 	} else if !isAsync && (len(args.List) == 0 || hasLastRest) {
@@ -2298,8 +3240,8 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 
 		if isAsync {
 			// call expression
-			left = p.scope.Use(async)
-			left = &CallExpr{left, args, OpCall, false}
+			left = p.use(async, asyncLoc)
+			left = &CallExpr{left, args, OpCall, false, p.loc(start)}
 			precLeft = OpCall
 		} else {
 			// parenthesized expression
@@ -2308,23 +3250,32 @@ func (p *Parser) parseParenthesizedExpression(prec OpPrec, async []byte) IExpr {
 				for _, arg := range args.List {
 					commaExpr.List = append(commaExpr.List, arg.Value)
 				}
-				left = &GroupExpr{commaExpr}
+				commaExpr.Loc = Loc{argsStart, argsEnd}
+				left = &GroupExpr{commaExpr, p.loc(start)}
 			} else {
-				left = &GroupExpr{args.List[0].Value}
+				left = &GroupExpr{args.List[0].Value, p.loc(start)}
 			}
 		}
 	}
-	return p.parseExpressionSuffix(left, prec, precLeft)
+	return p.parseExpressionSuffix(left, start, prec, precLeft)
 }
 
 // exprToBindingElement and exprToBinding convert a CoverParenthesizedExpressionAndArrowParameterList into FormalParameters.
-// Any unbound variables of the parameters (Initializer, ComputedPropertyName) are kept in the parent scope
-func (p *Parser) exprToBindingElement(expr IExpr) (bindingElement BindingElement) {
+// Any unbound variables of the parameters (Initializer, ComputedPropertyName) are kept in the parent scope. The location of expr is used for identifiers, which do not keep their location, and may be unset.
+func (p *Parser) exprToBindingElement(expr IExpr, loc Loc) (bindingElement BindingElement) {
 	if assign, ok := expr.(*BinaryExpr); ok && assign.Op == EqToken {
 		bindingElement.Binding = p.exprToBinding(assign.X)
 		bindingElement.Default = assign.Y
+		bindingElement.Loc = assign.Loc
 	} else {
 		bindingElement.Binding = p.exprToBinding(expr)
+		if v, ok := expr.(*Var); ok && loc.IsSet() {
+			bindingElement.Loc = loc
+		} else if ok && 0 < len(v.Locs) {
+			bindingElement.Loc = v.Locs[0] // the declaration
+		} else {
+			bindingElement.Loc = NodeLoc(expr)
+		}
 	}
 	return
 }
@@ -2343,9 +3294,10 @@ func (p *Parser) exprToBinding(expr IExpr) (binding IBinding) {
 				break
 			}
 			var bindingElement BindingElement
-			bindingElement = p.exprToBindingElement(item.Value)
+			bindingElement = p.exprToBindingElement(item.Value, Loc{})
 			bindingArray.List = append(bindingArray.List, bindingElement)
 		}
+		bindingArray.Loc = array.Loc
 		binding = &bindingArray
 	} else if object, ok := expr.(*ObjectExpr); ok {
 		bindingObject := BindingObject{}
@@ -2356,13 +3308,21 @@ func (p *Parser) exprToBinding(expr IExpr) (binding IBinding) {
 				break
 			}
 
-			bindingElement := p.exprToBindingElement(item.Value)
+			var loc Loc
+			if item.Name == nil {
+				loc = item.Loc // shorthand property
+			}
+			bindingElement := p.exprToBindingElement(item.Value, loc)
 			if v, ok := item.Value.(*Var); item.Name == nil || (ok && item.Name.IsIdent(v.Data)) {
 				// IdentifierReference : Initializer
 				bindingElement.Default = item.Init
 			}
+			if bindingElement.Default != nil {
+				bindingElement.Loc = item.Loc
+			}
 			bindingObject.List = append(bindingObject.List, BindingObjectItem{Key: item.Name, Value: bindingElement})
 		}
+		bindingObject.Loc = object.Loc
 		binding = &bindingObject
 	} else {
 		p.failMessage("invalid parameters in arrow function")
@@ -2370,6 +3330,221 @@ func (p *Parser) exprToBinding(expr IExpr) (binding IBinding) {
 	return
 }
 
+// parseJSXElement parses a JSX element or fragment that starts at the < at offset start. It returns with the closing > as the current token, so that the caller can continue lexing in the right context.
+func (p *Parser) parseJSXElement(start int) IExpr {
+	// assume we're at the token after <
+	if p.tt == GtToken {
+		fragment := &JSXFragment{}
+		fragment.Children = p.parseJSXChildren()
+		if p.tt != GtToken {
+			if p.tt == JSXIdentifierToken {
+				p.failMessage("expected closing tag for JSX fragment")
+			} else {
+				p.fail("JSX fragment", GtToken)
+			}
+			return nil
+		}
+		fragment.Loc = Loc{start, p.end}
+		return fragment
+	}
+
+	element := &JSXElement{}
+	nameStart := p.start
+	element.Name = p.parseJSXElementName()
+	if element.Name == nil {
+		return nil
+	}
+	name := p.l.r.Bytes()[nameStart:p.prevEnd]
+	for p.tt != GtToken && p.tt != DivToken {
+		attrStart := p.start
+		if p.tt == OpenBraceToken {
+			// spread attribute
+			p.next()
+			if !p.consume("JSX spread attribute", EllipsisToken) {
+				return nil
+			}
+			value := p.parseExpression(OpAssign)
+			if p.tt != CloseBraceToken {
+				p.fail("JSX spread attribute", CloseBraceToken)
+				return nil
+			}
+			p.nextJSXTag()
+			element.Attrs = append(element.Attrs, JSXAttribute{nil, value, true, p.loc(attrStart)})
+			continue
+		} else if p.tt != JSXIdentifierToken {
+			p.fail("JSX element", JSXIdentifierToken, OpenBraceToken, GtToken, DivToken)
+			return nil
+		}
+
+		attr := JSXAttribute{Name: p.data}
+		p.nextJSXTag()
+		if p.tt == ColonToken {
+			p.nextJSXTag()
+			if p.tt != JSXIdentifierToken {
+				p.fail("JSX attribute", JSXIdentifierToken)
+				return nil
+			}
+			attr.Name = append(append(append([]byte{}, attr.Name...), ':'), p.data...)
+			p.nextJSXTag()
+		}
+		if p.tt == EqToken {
+			p.nextJSXTag()
+			valueStart := p.start
+			if p.tt == JSXStringToken {
+				attr.Value = &LiteralExpr{JSXStringToken, p.data, Loc{p.start, p.end}}
+			} else if p.tt == OpenBraceToken {
+				p.next()
+				if p.tt == CloseBraceToken {
+					p.failMessage("unexpected empty expression in JSX attribute")
+					return nil
+				}
+				x := p.parseExpression(OpAssign)
+				if p.tt != CloseBraceToken {
+					p.fail("JSX attribute", CloseBraceToken)
+					return nil
+				}
+				attr.Value = &JSXExprContainer{x, false, Loc{valueStart, p.end}}
+			} else if p.tt == LtToken {
+				p.nextJSXTag()
+				if attr.Value = p.parseJSXElement(valueStart); attr.Value == nil {
+					return nil
+				}
+			} else {
+				p.fail("JSX attribute", JSXStringToken, OpenBraceToken, LtToken)
+				return nil
+			}
+			p.nextJSXTag()
+		}
+		attr.Loc = p.loc(attrStart)
+		element.Attrs = append(element.Attrs, attr)
+	}
+
+	if p.tt == DivToken {
+		// self-closing element
+		p.nextJSXTag()
+		if p.tt != GtToken {
+			p.fail("JSX element", GtToken)
+			return nil
+		}
+		element.Loc = Loc{start, p.end}
+		return element
+	}
+
+	element.Children = p.parseJSXChildren()
+	if p.tt == GtToken {
+		p.failMessage("expected closing tag for JSX element %s", name)
+		return nil
+	} else if p.tt != JSXIdentifierToken {
+		p.fail("JSX closing tag", JSXIdentifierToken)
+		return nil
+	}
+	closingStart := p.start
+	if closingName := p.parseJSXElementName(); closingName == nil {
+		return nil
+	} else if closingName.String() != element.Name.String() {
+		p.failMessage("expected closing tag for JSX element %s instead of %s", name, p.l.r.Bytes()[closingStart:p.prevEnd])
+		return nil
+	} else if p.tt != GtToken {
+		p.fail("JSX closing tag", GtToken)
+		return nil
+	}
+	element.Loc = Loc{start, p.end}
+	return element
+}
+
+// parseJSXElementName parses the name of a JSX element, which is an intrinsic element name (possibly namespaced), an identifier of a component, or a member expression.
+func (p *Parser) parseJSXElementName() IExpr {
+	// assume we're at JSXIdentifier
+	if p.tt != JSXIdentifierToken {
+		p.fail("JSX element", JSXIdentifierToken)
+		return nil
+	}
+	start := p.start
+	name := p.data
+	p.nextJSXTag()
+	if p.tt == ColonToken {
+		// namespaced name
+		p.nextJSXTag()
+		if p.tt != JSXIdentifierToken {
+			p.fail("JSX element", JSXIdentifierToken)
+			return nil
+		}
+		name = append(append(append([]byte{}, name...), ':'), p.data...)
+		p.nextJSXTag()
+		return &LiteralExpr{JSXIdentifierToken, name, p.loc(start)}
+	} else if p.tt != DotToken && (bytes.IndexByte(name, '-') != -1 || 'a' <= name[0] && name[0] <= 'z' && !bytes.Equal(name, []byte("this"))) {
+		// intrinsic element, such as div
+		return &LiteralExpr{JSXIdentifierToken, name, p.loc(start)}
+	}
+
+	var left IExpr
+	if bytes.Equal(name, []byte("this")) {
+		left = &LiteralExpr{ThisToken, name, p.loc(start)}
+	} else if bytes.IndexByte(name, '-') == -1 {
+		left = p.use(name, p.loc(start))
+	} else {
+		p.failMessage("unexpected %s in JSX member expression", name)
+		return nil
+	}
+	for p.tt == DotToken {
+		p.nextJSXTag()
+		if p.tt != JSXIdentifierToken || bytes.IndexByte(p.data, '-') != -1 {
+			p.fail("JSX member expression", IdentifierToken)
+			return nil
+		}
+		left = &DotExpr{left, LiteralExpr{IdentifierToken, p.data, Loc{p.start, p.end}}, OpMember, false, Loc{start, p.end}}
+		p.nextJSXTag()
+	}
+	return left
+}
+
+// parseJSXChildren parses the children of a JSX element or fragment up to and including the name of the closing tag, which is the current token upon return.
+func (p *Parser) parseJSXChildren() (children []IExpr) {
+	// assume we're at the > of the opening tag
+	for {
+		p.nextJSXChild()
+		start := p.start
+		switch p.tt {
+		case JSXTextToken:
+			children = append(children, &LiteralExpr{JSXTextToken, p.data, Loc{p.start, p.end}})
+		case OpenBraceToken:
+			container := &JSXExprContainer{}
+			p.next()
+			if p.tt == EllipsisToken {
+				container.Spread = true
+				p.next()
+			}
+			if p.tt != CloseBraceToken {
+				container.X = p.parseExpression(OpAssign)
+			} else if container.Spread {
+				p.fail("JSX expression", IdentifierToken)
+				return
+			}
+			if p.tt != CloseBraceToken {
+				p.fail("JSX expression", CloseBraceToken)
+				return
+			}
+			container.Loc = Loc{start, p.end}
+			children = append(children, container)
+		case LtToken:
+			p.nextJSXTag()
+			if p.tt == DivToken {
+				// closing tag
+				p.nextJSXTag()
+				return
+			}
+			child := p.parseJSXElement(start)
+			if child == nil {
+				return
+			}
+			children = append(children, child)
+		default:
+			p.fail("JSX element")
+			return
+		}
+	}
+}
+
 func (p *Parser) isIdentifierReference(tt TokenType) bool {
 	return IsIdentifier(tt) || !p.yield && tt == YieldToken || !p.await && tt == AwaitToken
 }
@@ -1,6 +1,8 @@
 package js
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sort"
@@ -31,6 +33,16 @@ func TestParse(t *testing.T) {
 		{"var a = b;", "Decl(var Binding(a = b))"},
 		{"const a = b;", "Decl(const Binding(a = b))"},
 		{"let a = b;", "Decl(let Binding(a = b))"},
+		{"using a = b, c = d;", "Decl(using Binding(a = b) Binding(c = d))"},
+		{"await using a = b", "Decl(await using Binding(a = b))"},
+		{"async function f() { await using a = b }", "Decl(async function f Params() Stmt({ Decl(await using Binding(a = b)) }))"},
+		{"for (using a of b) {}", "Stmt(for Decl(using Binding(a)) of b Stmt({ }))"},
+		{"for (await using a of b) {}", "Stmt(for Decl(await using Binding(a)) of b Stmt({ }))"},
+		{"for (using a = b;;) {}", "Stmt(for Decl(using Binding(a = b)) ; ; Stmt({ }))"},
+		{"for (using of b) {}", "Stmt(for using of b Stmt({ }))"},
+		{"using\na = b", "Stmt(using) Stmt(a=b)"},
+		{"using = a; using[a]; using.a", "Stmt(using=a) Stmt(using[a]) Stmt(using.a)"},
+		{"async function f() { await using\na }", "Decl(async function f Params() Stmt({ Stmt(await using) Stmt(a) }))"},
 		{"let [a,b] = [1, 2];", "Decl(let Binding([ Binding(a), Binding(b) ] = [1, 2]))"},
 		{"let [a,[b,c]] = [1, [2, 3]];", "Decl(let Binding([ Binding(a), Binding([ Binding(b), Binding(c) ]) ] = [1, [2, 3]]))"},
 		{"let [,,c] = [1, 2, 3];", "Decl(let Binding([ Binding(), Binding(), Binding(c) ] = [1, 2, 3]))"},
@@ -124,6 +136,16 @@ func TestParse(t *testing.T) {
 		{"class A { field static get method(){ return 5 } }", "Decl(class A Field(field) Method(static get method Params() Stmt({ Stmt(return 5) })))"},
 		{"class A { static { this.field = 5 } }", "Decl(class A Static(Stmt({ Stmt((this.field)=5) })))"},
 		{"class A { get #a(){} set #a(x){} }", "Decl(class A Method(get #a Params() Stmt({ })) Method(set #a Params(Binding(x)) Stmt({ })))"},
+		{"@a class A {}", "Decl(@a class A)"},
+		{"@a.b @c(d) @(e) class A {}", "Decl(@(a.b) @(c(d)) @(e) class A)"},
+		{"x = @a class {}", "Stmt(x=Decl(@a class))"},
+		{"class A { @a m(){} @b static f }", "Decl(class A Method(@a m Params() Stmt({ })) Field(@b static f))"},
+		{"class A { @a.#b f; #b }", "Decl(class A Field(@(a.#b) f) Field(#b))"},
+		{"class A { accessor a; static accessor #b = 1; @c accessor [d] }", "Decl(class A Field(accessor a) Field(static accessor #b = 1) Field(@c accessor [d]))"},
+		{"class A { accessor; accessor(){} static accessor = 1; accessor\nb }", "Decl(class A Field(accessor) Method(accessor Params() Stmt({ })) Field(static accessor = 1) Field(accessor) Field(b))"},
+		{"@a export class A {}", "Stmt(export Decl(@a class A))"},
+		{"export @a class A {}", "Stmt(export Decl(@a class A))"},
+		{"export default @a class {}", "Stmt(export default Decl(@a class))"},
 		//{"class A { get get get(){} }", "Decl(class A Definition(get) Method(get get Params() Stmt({ })))"}, // doesn't look like this should be supported
 		{"`tmpl`", "Stmt(`tmpl`)"},
 		{"`tmpl${x}`", "Stmt(`tmpl${x}`)"},
@@ -136,6 +158,11 @@ func TestParse(t *testing.T) {
 		{`import yield, {yield} from "pkg"`, `Stmt(import yield , { yield } from "pkg")`},
 		{`import {yield,} from "pkg"`, `Stmt(import { yield , } from "pkg")`},
 		{`import {"abc'def" as a} from "pkg"`, `Stmt(import { "abc'def" as a } from "pkg")`},
+		{`import a from "pkg" with {type: "json"}`, `Stmt(import a from "pkg" with { type: "json" })`},
+		{`import "pkg" with {type: "json", "a": 'b',}`, `Stmt(import "pkg" with { type: "json" , "a": 'b' })`},
+		{`import "pkg" with {}`, `Stmt(import "pkg" with { })`},
+		{`export * from "pkg" with {type: "json"}`, `Stmt(export * from "pkg" with { type: "json" })`},
+		{`export {a} from "pkg" with {type: "json"}`, `Stmt(export { a } from "pkg" with { type: "json" })`},
 		{`export * from "pkg";`, `Stmt(export * from "pkg")`},
 		{`export * as for from "pkg"`, `Stmt(export * as for from "pkg")`},
 		{`export * as "abc'def" from "pkg"`, `Stmt(export * as "abc'def" from "pkg")`},
@@ -559,6 +586,14 @@ func TestParseError(t *testing.T) {
 		{"import {", "expected } instead of EOF in import statement"},
 		{"import {yield", "expected } instead of EOF in import statement"},
 		{"import {yield as", "expected Identifier instead of EOF in import statement"},
+		{`import a from "pkg" with`, "expected { instead of EOF in import statement"},
+		{`import a from "pkg" with {type}`, "expected : instead of } in import statement"},
+		{`import a from "pkg" with {type: json}`, "expected String instead of json in import statement"},
+		{`import a from "pkg" with {a: "b" c: "d"}`, "expected , or } instead of c in import statement"},
+		{`export * from "pkg" with {1: "a"}`, "expected Identifier or String instead of 1 in export statement"},
+		{"using a", "expected = instead of EOF in using declaration"},
+		{"using a = b c", "unexpected c in using declaration"},
+		{"for (using a in b) {}", "expected = instead of in in using declaration"},
 		{"import {yield,", "expected } instead of EOF in import statement"},
 		{"import yield", "expected from instead of EOF in import statement"},
 		{"import yield,", "expected * or { instead of EOF in import statement"},
@@ -723,7 +758,13 @@ func TestParseError(t *testing.T) {
 
 		// other
 		{"\x00", "unexpected 0x00"},
-		{"@", "unexpected @"},
+		{"@", "expected Identifier or ( instead of EOF in decorator"},
+		{"@a b", "expected class or export instead of b in decorator"},
+		{"@a.(b) class A {}", "expected Identifier instead of ( in decorator"},
+		{"@a export const b = 1", "expected class instead of const in export statement"},
+		{"@a export @b class A {}", "expected class instead of @ in export statement"},
+		{"class A { @a static {} }", "unexpected { in class static block"},
+		{"class A { accessor m() {} }", "unexpected ( in accessor field"},
 		{"\u200F", "unexpected U+200F"},
 		{"\u2010", "unexpected \u2010"},
 		{"a=\u2010", "unexpected \u2010 in expression"},
@@ -1101,6 +1142,417 @@ func TestScope(t *testing.T) {
 	test.T(t, ast.List[3].(*BlockStmt).Scope.String(), "Scope{Declared: [], Undeclared: [Var{NoDecl d 0 2}]}")
 	test.T(t, ast.List[4].(*BlockStmt).Scope.String(), "Scope{Declared: [], Undeclared: [Var{NoDecl d 0 2}]}")
 	test.T(t, ast.List[4].(*BlockStmt).List[0].(*BlockStmt).Scope.String(), "Scope{Declared: [], Undeclared: [Var{NoDecl d 1 2}]}")
+
+	// test parent of module scope
+	test.That(t, ast.List[3].(*BlockStmt).Scope.Parent == &ast.Scope, "parent must be the module scope")
+	test.That(t, ast.Scope.Func == &ast.Scope, "module scope must be a function scope")
+}
+
+type locWalker struct {
+	src  string
+	locs []string
+}
+
+func (w *locWalker) Enter(n INode) IVisitor {
+	if loc := NodeLoc(n); loc.IsSet() {
+		w.locs = append(w.locs, w.src[loc.Start:loc.End])
+	}
+	return w
+}
+
+func (w *locWalker) Exit(n INode) {}
+
+func TestParseJSX(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{`<div/>`, `<div/>;`},
+		{`<div></div>`, `<div/>;`},
+		{`<div className="a" id='b' hidden/>`, `<div className="a" id='b' hidden/>;`},
+		{`<a:b c:d="e"/>`, `<a:b c:d="e"/>;`},
+		{`<my-element data-x="1"/>`, `<my-element data-x="1"/>;`},
+		{`<Foo/>`, `<Foo/>;`},
+		{`<Foo.Bar.Baz/>`, `<Foo.Bar.Baz/>;`},
+		{`<this.Foo/>`, `<this.Foo/>;`},
+		{`<div {...props} key={i}/>`, `<div {...props} key={i}/>;`},
+		{`<div x=<b/>/>`, `<div x=<b/>/>;`},
+		{`<div>hello {name}!</div>`, `<div>hello {name}!</div>;`},
+		{`<div>{/* comment */}{...list}</div>`, `<div>{}{...list}</div>;`},
+		{`<div><span>a</span><br/></div>`, `<div><span>a</span><br/></div>;`},
+		{`<>a<b/></>`, `<>a<b/></>;`},
+		{`x = <div>{a ? <b/> : <c></c>}</div>`, `x = <div>{a ? <b/> : <c/>}</div>;`},
+		{`f(<a/>, <b/> / 2)`, `f(<a/>, <b/> / 2);`},
+		{`() => <div/>`, "() => {\n    return <div/>;\n};"},
+		{`a < b`, `a < b;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{JSX: true})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+
+	// JSX is disabled by default
+	_, err := Parse(parse.NewInputString("<div/>"), Options{})
+	test.That(t, err != nil, "must fail without JSX option")
+}
+
+func TestParseJSXError(t *testing.T) {
+	var tests = []struct {
+		js  string
+		err string
+	}{
+		{`<div`, "expected JSXIdentifier, {, >, or / instead of EOF in JSX element"},
+		{`<div x=5/>`, "unexpected 5 in JSX attribute"},
+		{`<div x={}/>`, "unexpected empty expression in JSX attribute"},
+		{`<div {a}/>`, "expected ... instead of a in JSX spread attribute"},
+		{`<div>`, "unexpected EOF in JSX element"},
+		{`<div></span>`, "expected closing tag for JSX element div instead of span"},
+		{`<A.B></A.C>`, "expected closing tag for JSX element A.B instead of A.C"},
+		{`<div><></div>`, "expected closing tag for JSX fragment"},
+		{`<div>a > b</div>`, "unexpected > in JSX text in JSX element"},
+		{`<div>}</div>`, "unexpected } in JSX text in JSX element"},
+		{`<div>{a b}</div>`, "expected } instead of b in JSX expression"},
+		{`<div x="a/>`, "unterminated string literal in JSX attribute"},
+		{`<a-b.c/>`, "unexpected a-b in JSX member expression"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			_, err := Parse(parse.NewInputString(tt.js), Options{JSX: true})
+			test.That(t, err != io.EOF && err != nil)
+
+			e := err.Error()
+			if len(tt.err) < len(err.Error()) {
+				e = e[:len(tt.err)]
+			}
+			test.String(t, e, tt.err)
+		})
+	}
+}
+
+func TestParseTypeScript(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{`let x: number = 5`, `let x = 5;`},
+		{`let x!: string`, `let x;`},
+		{`let x: Array<Map<string, number>>= []`, `let x = [];`},
+		{`let x: (a: number) => void`, `let x;`},
+		{`let x: typeof y[number] | keyof T & U`, `let x;`},
+		{`let x: T extends (infer U)[] ? U : never`, `let x;`},
+		{"let x: `a${string}` | import('a').B", `let x;`},
+		{`let x: [a: string, b?: number, ...c: any[]]`, `let x;`},
+		{`function f<T>(a: T, b?: number, ...c: string[]): T { return a }`, "function f(a, b, ...c) {\n    return a;\n}"},
+		{`function f(this: Foo, a) {}`, `function f(a) {}`},
+		{`function isA(x): x is A { return true }`, "function isA(x) {\n    return true;\n}"},
+		{`function f(a: number): void; function f(a) {}`, `function f(a) {}`},
+		{`let f = function<T>(x: T) {}`, `let f = function(x) {};`},
+		{`let f = <T,>(x: T): T => x`, "let f = (x) => {\n    return x;\n};"},
+		{`let f = (a: number, {b}: {b: string} = {b: ''}): void => {}`, `let f = (a, {b} = {b: ''}) => {};`},
+		{`let f = async (a?: number): Promise<void> => {}`, `let f = async (a) => {};`},
+		{`let f = async <T>(y: T) => y`, "let f = async (y) => {\n    return y;\n};"},
+		{`let f = async <T,>(y: T): Promise<T> => y`, "let f = async (y) => {\n    return y;\n};"},
+		{`async<T>(x)`, `async(x);`},
+		{`let o = { m<T>(x: T) {} }`, `let o = {m (x) {}};`},
+		{`try {} catch (e: unknown) {}`, `try {} catch(e) {}`},
+		{`interface A extends B { x: number; y(): void }`, ``},
+		{`type A<T> = T | { x: number }; let a`, `let a;`},
+		{`declare const x: number; let y`, `let y;`},
+		{`declare module 'foo' { export const x: number }`, ``},
+		{`x as any as T`, `x;`},
+		{`(x as any).y`, `(x).y;`},
+		{`let v = { a: 1 } as const`, `let v = {a: 1};`},
+		{`let y = x satisfies T`, `let y = x;`},
+		{`x!.y! + z`, `x.y + z;`},
+		{`let y = <any>x`, `let y = x;`},
+		{`f<T>(x)`, `f(x);`},
+		{`new Foo<T, U>()`, `new Foo();`},
+		{`a < b && c > d`, `a < b && c > d;`},
+		{`a ? (b) : c`, `a ? (b) : c;`},
+		{`x = a ? (b) : c => d`, "x = a ? (b) : (c) => {\n    return d;\n};"},
+		{`x = a ? (b): T => c : d`, "x = a ? (b) => {\n    return c;\n} : d;"},
+		{`x = a ? f((b): T => c) : d`, "x = a ? f((b) => {\n    return c;\n}) : d;"},
+		{`a?.<T>()`, `a?.();`},
+		{`for (const x of y as any[]) {}`, `for (const x of y) {}`},
+		{`enum E { A, B = 5, C }`, "var E = (function(E) {\n    E[E[\"A\"] = 0] = \"A\";\n    E[E[\"B\"] = 5] = \"B\";\n    E[E[\"C\"] = E[\"B\"] + 1] = \"C\";\n    return E;\n})(E || {});"},
+		{`enum E { A = 'a' }`, "var E = (function(E) {\n    E[\"A\"] = 'a';\n    return E;\n})(E || {});"},
+		{`enum E { A = "a", B = A, C = E.B + 1 }`, "var E = (function(E) {\n    E[\"A\"] = \"a\";\n    E[\"B\"] = E.A;\n    E[\"C\"] = E.B + 1;\n    return E;\n})(E || {});"},
+		{`enum E { A = 1, B = A << 1 }`, "var E = (function(E) {\n    E[E[\"A\"] = 1] = \"A\";\n    E[E[\"B\"] = E.A << 1] = \"B\";\n    return E;\n})(E || {});"},
+		{`export const enum E { A }`, "export var E = (function(E) {\n    E[E[\"A\"] = 0] = \"A\";\n    return E;\n})(E || {});"},
+		{`abstract class A { abstract f(): void; g() {} }`, "class A {\n    g () {}\n}"},
+		{`class A<T> extends B<T> implements C, D<T> { private x: number = 5; readonly y?: string; z!: number; declare q: number; [k: string]: any; m<U>(x: U): U {} n(): void; n(x?) {} }`, "class A extends B {\n    x = 5;\n    y;\n    z;\n    m (x) {}\n    n (x) {}\n}"},
+		{`class A extends B { constructor(public a, private readonly b = 2) { super(); f() } }`, "class A extends B {\n    constructor (a, b = 2) {\n        super();\n        this.a = a;\n        this.b = b;\n        f();\n    }\n}"},
+		{`import type { A } from 'a'`, ``},
+		{`import { A, type B, C } from 'a'; C(); let x: A`, "import { C } from 'a';\nC();\nlet x;"},
+		{`import A, { type B } from "a"; A()`, "import A from \"a\";\nA();"},
+		{`import { A } from 'a'; export { A }`, "import { A } from 'a';\nexport { A };"},
+		{`export type { A } from 'a'`, ``},
+		{`interface A {} export { A }`, ``},
+		{`export {}`, `export {};`},
+		{`export default interface A {}`, ``},
+		{`export declare const x: number`, ``},
+		{`export abstract class A {}`, `export class A {};`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{TypeScript: true})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+
+	// TSX
+	ast, err := Parse(parse.NewInputString(`let f = <T,>(x: T) => <div>{x as any}</div>`), Options{TypeScript: true, JSX: true})
+	if err != io.EOF {
+		test.Error(t, err)
+	}
+	test.String(t, ast.JSString(), "let f = (x) => {\n    return <div>{x}</div>;\n};")
+}
+
+func TestParseTypeScriptError(t *testing.T) {
+	var tests = []struct {
+		js  string
+		err string
+	}{
+		{`let x: = 5`, "unexpected = in type"},
+		{`let x: A<B`, "expected > instead of EOF in type arguments"},
+		{`namespace A {}`, "TypeScript namespaces are not supported"},
+		{`enum E { A`, "expected } instead of EOF in enum declaration"},
+		{`let x = a as T < b`, "expected > instead of EOF in type arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			_, err := Parse(parse.NewInputString(tt.js), Options{TypeScript: true})
+			test.That(t, err != io.EOF && err != nil)
+
+			e := err.Error()
+			if len(tt.err) < len(err.Error()) {
+				e = e[:len(tt.err)]
+			}
+			test.String(t, e, tt.err)
+		})
+	}
+}
+
+func TestParseTolerant(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+		errs     []string
+	}{
+		{"a = 1; b = 2", "Stmt(a=1) Stmt(b=2)", nil},
+		{"a = ; b = 1", "Stmt(bad) Stmt(b=1)", []string{"unexpected ; in expression"}},
+		{"let x = 5\nlet y = )\nlet z = 3", "Decl(let Binding(x = 5)) Stmt(bad) Decl(let Binding(z = 3))", []string{"unexpected ) in expression"}},
+		{"a = ); b = (; c = 1", "Stmt(bad) Stmt(bad) Stmt(c=1)", []string{"unexpected ) in expression", "unexpected ; in expression"}},
+		{"function f() { a = ; return 1 }\nf()", "Decl(function f Params() Stmt({ Stmt(bad) Stmt(return 1) })) Stmt(f())", []string{"unexpected ; in expression"}},
+		{"function f() { let x = 1", "Decl(function f Params() Stmt({ Decl(let Binding(x = 1)) }))", []string{"unexpected EOF"}},
+		{"if (a) { b( }\nc()", "Stmt(if a Stmt({ Stmt(bad) })) Stmt(c())", []string{"unexpected } in expression"}},
+		{"}\nx()", "Stmt(bad) Stmt(x())", []string{"unexpected } in expression"}},
+		{"a = (1 +\n", "Stmt(bad)", []string{"unexpected EOF in expression"}},
+		{"x = 1; \\\ny", "Stmt(x=1) Stmt(bad) Stmt(y)", []string{"unexpected \\ in statement"}},
+		{"x = 1; @1\ny", "Stmt(x=1) Stmt(bad) Stmt(y)", []string{"expected Identifier or ( instead of 1 in decorator"}},
+		{"import { from 'x'\nlet a = 1", "Stmt(bad) Decl(let Binding(a = 1))", []string{"expected Identifier or String instead of let in import statement"}},
+		{"a = 1 +\nlet b = 2", "Stmt(bad) Decl(let Binding(b = 2))", []string{"unexpected b in expression"}},
+		{"a = 1 +\nasync function f(){} g()", "Stmt(bad) Decl(async function f Params() Stmt({ })) Stmt(g())", []string{"unexpected g in expression"}},
+		{"a = )\nasync function f(){}", "Stmt(bad) Decl(async function f Params() Stmt({ }))", []string{"unexpected ) in expression"}},
+		{"a = )\nlet = 1", "Stmt(bad) Stmt(let=1)", []string{"unexpected ) in expression"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{Tolerant: true})
+			test.String(t, ast.String(), tt.expected)
+
+			errs := []string{}
+			if err != nil {
+				for _, err := range err.(ErrorList) {
+					errs = append(errs, err.Message)
+				}
+			}
+			test.T(t, len(errs), len(tt.errs), "number of errors")
+			for i := range tt.errs {
+				if i < len(errs) {
+					test.String(t, errs[i], tt.errs[i])
+				}
+			}
+		})
+	}
+
+	// bad statements keep the source and position
+	ast, _ := Parse(parse.NewInputString("a = 1\nb = );\nc = 2"), Options{Tolerant: true})
+	bad, ok := ast.List[1].(*BadStmt)
+	test.That(t, ok, "must be BadStmt")
+	test.String(t, string(bad.Value), "b = );")
+	test.T(t, bad.Loc, Loc{6, 12})
+	test.String(t, ast.JSString(), "a = 1;\nb = );\nc = 2;")
+
+	// bad statements at unterminated tokens extend to the end of the input
+	for _, src := range []string{"x = /re", "x = 'abc", "a(\nx = `b"} {
+		ast, _ = Parse(parse.NewInputString("y = 1;"+src), Options{Tolerant: true})
+		bad, ok = ast.List[1].(*BadStmt)
+		test.That(t, ok, "must be BadStmt")
+		test.String(t, string(bad.Value), src)
+		test.T(t, bad.Loc, Loc{6, 6 + len(src)})
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"/** doc */\nfunction f() {}", "/** doc */\nfunction f() {}"},
+		{"/** doc */ function f() {} // f\nlet a = 1; // a\n// b1\n/* b2 */\nvar b", "/** doc */\nfunction f() {} // f\nlet a = 1; // a\n// b1\n/* b2 */\nvar b;"},
+		{"a(); // a\nlet x", "a();\n// a\nlet x;"},
+		{"function f(){} /* after */ g()", "function f() {}\n/* after */\ng();"},
+		{"let a = 1\n// end", "let a = 1;\n// end"},
+		{"function f() {\n  a() // a\n  // end\n}", "function f() {\n    a();\n    // a\n    // end\n}"},
+		{"export /** e */ const y = 2", "/** e */\nexport const y = 2;"},
+		{"export default /** d */ function f() {}", "/** d */\nexport default function f() {};"},
+		{"/** doc */\nexport function f() {} // f", "/** doc */\nexport function f() {}; // f"},
+		{"// A\nclass A {\n  /** m */\n  m() {} // m\n  // n\n  static n() {}\n}", "// A\nclass A {\n    /** m */\n    m () {} // m\n    // n\n    static n () {}\n}"},
+		{"x = {\n  a: 1, // a\n  /* b */ b: 2 // b\n}", "x = {a: 1, // a\n/* b */ b: 2 // b\n};"},
+		{"function f() {\n  // x\n  let x = 1 // x\n}", "function f() {\n    // x\n    let x = 1; // x\n}"},
+		{"/*! bang */\nlet a", "/*! bang */\nlet a;"},
+		{"class A {\n  /** f */ x = 1; // x\n  // y\n  static y\n}", "class A {\n    /** f */\n    x = 1; // x\n    // y\n    static y;\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{Comments: true})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+
+	ast, err := Parse(parse.NewInputString("/** a */\n// b\nfunction f() {} /* c */\n"), Options{Comments: true})
+	test.Error(t, err)
+	funcDecl := ast.List[0].(*FuncDecl)
+	test.T(t, len(funcDecl.Comments.Leading), 2)
+	test.String(t, string(funcDecl.Comments.Leading[0].Value), "/** a */")
+	test.T(t, funcDecl.Comments.Leading[0].Loc, Loc{0, 8})
+	test.String(t, string(funcDecl.Comments.Leading[1].Value), "// b")
+	test.T(t, len(funcDecl.Comments.Trailing), 1)
+	test.String(t, string(funcDecl.Comments.Trailing[0].Value), "/* c */")
+
+	// comments after export belong to the declaration
+	ast, err = Parse(parse.NewInputString("/** a */ export /** b */ const c = 1"), Options{Comments: true})
+	test.Error(t, err)
+	varDecl := ast.List[0].(*ExportStmt).Decl.(*VarDecl)
+	test.T(t, len(varDecl.Comments.Leading), 2)
+	test.String(t, string(varDecl.Comments.Leading[1].Value), "/** b */")
+
+	// comments are not attached by default
+	ast, err = Parse(parse.NewInputString("/** a */\nfunction f() {}"), Options{})
+	test.Error(t, err)
+	test.T(t, len(ast.List[0].(*FuncDecl).Comments.Leading), 0)
+}
+
+func TestParseLoc(t *testing.T) {
+	// the source of all nodes that have a location, in the order of Walk
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"a = b + 1 ;", "a = b + 1 ; | a = b + 1 | b + 1 | 1"},
+		{"if (a) { b() } else c", "if (a) { b() } else c | { b() } | b() | b() | c"},
+		{"do x; while (y)", "do x; while (y) | x;"},
+		{"l: for (;;) break l", "l: for (;;) break l | for (;;) break l | break l | break l"},
+		{"for (let i = 0; i < 10; i++) x;", "for (let i = 0; i < 10; i++) x; | x; | x; | let i = 0 | i = 0 | 0 | i < 10 | 10 | i++"},
+		{"for (const k of o) {}", "for (const k of o) {} | {} | const k | k"},
+		{"switch (a) { case 1: b; default: c }", "switch (a) { case 1: b; default: c } | case 1: b; | b; | 1 | default: c | c"},
+		{"try { a } catch (e) { b } finally { c }", "try { a } catch (e) { b } finally { c } | { a } | a | { b } | b | { c } | c"},
+		{"let [x, y = 2] = z, {p, q: r} = t", "let [x, y = 2] = z, {p, q: r} = t | [x, y = 2] = z | [x, y = 2] | x | y = 2 | 2 | {p, q: r} = t | {p, q: r} | p | p | q | r"},
+		{"async function f(a) { return await a }", "async function f(a) { return await a } | { return await a } | return await a | await a | a"},
+		{"(a, b) => a; async x => x; (a, b)", "(a, b) => a; | (a, b) => a | a | a | a | b | async x => x; | async x => x | x | x | x | (a, b) | (a, b) | a, b"},
+		{"async(a)", "async(a) | async(a)"},
+		{"class C { static x = 1; m() {} }", "class C { static x = 1; m() {} } | static x = 1 | 1 | m() {} | {}"},
+		{"o = {a, b: 1, get c() {}, ...d}", "o = {a, b: 1, get c() {}, ...d} | o = {a, b: 1, get c() {}, ...d} | {a, b: 1, get c() {}, ...d} | a | a | b: 1 | b | 1 | get c() {} | get c() {} | {} | ...d"},
+		{"x?.y[0]?.(1)`t`", "x?.y[0]?.(1)`t` | x?.y[0]?.(1)`t` | x?.y[0]?.(1) | 1 | x?.y[0] | x?.y | y | 0"},
+		{"new A(1); new.target; import.meta", "new A(1); | new A(1) | 1 | new.target; | new.target | import.meta | import.meta"},
+		{"/re/g.test(s)", "/re/g.test(s) | /re/g.test(s) | /re/g.test | /re/g | test"},
+		{"a ? b : c, -d", "a ? b : c, -d | a ? b : c, -d | a ? b : c | -d"},
+		{"import x from 'm'; export const w = 1 /*! c */", "/*! c */ | import x from 'm'; | export const w = 1 | const w = 1 | w = 1 | 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil {
+				test.Error(t, err)
+			}
+			w := &locWalker{src: tt.js}
+			for _, item := range ast.List {
+				Walk(w, item)
+			}
+			test.String(t, strings.Join(w.locs, " | "), tt.expected)
+		})
+	}
+}
+
+func TestParseVarLocs(t *testing.T) {
+	// the start offsets of all declarations and uses of the variables in the global scope
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"a; a", "a=0,3"},
+		{"var a = b; {a}", "a=4,12 b=8"},
+		{"{a} {var a}", "a=1,9"},
+		{"{a} a", "a=1,4"},
+		{"function f(b) { b; a } a", "f=9 a=19,23"},
+		{"(a) + (a)", "a=1,7"},
+		{"var a; a => a; a", "a=4,15"},
+		{"x => x; x", "x=8"},
+		{"async(a)", "a=6 async=0"},
+		{"({a, b: c})", "a=2 c=8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			if err != nil {
+				test.Error(t, err)
+			}
+			vars := []string{}
+			for _, v := range append(ast.Scope.Declared, ast.Scope.Undeclared...) {
+				offsets := []string{}
+				for _, loc := range v.Locs {
+					test.String(t, tt.js[loc.Start:loc.End], string(v.Data))
+					offsets = append(offsets, fmt.Sprintf("%d", loc.Start))
+				}
+				vars = append(vars, string(v.Data)+"="+strings.Join(offsets, ","))
+			}
+			test.String(t, strings.Join(vars, " "), tt.expected)
+		})
+	}
+}
+
+func TestLocPosition(t *testing.T) {
+	js := "a = 1;\nif (b) {\n\tc()\n}"
+	ast, err := Parse(parse.NewInputString(js), Options{})
+	if err != nil {
+		test.Error(t, err)
+	}
+
+	ifStmt := ast.List[1].(*IfStmt)
+	line, col, _ := ifStmt.Position(strings.NewReader(js))
+	test.T(t, line, 2, "line")
+	test.T(t, col, 1, "column")
+	line, col, _ = ifStmt.EndPosition(strings.NewReader(js))
+	test.T(t, line, 4, "line")
+	test.T(t, col, 2, "column")
+
+	call := ifStmt.Body.(*BlockStmt).List[0].(*ExprStmt).Value
+	line, col, _ = NodeLoc(call).Position(strings.NewReader(js))
+	test.T(t, line, 3, "line")
+	test.T(t, col, 2, "column")
+	test.T(t, NodeLoc(&Var{}), Loc{})
 }
 
 func TestParseInputError(t *testing.T) {
@@ -1110,3 +1562,52 @@ func TestParseInputError(t *testing.T) {
 	_, err = Parse(parse.NewInput(test.NewErrorReader(1)), Options{})
 	test.T(t, err, test.ErrPlain)
 }
+
+func TestParseLimit(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var tests = []struct {
+		js    string
+		o     Options
+		limit string
+		err   string
+	}{
+		{"{{{a}}}", Options{NestedStmtLimit: 2}, "NestedStmtLimit", "too many nested statements"},
+		{"(((a)))", Options{NestedExprLimit: 2}, "NestedExprLimit", "too many nested expressions"},
+		{"a = b + c", Options{MaxTokens: 4}, "MaxTokens", "too many tokens"},
+		{"let [a, b] = c", Options{MaxNodes: 3}, "MaxNodes", "too many nodes"},
+		{"a = 'abc'; b", Options{MaxBytes: 8}, "MaxBytes", "source too large"},
+		{"a = '" + strings.Repeat("b", 1000) + "'", Options{MaxBytes: 8}, "MaxBytes", "source too large"},
+		{"a = 1", Options{Context: canceled}, "Context", "parsing stopped: context canceled"},
+		{"a = ; b = b + c", Options{Tolerant: true, MaxTokens: 6}, "MaxTokens", "too many tokens"},
+		{"let x = <T,>(a: T) => a + b", Options{TypeScript: true, MaxTokens: 8}, "MaxTokens", "too many tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), tt.o)
+			test.That(t, ast == nil, "must not return an AST")
+			limitErr, ok := err.(*LimitError)
+			test.That(t, ok, "must return LimitError")
+			if ok {
+				test.String(t, limitErr.Limit, tt.limit)
+				test.That(t, strings.HasPrefix(limitErr.Error(), tt.err), limitErr.Error())
+			}
+		})
+	}
+
+	_, err := Parse(parse.NewInputString("a = 1"), Options{Context: canceled})
+	test.That(t, errors.Is(err, context.Canceled), "must wrap context.Canceled")
+
+	// the context is checked while skipping whitespace and comments
+	_, err = Parse(parse.NewInputString(strings.Repeat("/**/\n", 2048)+"a"), Options{Context: canceled})
+	test.That(t, errors.Is(err, context.Canceled), "must wrap context.Canceled")
+	line, _, _ := err.(*LimitError).Position()
+	test.T(t, line, 1)
+
+	// limits are per parse
+	_, err = Parse(parse.NewInputString("{{{a}}}"), Options{NestedStmtLimit: 4})
+	test.Error(t, err)
+	_, err = Parse(parse.NewInputString("let [a, b] = c + d"), Options{MaxTokens: 11, MaxNodes: 6, MaxBytes: 18}) // EOF is a token
+	test.Error(t, err)
+}
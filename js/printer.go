@@ -0,0 +1,1093 @@
+package js
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/tdewolff/parse/v2"
+)
+
+// ErrInvalidQuote is returned by Print when PrintOptions.Quote is not a quote character.
+var ErrInvalidQuote = errors.New("invalid quote character")
+
+// PrintOptions are the options for Print.
+type PrintOptions struct {
+	Indent         int  // number of spaces per indentation level, defaults to 4
+	OmitSemicolons bool // omit semicolons at the end of lines where automatic semicolon insertion allows it
+	Quote          byte // quote character of string literals, either ' or ", or 0 to keep the original quotes
+	LineWidth      int  // line width after which arguments, parameters, arrays, and objects are broken over multiple lines, 0 for no limit
+
+	SourceMap *SourceMapBuilder // adds the mappings of the output to the source map when set
+}
+
+// Print writes a node, usually an *AST, as formatted JavaScript to the writer. Blocks are indented using a parse.Indenter, and it returns the first error of the writer.
+func Print(w io.Writer, n INode, o PrintOptions) error {
+	if o.Indent <= 0 {
+		o.Indent = 4
+	}
+	if o.Quote != 0 && o.Quote != '"' && o.Quote != '\'' {
+		return ErrInvalidQuote
+	}
+	p := newPrinter(w, o)
+	switch n := n.(type) {
+	case *AST:
+		p.printStmts(n.List, false)
+	case IStmt:
+		p.printStmt(n)
+	case IExpr:
+		p.printExpr(n)
+	case IBinding:
+		p.printBinding(n)
+	default:
+		n.JS(p.w)
+	}
+	return p.lw.err
+}
+
+// lineWriter keeps track of the current line and column in UTF-16 code units, and the first write error.
+type lineWriter struct {
+	w         io.Writer
+	line, col int
+	err       error
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if i := bytes.LastIndexByte(b, '\n'); i != -1 {
+		w.line += bytes.Count(b[:i+1], []byte("\n"))
+		w.col = utf16Len(b[i+1:])
+	} else {
+		w.col += utf16Len(b)
+	}
+	n, err := w.w.Write(b)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+type printer struct {
+	o    PrintOptions
+	w    io.Writer // lw or a parse.Indenter wrapping lw
+	lw   *lineWriter
+	semi bool // semicolon is pending, it is dropped before a newline when semicolons are omitted
+
+	lossless *losslessWriter // writes unchanged nodes as in the source, see PrintLossless
+}
+
+func newPrinter(w io.Writer, o PrintOptions) *printer {
+	lw := &lineWriter{w: w}
+	return &printer{
+		o:  o,
+		w:  lw,
+		lw: lw,
+	}
+}
+
+func (p *printer) flush(b []byte) {
+	if p.semi {
+		p.semi = false
+		if len(b) == 0 || b[0] != '\n' {
+			p.w.Write([]byte(";"))
+		}
+	}
+}
+
+func (p *printer) write(s string) {
+	p.flush([]byte(s))
+	p.w.Write([]byte(s))
+}
+
+func (p *printer) writeBytes(b []byte) {
+	p.flush(b)
+	p.w.Write(b)
+}
+
+// writeRaw writes without indenting after newlines, used for literals that may contain newlines.
+func (p *printer) writeRaw(b []byte) {
+	p.flush(b)
+	p.lw.Write(b)
+}
+
+// semicolon ends a statement.
+func (p *printer) semicolon() {
+	if p.o.OmitSemicolons {
+		p.semi = true
+	} else {
+		p.write(";")
+	}
+}
+
+// mark adds a mapping from the current position to the start of the node to the source map.
+func (p *printer) mark(n INode) {
+	if p.o.SourceMap != nil {
+		if loc := NodeLoc(n); loc.Start < loc.End {
+			p.flush(nil)
+			p.o.SourceMap.add(p.lw.line, p.lw.col, loc.Start, nil)
+		}
+	}
+}
+
+// markVar adds a mapping from the current position to the variable to the source map.
+func (p *printer) markVar(v *Var) {
+	if p.o.SourceMap != nil {
+		p.flush(nil)
+		p.o.SourceMap.addVar(p.lw.line, p.lw.col, v)
+	}
+}
+
+// indent increases the indentation and returns the previous writer to restore afterwards.
+func (p *printer) indent() io.Writer {
+	w := p.w
+	p.w = parse.NewIndenter(p.w, p.o.Indent)
+	return w
+}
+
+// fits returns true if the first line written by f fits within the line width from the current column.
+func (p *printer) fits(f func(*printer)) bool {
+	if p.o.LineWidth <= 0 {
+		return true
+	}
+	buf := &bytes.Buffer{}
+	o := p.o
+	o.LineWidth = 0
+	o.SourceMap = nil
+	f(newPrinter(buf, o))
+	line := buf.Bytes()
+	if i := bytes.IndexByte(line, '\n'); i != -1 {
+		line = line[:i]
+	}
+	return p.lw.col+utf16Len(line) <= p.o.LineWidth
+}
+
+// printList prints n items separated by commas on a single line, or on separate lines when exceeding the line width.
+func (p *printer) printList(open, close string, n int, breakable bool, item func(*printer, int)) {
+	inline := func(q *printer) {
+		q.write(open)
+		for i := 0; i < n; i++ {
+			if i != 0 {
+				q.write(", ")
+			}
+			item(q, i)
+		}
+		q.write(close)
+	}
+	if n == 0 || !breakable || p.fits(inline) {
+		inline(p)
+		return
+	}
+	p.write(open)
+	w := p.indent()
+	for i := 0; i < n; i++ {
+		p.write("\n")
+		item(p, i)
+		if i+1 < n {
+			p.write(",")
+		}
+	}
+	p.w = w
+	p.write("\n" + close)
+}
+
+// printArray prints an array or array binding, where a trailing elision requires an extra comma.
+func (p *printer) printArray(n int, elision bool, item func(*printer, int)) {
+	if !elision {
+		p.printList("[", "]", n, true, item)
+		return
+	}
+	p.write("[")
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			p.write(", ")
+		}
+		item(p, i)
+	}
+	p.write(",]")
+}
+
+func (p *printer) printLeadingComments(n INode) {
+	if comments := nodeComments(n); comments != nil {
+		for _, comment := range comments.Leading {
+			p.writeRaw(comment.Value)
+			p.write("\n")
+		}
+	}
+}
+
+func (p *printer) printTrailingComments(n INode) {
+	if comments := nodeComments(n); comments != nil {
+		for _, comment := range comments.Trailing {
+			p.semi = false
+			p.write(" ")
+			p.writeRaw(comment.Value)
+		}
+	}
+}
+
+func hasComments(n INode) bool {
+	comments := nodeComments(n)
+	return comments != nil && (0 < len(comments.Leading) || 0 < len(comments.Trailing))
+}
+
+func (p *printer) printStmts(list []IStmt, block bool) {
+	for i, item := range list {
+		if block || i != 0 {
+			p.write("\n")
+		}
+		p.printLeadingComments(item)
+		if p.o.OmitSemicolons {
+			if exprStmt, ok := item.(*ExprStmt); ok && startsWithHazard(exprStmt.Value) {
+				p.write(";")
+			}
+		}
+		p.printStmt(item)
+		p.printTrailingComments(item)
+	}
+}
+
+func (p *printer) printBlock(list []IStmt) {
+	if len(list) == 0 {
+		p.write("{}")
+		return
+	}
+	p.write("{")
+	w := p.indent()
+	p.printStmts(list, true)
+	p.w = w
+	p.write("\n}")
+}
+
+// printBody prints the body of an if, while, or with statement on the same line.
+func (p *printer) printBody(stmt IStmt) {
+	if _, ok := stmt.(*EmptyStmt); ok {
+		p.write(";")
+		return
+	}
+	p.write(" ")
+	p.printStmt(stmt)
+}
+
+func (p *printer) printStmt(stmt IStmt) {
+	p.mark(stmt)
+	if p.lossless != nil && p.lossless.node(p, stmt) {
+		if p.lossless.needsSemicolon(stmt) {
+			p.semicolon()
+		}
+		return
+	}
+	switch n := stmt.(type) {
+	case *Comment:
+		p.writeRaw(n.Value)
+	case *BlockStmt:
+		p.printBlock(n.List)
+	case *EmptyStmt:
+		p.write(";")
+	case *BadStmt:
+		p.writeRaw(n.Value)
+	case *ExprStmt:
+		group := false
+		switch x := leftmostExpr(n.Value).(type) {
+		case *Var:
+			group = bytes.Equal(x.Data, []byte("let"))
+		case *ObjectExpr, *FuncDecl, *ClassDecl:
+			group = true
+		}
+		if group {
+			p.write("(")
+		}
+		p.printExpr(n.Value)
+		if group {
+			p.write(")")
+		}
+		p.semicolon()
+	case *IfStmt:
+		p.write("if (")
+		p.printExpr(n.Cond)
+		p.write(")")
+		p.printBody(n.Body)
+		if n.Else != nil {
+			p.write(" else")
+			p.printBody(n.Else)
+		}
+	case *DoWhileStmt:
+		p.write("do")
+		p.printBody(n.Body)
+		p.write(" while (")
+		p.printExpr(n.Cond)
+		p.write(")")
+		p.semicolon()
+	case *WhileStmt:
+		p.write("while (")
+		p.printExpr(n.Cond)
+		p.write(")")
+		p.printBody(n.Body)
+	case *ForStmt:
+		p.write("for (")
+		if v, ok := n.Init.(*VarDecl); !ok && n.Init != nil || ok && len(v.List) != 0 {
+			p.printExpr(n.Init)
+		}
+		p.write(";")
+		if n.Cond != nil {
+			p.write(" ")
+			p.printExpr(n.Cond)
+		}
+		p.write(";")
+		if n.Post != nil {
+			p.write(" ")
+			p.printExpr(n.Post)
+		}
+		p.write(") ")
+		p.printBlock(n.Body.List)
+	case *ForInStmt:
+		p.write("for (")
+		p.printExpr(n.Init)
+		p.write(" in ")
+		p.printExpr(n.Value)
+		p.write(") ")
+		p.printBlock(n.Body.List)
+	case *ForOfStmt:
+		if n.Await {
+			p.write("for await (")
+		} else {
+			p.write("for (")
+		}
+		p.printExpr(n.Init)
+		p.write(" of ")
+		p.printExpr(n.Value)
+		p.write(") ")
+		p.printBlock(n.Body.List)
+	case *SwitchStmt:
+		p.write("switch (")
+		p.printExpr(n.Init)
+		p.write(")")
+		if len(n.List) == 0 {
+			p.write(" {}")
+			return
+		}
+		p.write(" {")
+		w := p.indent()
+		for _, clause := range n.List {
+			p.write("\n")
+			if clause.Cond != nil {
+				p.write("case ")
+				p.printExpr(clause.Cond)
+			} else {
+				p.write("default")
+			}
+			p.write(":")
+			wc := p.indent()
+			p.printStmts(clause.List, true)
+			p.w = wc
+		}
+		p.w = w
+		p.write("\n}")
+	case *BranchStmt:
+		p.writeBytes(n.Type.Bytes())
+		if n.Label != nil {
+			p.write(" ")
+			p.writeBytes(n.Label)
+		}
+		p.semicolon()
+	case *ReturnStmt:
+		p.write("return")
+		if n.Value != nil {
+			p.write(" ")
+			p.printExpr(n.Value)
+		}
+		p.semicolon()
+	case *WithStmt:
+		p.write("with (")
+		p.printExpr(n.Cond)
+		p.write(")")
+		p.printBody(n.Body)
+	case *LabelledStmt:
+		p.writeBytes(n.Label)
+		p.write(":")
+		p.printBody(n.Value)
+	case *ThrowStmt:
+		p.write("throw ")
+		p.printExpr(n.Value)
+		p.semicolon()
+	case *TryStmt:
+		p.write("try ")
+		p.printBlock(n.Body.List)
+		if n.Catch != nil {
+			p.write(" catch ")
+			if n.Binding != nil {
+				p.write("(")
+				p.printBinding(n.Binding)
+				p.write(") ")
+			}
+			p.printBlock(n.Catch.List)
+		}
+		if n.Finally != nil {
+			p.write(" finally ")
+			p.printBlock(n.Finally.List)
+		}
+	case *DebuggerStmt:
+		p.write("debugger")
+		p.semicolon()
+	case *ImportStmt:
+		p.write("import")
+		if n.Default != nil {
+			p.write(" ")
+			p.writeBytes(n.Default)
+			if n.List != nil {
+				p.write(",")
+			}
+		}
+		if len(n.List) == 1 && len(n.List[0].Name) == 1 && n.List[0].Name[0] == '*' {
+			p.write(" ")
+			p.printAlias(n.List[0])
+		} else if n.List != nil {
+			p.printAliases(n.List)
+		}
+		if n.Default != nil || n.List != nil {
+			p.write(" from")
+		}
+		p.write(" ")
+		p.writeRaw(p.quote(n.Module))
+		p.printImportAttributes(n.Attributes)
+		p.semicolon()
+	case *ExportStmt:
+		p.write("export")
+		if n.Decl != nil {
+			if n.Default {
+				p.write(" default")
+			}
+			p.write(" ")
+			switch decl := n.Decl.(type) {
+			case *FuncDecl:
+				p.printFunc(decl)
+			case *ClassDecl:
+				p.printClass(decl)
+			case *VarDecl:
+				p.printVarDecl(decl)
+				p.semicolon()
+			default:
+				p.printExpr(decl)
+				p.semicolon()
+			}
+			return
+		} else if len(n.List) == 1 && (len(n.List[0].Name) == 1 && n.List[0].Name[0] == '*' || n.List[0].Name == nil && len(n.List[0].Binding) == 1 && n.List[0].Binding[0] == '*') {
+			p.write(" ")
+			p.printAlias(n.List[0])
+		} else {
+			p.printAliases(n.List)
+		}
+		if n.Module != nil {
+			p.write(" from ")
+			p.writeRaw(p.quote(n.Module))
+			p.printImportAttributes(n.Attributes)
+		}
+		p.semicolon()
+	case *DirectivePrologueStmt:
+		p.writeRaw(p.quote(n.Value))
+		p.semicolon()
+	case *VarDecl:
+		p.printVarDecl(n)
+		p.semicolon()
+	case *FuncDecl:
+		p.printFunc(n)
+	case *ClassDecl:
+		p.printClass(n)
+	default:
+		p.flush(nil)
+		stmt.JS(p.w)
+	}
+}
+
+func (p *printer) printAlias(alias Alias) {
+	if alias.Name != nil {
+		p.writeBytes(alias.Name)
+		p.write(" as ")
+	}
+	p.writeBytes(alias.Binding)
+}
+
+func (p *printer) printAliases(list []Alias) {
+	if len(list) == 0 {
+		p.write(" {}")
+		return
+	}
+	p.write(" {")
+	for i, item := range list {
+		if i != 0 {
+			p.write(",")
+		}
+		if item.Binding != nil {
+			p.write(" ")
+			p.printAlias(item)
+		}
+	}
+	p.write(" }")
+}
+
+func (p *printer) printImportAttributes(list []ImportAttribute) {
+	if list == nil {
+		return
+	} else if len(list) == 0 {
+		p.write(" with {}")
+		return
+	}
+	p.write(" with {")
+	for i, item := range list {
+		if i != 0 {
+			p.write(",")
+		}
+		p.write(" ")
+		if item.Key[0] == '"' || item.Key[0] == '\'' {
+			p.writeRaw(p.quote(item.Key))
+		} else {
+			p.writeBytes(item.Key)
+		}
+		p.write(": ")
+		p.writeRaw(p.quote(item.Value))
+	}
+	p.write(" }")
+}
+
+func (p *printer) printVarDecl(n *VarDecl) {
+	if n.Await {
+		p.write("await ")
+	}
+	p.writeBytes(n.TokenType.Bytes())
+	for i, item := range n.List {
+		if i != 0 {
+			p.write(",")
+		}
+		p.write(" ")
+		p.printBindingElement(item)
+	}
+}
+
+func (p *printer) printParams(params Params) {
+	n := len(params.List)
+	if params.Rest != nil {
+		n++
+	}
+	p.printList("(", ")", n, true, func(p *printer, i int) {
+		if i < len(params.List) {
+			p.printBindingElement(params.List[i])
+		} else {
+			p.write("...")
+			p.printBinding(params.Rest)
+		}
+	})
+}
+
+func (p *printer) printFunc(n *FuncDecl) {
+	if n.Async {
+		p.write("async ")
+	}
+	p.write("function")
+	if n.Generator {
+		p.write("*")
+	}
+	if n.Name != nil {
+		p.write(" ")
+		p.markVar(n.Name)
+		p.writeBytes(n.Name.Data)
+	}
+	p.printParams(n.Params)
+	p.write(" ")
+	p.printBlock(n.Body.List)
+}
+
+func (p *printer) printMethod(n *MethodDecl) {
+	p.mark(n)
+	if p.lossless != nil && p.lossless.node(p, n) {
+		return
+	}
+	p.printDecorators(n.Decorators)
+	if n.Static {
+		p.write("static ")
+	}
+	if n.Async {
+		p.write("async ")
+	}
+	if n.Get {
+		p.write("get ")
+	}
+	if n.Set {
+		p.write("set ")
+	}
+	if n.Generator {
+		p.write("*")
+	}
+	p.printClassElementName(n.Name)
+	p.printParams(n.Params)
+	p.write(" ")
+	p.printBlock(n.Body.List)
+}
+
+func (p *printer) printDecorators(decorators []IExpr) {
+	for _, decorator := range decorators {
+		p.write("@")
+		p.printExpr(decorator)
+		p.write(" ")
+	}
+}
+
+func (p *printer) printClass(n *ClassDecl) {
+	p.printDecorators(n.Decorators)
+	p.write("class")
+	if n.Name != nil {
+		p.write(" ")
+		p.markVar(n.Name)
+		p.writeBytes(n.Name.Data)
+	}
+	if n.Extends != nil {
+		p.write(" extends ")
+		p.printExpr(n.Extends)
+	}
+	if len(n.List) == 0 {
+		p.write(" {}")
+		return
+	}
+	p.write(" {")
+	w := p.indent()
+	for i, item := range n.List {
+		p.write("\n")
+		if item.StaticBlock != nil {
+			p.write("static ")
+			p.printBlock(item.StaticBlock.List)
+		} else if item.Method != nil {
+			p.printLeadingComments(item.Method)
+			p.printMethod(item.Method)
+			p.printTrailingComments(item.Method)
+		} else {
+			p.printLeadingComments(&item.Field)
+			p.printDecorators(item.Decorators)
+			if item.Static {
+				p.write("static ")
+			}
+			if item.Accessor {
+				p.write("accessor ")
+			}
+			p.printClassElementName(item.Name)
+			if item.Init != nil {
+				p.write(" = ")
+				p.printExpr(item.Init)
+			}
+			if !p.o.OmitSemicolons || fieldNeedsSemicolon(item.Field, n.List[i+1:]) {
+				p.write(";")
+			}
+			p.printTrailingComments(&item.Field)
+		}
+	}
+	p.w = w
+	p.write("\n}")
+}
+
+// fieldNeedsSemicolon returns true if a field must be terminated by a semicolon, which is the case when the next element would otherwise continue the field.
+func fieldNeedsSemicolon(field Field, next []ClassElement) bool {
+	if field.Init == nil && field.Name.Private == nil && !field.Name.IsComputed() {
+		switch string(field.Name.Literal.Data) {
+		case "get", "set", "static", "async", "accessor":
+			return true
+		}
+	}
+	if len(next) == 0 || next[0].StaticBlock != nil {
+		return false
+	}
+	name := next[0].Name
+	if method := next[0].Method; method != nil {
+		if 0 < len(method.Decorators) || method.Static || method.Async || method.Get || method.Set {
+			return false
+		} else if method.Generator {
+			return true
+		}
+		name = method.Name
+	} else if 0 < len(next[0].Decorators) || next[0].Static || next[0].Accessor {
+		return false
+	}
+	return name.IsComputed() || name.Private == nil && (name.Literal.TokenType == InToken || name.Literal.TokenType == InstanceofToken)
+}
+
+func (p *printer) printClassElementName(n ClassElementName) {
+	if n.Private != nil {
+		p.writeBytes(n.Private.Data)
+		return
+	}
+	p.printPropertyName(n.PropertyName)
+}
+
+func (p *printer) printPropertyName(n PropertyName) {
+	if n.Computed != nil {
+		p.write("[")
+		p.printExpr(n.Computed)
+		p.write("]")
+		return
+	}
+	p.printExpr(&n.Literal)
+}
+
+func (p *printer) printBinding(binding IBinding) {
+	p.mark(binding)
+	if p.lossless != nil && p.lossless.node(p, binding) {
+		return
+	}
+	switch n := binding.(type) {
+	case *Var:
+		p.markVar(n)
+		p.writeBytes(n.Data)
+	case *BindingArray:
+		m := len(n.List)
+		if n.Rest != nil {
+			m++
+		}
+		p.printArray(m, n.Rest == nil && 0 < len(n.List) && n.List[len(n.List)-1].Binding == nil, func(p *printer, i int) {
+			if i < len(n.List) {
+				p.printBindingElement(n.List[i])
+			} else {
+				p.write("...")
+				p.printBinding(n.Rest)
+			}
+		})
+	case *BindingObject:
+		m := len(n.List)
+		if n.Rest != nil {
+			m++
+		}
+		p.printList("{", "}", m, true, func(p *printer, i int) {
+			if i < len(n.List) {
+				item := n.List[i]
+				if item.Key != nil {
+					if v, ok := item.Value.Binding.(*Var); !ok || !item.Key.IsIdent(v.Data) {
+						p.printPropertyName(*item.Key)
+						p.write(": ")
+					}
+				}
+				p.printBindingElement(item.Value)
+			} else {
+				p.write("...")
+				p.markVar(n.Rest)
+				p.writeBytes(n.Rest.Data)
+			}
+		})
+	default:
+		p.flush(nil)
+		binding.JS(p.w)
+	}
+}
+
+func (p *printer) printBindingElement(n BindingElement) {
+	if n.Binding == nil {
+		return
+	}
+	p.printBinding(n.Binding)
+	if n.Default != nil {
+		p.write(" = ")
+		p.printExpr(n.Default)
+	}
+}
+
+func (p *printer) printArgs(args Args) {
+	p.printList("(", ")", len(args.List), true, func(p *printer, i int) {
+		if args.List[i].Rest {
+			p.write("...")
+		}
+		p.printExpr(args.List[i].Value)
+	})
+}
+
+func (p *printer) printExpr(expr IExpr) {
+	p.mark(expr)
+	if p.lossless != nil && p.lossless.node(p, expr) {
+		return
+	}
+	switch n := expr.(type) {
+	case *Var:
+		p.markVar(n)
+		p.writeBytes(n.Data)
+	case *LiteralExpr:
+		if n.TokenType == StringToken {
+			p.writeRaw(p.quote(n.Data))
+		} else {
+			p.writeRaw(n.Data)
+		}
+	case *ArrayExpr:
+		p.printArray(len(n.List), 0 < len(n.List) && n.List[len(n.List)-1].Value == nil, func(p *printer, i int) {
+			if n.List[i].Spread {
+				p.write("...")
+			}
+			if n.List[i].Value != nil {
+				p.printExpr(n.List[i].Value)
+			}
+		})
+	case *ObjectExpr:
+		p.printObject(n)
+	case *TemplateExpr:
+		// expressions in templates are not indented to keep newlines in the literal intact
+		w := p.w
+		p.w = p.lw
+		if n.Tag != nil {
+			p.printExpr(n.Tag)
+			if n.Optional {
+				p.write("?.")
+			}
+		}
+		for _, item := range n.List {
+			p.writeRaw(item.Value)
+			p.printExpr(item.Expr)
+		}
+		p.writeRaw(n.Tail)
+		p.w = w
+	case *GroupExpr:
+		p.write("(")
+		p.printExpr(n.X)
+		p.write(")")
+	case *IndexExpr:
+		p.printExpr(n.X)
+		if n.Optional {
+			p.write("?.[")
+		} else {
+			p.write("[")
+		}
+		p.printExpr(n.Y)
+		p.write("]")
+	case *DotExpr:
+		lit, ok := n.X.(*LiteralExpr)
+		group := ok && !n.Optional && (lit.TokenType == DecimalToken || lit.TokenType == IntegerToken)
+		if group {
+			p.write("(")
+		}
+		p.printExpr(n.X)
+		if group {
+			p.write(")")
+		}
+		if n.Optional {
+			p.write("?.")
+		} else {
+			p.write(".")
+		}
+		p.printExpr(n.Y)
+	case *NewTargetExpr:
+		p.write("new.target")
+	case *ImportMetaExpr:
+		p.write("import.meta")
+	case *NewExpr:
+		p.write("new ")
+		p.printExpr(n.X)
+		if n.Args != nil {
+			p.printArgs(*n.Args)
+		} else {
+			p.write("()")
+		}
+	case *CallExpr:
+		p.printExpr(n.X)
+		if n.Optional {
+			p.write("?.")
+		}
+		p.printArgs(n.Args)
+	case *UnaryExpr:
+		if n.Op == PostIncrToken || n.Op == PostDecrToken {
+			p.printExpr(n.X)
+			p.writeBytes(n.Op.Bytes())
+			return
+		}
+		p.writeBytes(n.Op.Bytes())
+		if unary, ok := n.X.(*UnaryExpr); ok && (n.Op == PosToken && (unary.Op == PreIncrToken || unary.Op == PosToken) || n.Op == NegToken && (unary.Op == PreDecrToken || unary.Op == NegToken)) || IsIdentifierName(n.Op) {
+			p.write(" ")
+		}
+		p.printExpr(n.X)
+	case *BinaryExpr:
+		p.printExpr(n.X)
+		p.write(" ")
+		p.writeBytes(n.Op.Bytes())
+		p.write(" ")
+		p.printExpr(n.Y)
+	case *CondExpr:
+		p.printExpr(n.Cond)
+		p.write(" ? ")
+		p.printExpr(n.X)
+		p.write(" : ")
+		p.printExpr(n.Y)
+	case *YieldExpr:
+		p.write("yield")
+		if n.X == nil {
+			return
+		}
+		if n.Generator {
+			p.write("*")
+		}
+		p.write(" ")
+		p.printExpr(n.X)
+	case *ArrowFunc:
+		if n.Async {
+			p.write("async ")
+		}
+		p.printParams(n.Params)
+		p.write(" => ")
+		if len(n.Body.List) == 1 {
+			if ret, ok := n.Body.List[0].(*ReturnStmt); ok && ret.Value != nil {
+				// concise body
+				switch ret.Value.(type) {
+				case *ObjectExpr, *CommaExpr:
+					p.write("(")
+					p.printExpr(ret.Value)
+					p.write(")")
+				default:
+					p.printExpr(ret.Value)
+				}
+				return
+			}
+		}
+		p.printBlock(n.Body.List)
+	case *CommaExpr:
+		for i, item := range n.List {
+			if i != 0 {
+				p.write(", ")
+			}
+			p.printExpr(item)
+		}
+	case *FuncDecl:
+		p.printFunc(n)
+	case *ClassDecl:
+		p.printClass(n)
+	case *MethodDecl:
+		p.printMethod(n)
+	case *VarDecl:
+		p.printVarDecl(n)
+	default:
+		// JSX and other nodes are written as is
+		p.flush(nil)
+		n.JS(p.lw)
+	}
+}
+
+func (p *printer) printObject(n *ObjectExpr) {
+	multiline := false
+	for i := range n.List {
+		if _, ok := n.List[i].Value.(*MethodDecl); ok || hasComments(&n.List[i]) {
+			// methods and comments are printed on separate lines
+			multiline = true
+			break
+		}
+	}
+	if !multiline {
+		p.printList("{", "}", len(n.List), true, func(p *printer, i int) {
+			p.printProperty(&n.List[i])
+		})
+		return
+	}
+
+	p.write("{")
+	w := p.indent()
+	for i := range n.List {
+		p.write("\n")
+		p.printLeadingComments(&n.List[i])
+		p.printProperty(&n.List[i])
+		if i+1 < len(n.List) {
+			p.write(",")
+		}
+		p.printTrailingComments(&n.List[i])
+	}
+	p.w = w
+	p.write("\n}")
+}
+
+func (p *printer) printProperty(n *Property) {
+	p.mark(n)
+	if p.lossless != nil && p.lossless.node(p, n) {
+		return
+	}
+	if n.Name != nil {
+		if v, ok := n.Value.(*Var); !ok || !n.Name.IsIdent(v.Data) {
+			p.printPropertyName(*n.Name)
+			p.write(": ")
+		}
+	} else if n.Spread {
+		p.write("...")
+	}
+	p.printExpr(n.Value)
+	if n.Init != nil {
+		p.write(" = ")
+		p.printExpr(n.Init)
+	}
+}
+
+// quote returns the string literal with the configured quotes.
+func (p *printer) quote(s []byte) []byte {
+	if p.o.Quote == 0 || len(s) < 2 || s[0] == p.o.Quote || s[0] != '"' && s[0] != '\'' {
+		return s
+	}
+	prev := s[0]
+	if bytes.Count(s, []byte{p.o.Quote}) > bytes.Count(s, []byte{prev})-2 {
+		// keep quotes that need fewer escapes
+		return s
+	}
+
+	b := make([]byte, 0, len(s)+2)
+	b = append(b, p.o.Quote)
+	for i := 1; i < len(s)-1; i++ {
+		c := s[i]
+		if c == '\\' && i+2 < len(s) {
+			i++
+			if s[i] != prev {
+				b = append(b, '\\')
+			}
+			c = s[i]
+		} else if c == p.o.Quote {
+			b = append(b, '\\')
+		}
+		b = append(b, c)
+	}
+	return append(b, p.o.Quote)
+}
+
+// leftmostExpr returns the expression that is printed first.
+func leftmostExpr(expr IExpr) IExpr {
+	for {
+		switch n := expr.(type) {
+		case *DotExpr:
+			if lit, ok := n.X.(*LiteralExpr); ok && !n.Optional && (lit.TokenType == DecimalToken || lit.TokenType == IntegerToken) {
+				return n // grouped
+			}
+			expr = n.X
+		case *IndexExpr:
+			expr = n.X
+		case *CallExpr:
+			expr = n.X
+		case *BinaryExpr:
+			expr = n.X
+		case *CondExpr:
+			expr = n.Cond
+		case *CommaExpr:
+			expr = n.List[0]
+		case *UnaryExpr:
+			if n.Op != PostIncrToken && n.Op != PostDecrToken {
+				return n
+			}
+			expr = n.X
+		case *TemplateExpr:
+			if n.Tag == nil {
+				return n
+			}
+			expr = n.Tag
+		default:
+			return expr
+		}
+	}
+}
+
+// startsWithHazard returns true if an expression statement could continue the previous line when semicolons are omitted, such as when it starts with a parenthesis.
+func startsWithHazard(expr IExpr) bool {
+	switch n := leftmostExpr(expr).(type) {
+	case *GroupExpr, *ArrayExpr, *ObjectExpr, *TemplateExpr, *DotExpr, *FuncDecl, *ClassDecl, *JSXElement, *JSXFragment:
+		return true
+	case *ArrowFunc:
+		return !n.Async
+	case *UnaryExpr:
+		return n.Op == PosToken || n.Op == NegToken || n.Op == PreIncrToken || n.Op == PreDecrToken
+	case *LiteralExpr:
+		return n.TokenType == RegExpToken
+	case *Var:
+		return bytes.Equal(n.Data, []byte("let"))
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package js
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestPrint(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"a;b", "a;\nb;"},
+		{"if(a)b();else{c()}", "if (a) b(); else {\n    c();\n}"},
+		{"if(a){}else if(b){}", "if (a) {} else if (b) {}"},
+		{"for(;;){}", "for (;;) {}"},
+		{"for(let i=0;i<n;i++)f(i)", "for (let i = 0; i < n; i++) {\n    f(i);\n}"},
+		{"for await(const x of y){}", "for await (const x of y) {}"},
+		{"do x++;while(x<5)", "do x++; while (x < 5);"},
+		{"switch(a){case 1:b();break;default:}", "switch (a) {\n    case 1:\n        b();\n        break;\n    default:\n}"},
+		{"try{a()}catch{}finally{b()}", "try {\n    a();\n} catch {} finally {\n    b();\n}"},
+		{"try{}catch(e){}", "try {} catch (e) {}"},
+		{"a:for(;;){continue a}", "a: for (;;) {\n    continue a;\n}"},
+		{"function*f(a,...b){yield*a}", "function* f(a, ...b) {\n    yield* a;\n}"},
+		{"async function f(){await x}", "async function f() {\n    await x;\n}"},
+		{"class A extends B{static x=1;#y;static async*m(){}get z(){}static{a()}}", "class A extends B {\n    static x = 1;\n    #y;\n    static async *m() {}\n    get z() {}\n    static {\n        a();\n    }\n}"},
+		{"@a@b.c(d)@(e)class A{@f m(){}@g static accessor #h=1}", "@a @b.c(d) @(e) class A {\n    @f m() {}\n    @g static accessor #h = 1;\n}"},
+		{"x=(a,b)=>a+b", "x = (a, b) => a + b;"},
+		{"import a from'b'with{type:'json','c':'d'};export*from'e'with{}", "import a from 'b' with { type: 'json', 'c': 'd' };\nexport * from 'e' with {};"},
+		{"{using a=b;await using c=d}", "{\n    using a = b;\n    await using c = d;\n}"},
+		{"x=()=>({})", "x = () => ({});"},
+		{"x=async a=>{f();return a}", "x = async (a) => {\n    f();\n    return a;\n};"},
+		{"x={a,b:1,...c,[d]:2,m(){}}", "x = {\n    a,\n    b: 1,\n    ...c,\n    [d]: 2,\n    m() {}\n};"},
+		{"x=[a,,b,...c]", "x = [a, , b, ...c];"},
+		{"x=[a,,]", "x = [a, ,];"},
+		{"let[a,,b=1]=c,{d,e:f,...g}=h", "let [a, , b = 1] = c, {d, e: f, ...g} = h;"},
+		{"x=a?.b?.[c]?.(d)", "x = a?.b?.[c]?.(d);"},
+		{"new A;new A(b)", "new A();\nnew A(b);"},
+		{"- -a;+ +a;!a;typeof a;a++", "- -a;\n+ +a;\n!a;\ntypeof a;\na++;"},
+		{"(function(){})()", "(function() {})();"},
+		{"({a}=b)", "({a} = b);"},
+		{"x=`a${b}\nc`", "x = `a${b}\nc`;"},
+		{"f(function(){return`\na`})", "f(function() {\n    return `\na`;\n});"},
+		{"import a,{b as c}from'd';import*as e from'f'", "import a, { b as c } from 'd';\nimport * as e from 'f';"},
+		{"export{a as b};export*from'c';export default function(){}", "export { a as b };\nexport * from 'c';\nexport default function() {}"},
+		{"export const a=1;export default a+b", "export const a = 1;\nexport default a + b;"},
+		{"'use strict';a", "'use strict';\na;"},
+		{"/** doc */\nfunction f(){} // end", "/** doc */\nfunction f() {} // end"},
+		{"class A{/** f */ x=1; // x\n}", "class A {\n    /** f */\n    x = 1; // x\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{Comments: true})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			sb := &strings.Builder{}
+			test.Error(t, Print(sb, ast, PrintOptions{}))
+			test.String(t, sb.String(), tt.expected)
+		})
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	var tests = []struct {
+		js       string
+		o        PrintOptions
+		expected string
+	}{
+		{"if(a){b()}", PrintOptions{Indent: 2}, "if (a) {\n  b();\n}"},
+		{"a;b", PrintOptions{OmitSemicolons: true}, "a\nb"},
+		{"a;(b);[c];`d`;+e;/f/.g()", PrintOptions{OmitSemicolons: true}, "a\n;(b)\n;[c]\n;`d`\n;+e\n;/f/.g()"},
+		{"if(a)b();else c()", PrintOptions{OmitSemicolons: true}, "if (a) b(); else c()"},
+		{"do x++;while(x<5);y", PrintOptions{OmitSemicolons: true}, "do x++; while (x < 5)\ny"},
+		{"for(;;){a()}", PrintOptions{OmitSemicolons: true}, "for (;;) {\n    a()\n}"},
+		{"class A{a=1;b;get;c(){}x;*d(){}y;[e];static z;in}", PrintOptions{OmitSemicolons: true}, "class A {\n    a = 1\n    b\n    get;\n    c() {}\n    x;\n    *d() {}\n    y;\n    [e]\n    static z\n    in\n}"},
+		{"class A{accessor;a;@b [c];accessor d;*e(){}}", PrintOptions{OmitSemicolons: true}, "class A {\n    accessor;\n    a\n    @b [c]\n    accessor d;\n    *e() {}\n}"},
+		{`a='b';c="d";e='"';f='\'';g="'\""`, PrintOptions{Quote: '"'}, `a = "b";` + "\n" + `c = "d";` + "\n" + `e = '"';` + "\n" + `f = "'";` + "\n" + `g = "'\"";`},
+		{`a="b";c='d';e="'";f="\""`, PrintOptions{Quote: '\''}, `a = 'b';` + "\n" + `c = 'd';` + "\n" + `e = "'";` + "\n" + `f = '"';`},
+		{`import a from 'b';x={'c':1}`, PrintOptions{Quote: '"'}, `import a from "b";` + "\n" + `x = {c: 1};`},
+		{"f(aaaa,bbbb,cccc)", PrintOptions{LineWidth: 19}, "f(aaaa, bbbb, cccc);"},
+		{"f(aaaa,bbbb,cccc)", PrintOptions{LineWidth: 18}, "f(\n    aaaa,\n    bbbb,\n    cccc\n);"},
+		{"x=[aaaa,{b:bbbb,c:cccc}]", PrintOptions{LineWidth: 23}, "x = [\n    aaaa,\n    {b: bbbb, c: cccc}\n];"},
+		{"function f(aaaa,bbbb){return[cccc,dddd]}", PrintOptions{LineWidth: 21, Indent: 2}, "function f(\n  aaaa,\n  bbbb\n) {\n  return [cccc, dddd];\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			sb := &strings.Builder{}
+			test.Error(t, Print(sb, ast, tt.o))
+			test.String(t, sb.String(), tt.expected)
+		})
+	}
+}
+
+func TestPrintNode(t *testing.T) {
+	ast, err := Parse(parse.NewInputString("x = {a: [1, 2], b: 3}"), Options{})
+	if err != io.EOF {
+		test.Error(t, err)
+	}
+	sb := &strings.Builder{}
+	test.Error(t, Print(sb, ast.List[0].(*ExprStmt).Value.(*BinaryExpr).Y, PrintOptions{LineWidth: 14}))
+	test.String(t, sb.String(), "{\n    a: [1, 2],\n    b: 3\n}")
+
+	test.T(t, Print(sb, ast, PrintOptions{Quote: '`'}), ErrInvalidQuote)
+	test.T(t, Print(test.NewErrorWriter(0), ast, PrintOptions{}), test.ErrPlain)
+}
@@ -0,0 +1,159 @@
+package js
+
+import (
+	"io"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/buffer"
+)
+
+// ErrorList is a list of errors returned by Parse when Options.Tolerant is set.
+type ErrorList []*parse.Error
+
+// Error returns the error strings of all errors separated by newlines.
+func (errs ErrorList) Error() string {
+	sb := strings.Builder{}
+	for i, err := range errs {
+		if 0 < i {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// error returns the current error with its position in the source.
+func (p *Parser) error() *parse.Error {
+	return parse.NewError(buffer.NewReader(p.l.r.Bytes()), p.errOffset, p.err.Error())
+}
+
+// checkpoint is the parser state at the start of a statement, which is restored after an error in tolerant mode.
+type checkpoint struct {
+	start                                           int
+	state                                           state
+	orphans                                         int
+	scope                                           *Scope
+	in, await, yield, deflt, retrn, assumeArrowFunc bool
+	stmtLevel, exprLevel                            int
+}
+
+func (p *Parser) checkpoint() checkpoint {
+	return checkpoint{p.start, p.save(), len(p.orphans), p.scope, p.in, p.await, p.yield, p.deflt, p.retrn, p.assumeArrowFunc, p.stmtLevel, p.exprLevel}
+}
+
+// addError records the current error in tolerant mode, unless an error was already recorded at the same position.
+func (p *Parser) addError() {
+	err := p.error()
+	if n := len(p.errs); n == 0 || p.errs[n-1].Line != err.Line || p.errs[n-1].Column != err.Column {
+		p.errs = append(p.errs, err)
+	}
+	p.err = nil
+}
+
+// parseStmtTolerant parses a statement, and in tolerant mode it recovers from errors.
+func (p *Parser) parseStmtTolerant(allowDeclaration bool) IStmt {
+	if !p.o.Tolerant {
+		return p.parseStmt(allowDeclaration)
+	}
+	c := p.checkpoint()
+	stmt := p.parseStmt(allowDeclaration)
+	if p.err != nil && p.o.Tolerant {
+		stmt = p.synchronize(c)
+	}
+	return stmt
+}
+
+// synchronize records the current error, skips tokens until the start of the next statement (after a semicolon or at a keyword or identifier on a new line), and returns a BadStmt that spans the source since the checkpoint. When the statement continued onto a line that starts with a keyword before the error, such as a = 1 +\nlet b = 2, it ends before that keyword instead.
+func (p *Parser) synchronize(c checkpoint) IStmt {
+	p.scope = c.scope
+	p.in, p.await, p.yield, p.deflt, p.retrn, p.assumeArrowFunc = c.in, c.await, c.yield, c.deflt, c.retrn, c.assumeArrowFunc
+	p.stmtLevel, p.exprLevel = c.stmtLevel, c.exprLevel
+	p.orphans = p.orphans[:c.orphans] // comments are kept in the source of the BadStmt
+	p.addError()
+	if p.resync(c) {
+		return p.badStmt(c.start, p.prevEnd)
+	} else if p.tt == ErrorToken && p.l.Err() == io.EOF {
+		return p.badStmt(c.start, p.l.r.Offset())
+	}
+
+	if p.tt == ErrorToken {
+		p.tt = p.errTT // continue at the token that caused the error
+	}
+	if p.tt == ErrorToken || p.start <= c.start {
+		p.next() // skip lexer errors and make progress
+	}
+
+	level := 0
+Loop:
+	for {
+		switch p.tt {
+		case ErrorToken:
+			if p.l.Err() == io.EOF {
+				break Loop
+			}
+		case SemicolonToken:
+			if level == 0 {
+				p.next()
+				break Loop
+			}
+		case OpenBraceToken:
+			level++
+		case CloseBraceToken:
+			if level == 0 {
+				break Loop
+			}
+			level--
+		case VarToken, LetToken, ConstToken, FunctionToken, AsyncToken, ClassToken, IfToken, ForToken, WhileToken, DoToken, ReturnToken, BreakToken, ContinueToken, ThrowToken, TryToken, SwitchToken, ImportToken, ExportToken:
+			if level == 0 && p.prevLT {
+				break Loop
+			}
+		default:
+			if level == 0 && p.prevLT && IsIdentifier(p.tt) {
+				break Loop
+			}
+		}
+		p.next()
+	}
+	if p.tt == ErrorToken {
+		return p.badStmt(c.start, p.l.r.Offset())
+	}
+	return p.badStmt(c.start, p.prevEnd)
+}
+
+// resync rescans the statement since the checkpoint up to the error, and moves the parser back to the last keyword that starts a line outside of parentheses, brackets, and braces. It returns false if there is none.
+func (p *Parser) resync(c checkpoint) bool {
+	end := p.start
+	s := p.save()
+	p.restore(c.state)
+	cut, level := -1, 0
+	for p.tt != ErrorToken && p.start < end {
+		switch p.tt {
+		case OpenParenToken, OpenBracketToken, OpenBraceToken, TemplateStartToken:
+			level++
+		case CloseParenToken, CloseBracketToken, CloseBraceToken, TemplateEndToken:
+			level--
+		case VarToken, LetToken, ConstToken, FunctionToken, AsyncToken, ClassToken, IfToken, ForToken, WhileToken, DoToken, ReturnToken, BreakToken, ContinueToken, ThrowToken, TryToken, SwitchToken, ImportToken, ExportToken:
+			if level == 0 && p.prevLT && c.start < p.start {
+				cut = p.start
+			}
+		}
+		p.next()
+	}
+	if cut == -1 {
+		p.restore(s)
+		return false
+	}
+	p.restore(c.state)
+	for p.start < cut {
+		p.next()
+	}
+	return true
+}
+
+func (p *Parser) badStmt(start, end int) *BadStmt {
+	if end < start {
+		end = start
+	}
+	return &BadStmt{p.l.r.Bytes()[start:end], Loc{start, end}}
+}
@@ -0,0 +1,354 @@
+package regexp
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Flags are the flags of a regular expression.
+type Flags struct {
+	HasIndices  bool // d
+	Global      bool // g
+	IgnoreCase  bool // i
+	Multiline   bool // m
+	DotAll      bool // s
+	Unicode     bool // u
+	UnicodeSets bool // v
+	Sticky      bool // y
+}
+
+// String returns the flags in canonical order.
+func (f Flags) String() string {
+	sb := strings.Builder{}
+	for i, ok := range []bool{f.HasIndices, f.Global, f.IgnoreCase, f.Multiline, f.DotAll, f.Unicode, f.UnicodeSets, f.Sticky} {
+		if ok {
+			sb.WriteByte(flagChars[i])
+		}
+	}
+	return sb.String()
+}
+
+const flagChars = "dgimsuvy"
+
+// RegExp is a parsed regular expression.
+type RegExp struct {
+	Pattern Node
+	Flags   Flags
+	Groups  int      // number of capturing groups
+	Names   []string // names of the capturing groups by index minus one, empty for unnamed groups
+}
+
+// String returns the regular expression as a literal.
+func (re *RegExp) String() string {
+	return "/" + re.Pattern.String() + "/" + re.Flags.String()
+}
+
+// Loc is the location of a node as byte offsets into the pattern, where End is exclusive.
+type Loc struct {
+	Start, End int
+}
+
+// Node is a node of the pattern.
+type Node interface {
+	String() string
+	loc() Loc
+}
+
+func (n Loc) loc() Loc {
+	return n
+}
+
+// NodeLoc returns the location of a node.
+func NodeLoc(n Node) Loc {
+	return n.loc()
+}
+
+// Disjunction is a list of alternatives separated by |.
+type Disjunction struct {
+	Loc
+	List []Node
+}
+
+func (n *Disjunction) String() string {
+	sb := strings.Builder{}
+	for i, item := range n.List {
+		if i != 0 {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(item.String())
+	}
+	return sb.String()
+}
+
+// Alternative is a sequence of terms, which may be empty.
+type Alternative struct {
+	Loc
+	List []Node
+}
+
+func (n *Alternative) String() string {
+	sb := strings.Builder{}
+	for _, item := range n.List {
+		sb.WriteString(item.String())
+	}
+	return sb.String()
+}
+
+// Assertion is one of ^ $ \b \B, stored as the characters '^', '$', 'b', and 'B' respectively.
+type Assertion struct {
+	Loc
+	Kind byte
+}
+
+func (n *Assertion) String() string {
+	if n.Kind == 'b' || n.Kind == 'B' {
+		return `\` + string(n.Kind)
+	}
+	return string(n.Kind)
+}
+
+// Lookaround is a lookahead (?=…) (?!…) or lookbehind (?<=…) (?<!…) assertion.
+type Lookaround struct {
+	Loc
+	Behind   bool
+	Negative bool
+	X        Node
+}
+
+func (n *Lookaround) String() string {
+	s := "(?"
+	if n.Behind {
+		s += "<"
+	}
+	if n.Negative {
+		s += "!"
+	} else {
+		s += "="
+	}
+	return s + n.X.String() + ")"
+}
+
+// Group is a capturing group (…) or (?<name>…), or a non-capturing group (?:…) with optional modifiers (?ims-ims:…).
+type Group struct {
+	Loc
+	Index       int    // index of the capturing group starting at one, zero if non-capturing
+	Name        string // name of the capturing group
+	Add, Remove string // modifiers of the non-capturing group
+	X           Node
+}
+
+func (n *Group) String() string {
+	if n.Name != "" {
+		return "(?<" + n.Name + ">" + n.X.String() + ")"
+	} else if n.Index != 0 {
+		return "(" + n.X.String() + ")"
+	}
+	s := "(?" + n.Add
+	if n.Remove != "" {
+		s += "-" + n.Remove
+	}
+	return s + ":" + n.X.String() + ")"
+}
+
+// Quantifier is a repeated term, such as * + ? {n} {n,} {n,m} with an optional ? for lazy matching. Max is -1 if unbounded.
+type Quantifier struct {
+	Loc
+	Min, Max int
+	Greedy   bool
+	X        Node
+}
+
+func (n *Quantifier) String() string {
+	s := n.X.String()
+	switch {
+	case n.Min == 0 && n.Max == -1:
+		s += "*"
+	case n.Min == 1 && n.Max == -1:
+		s += "+"
+	case n.Min == 0 && n.Max == 1:
+		s += "?"
+	case n.Max == -1:
+		s += "{" + strconv.Itoa(n.Min) + ",}"
+	case n.Min == n.Max:
+		s += "{" + strconv.Itoa(n.Min) + "}"
+	default:
+		s += "{" + strconv.Itoa(n.Min) + "," + strconv.Itoa(n.Max) + "}"
+	}
+	if !n.Greedy {
+		s += "?"
+	}
+	return s
+}
+
+// Char is a single character, either literal or escaped. Without the u or v flag, characters outside the basic multilingual plane written as surrogate escapes are separate characters.
+type Char struct {
+	Loc
+	Value rune
+}
+
+func (n *Char) String() string {
+	return escapeRune(n.Value, false)
+}
+
+// Dot matches any character (except line terminators without the s flag).
+type Dot struct {
+	Loc
+}
+
+func (n *Dot) String() string {
+	return "."
+}
+
+// ClassEscape is one of the character class escapes \d \D \s \S \w \W, stored as the escaped character.
+type ClassEscape struct {
+	Loc
+	Kind byte
+}
+
+func (n *ClassEscape) String() string {
+	return `\` + string(n.Kind)
+}
+
+// Property is a unicode property escape \p{…} or \P{…} as either a lone name or value, or a name=value pair.
+type Property struct {
+	Loc
+	Negated bool
+	Name    string
+	Value   string
+}
+
+func (n *Property) String() string {
+	s := `\p{`
+	if n.Negated {
+		s = `\P{`
+	}
+	if n.Name != "" {
+		s += n.Name + "="
+	}
+	return s + n.Value + "}"
+}
+
+// Backreference is a reference to a capturing group by index \1 or name \k<name>. The index is zero for named references.
+type Backreference struct {
+	Loc
+	Index int
+	Name  string
+}
+
+func (n *Backreference) String() string {
+	if n.Name != "" {
+		return `\k<` + n.Name + ">"
+	}
+	return `\` + strconv.Itoa(n.Index)
+}
+
+// ClassOp is the operation of a character class, which are only available with the v flag.
+type ClassOp int
+
+// ClassOp values.
+const (
+	UnionOp ClassOp = iota
+	IntersectionOp
+	SubtractionOp
+)
+
+// Class is a character class […] or [^…]. With the v flag classes can be nested and contain strings, and its operands can be combined by intersection or subtraction.
+type Class struct {
+	Loc
+	Negated bool
+	Op      ClassOp
+	List    []Node
+}
+
+func (n *Class) String() string {
+	sb := strings.Builder{}
+	sb.WriteByte('[')
+	if n.Negated {
+		sb.WriteByte('^')
+	}
+	for i, item := range n.List {
+		if 0 < i && n.Op == IntersectionOp {
+			sb.WriteString("&&")
+		} else if 0 < i && n.Op == SubtractionOp {
+			sb.WriteString("--")
+		}
+		if c, ok := item.(*Char); ok {
+			sb.WriteString(escapeRune(c.Value, true))
+		} else {
+			sb.WriteString(item.String())
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// ClassRange is a range of characters in a character class.
+type ClassRange struct {
+	Loc
+	From, To rune
+}
+
+func (n *ClassRange) String() string {
+	return escapeRune(n.From, true) + "-" + escapeRune(n.To, true)
+}
+
+// ClassStrings is a list of strings \q{…|…} in a character class, which is only available with the v flag.
+type ClassStrings struct {
+	Loc
+	List []string
+}
+
+func (n *ClassStrings) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(`\q{`)
+	for i, s := range n.List {
+		if i != 0 {
+			sb.WriteByte('|')
+		}
+		for _, r := range s {
+			if r == '|' || r == '}' {
+				sb.WriteString(`\` + string(r))
+			} else {
+				sb.WriteString(escapeRune(r, true))
+			}
+		}
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func escapeRune(r rune, inClass bool) string {
+	switch r {
+	case '\t':
+		return `\t`
+	case '\n':
+		return `\n`
+	case '\v':
+		return `\v`
+	case '\f':
+		return `\f`
+	case '\r':
+		return `\r`
+	case '/', '\\', '^', '$', '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|':
+		return `\` + string(r)
+	case '-':
+		if inClass {
+			return `\-`
+		}
+	}
+	if r < 0x20 || r == 0x7F || 0xD800 <= r && r <= 0xDFFF || r == 0x2028 || r == 0x2029 || !utf8.ValidRune(r) {
+		if r <= 0xFF {
+			return `\x` + hex(int(r), 2)
+		} else if r <= 0xFFFF {
+			return `\u` + hex(int(r), 4)
+		}
+		return `\u{` + strconv.FormatInt(int64(r), 16) + `}`
+	}
+	return string(r)
+}
+
+func hex(v, n int) string {
+	s := strconv.FormatInt(int64(v), 16)
+	return strings.Repeat("0", n-len(s)) + s
+}
@@ -0,0 +1,956 @@
+// Package regexp parses the pattern and flags of JavaScript regular expression literals into an AST and reports the early errors of invalid patterns, following the ECMAScript specification at https://tc39.es/ecma262/#sec-regexp-regular-expression-objects.
+package regexp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is an early error of a regular expression. Offset is the byte offset into the regular expression literal /pattern/flags.
+type Error struct {
+	Message string
+	Offset  int
+}
+
+// Error returns the error string.
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid regular expression: %s at offset %d", e.Message, e.Offset)
+}
+
+// Parse parses a pattern with its flags, such as the source and flags of the RegExp constructor. Without the u or v flag, the pattern is parsed with the web compatible grammar of Annex B. The returned error is of type *Error.
+func Parse(pattern, flags []byte) (*RegExp, error) {
+	return parseRegExp(pattern, flags, 1, len(pattern)+2)
+}
+
+// ParseLiteral parses a regular expression literal /pattern/flags, such as returned by js.Lexer.RegExp. The returned error is of type *Error.
+func ParseLiteral(b []byte) (*RegExp, error) {
+	if len(b) == 0 || b[0] != '/' {
+		return nil, &Error{"expected regular expression literal", 0}
+	}
+	inClass := false
+	for i := 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				return parseRegExp(b[1:i], b[i+1:], 1, i+1)
+			}
+		}
+	}
+	return nil, &Error{"unterminated regular expression literal", len(b)}
+}
+
+func parseRegExp(pattern, flags []byte, patternOffset, flagsOffset int) (*RegExp, error) {
+	re := &RegExp{}
+	for i, c := range flags {
+		var flag *bool
+		switch c {
+		case 'd':
+			flag = &re.Flags.HasIndices
+		case 'g':
+			flag = &re.Flags.Global
+		case 'i':
+			flag = &re.Flags.IgnoreCase
+		case 'm':
+			flag = &re.Flags.Multiline
+		case 's':
+			flag = &re.Flags.DotAll
+		case 'u':
+			flag = &re.Flags.Unicode
+		case 'v':
+			flag = &re.Flags.UnicodeSets
+		case 'y':
+			flag = &re.Flags.Sticky
+		default:
+			return nil, &Error{fmt.Sprintf("invalid flag %q", c), flagsOffset + i}
+		}
+		if *flag {
+			return nil, &Error{fmt.Sprintf("duplicate flag %q", c), flagsOffset + i}
+		}
+		*flag = true
+	}
+	if re.Flags.Unicode && re.Flags.UnicodeSets {
+		return nil, &Error{"flags u and v can't be combined", flagsOffset}
+	}
+
+	p := &parser{
+		b:      pattern,
+		offset: patternOffset,
+		u:      re.Flags.Unicode || re.Flags.UnicodeSets,
+		v:      re.Flags.UnicodeSets,
+	}
+	p.countGroups()
+	re.Pattern = p.parseDisjunction()
+	if p.pos < len(p.b) {
+		p.fail(p.pos, "unmatched )")
+	}
+	for _, ref := range p.refs {
+		if !p.hasName(ref.Name) {
+			p.fail(ref.Start, "invalid named reference")
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	re.Groups = p.index
+	re.Names = p.names
+	return re, nil
+}
+
+////////////////////////////////////////////////////////////////
+
+// altPos is the position of an alternative in a disjunction, used to allow duplicate group names in different alternatives.
+type altPos struct {
+	disj, alt int
+}
+
+type namedGroup struct {
+	name string
+	path []altPos
+}
+
+type parser struct {
+	b      []byte
+	offset int // offset of the pattern in the literal
+	pos    int
+	err    error
+
+	u, v   bool // u or v flag, and v flag
+	named  bool // pattern has named groups
+	groups int  // number of capturing groups in the pattern
+
+	index       int // index of the last capturing group
+	names       []string
+	namedGroups []namedGroup
+	refs        []*Backreference
+	path        []altPos
+	disj        int
+}
+
+// fail sets the error and stops parsing by moving to the end of the pattern.
+func (p *parser) fail(pos int, message string) {
+	if p.err == nil {
+		p.err = &Error{message, p.offset + pos}
+	}
+	p.pos = len(p.b)
+}
+
+func (p *parser) peek(i int) byte {
+	if p.pos+i < len(p.b) {
+		return p.b[p.pos+i]
+	}
+	return 0
+}
+
+func (p *parser) eof() bool {
+	return len(p.b) <= p.pos
+}
+
+// countGroups counts the capturing groups ahead of parsing, which is required to distinguish backreferences from legacy octal escapes.
+func (p *parser) countGroups() {
+	class := 0
+	for i := 0; i < len(p.b); i++ {
+		switch p.b[i] {
+		case '\\':
+			i++
+		case '[':
+			if class == 0 || p.v {
+				class++
+			}
+		case ']':
+			if 0 < class {
+				class--
+			}
+		case '(':
+			if class == 0 {
+				if i+1 == len(p.b) || p.b[i+1] != '?' {
+					p.groups++
+				} else if i+3 < len(p.b) && p.b[i+2] == '<' && p.b[i+3] != '=' && p.b[i+3] != '!' {
+					p.groups++
+					p.named = true
+				}
+			}
+		}
+	}
+}
+
+func (p *parser) hasName(name string) bool {
+	for _, g := range p.namedGroups {
+		if g.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addName adds a group name, which may only be a duplicate when both groups are in different alternatives.
+func (p *parser) addName(name string, pos int) {
+	for _, g := range p.namedGroups {
+		if g.name != name {
+			continue
+		}
+		exclusive := false
+		for i := 0; i < len(g.path) && i < len(p.path); i++ {
+			if g.path[i] != p.path[i] {
+				exclusive = g.path[i].disj == p.path[i].disj
+				break
+			}
+		}
+		if !exclusive {
+			p.fail(pos, "duplicate capture group name")
+			return
+		}
+	}
+	p.namedGroups = append(p.namedGroups, namedGroup{name, append([]altPos{}, p.path...)})
+}
+
+func (p *parser) parseDisjunction() Node {
+	start := p.pos
+	disj := p.disj
+	p.disj++
+
+	list := []Node{}
+	for i := 0; ; i++ {
+		p.path = append(p.path, altPos{disj, i})
+		list = append(list, p.parseAlternative())
+		p.path = p.path[:len(p.path)-1]
+		if p.peek(0) != '|' || p.eof() {
+			break
+		}
+		p.pos++
+	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return &Disjunction{Loc{start, p.pos}, list}
+}
+
+func (p *parser) parseAlternative() Node {
+	start := p.pos
+	list := []Node{}
+	for !p.eof() && p.b[p.pos] != '|' && p.b[p.pos] != ')' {
+		list = append(list, p.parseTerm())
+	}
+	return &Alternative{Loc{start, p.pos}, list}
+}
+
+func (p *parser) parseTerm() Node {
+	start := p.pos
+	var n Node
+	quantifiable := true
+	switch c := p.b[p.pos]; {
+	case c == '^' || c == '$':
+		p.pos++
+		n = &Assertion{Loc{start, p.pos}, c}
+		quantifiable = false
+	case c == '\\' && (p.peek(1) == 'b' || p.peek(1) == 'B'):
+		p.pos += 2
+		n = &Assertion{Loc{start, p.pos}, p.b[p.pos-1]}
+		quantifiable = false
+	case c == '(' && p.peek(1) == '?' && (p.peek(2) == '=' || p.peek(2) == '!' || p.peek(2) == '<' && (p.peek(3) == '=' || p.peek(3) == '!')):
+		behind := p.peek(2) == '<'
+		p.pos += 3
+		if behind {
+			p.pos++
+		}
+		negative := p.b[p.pos-1] == '!'
+		x := p.parseDisjunction()
+		p.parseClose(start)
+		n = &Lookaround{Loc{start, p.pos}, behind, negative, x}
+		quantifiable = !behind && !p.u // Annex B
+	default:
+		n = p.parseAtom()
+	}
+
+	if min, max, end, ok := p.quantifier(); ok {
+		if !quantifiable {
+			p.fail(p.pos, "nothing to repeat")
+			return n
+		} else if max != -1 && max < min {
+			p.fail(p.pos, "numbers out of order in quantifier")
+			return n
+		}
+		p.pos = end
+		greedy := true
+		if p.peek(0) == '?' && !p.eof() {
+			p.pos++
+			greedy = false
+		}
+		n = &Quantifier{Loc{start, p.pos}, min, max, greedy, n}
+	}
+	return n
+}
+
+// quantifier returns the quantifier at the current position and the position after it, if any.
+func (p *parser) quantifier() (int, int, int, bool) {
+	if p.eof() {
+		return 0, 0, 0, false
+	}
+	switch p.b[p.pos] {
+	case '*':
+		return 0, -1, p.pos + 1, true
+	case '+':
+		return 1, -1, p.pos + 1, true
+	case '?':
+		return 0, 1, p.pos + 1, true
+	case '{':
+		i := p.pos + 1
+		min, n := decimal(p.b[i:])
+		if n == 0 {
+			return 0, 0, 0, false
+		}
+		i += n
+		max := min
+		if i < len(p.b) && p.b[i] == ',' {
+			i++
+			max, n = decimal(p.b[i:])
+			if n == 0 {
+				max = -1
+			}
+			i += n
+		}
+		if i < len(p.b) && p.b[i] == '}' {
+			return min, max, i + 1, true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// decimal parses a decimal number and returns it with the number of bytes read. Large numbers are clamped.
+func decimal(b []byte) (int, int) {
+	v, i := 0, 0
+	for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+		if v < 1<<30 {
+			v = v*10 + int(b[i]-'0')
+		}
+		i++
+	}
+	return v, i
+}
+
+func (p *parser) parseAtom() Node {
+	start := p.pos
+	switch c := p.b[p.pos]; c {
+	case '.':
+		p.pos++
+		return &Dot{Loc{start, p.pos}}
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return p.parseClass()
+	case '\\':
+		return p.parseAtomEscape()
+	case '*', '+', '?':
+		p.fail(start, "nothing to repeat")
+	case '{':
+		if p.u {
+			p.fail(start, "lone quantifier brackets")
+		} else if _, _, _, ok := p.quantifier(); ok {
+			p.fail(start, "nothing to repeat")
+		}
+	case '}':
+		if p.u {
+			p.fail(start, "lone quantifier brackets")
+		}
+	case ']':
+		if p.u {
+			p.fail(start, "lone ]")
+		}
+	}
+	if p.eof() {
+		return &Char{Loc{start, start}, 0}
+	}
+	r, n := utf8.DecodeRune(p.b[p.pos:])
+	p.pos += n
+	return &Char{Loc{start, p.pos}, r}
+}
+
+func (p *parser) parseClose(start int) {
+	if p.peek(0) != ')' || p.eof() {
+		p.fail(start, "unterminated group")
+		return
+	}
+	p.pos++
+}
+
+func (p *parser) parseGroup() Node {
+	start := p.pos
+	p.pos++
+	g := &Group{}
+	if p.peek(0) != '?' || p.eof() {
+		p.index++
+		g.Index = p.index
+		p.names = append(p.names, "")
+	} else if p.peek(1) == '<' {
+		p.pos += 2
+		g.Name = p.parseGroupName()
+		p.addName(g.Name, start)
+		p.index++
+		g.Index = p.index
+		p.names = append(p.names, g.Name)
+	} else {
+		p.pos++
+		g.Add = p.parseModifiers("")
+		if p.peek(0) == '-' {
+			p.pos++
+			g.Remove = p.parseModifiers(g.Add)
+			if g.Add == "" && g.Remove == "" {
+				p.fail(start, "invalid group")
+			}
+		}
+		if p.peek(0) != ':' || p.eof() {
+			p.fail(start, "invalid group")
+		}
+		p.pos++
+	}
+	g.X = p.parseDisjunction()
+	p.parseClose(start)
+	g.Loc = Loc{start, p.pos}
+	return g
+}
+
+// parseModifiers parses the flags of a modifier group, where each flag may only occur once.
+func (p *parser) parseModifiers(seen string) string {
+	start := p.pos
+	for !p.eof() && (p.b[p.pos] == 'i' || p.b[p.pos] == 'm' || p.b[p.pos] == 's') {
+		if strings.IndexByte(seen, p.b[p.pos]) != -1 || bytes.IndexByte(p.b[start:p.pos], p.b[p.pos]) != -1 {
+			p.fail(p.pos, "repeated flag in modifiers")
+			return ""
+		}
+		p.pos++
+	}
+	return string(p.b[start:p.pos])
+}
+
+// parseGroupName parses a group name after the < up to and including the >.
+func (p *parser) parseGroupName() string {
+	start := p.pos
+	name := []rune{}
+	for {
+		if p.eof() {
+			p.fail(start, "invalid capture group name")
+			return ""
+		} else if p.b[p.pos] == '>' {
+			p.pos++
+			break
+		}
+
+		var r rune
+		if p.b[p.pos] == '\\' {
+			ok := false
+			if p.peek(1) == 'u' {
+				p.pos += 2
+				r, ok = p.parseUnicodeEscape(true)
+			}
+			if !ok {
+				p.fail(start, "invalid capture group name")
+				return ""
+			}
+		} else {
+			var n int
+			r, n = utf8.DecodeRune(p.b[p.pos:])
+			p.pos += n
+		}
+		if len(name) == 0 && !isIDStart(r) || len(name) != 0 && !isIDContinue(r) {
+			p.fail(start, "invalid capture group name")
+			return ""
+		}
+		name = append(name, r)
+	}
+	if len(name) == 0 {
+		p.fail(start, "invalid capture group name")
+	}
+	return string(name)
+}
+
+func (p *parser) parseAtomEscape() Node {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		p.fail(start, "\\ at end of pattern")
+		return &Char{Loc{start, p.pos}, '\\'}
+	}
+
+	switch c := p.b[p.pos]; {
+	case '1' <= c && c <= '9':
+		if index, n := decimal(p.b[p.pos:]); index <= p.groups {
+			p.pos += n
+			return &Backreference{Loc{start, p.pos}, index, ""}
+		} else if p.u {
+			p.fail(start, "invalid escape")
+		} else if '8' <= c {
+			p.pos++
+			return &Char{Loc{start, p.pos}, rune(c)}
+		} else {
+			r := p.parseLegacyOctal()
+			return &Char{Loc{start, p.pos}, r}
+		}
+	case c == '0':
+		if '0' <= p.peek(1) && p.peek(1) <= '9' {
+			if p.u {
+				p.fail(start, "invalid decimal escape")
+			} else {
+				r := p.parseLegacyOctal()
+				return &Char{Loc{start, p.pos}, r}
+			}
+		} else {
+			p.pos++
+			return &Char{Loc{start, p.pos}, 0}
+		}
+	case c == 'k':
+		if p.u || p.named {
+			if p.peek(1) != '<' {
+				p.fail(start, "invalid named reference")
+				return &Char{Loc{start, p.pos}, 'k'}
+			}
+			p.pos += 2
+			ref := &Backreference{Name: p.parseGroupName()}
+			ref.Loc = Loc{start, p.pos}
+			p.refs = append(p.refs, ref)
+			return ref
+		}
+	case c == 'd' || c == 'D' || c == 's' || c == 'S' || c == 'w' || c == 'W':
+		p.pos++
+		return &ClassEscape{Loc{start, p.pos}, c}
+	case (c == 'p' || c == 'P') && p.u:
+		return p.parseProperty(start)
+	}
+	if p.eof() {
+		return &Char{Loc{start, p.pos}, 0}
+	}
+	r := p.parseCharacterEscape(start, false)
+	return &Char{Loc{start, p.pos}, r}
+}
+
+// parseLegacyOctal parses an octal escape of Annex B, which is at most 0o377.
+func (p *parser) parseLegacyOctal() rune {
+	first := p.b[p.pos]
+	r := rune(first - '0')
+	p.pos++
+	if c := p.peek(0); '0' <= c && c <= '7' && !p.eof() {
+		r = r*8 + rune(c-'0')
+		p.pos++
+		if c := p.peek(0); first <= '3' && '0' <= c && c <= '7' && !p.eof() {
+			r = r*8 + rune(c-'0')
+			p.pos++
+		}
+	}
+	return r
+}
+
+// parseCharacterEscape parses the escape sequence after the backslash and returns the escaped character.
+func (p *parser) parseCharacterEscape(start int, inClass bool) rune {
+	switch c := p.b[p.pos]; c {
+	case 'f':
+		p.pos++
+		return '\f'
+	case 'n':
+		p.pos++
+		return '\n'
+	case 'r':
+		p.pos++
+		return '\r'
+	case 't':
+		p.pos++
+		return '\t'
+	case 'v':
+		p.pos++
+		return '\v'
+	case 'c':
+		if d := p.peek(1); 'a' <= d && d <= 'z' || 'A' <= d && d <= 'Z' || !p.u && inClass && ('0' <= d && d <= '9' || d == '_') {
+			p.pos += 2
+			return rune(d % 32)
+		} else if p.u {
+			p.fail(start, "invalid unicode escape")
+		}
+		// Annex B: the backslash is a literal and the c is parsed next
+		return '\\'
+	case 'x':
+		p.pos++
+		if r, ok := p.parseHex(2); ok {
+			return r
+		} else if p.u {
+			p.fail(start, "invalid escape")
+		}
+		return 'x'
+	case 'u':
+		p.pos++
+		if r, ok := p.parseUnicodeEscape(p.u); ok {
+			return r
+		} else if p.u {
+			p.fail(start, "invalid unicode escape")
+		}
+		return 'u'
+	}
+
+	r, n := utf8.DecodeRune(p.b[p.pos:])
+	if p.u && !isSyntaxChar(r) && r != '/' && (!inClass || r != '-') || !p.u && r == 'k' && p.named {
+		p.fail(start, "invalid escape")
+		return r
+	}
+	p.pos += n
+	return r
+}
+
+func isSyntaxChar(r rune) bool {
+	return r < utf8.RuneSelf && strings.IndexByte(`^$\.*+?()[]{}|`, byte(r)) != -1
+}
+
+// parseHex parses exactly n hexadecimal digits, and doesn't move if they are missing.
+func (p *parser) parseHex(n int) (rune, bool) {
+	if len(p.b)-p.pos < n {
+		return 0, false
+	}
+	var r rune
+	for _, c := range p.b[p.pos : p.pos+n] {
+		d := hexDigit(c)
+		if d == -1 {
+			return 0, false
+		}
+		r = r*16 + d
+	}
+	p.pos += n
+	return r, true
+}
+
+func hexDigit(c byte) rune {
+	switch {
+	case '0' <= c && c <= '9':
+		return rune(c - '0')
+	case 'a' <= c && c <= 'f':
+		return rune(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return rune(c-'A') + 10
+	}
+	return -1
+}
+
+// parseUnicodeEscape parses the escape sequence after \u. In unicode mode, it also parses \u{…} and surrogate pairs.
+func (p *parser) parseUnicodeEscape(unicode bool) (rune, bool) {
+	if unicode && p.peek(0) == '{' {
+		i := p.pos + 1
+		var r rune
+		for i < len(p.b) && hexDigit(p.b[i]) != -1 {
+			if r = r*16 + hexDigit(p.b[i]); 0x10FFFF < r {
+				return 0, false
+			}
+			i++
+		}
+		if i == p.pos+1 || i == len(p.b) || p.b[i] != '}' {
+			return 0, false
+		}
+		p.pos = i + 1
+		return r, true
+	}
+
+	r, ok := p.parseHex(4)
+	if ok && unicode && 0xD800 <= r && r <= 0xDBFF && p.peek(0) == '\\' && p.peek(1) == 'u' {
+		pos := p.pos
+		p.pos += 2
+		if lo, ok := p.parseHex(4); ok && 0xDC00 <= lo && lo <= 0xDFFF {
+			return 0x10000 + (r-0xD800)<<10 + (lo - 0xDC00), true
+		}
+		p.pos = pos
+	}
+	return r, ok
+}
+
+// parseProperty parses a unicode property escape \p{…} or \P{…}, starting at the p.
+func (p *parser) parseProperty(start int) Node {
+	negated := p.b[p.pos] == 'P'
+	p.pos++
+	end := bytes.IndexByte(p.b[p.pos:], '}')
+	if p.peek(0) != '{' || end == -1 {
+		p.fail(start, "invalid property name")
+		return &Property{}
+	}
+	s := string(p.b[p.pos+1 : p.pos+end])
+	p.pos += end + 1
+
+	n := &Property{Loc{start, p.pos}, negated, "", s}
+	valid := false
+	if i := strings.IndexByte(s, '='); i != -1 {
+		n.Name, n.Value = s[:i], s[i+1:]
+		switch n.Name {
+		case "General_Category", "gc":
+			_, valid = generalCategories[n.Value]
+		case "Script", "sc", "Script_Extensions", "scx":
+			valid = isScript(n.Value)
+		}
+	} else if _, ok := generalCategories[s]; ok {
+		valid = true
+	} else if _, ok := binaryProperties[s]; ok {
+		valid = true
+	} else if stringProperties[s] {
+		valid = p.v && !negated
+	}
+	if !valid {
+		p.fail(start, "invalid property name")
+	}
+	return n
+}
+
+func (p *parser) parseClass() Node {
+	start := p.pos
+	p.pos++
+	c := &Class{}
+	if p.peek(0) == '^' && !p.eof() {
+		p.pos++
+		c.Negated = true
+	}
+	if p.v {
+		p.parseClassSet(c)
+		if c.Negated && mayContainStrings(&Class{Op: c.Op, List: c.List}) {
+			p.fail(start, "negated character class may contain strings")
+		}
+	} else {
+		for !p.eof() && p.b[p.pos] != ']' {
+			from := p.parseClassAtom()
+			if p.peek(0) != '-' || p.pos+1 == len(p.b) || p.b[p.pos+1] == ']' {
+				c.List = append(c.List, from)
+				continue
+			}
+
+			dash := p.pos
+			p.pos++
+			to := p.parseClassAtom()
+			fromChar, ok := from.(*Char)
+			toChar, ok2 := to.(*Char)
+			if !ok || !ok2 {
+				if p.u {
+					p.fail(dash, "invalid character class")
+				}
+				c.List = append(c.List, from, &Char{Loc{dash, dash + 1}, '-'}, to)
+			} else if toChar.Value < fromChar.Value {
+				p.fail(fromChar.Start, "range out of order in character class")
+			} else {
+				c.List = append(c.List, &ClassRange{Loc{fromChar.Start, toChar.End}, fromChar.Value, toChar.Value})
+			}
+		}
+	}
+	if p.eof() {
+		p.fail(start, "unterminated character class")
+	} else {
+		p.pos++
+	}
+	c.Loc = Loc{start, p.pos}
+	return c
+}
+
+func (p *parser) parseClassAtom() Node {
+	start := p.pos
+	if p.b[p.pos] != '\\' {
+		r, n := utf8.DecodeRune(p.b[p.pos:])
+		p.pos += n
+		return &Char{Loc{start, p.pos}, r}
+	}
+
+	p.pos++
+	if p.eof() {
+		p.fail(start, "\\ at end of pattern")
+		return &Char{Loc{start, p.pos}, '\\'}
+	}
+	switch c := p.b[p.pos]; {
+	case c == 'b':
+		p.pos++
+		return &Char{Loc{start, p.pos}, '\b'}
+	case c == '-' && p.u:
+		p.pos++
+		return &Char{Loc{start, p.pos}, '-'}
+	case c == 'd' || c == 'D' || c == 's' || c == 'S' || c == 'w' || c == 'W':
+		p.pos++
+		return &ClassEscape{Loc{start, p.pos}, c}
+	case (c == 'p' || c == 'P') && p.u:
+		return p.parseProperty(start)
+	case '0' <= c && c <= '9':
+		if p.u {
+			if c != '0' || '0' <= p.peek(1) && p.peek(1) <= '9' {
+				p.fail(start, "invalid class escape")
+				return &Char{Loc{start, p.pos}, 0}
+			}
+			p.pos++
+			return &Char{Loc{start, p.pos}, 0}
+		} else if '8' <= c {
+			p.pos++
+			return &Char{Loc{start, p.pos}, rune(c)}
+		}
+		r := p.parseLegacyOctal()
+		return &Char{Loc{start, p.pos}, r}
+	}
+	r := p.parseCharacterEscape(start, true)
+	return &Char{Loc{start, p.pos}, r}
+}
+
+// parseClassSet parses the contents of a class with the v flag, which is either a union of ranges and operands, or an intersection or subtraction of operands.
+func (p *parser) parseClassSet(c *Class) {
+	if p.eof() || p.b[p.pos] == ']' {
+		return
+	}
+	first := p.parseClassSetOperand()
+	if p.hasPrefix("&&") || p.hasPrefix("--") {
+		op := "&&"
+		c.Op = IntersectionOp
+		if p.b[p.pos] == '-' {
+			op = "--"
+			c.Op = SubtractionOp
+		}
+		c.List = append(c.List, first)
+		for p.hasPrefix(op) {
+			p.pos += 2
+			if op == "&&" && p.peek(0) == '&' {
+				p.fail(p.pos, "invalid set operation in character class")
+				return
+			}
+			c.List = append(c.List, p.parseClassSetOperand())
+		}
+		if !p.eof() && p.b[p.pos] != ']' {
+			p.fail(p.pos, "invalid set operation in character class")
+		}
+		return
+	}
+
+	operand := first
+	for {
+		if from, ok := operand.(*Char); ok && p.peek(0) == '-' && !p.hasPrefix("--") && !p.eof() {
+			p.pos++
+			to, ok := p.parseClassSetOperand().(*Char)
+			if !ok {
+				p.fail(from.Start, "invalid character class")
+				return
+			} else if to.Value < from.Value {
+				p.fail(from.Start, "range out of order in character class")
+				return
+			}
+			operand = &ClassRange{Loc{from.Start, to.End}, from.Value, to.Value}
+		}
+		c.List = append(c.List, operand)
+		if p.eof() || p.b[p.pos] == ']' {
+			return
+		} else if p.hasPrefix("&&") || p.hasPrefix("--") {
+			p.fail(p.pos, "invalid set operation in character class")
+			return
+		}
+		operand = p.parseClassSetOperand()
+	}
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return bytes.HasPrefix(p.b[p.pos:], []byte(s))
+}
+
+// parseClassSetOperand parses a nested class, a class escape, a string disjunction, or a character.
+func (p *parser) parseClassSetOperand() Node {
+	start := p.pos
+	if p.eof() {
+		return &Char{Loc{start, start}, 0}
+	} else if p.b[p.pos] == '[' {
+		return p.parseClass()
+	} else if p.b[p.pos] == '\\' {
+		switch c := p.peek(1); c {
+		case 'd', 'D', 's', 'S', 'w', 'W':
+			p.pos += 2
+			return &ClassEscape{Loc{start, p.pos}, c}
+		case 'p', 'P':
+			p.pos++
+			return p.parseProperty(start)
+		case 'q':
+			if p.peek(2) != '{' {
+				p.fail(start, "invalid escape")
+				return &Char{Loc{start, p.pos}, 'q'}
+			}
+			p.pos += 3
+			strs := []string{}
+			sb := strings.Builder{}
+			for {
+				if p.eof() {
+					p.fail(start, "unterminated class string disjunction")
+					return &ClassStrings{}
+				} else if p.b[p.pos] == '}' {
+					p.pos++
+					break
+				} else if p.b[p.pos] == '|' {
+					p.pos++
+					strs = append(strs, sb.String())
+					sb.Reset()
+					continue
+				}
+				sb.WriteRune(p.parseClassSetCharacter())
+			}
+			strs = append(strs, sb.String())
+			return &ClassStrings{Loc{start, p.pos}, strs}
+		}
+	}
+	r := p.parseClassSetCharacter()
+	return &Char{Loc{start, p.pos}, r}
+}
+
+func (p *parser) parseClassSetCharacter() rune {
+	start := p.pos
+	c := p.b[p.pos]
+	if c == '\\' {
+		p.pos++
+		if p.eof() {
+			p.fail(start, "\\ at end of pattern")
+			return '\\'
+		} else if c = p.b[p.pos]; c == 'b' {
+			p.pos++
+			return '\b'
+		} else if strings.IndexByte("&-!#%,:;<=>@`~", c) != -1 {
+			p.pos++
+			return rune(c)
+		}
+		return p.parseCharacterEscape(start, true)
+	} else if strings.IndexByte("()[]{}/-|", c) != -1 {
+		p.fail(start, "invalid character in character class")
+		return rune(c)
+	} else if p.peek(1) == c && strings.IndexByte("&!#$%*+,.:;<=>?@^`~", c) != -1 {
+		p.fail(start, "invalid set operation in character class")
+		return rune(c)
+	}
+	r, n := utf8.DecodeRune(p.b[p.pos:])
+	p.pos += n
+	return r
+}
+
+// mayContainStrings returns true if a class set operand may match strings that are not a single character, which is not allowed in negated classes.
+func mayContainStrings(n Node) bool {
+	switch n := n.(type) {
+	case *ClassStrings:
+		for _, s := range n.List {
+			if utf8.RuneCountInString(s) != 1 {
+				return true
+			}
+		}
+	case *Property:
+		return stringProperties[n.Value]
+	case *Class:
+		if n.Negated || len(n.List) == 0 {
+			return false
+		}
+		switch n.Op {
+		case UnionOp:
+			for _, item := range n.List {
+				if mayContainStrings(item) {
+					return true
+				}
+			}
+		case IntersectionOp:
+			for _, item := range n.List {
+				if !mayContainStrings(item) {
+					return false
+				}
+			}
+			return true
+		case SubtractionOp:
+			return mayContainStrings(n.List[0])
+		}
+	}
+	return false
+}
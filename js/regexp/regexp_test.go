@@ -0,0 +1,155 @@
+package regexp
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		regexp   string
+		expected string
+	}{
+		{`//`, `//`},
+		{`/a|b|/gi`, `/a|b|/gi`},
+		{`/^a.b$/ysm`, `/^a.b$/msy`},
+		{`/\bx\B/`, `/\bx\B/`},
+		{`/a*b+?c?d{2}e{2,}f{2,3}?/`, `/a*b+?c?d{2}e{2,}f{2,3}?/`},
+		{`/a{,2}b{/`, `/a\{,2\}b\{/`},
+		{`/(a)(?:b)(?<c>d)\1\k<c>/`, `/(a)(?:b)(?<c>d)\1\k<c>/`},
+		{`/(?i:a)(?-m:b)(?s-i:c)/`, `/(?i:a)(?-m:b)(?s-i:c)/`},
+		{`/(?=a)(?!b)(?<=c)(?<!d)/`, `/(?=a)(?!b)(?<=c)(?<!d)/`},
+		{`/(?=a)*/`, `/(?=a)*/`},
+		{`/\d\D\s\S\w\W/`, `/\d\D\s\S\w\W/`},
+		{`/\t\n\v\f\r\cJ\x41B\0/`, `/\t\n\v\f\r\nAB\x00/`},
+		{`/\u{1F600}😀/u`, `/😀😀/u`},
+		{`/😀/`, `/😀/`},
+		{`/\1(a)/`, `/\1(a)/`},
+		{`/\2(a)\8\101\400/`, `/\x02(a)8A 0/`},
+		{`/\c\q\k/`, `/\\cqk/`},
+		{`/[a-z\d-]/`, `/[a-z\d\-]/`},
+		{`/[^\b\-\w-a]/`, `/[^\x08\-\w\-a]/`},
+		{`/[\c_\01]/`, `/[\x1f\x01]/`},
+		{`/]{}/`, `/\]\{\}/`},
+		{`/\p{L}/`, `/p\{L\}/`},
+		{`/\p{L}\P{Lu}\p{Script=Greek}\p{sc=Grek}\p{scx=Latn}\p{ASCII_Hex_Digit}/u`, `/\p{L}\P{Lu}\p{Script=Greek}\p{sc=Grek}\p{scx=Latn}\p{ASCII_Hex_Digit}/u`},
+		{`/[\p{L}--\p{Lu}][\w&&\d][[a-z]--[aeiou]]/v`, `/[\p{L}--\p{Lu}][\w&&\d][[a-z]--[aeiou]]/v`},
+		{`/[\p{RGI_Emoji}\q{abc|d}\&a-c]/v`, `/[\p{RGI_Emoji}\q{abc|d}&a-c]/v`},
+		{`/[^\q{a|b}]/v`, `/[^\q{a|b}]/v`},
+		{`/(?<a>x)|(?<a>y)/`, `/(?<a>x)|(?<a>y)/`},
+		{`/(?<$a>x)\k<$a>/`, `/(?<$a>x)\k<$a>/`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.regexp, func(t *testing.T) {
+			re, err := ParseLiteral([]byte(tt.regexp))
+			test.Error(t, err)
+			test.String(t, re.String(), tt.expected)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		regexp string
+		err    string
+		offset int
+	}{
+		{`/a`, "unterminated regular expression literal", 2},
+		{`/a/x`, "invalid flag 'x'", 3},
+		{`/a/gg`, "duplicate flag 'g'", 4},
+		{`/a/uv`, "flags u and v can't be combined", 3},
+		{`/a)/`, "unmatched )", 2},
+		{`/(a/`, "unterminated group", 1},
+		{`/[[a]/v`, "unterminated character class", 1},
+		{`/*/`, "nothing to repeat", 1},
+		{`/a**/`, "nothing to repeat", 3},
+		{`/^*/`, "nothing to repeat", 2},
+		{`/{1}/`, "nothing to repeat", 1},
+		{`/(?<=a)?/`, "nothing to repeat", 7},
+		{`/(?=a)?/u`, "nothing to repeat", 6},
+		{`/a{2,1}/`, "numbers out of order in quantifier", 2},
+		{`/a{/u`, "lone quantifier brackets", 2},
+		{`/}/u`, "lone quantifier brackets", 1},
+		{`/]/u`, "lone ]", 1},
+		{`/\/`, "unterminated regular expression literal", 3},
+		{`/a\/`, "unterminated regular expression literal", 4},
+		{`/(?x)/`, "invalid group", 1},
+		{`/(?-:a)/`, "invalid group", 1},
+		{`/(?ii:a)/`, "repeated flag in modifiers", 4},
+		{`/(?i-i:a)/`, "repeated flag in modifiers", 5},
+		{`/(?<1>a)/`, "invalid capture group name", 4},
+		{`/(?<>a)/`, "invalid capture group name", 4},
+		{`/(?<a>x)(?<a>y)/`, "duplicate capture group name", 8},
+		{`/(?:(?<a>x)|y)(?<a>z)/`, "duplicate capture group name", 14},
+		{`/(?<a>x)\k<b>/`, "invalid named reference", 8},
+		{`/(?<a>x)\k/`, "invalid named reference", 8},
+		{`/\k<a>/u`, "invalid named reference", 1},
+		{`/\2(a)/u`, "invalid escape", 1},
+		{`/\01/u`, "invalid decimal escape", 1},
+		{`/\c1/u`, "invalid unicode escape", 1},
+		{`/\x1/u`, "invalid escape", 1},
+		{`/\u12/u`, "invalid unicode escape", 1},
+		{`/\u{110000}/u`, "invalid unicode escape", 1},
+		{`/\q/u`, "invalid escape", 1},
+		{`/[\k](?<a>)/`, "invalid escape", 2},
+		{`/[z-a]/`, "range out of order in character class", 2},
+		{`/[\d-a]/u`, "invalid character class", 4},
+		{`/[\1]/u`, "invalid class escape", 2},
+		{`/\p{Foo}/u`, "invalid property name", 1},
+		{`/\p{Script=Foo}/u`, "invalid property name", 1},
+		{`/\p{Foo=Latin}/u`, "invalid property name", 1},
+		{`/\p{RGI_Emoji}/u`, "invalid property name", 1},
+		{`/\P{RGI_Emoji}/v`, "invalid property name", 1},
+		{`/\p{L/u`, "invalid property name", 1},
+		{`/[a&&&b]/v`, "invalid set operation in character class", 5},
+		{`/[a&&b--c]/v`, "invalid set operation in character class", 6},
+		{`/[ab--c]/v`, "invalid set operation in character class", 4},
+		{`/[a!!b]/v`, "invalid set operation in character class", 3},
+		{`/[(]/v`, "invalid character in character class", 2},
+		{`/[a-\d]/v`, "invalid character class", 2},
+		{`/[^\q{ab}]/v`, "negated character class may contain strings", 1},
+		{`/[^[\p{RGI_Emoji}&&\q{a|bc}]]/v`, "negated character class may contain strings", 1},
+		{`/\q{a/v`, "invalid escape", 1},
+		{`/[\q{a]/v`, "invalid character in character class", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.regexp, func(t *testing.T) {
+			_, err := ParseLiteral([]byte(tt.regexp))
+			test.That(t, err != nil)
+			if err != nil {
+				test.String(t, err.(*Error).Message, tt.err)
+				test.T(t, err.(*Error).Offset, tt.offset)
+			}
+		})
+	}
+}
+
+func TestParseAST(t *testing.T) {
+	re, err := Parse([]byte(`(?<year>\d{4})-(\d\d)|x`), []byte("d"))
+	test.Error(t, err)
+	test.T(t, re.Flags, Flags{HasIndices: true})
+	test.T(t, re.Groups, 2)
+	test.T(t, re.Names, []string{"year", ""})
+
+	disj := re.Pattern.(*Disjunction)
+	test.T(t, len(disj.List), 2)
+	test.T(t, NodeLoc(disj), Loc{0, 23})
+	alt := disj.List[0].(*Alternative)
+	test.T(t, len(alt.List), 3)
+	group := alt.List[0].(*Group)
+	test.T(t, group.Index, 1)
+	test.T(t, group.Name, "year")
+	test.T(t, NodeLoc(group), Loc{0, 14})
+	quant := group.X.(*Alternative).List[0].(*Quantifier)
+	test.T(t, quant.Min, 4)
+	test.T(t, quant.Max, 4)
+	test.T(t, quant.X.(*ClassEscape).Kind, byte('d'))
+	test.T(t, alt.List[1].(*Char).Value, '-')
+	test.T(t, NodeLoc(alt.List[1]), Loc{14, 15})
+
+	_, err = Parse([]byte(`a(`), nil)
+	test.String(t, err.Error(), "invalid regular expression: unterminated group at offset 2")
+	_, err = Parse([]byte(`[\q{a`), []byte("v"))
+	test.String(t, err.Error(), "invalid regular expression: unterminated class string disjunction at offset 2")
+}
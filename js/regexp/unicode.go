@@ -0,0 +1,115 @@
+package regexp
+
+import "unicode"
+
+// generalCategories are the values of the General_Category property, mapping long and short aliases to the short alias.
+var generalCategories = map[string]string{
+	"Cased_Letter": "LC", "Close_Punctuation": "Pe", "Connector_Punctuation": "Pc", "Control": "Cc", "cntrl": "Cc",
+	"Currency_Symbol": "Sc", "Dash_Punctuation": "Pd", "Decimal_Number": "Nd", "digit": "Nd", "Enclosing_Mark": "Me",
+	"Final_Punctuation": "Pf", "Format": "Cf", "Initial_Punctuation": "Pi", "Letter": "L", "Letter_Number": "Nl",
+	"Line_Separator": "Zl", "Lowercase_Letter": "Ll", "Mark": "M", "Combining_Mark": "M", "Math_Symbol": "Sm",
+	"Modifier_Letter": "Lm", "Modifier_Symbol": "Sk", "Nonspacing_Mark": "Mn", "Number": "N", "Open_Punctuation": "Ps",
+	"Other": "C", "Other_Letter": "Lo", "Other_Number": "No", "Other_Punctuation": "Po", "Other_Symbol": "So",
+	"Paragraph_Separator": "Zp", "Private_Use": "Co", "Punctuation": "P", "punct": "P", "Separator": "Z",
+	"Space_Separator": "Zs", "Spacing_Mark": "Mc", "Surrogate": "Cs", "Symbol": "S", "Titlecase_Letter": "Lt",
+	"Unassigned": "Cn", "Uppercase_Letter": "Lu",
+	"LC": "LC", "Pe": "Pe", "Pc": "Pc", "Cc": "Cc", "Sc": "Sc", "Pd": "Pd", "Nd": "Nd", "Me": "Me", "Pf": "Pf", "Cf": "Cf",
+	"Pi": "Pi", "L": "L", "Nl": "Nl", "Zl": "Zl", "Ll": "Ll", "M": "M", "Sm": "Sm", "Lm": "Lm", "Sk": "Sk", "Mn": "Mn",
+	"N": "N", "Ps": "Ps", "C": "C", "Lo": "Lo", "No": "No", "Po": "Po", "So": "So", "Zp": "Zp", "Co": "Co", "P": "P",
+	"Z": "Z", "Zs": "Zs", "Mc": "Mc", "Cs": "Cs", "S": "S", "Lt": "Lt", "Cn": "Cn", "Lu": "Lu",
+}
+
+// binaryProperties are the binary unicode properties that may be used as a lone name, mapping the long and short aliases to the long alias.
+var binaryProperties = map[string]string{
+	"ASCII": "ASCII", "ASCII_Hex_Digit": "ASCII_Hex_Digit", "AHex": "ASCII_Hex_Digit", "Alphabetic": "Alphabetic",
+	"Alpha": "Alphabetic", "Any": "Any", "Assigned": "Assigned", "Bidi_Control": "Bidi_Control", "Bidi_C": "Bidi_Control",
+	"Bidi_Mirrored": "Bidi_Mirrored", "Bidi_M": "Bidi_Mirrored", "Case_Ignorable": "Case_Ignorable", "CI": "Case_Ignorable",
+	"Cased": "Cased", "Changes_When_Casefolded": "Changes_When_Casefolded", "CWCF": "Changes_When_Casefolded",
+	"Changes_When_Casemapped": "Changes_When_Casemapped", "CWCM": "Changes_When_Casemapped",
+	"Changes_When_Lowercased": "Changes_When_Lowercased", "CWL": "Changes_When_Lowercased",
+	"Changes_When_NFKC_Casefolded": "Changes_When_NFKC_Casefolded", "CWKCF": "Changes_When_NFKC_Casefolded",
+	"Changes_When_Titlecased": "Changes_When_Titlecased", "CWT": "Changes_When_Titlecased",
+	"Changes_When_Uppercased": "Changes_When_Uppercased", "CWU": "Changes_When_Uppercased", "Dash": "Dash",
+	"Default_Ignorable_Code_Point": "Default_Ignorable_Code_Point", "DI": "Default_Ignorable_Code_Point",
+	"Deprecated": "Deprecated", "Dep": "Deprecated", "Diacritic": "Diacritic", "Dia": "Diacritic", "Emoji": "Emoji",
+	"Emoji_Component": "Emoji_Component", "EComp": "Emoji_Component", "Emoji_Modifier": "Emoji_Modifier",
+	"EMod": "Emoji_Modifier", "Emoji_Modifier_Base": "Emoji_Modifier_Base", "EBase": "Emoji_Modifier_Base",
+	"Emoji_Presentation": "Emoji_Presentation", "EPres": "Emoji_Presentation",
+	"Extended_Pictographic": "Extended_Pictographic", "ExtPict": "Extended_Pictographic", "Extender": "Extender",
+	"Ext": "Extender", "Grapheme_Base": "Grapheme_Base", "Gr_Base": "Grapheme_Base", "Grapheme_Extend": "Grapheme_Extend",
+	"Gr_Ext": "Grapheme_Extend", "Hex_Digit": "Hex_Digit", "Hex": "Hex_Digit", "IDS_Binary_Operator": "IDS_Binary_Operator",
+	"IDSB": "IDS_Binary_Operator", "IDS_Trinary_Operator": "IDS_Trinary_Operator", "IDST": "IDS_Trinary_Operator",
+	"ID_Continue": "ID_Continue", "IDC": "ID_Continue", "ID_Start": "ID_Start", "IDS": "ID_Start",
+	"Ideographic": "Ideographic", "Ideo": "Ideographic", "Join_Control": "Join_Control", "Join_C": "Join_Control",
+	"Logical_Order_Exception": "Logical_Order_Exception", "LOE": "Logical_Order_Exception", "Lowercase": "Lowercase",
+	"Lower": "Lowercase", "Math": "Math", "Noncharacter_Code_Point": "Noncharacter_Code_Point",
+	"NChar": "Noncharacter_Code_Point", "Pattern_Syntax": "Pattern_Syntax", "Pat_Syn": "Pattern_Syntax",
+	"Pattern_White_Space": "Pattern_White_Space", "Pat_WS": "Pattern_White_Space", "Quotation_Mark": "Quotation_Mark",
+	"QMark": "Quotation_Mark", "Radical": "Radical", "Regional_Indicator": "Regional_Indicator", "RI": "Regional_Indicator",
+	"Sentence_Terminal": "Sentence_Terminal", "STerm": "Sentence_Terminal", "Soft_Dotted": "Soft_Dotted", "SD": "Soft_Dotted",
+	"Terminal_Punctuation": "Terminal_Punctuation", "Term": "Terminal_Punctuation", "Unified_Ideograph": "Unified_Ideograph",
+	"UIdeo": "Unified_Ideograph", "Uppercase": "Uppercase", "Upper": "Uppercase", "Variation_Selector": "Variation_Selector",
+	"VS": "Variation_Selector", "White_Space": "White_Space", "space": "White_Space", "XID_Continue": "XID_Continue",
+	"XIDC": "XID_Continue", "XID_Start": "XID_Start", "XIDS": "XID_Start",
+}
+
+// stringProperties are the properties of strings, which are only available with the v flag.
+var stringProperties = map[string]bool{
+	"Basic_Emoji": true, "Emoji_Keycap_Sequence": true, "RGI_Emoji_Modifier_Sequence": true, "RGI_Emoji_Flag_Sequence": true,
+	"RGI_Emoji_Tag_Sequence": true, "RGI_Emoji_ZWJ_Sequence": true, "RGI_Emoji": true,
+}
+
+// scriptAliases are the short ISO 15924 aliases of the scripts, the long names are taken from unicode.Scripts.
+var scriptAliases = map[string]string{
+	"Adlm": "Adlam", "Aghb": "Caucasian_Albanian", "Ahom": "Ahom", "Arab": "Arabic", "Armi": "Imperial_Aramaic",
+	"Armn": "Armenian", "Avst": "Avestan", "Bali": "Balinese", "Bamu": "Bamum", "Bass": "Bassa_Vah", "Batk": "Batak",
+	"Beng": "Bengali", "Bhks": "Bhaiksuki", "Bopo": "Bopomofo", "Brah": "Brahmi", "Brai": "Braille", "Bugi": "Buginese",
+	"Buhd": "Buhid", "Cakm": "Chakma", "Cans": "Canadian_Aboriginal", "Cari": "Carian", "Cham": "Cham", "Cher": "Cherokee",
+	"Chrs": "Chorasmian", "Copt": "Coptic", "Qaac": "Coptic", "Cpmn": "Cypro_Minoan", "Cprt": "Cypriot", "Cyrl": "Cyrillic",
+	"Deva": "Devanagari", "Diak": "Dives_Akuru", "Dogr": "Dogra", "Dsrt": "Deseret", "Dupl": "Duployan",
+	"Egyp": "Egyptian_Hieroglyphs", "Elba": "Elbasan", "Elym": "Elymaic", "Ethi": "Ethiopic", "Geor": "Georgian",
+	"Glag": "Glagolitic", "Gong": "Gunjala_Gondi", "Gonm": "Masaram_Gondi", "Goth": "Gothic", "Gran": "Grantha",
+	"Grek": "Greek", "Gujr": "Gujarati", "Guru": "Gurmukhi", "Hang": "Hangul", "Hani": "Han", "Hano": "Hanunoo",
+	"Hatr": "Hatran", "Hebr": "Hebrew", "Hira": "Hiragana", "Hluw": "Anatolian_Hieroglyphs", "Hmng": "Pahawh_Hmong",
+	"Hmnp": "Nyiakeng_Puachue_Hmong", "Hung": "Old_Hungarian", "Ital": "Old_Italic", "Java": "Javanese",
+	"Kali": "Kayah_Li", "Kana": "Katakana", "Kawi": "Kawi", "Khar": "Kharoshthi", "Khmr": "Khmer", "Khoj": "Khojki",
+	"Kits": "Khitan_Small_Script", "Knda": "Kannada", "Kthi": "Kaithi", "Lana": "Tai_Tham", "Laoo": "Lao", "Latn": "Latin",
+	"Lepc": "Lepcha", "Limb": "Limbu", "Lina": "Linear_A", "Linb": "Linear_B", "Lisu": "Lisu", "Lyci": "Lycian",
+	"Lydi": "Lydian", "Mahj": "Mahajani", "Maka": "Makasar", "Mand": "Mandaic", "Mani": "Manichaean", "Marc": "Marchen",
+	"Medf": "Medefaidrin", "Mend": "Mende_Kikakui", "Merc": "Meroitic_Cursive", "Mero": "Meroitic_Hieroglyphs",
+	"Mlym": "Malayalam", "Modi": "Modi", "Mong": "Mongolian", "Mroo": "Mro", "Mtei": "Meetei_Mayek", "Mult": "Multani",
+	"Mymr": "Myanmar", "Nagm": "Nag_Mundari", "Nand": "Nandinagari", "Narb": "Old_North_Arabian", "Nbat": "Nabataean",
+	"Newa": "Newa", "Nkoo": "Nko", "Nshu": "Nushu", "Ogam": "Ogham", "Olck": "Ol_Chiki", "Orkh": "Old_Turkic",
+	"Orya": "Oriya", "Osge": "Osage", "Osma": "Osmanya", "Ougr": "Old_Uyghur", "Palm": "Palmyrene", "Pauc": "Pau_Cin_Hau",
+	"Perm": "Old_Permic", "Phag": "Phags_Pa", "Phli": "Inscriptional_Pahlavi", "Phlp": "Psalter_Pahlavi",
+	"Phnx": "Phoenician", "Plrd": "Miao", "Prti": "Inscriptional_Parthian", "Rjng": "Rejang", "Rohg": "Hanifi_Rohingya",
+	"Runr": "Runic", "Samr": "Samaritan", "Sarb": "Old_South_Arabian", "Saur": "Saurashtra", "Sgnw": "SignWriting",
+	"Shaw": "Shavian", "Shrd": "Sharada", "Sidd": "Siddham", "Sind": "Khudawadi", "Sinh": "Sinhala", "Sogd": "Sogdian",
+	"Sogo": "Old_Sogdian", "Sora": "Sora_Sompeng", "Soyo": "Soyombo", "Sund": "Sundanese", "Sylo": "Syloti_Nagri",
+	"Syrc": "Syriac", "Tagb": "Tagbanwa", "Takr": "Takri", "Tale": "Tai_Le", "Talu": "New_Tai_Lue", "Taml": "Tamil",
+	"Tang": "Tangut", "Tavt": "Tai_Viet", "Telu": "Telugu", "Tfng": "Tifinagh", "Tglg": "Tagalog", "Thaa": "Thaana",
+	"Thai": "Thai", "Tibt": "Tibetan", "Tirh": "Tirhuta", "Tnsa": "Tangsa", "Toto": "Toto", "Ugar": "Ugaritic",
+	"Vaii": "Vai", "Vith": "Vithkuqi", "Wara": "Warang_Citi", "Wcho": "Wancho", "Xpeo": "Old_Persian", "Xsux": "Cuneiform",
+	"Yezi": "Yezidi", "Yiii": "Yi", "Zanb": "Zanabazar_Square", "Zinh": "Inherited", "Qaai": "Inherited", "Zyyy": "Common",
+	"Zzzz": "Unknown",
+}
+
+// isScript returns true if the name is a long or short alias of a script.
+func isScript(name string) bool {
+	if _, ok := scriptAliases[name]; ok {
+		return true
+	} else if _, ok := unicode.Scripts[name]; ok {
+		return true
+	}
+	return name == "Unknown"
+}
+
+// isIDStart returns true if the character can start a capture group name.
+func isIDStart(r rune) bool {
+	return r == '$' || r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || 0x80 <= r && unicode.In(r, unicode.Lu, unicode.Ll, unicode.Lt, unicode.Lm, unicode.Lo, unicode.Nl, unicode.Other_ID_Start)
+}
+
+// isIDContinue returns true if the character can continue a capture group name.
+func isIDContinue(r rune) bool {
+	return isIDStart(r) || '0' <= r && r <= '9' || r == 0x200C || r == 0x200D || 0x80 <= r && unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+}
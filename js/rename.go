@@ -0,0 +1,294 @@
+package js
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrRename is returned by Rename when a variable cannot be renamed without changing the meaning of the program.
+var ErrRename = errors.New("cannot rename variable")
+
+// strictKeywords are the identifiers that cannot be used as binding names in strict mode code, in addition to the reserved words.
+var strictKeywords = map[string]bool{
+	"let":        true,
+	"static":     true,
+	"implements": true,
+	"interface":  true,
+	"package":    true,
+	"private":    true,
+	"protected":  true,
+	"public":     true,
+	"await":      true,
+	"yield":      true,
+	"eval":       true,
+	"arguments":  true,
+}
+
+// isBindingName returns true if name can be used as the name of a declaration in strict mode code.
+func isBindingName(name []byte) bool {
+	if !AsIdentifierName(name) || strictKeywords[string(name)] {
+		return false
+	}
+	tt, ok := Keywords[string(name)]
+	return !ok || !IsReservedWord(tt)
+}
+
+// scopeCollector collects all scopes and variables of an AST in depth-first order, so that parent scopes come before their children.
+type scopeCollector struct {
+	scopes []*Scope
+	vars   []*Var
+}
+
+func (c *scopeCollector) Enter(n INode) IVisitor {
+	switch n := n.(type) {
+	case *BlockStmt:
+		c.scopes = append(c.scopes, &n.Scope)
+	case *SwitchStmt:
+		c.scopes = append(c.scopes, &n.Scope)
+	case *ClassDecl:
+		c.scopes = append(c.scopes, &n.Scope)
+	case *Var:
+		c.vars = append(c.vars, n)
+	}
+	return c
+}
+
+func (c *scopeCollector) Exit(n INode) {}
+
+func collectScopes(ast *AST) *scopeCollector {
+	c := &scopeCollector{}
+	Walk(c, ast)
+	return c
+}
+
+// within returns true if s is the scope or one of its descendants.
+func (s *Scope) within(scope *Scope) bool {
+	for ; s != nil; s = s.Parent {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the variable is in the array.
+func (vs VarArray) contains(v *Var) bool {
+	for _, w := range vs {
+		if w.Resolve() == v {
+			return true
+		}
+	}
+	return false
+}
+
+// usesEval returns true if there is a direct call to eval in the scope or its descendants.
+func (s *Scope) usesEval() bool {
+	for _, v := range s.Undeclared {
+		if v = v.Resolve(); 0 < v.Uses && v.Decl == NoDecl && bytes.Equal(v.Data, []byte("eval")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rename renames a declared variable and all its uses. It returns an error that wraps ErrRename if the name is not a valid identifier, if the variable is global or a private name, if the name is already declared in a scope where the variable is visible, if the variable would capture uses of another variable with the same name, if the variable is visible to a with statement or direct eval call, or if the variable is exported by its declaration. Local names in export lists are updated so that the exported names stay the same.
+func Rename(ast *AST, v *Var, name []byte) error {
+	v = v.Resolve()
+	if !isBindingName(name) {
+		return fmt.Errorf("%w: %s is not a valid identifier", ErrRename, name)
+	} else if v.Decl == NoDecl {
+		return fmt.Errorf("%w: %s is not declared", ErrRename, v.Data)
+	} else if v.Decl == PrivateDecl {
+		return fmt.Errorf("%w: %s is a private name", ErrRename, v.Data)
+	} else if bytes.Equal(v.Data, name) {
+		return nil
+	}
+
+	c := collectScopes(ast)
+	var decl *Scope
+	for _, s := range c.scopes {
+		for _, w := range s.Declared {
+			if w == v {
+				decl = s
+				break
+			}
+		}
+	}
+	if decl == nil {
+		return fmt.Errorf("%w: %s is not declared in a scope of the AST", ErrRename, v.Data)
+	}
+
+	for _, s := range c.scopes {
+		if !s.within(decl) {
+			continue
+		} else if s.Func.HasWith {
+			return fmt.Errorf("%w: %s is visible to a with statement", ErrRename, v.Data)
+		} else if s.usesEval() {
+			return fmt.Errorf("%w: %s is visible to a direct eval call", ErrRename, v.Data)
+		}
+		if s == decl || s.Undeclared.contains(v) {
+			// the variable is visible in the scope, declarations in scopes that don't use it may shadow it
+			for _, w := range s.Declared {
+				if w != v && bytes.Equal(w.Data, name) {
+					return fmt.Errorf("%w: %s is already declared", ErrRename, name)
+				}
+			}
+		}
+		for _, w := range s.Undeclared {
+			if w = w.Resolve(); w != v && 0 < w.Uses && bytes.Equal(w.Data, name) {
+				return fmt.Errorf("%w: %s would refer to %s instead of the variable with the same name", ErrRename, name, v.Data)
+			}
+		}
+	}
+
+	var exports []*Alias
+	if decl == &ast.BlockStmt.Scope {
+		for _, item := range ast.List {
+			exportStmt, ok := item.(*ExportStmt)
+			if !ok || exportStmt.Module != nil || exportStmt.Default {
+				continue
+			} else if exportStmt.Decl != nil {
+				if declares(exportStmt.Decl, v) {
+					return fmt.Errorf("%w: %s is exported by its declaration", ErrRename, v.Data)
+				}
+				continue
+			}
+			for i, alias := range exportStmt.List {
+				if alias.Name == nil && bytes.Equal(alias.Binding, v.Data) || bytes.Equal(alias.Name, v.Data) {
+					exports = append(exports, &exportStmt.List[i])
+				}
+			}
+		}
+	}
+
+	name = append([]byte{}, name...)
+	for _, alias := range exports {
+		if bytes.Equal(alias.Binding, name) {
+			alias.Name = nil
+		} else {
+			if alias.Name == nil {
+				alias.Binding = append([]byte{}, alias.Binding...) // the exported name may share its data with the variable
+			}
+			alias.Name = name
+		}
+	}
+	for _, w := range c.vars {
+		if w.Resolve() == v {
+			w.Data = name
+		}
+	}
+	v.Data = name
+	return nil
+}
+
+// declares returns true if the declaration declares the variable.
+func declares(decl IExpr, v *Var) bool {
+	switch decl := decl.(type) {
+	case *VarDecl:
+		for _, item := range decl.List {
+			if bindingDeclares(item.Binding, v) {
+				return true
+			}
+		}
+	case *FuncDecl:
+		return decl.Name != nil && decl.Name.Resolve() == v
+	case *ClassDecl:
+		return decl.Name != nil && decl.Name.Resolve() == v
+	}
+	return false
+}
+
+func bindingDeclares(binding IBinding, v *Var) bool {
+	switch binding := binding.(type) {
+	case *Var:
+		return binding.Resolve() == v
+	case *BindingArray:
+		for _, item := range binding.List {
+			if bindingDeclares(item.Binding, v) {
+				return true
+			}
+		}
+		return bindingDeclares(binding.Rest, v)
+	case *BindingObject:
+		for _, item := range binding.List {
+			if bindingDeclares(item.Value.Binding, v) {
+				return true
+			}
+		}
+		return binding.Rest != nil && binding.Rest.Resolve() == v
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////
+
+var mangleStart = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_$")
+var mangleContinue = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_$0123456789")
+
+// mangledName returns the i-th short name, which are a, b, ..., $, aa, ba, and so on.
+func mangledName(i int) []byte {
+	name := []byte{mangleStart[i%len(mangleStart)]}
+	i /= len(mangleStart)
+	for 0 < i {
+		i--
+		name = append(name, mangleContinue[i%len(mangleContinue)])
+		i /= len(mangleContinue)
+	}
+	return name
+}
+
+// Mangle renames the local variables to short names, where the variables used most often get the shortest names. Variables declared in the global or module scope and private names are not renamed, nor are the variables that are visible to a with statement or a direct eval call. Variables of sibling scopes may get the same name. Names that are used by a scope for variables declared outside of it are never reused, so that the program keeps its meaning.
+func Mangle(ast *AST) {
+	c := collectScopes(ast)
+
+	// scopes that contain a with statement or a direct eval call, and their parents, are not renamed
+	unsafe := map[*Scope]bool{}
+	for _, s := range c.scopes {
+		if s.Func.HasWith || s.usesEval() {
+			for ; s != nil && !unsafe[s]; s = s.Parent {
+				unsafe[s] = true
+			}
+		}
+	}
+
+	for _, s := range c.scopes {
+		if s.Parent == nil || unsafe[s] {
+			continue
+		}
+
+		// names of variables that are declared outside the scope, these have been renamed already
+		taken := map[string]bool{}
+		for _, v := range s.Undeclared {
+			if v = v.Resolve(); 0 < v.Uses {
+				taken[string(v.Data)] = true
+			}
+		}
+
+		vars := VarArray{}
+		for _, v := range s.Declared {
+			if v.Decl != PrivateDecl {
+				vars = append(vars, v)
+			}
+		}
+		sort.Stable(VarsByUses(vars))
+
+		i := 0
+		for _, v := range vars {
+			name := mangledName(i)
+			for taken[string(name)] || !isBindingName(name) {
+				i++
+				name = mangledName(i)
+			}
+			i++
+			v.Data = name
+		}
+	}
+
+	for _, v := range c.vars {
+		if v.Link != nil {
+			v.Data = v.Resolve().Data
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package js
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+// findVar returns the variable of the first occurrence of name in the source.
+func findVar(t *testing.T, ast *AST, src, name string) *Var {
+	t.Helper()
+	loc := regexp.MustCompile(`\b` + name + `\b`).FindStringIndex(src)
+	if loc == nil {
+		t.Fatalf("variable %s not found", name)
+	}
+	v := AnalyzeScopes(ast).Lookup(loc[0])
+	if v == nil {
+		t.Fatalf("variable %s not found", name)
+	}
+	return v
+}
+
+func TestRename(t *testing.T) {
+	var tests = []struct {
+		js       string
+		old, new string
+		expected string
+	}{
+		{"let a = 1; a++", "a", "b", "let b = 1;\nb++;"},
+		{"function f(x) { return {x} }", "x", "y", "function f(y) {\n    return {x: y};\n}"},
+		{"function f(x) { let {x: y} = x; { y } }", "y", "z", "function f(x) {\n    let {x: z} = x;\n    {\n        z;\n    }\n}"},
+		{"function f() { { a } var a }", "a", "b", "function f() {\n    {\n        b;\n    }\n    var b;\n}"},
+		{"let a; export {a}", "a", "b", "let b;\nexport { b as a };"},
+		{"let a; export {a as c}", "a", "b", "let b;\nexport { b as c };"},
+		{"let a; export {a as b}", "a", "b", "let b;\nexport { b };"},
+		{"function a() {} a()", "a", "f", "function f() {}\nf();"},
+		{"let a = 1; function g() { let b = 2; return b }", "a", "b", "let b = 1;\nfunction g() {\n    let b = 2;\n    return b;\n}"},
+		{"let a = 1; a = 2", "a", "a", "let a = 1;\na = 2;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			test.Error(t, err)
+			v := findVar(t, ast, tt.js, tt.old)
+			test.Error(t, Rename(ast, v, []byte(tt.new)))
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+func TestRenameError(t *testing.T) {
+	var tests = []struct {
+		js       string
+		old, new string
+	}{
+		{"let a, b", "a", "b"},                                          // already declared
+		{"let a; { let b; a }", "a", "b"},                               // shadowed
+		{"let a; function f() { b }", "a", "b"},                         // captures global
+		{"let a; function f() { let b; return () => b + a }", "a", "b"}, // shadowed in function
+		{"a = 1", "a", "b"},                                             // global
+		{"let a", "a", "if"},                                            // reserved word
+		{"let a", "a", "1a"},                                            // invalid identifier
+		{"let a", "a", "arguments"},                                     // strict mode
+		{"function f(o) { var a; with (o) { a } }", "a", "b"},           // with statement
+		{"function f(s) { var a; eval(s) }", "a", "b"},                  // direct eval
+		{"export let a = 1", "a", "b"},                                  // exported declaration
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			test.Error(t, err)
+			v := findVar(t, ast, tt.js, tt.old)
+			src := ast.JSString()
+			err = Rename(ast, v, []byte(tt.new))
+			test.That(t, errors.Is(err, ErrRename), "must return ErrRename")
+			test.String(t, ast.JSString(), src)
+		})
+	}
+}
+
+func TestMangle(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"function f(foo, bar) { return bar + bar + foo }", "function f(b, a) {\n    return a + a + b;\n}"},
+		{"let x = 1; function f(foo) { let y = foo; return x + y }", "let x = 1;\nfunction f(a) {\n    let b = a;\n    return x + b;\n}"},
+		{"function f(foo) { return a + foo }", "function f(b) {\n    return a + b;\n}"},
+		{"function f(foo) { { let bar = foo; bar } { let baz = 1; baz } }", "function f(a) {\n    {\n        let b = a;\n        b;\n    }\n    {\n        let a = 1;\n        a;\n    }\n}"},
+		{"function f(foo) { return {foo} }", "function f(a) {\n    return {foo: a};\n}"},
+		{"function f(foo) { { foo } var foo }", "function f(a) {\n    {\n        a;\n    }\n    var a;\n}"},
+		{"function f(foo, s) { eval(s) }", "function f(foo, s) {\n    eval(s);\n}"},
+		{"function f(foo, o) { with (o) { foo } } function g(foo) { foo }", "function f(foo, o) {\n    with (o) {\n        foo;\n    }\n}\nfunction g(a) {\n    a;\n}"},
+		{"class A { #x; m(foo) { return this.#x + foo } }", "class A {\n    #x;\n    m (a) {\n        return this.#x + a;\n    }\n}"},
+		{"x => y => x + y", "(a) => {\n    return (b) => {\n        return a + b;\n    };\n};"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			test.Error(t, err)
+			Mangle(ast)
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+func TestMangledName(t *testing.T) {
+	test.String(t, string(mangledName(0)), "a")
+	test.String(t, string(mangledName(53)), "$")
+	test.String(t, string(mangledName(54)), "aa")
+	test.String(t, string(mangledName(55)), "ba")
+	test.String(t, string(mangledName(54+54)), "ab")
+}
@@ -0,0 +1,717 @@
+package js
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrRewrite is returned by Rewrite when a node is replaced by a node of the wrong type.
+var ErrRewrite = errors.New("invalid replacement")
+
+// IRewriter represents an AST rewriter
+// Each INode encountered by `Rewrite` is passed to `Enter`, children nodes will be ignored if the returned IRewriter is nil
+// `Exit` is called upon the exit of a node and returns the node to replace it with, which is the node itself to keep it or nil to delete it
+type IRewriter interface {
+	Enter(n INode) IRewriter
+	Exit(n INode) INode
+}
+
+// Rewrite traverses an AST in depth-first order like Walk and replaces statements, expressions, and bindings by the node returned from Exit, which is called after rewriting the children. An expression returned for a statement is wrapped in an ExprStmt.
+//
+// Deleted statements are removed from statement lists or replaced by an EmptyStmt when they are the body of another statement. Deleted expressions and bindings are removed from lists such as arguments, array elements, properties, and declarations, become nil when optional, and are otherwise replaced by `void 0`. Expression statements and declarations that become empty are deleted as well, as are assignments and updates whose target is deleted. Properties, elements, arguments, binding elements, case clauses, template parts, and JSX attributes can also be deleted or replaced by a node of the same type.
+//
+// The Uses of the variables in deleted or inserted nodes are updated, and their Locs within replaced or deleted nodes are removed. Variables that are no longer used are removed from the Declared and Undeclared lists of the scopes, and newly used variables are added to the Undeclared lists of the scopes up to where they are declared. It returns the rewritten node, and an error that wraps ErrRewrite for the first node that was replaced by a node of the wrong type, such as a statement for an expression, or that was deleted while it can't be, such as the initializer of a for-in or for-of statement. Those nodes are kept.
+func Rewrite(r IRewriter, n INode) (INode, error) {
+	w := &rewriter{r: r}
+	return w.rewrite(n, anyKind), w.err
+}
+
+type rewriter struct {
+	r      IRewriter
+	scopes []*Scope // scopes of the current node, innermost last
+	err    error
+}
+
+// nodeKind is the kind of node that a node can be replaced by.
+type nodeKind int
+
+const (
+	anyKind     nodeKind = iota
+	stmtKind             // statements and expressions, which are wrapped in an ExprStmt
+	exprKind             // expressions
+	bindingKind          // bindings
+	targetKind           // expressions that can't be deleted, such as the initializer of a for-in or for-of statement
+	sameKind             // nodes of the same type
+)
+
+// accepts returns true if node n can be replaced by m, which is not nil.
+func (k nodeKind) accepts(n, m INode) bool {
+	switch k {
+	case stmtKind:
+		_, isStmt := m.(IStmt)
+		_, isExpr := m.(IExpr)
+		return isStmt || isExpr
+	case exprKind, targetKind:
+		_, ok := m.(IExpr)
+		return ok
+	case bindingKind:
+		_, ok := m.(IBinding)
+		return ok
+	case sameKind:
+		return reflect.TypeOf(n) == reflect.TypeOf(m)
+	}
+	return true
+}
+
+// rewrite rewrites a node that can be replaced by a node of the given kind.
+func (w *rewriter) rewrite(n INode, kind nodeKind) INode {
+	if n == nil {
+		return nil
+	}
+
+	r := w.r.Enter(n)
+	if r == nil {
+		return n
+	}
+
+	parent := w.r
+	w.r = r
+	var m INode
+	if w.children(n) {
+		m = r.Exit(n)
+	}
+	w.r = parent
+	if m == nil && kind == targetKind {
+		if w.err == nil {
+			w.err = fmt.Errorf("%w: cannot delete %T", ErrRewrite, n)
+		}
+		return n
+	} else if m != nil && !kind.accepts(n, m) {
+		if w.err == nil {
+			w.err = fmt.Errorf("%w: cannot replace %T by %T", ErrRewrite, n, m)
+		}
+		return n
+	} else if !sameNode(n, m) {
+		w.replace(n, m)
+	}
+	return m
+}
+
+// sameNode returns true if m is n. Nodes that are stored by value, such as the LiteralExpr in DotExpr, are the same when they have the same type.
+func sameNode(n, m INode) bool {
+	if m == nil {
+		return false
+	} else if t := reflect.TypeOf(n); t != reflect.TypeOf(m) {
+		return false
+	} else if t.Kind() != reflect.Ptr {
+		return true
+	}
+	return n == m
+}
+
+func (w *rewriter) pushScope(s *Scope) {
+	w.scopes = append(w.scopes, s)
+}
+
+func (w *rewriter) popScope() {
+	w.scopes = w.scopes[:len(w.scopes)-1]
+}
+
+// undefined returns the replacement for deleted expressions that are required.
+func undefined() IExpr {
+	return &UnaryExpr{Op: VoidToken, X: &LiteralExpr{TokenType: IntegerToken, Data: []byte("0")}}
+}
+
+func (w *rewriter) stmt(n IStmt) IStmt {
+	switch m := w.rewrite(n, stmtKind).(type) {
+	case IStmt:
+		return m
+	case nil:
+		return nil
+	default:
+		return &ExprStmt{Value: m.(IExpr)}
+	}
+}
+
+// body rewrites the body of a statement, which can't be deleted.
+func (w *rewriter) body(n IStmt) IStmt {
+	if m := w.stmt(n); m != nil {
+		return m
+	}
+	return &EmptyStmt{}
+}
+
+func (w *rewriter) stmts(list []IStmt) []IStmt {
+	j := 0
+	for _, item := range list {
+		if m := w.stmt(item); m != nil {
+			list[j] = m
+			j++
+		}
+	}
+	return list[:j]
+}
+
+// block rewrites a block statement, which can't be deleted.
+func (w *rewriter) block(n *BlockStmt) *BlockStmt {
+	switch m := w.stmt(n).(type) {
+	case *BlockStmt:
+		return m
+	case nil:
+		return &BlockStmt{Scope: n.Scope, Loc: n.Loc}
+	default:
+		return &BlockStmt{List: []IStmt{m}, Scope: n.Scope, Loc: n.Loc}
+	}
+}
+
+// blockValue rewrites a block statement that is stored as a value.
+func (w *rewriter) blockValue(n *BlockStmt) {
+	if m := w.block(n); m != n {
+		*n = *m
+	}
+}
+
+func (w *rewriter) expr(n IExpr) IExpr {
+	if m := w.rewrite(n, exprKind); m != nil {
+		return m.(IExpr)
+	}
+	return nil
+}
+
+// required rewrites an expression that can't be deleted.
+func (w *rewriter) required(n IExpr) IExpr {
+	if m := w.expr(n); m != nil {
+		return m
+	}
+	return undefined()
+}
+
+func (w *rewriter) binding(n IBinding) IBinding {
+	if m := w.rewrite(n, bindingKind); m != nil {
+		return m.(IBinding)
+	}
+	return nil
+}
+
+// bindingElement rewrites a binding element and returns false if it was deleted.
+func (w *rewriter) bindingElement(n *BindingElement) bool {
+	m, ok := w.rewrite(n, sameKind).(*BindingElement)
+	if !ok || m.Binding == nil {
+		return false
+	} else if m != n {
+		*n = *m
+	}
+	return true
+}
+
+func (w *rewriter) bindingElements(list []BindingElement) []BindingElement {
+	j := 0
+	for i := range list {
+		if w.bindingElement(&list[i]) {
+			list[j] = list[i]
+			j++
+		}
+	}
+	return list[:j]
+}
+
+func (w *rewriter) params(n *Params, scope *Scope) {
+	w.pushScope(scope)
+	n.List = w.bindingElements(n.List)
+	n.Rest = w.binding(n.Rest)
+	w.popScope()
+}
+
+func (w *rewriter) propertyName(n *PropertyName) {
+	if n.Computed != nil {
+		n.Computed = w.required(n.Computed)
+	}
+}
+
+// children rewrites the children of a node and returns false if the node must be deleted.
+func (w *rewriter) children(n INode) bool {
+	switch n := n.(type) {
+	case *AST:
+		w.blockValue(&n.BlockStmt)
+	case *BlockStmt:
+		w.pushScope(&n.Scope)
+		n.List = w.stmts(n.List)
+		w.popScope()
+	case *ExprStmt:
+		n.Value = w.expr(n.Value)
+		return n.Value != nil
+	case *IfStmt:
+		n.Body = w.body(n.Body)
+		if n.Else != nil {
+			n.Else = w.stmt(n.Else)
+		}
+		n.Cond = w.required(n.Cond)
+	case *DoWhileStmt:
+		n.Body = w.body(n.Body)
+		n.Cond = w.required(n.Cond)
+	case *WhileStmt:
+		n.Body = w.body(n.Body)
+		n.Cond = w.required(n.Cond)
+	case *ForStmt:
+		n.Body = w.block(n.Body)
+		n.Init = w.expr(n.Init)
+		n.Cond = w.expr(n.Cond)
+		n.Post = w.expr(n.Post)
+	case *ForInStmt:
+		n.Body = w.block(n.Body)
+		n.Init = w.rewrite(n.Init, targetKind).(IExpr)
+		n.Value = w.required(n.Value)
+	case *ForOfStmt:
+		n.Body = w.block(n.Body)
+		n.Init = w.rewrite(n.Init, targetKind).(IExpr)
+		n.Value = w.required(n.Value)
+	case *CaseClause:
+		n.List = w.stmts(n.List)
+		if n.Cond != nil {
+			n.Cond = w.required(n.Cond)
+		}
+	case *SwitchStmt:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*CaseClause); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+		n.Init = w.required(n.Init)
+	case *ReturnStmt:
+		n.Value = w.expr(n.Value)
+	case *WithStmt:
+		n.Body = w.body(n.Body)
+		n.Cond = w.required(n.Cond)
+	case *LabelledStmt:
+		n.Value = w.body(n.Value)
+	case *ThrowStmt:
+		n.Value = w.required(n.Value)
+	case *TryStmt:
+		n.Body = w.block(n.Body)
+		if n.Catch != nil {
+			n.Catch = w.block(n.Catch)
+		}
+		if n.Finally != nil {
+			n.Finally = w.block(n.Finally)
+		}
+		if n.Binding != nil {
+			n.Binding = w.binding(n.Binding)
+		}
+	case *ImportStmt:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*Alias); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+	case *ExportStmt:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*Alias); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+		if n.Decl != nil {
+			n.Decl = w.expr(n.Decl)
+			return n.Decl != nil
+		}
+	case *PropertyName:
+		w.propertyName(n)
+	case *BindingArray:
+		for i := range n.List {
+			if !w.bindingElement(&n.List[i]) {
+				n.List[i] = BindingElement{} // elision
+			}
+		}
+		n.Rest = w.binding(n.Rest)
+	case *BindingObjectItem:
+		if n.Key != nil {
+			w.propertyName(n.Key)
+		}
+		return w.bindingElement(&n.Value)
+	case *BindingObject:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*BindingObjectItem); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+		if n.Rest != nil {
+			n.Rest, _ = w.rewrite(n.Rest, sameKind).(*Var)
+		}
+	case *BindingElement:
+		n.Binding = w.binding(n.Binding)
+		if n.Default != nil {
+			n.Default = w.expr(n.Default)
+		}
+	case *VarDecl:
+		n.List = w.bindingElements(n.List)
+		return len(n.List) != 0
+	case *Params:
+		n.List = w.bindingElements(n.List)
+		n.Rest = w.binding(n.Rest)
+	case *FuncDecl:
+		w.blockValue(&n.Body)
+		w.params(&n.Params, &n.Body.Scope)
+		if n.Name != nil {
+			if v, ok := w.rewrite(n.Name, sameKind).(*Var); ok {
+				n.Name = v
+			}
+		}
+	case *MethodDecl:
+		n.Decorators = w.exprs(n.Decorators)
+		w.blockValue(&n.Body)
+		w.params(&n.Params, &n.Body.Scope)
+		w.propertyName(&n.Name.PropertyName)
+	case *Field:
+		n.Decorators = w.exprs(n.Decorators)
+		w.propertyName(&n.Name.PropertyName)
+		if n.Init != nil {
+			n.Init = w.expr(n.Init)
+		}
+	case *ClassDecl:
+		n.Decorators = w.exprs(n.Decorators)
+		if n.Name != nil {
+			if v, ok := w.rewrite(n.Name, sameKind).(*Var); ok {
+				n.Name = v
+			}
+		}
+		if n.Extends != nil {
+			n.Extends = w.expr(n.Extends)
+		}
+
+		w.pushScope(&n.Scope)
+		j := 0
+		for _, item := range n.List {
+			if item.StaticBlock != nil {
+				if item.StaticBlock = w.stmtBlock(item.StaticBlock); item.StaticBlock == nil {
+					continue
+				}
+			} else if item.Method != nil {
+				if item.Method, _ = w.rewrite(item.Method, sameKind).(*MethodDecl); item.Method == nil {
+					continue
+				}
+			} else if m, ok := w.rewrite(&item.Field, sameKind).(*Field); !ok {
+				continue
+			} else {
+				item.Field = *m
+			}
+			n.List[j] = item
+			j++
+		}
+		n.List = n.List[:j]
+		w.popScope()
+	case *Element:
+		n.Value = w.expr(n.Value)
+		return n.Value != nil
+	case *ArrayExpr:
+		j := 0
+		for i := range n.List {
+			if n.List[i].Value == nil {
+				n.List[j] = n.List[i] // elision
+				j++
+			} else if m, ok := w.rewrite(&n.List[i], sameKind).(*Element); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+	case *Property:
+		if n.Name != nil {
+			w.propertyName(n.Name)
+		}
+		n.Value = w.expr(n.Value)
+		if n.Init != nil {
+			n.Init = w.expr(n.Init)
+		}
+		return n.Value != nil
+	case *ObjectExpr:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*Property); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+	case *TemplatePart:
+		n.Expr = w.required(n.Expr)
+	case *TemplateExpr:
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*TemplatePart); ok {
+				n.List[i] = *m
+			} else {
+				n.List[i].Expr = undefined()
+			}
+		}
+		if n.Tag != nil {
+			if n.Tag = w.expr(n.Tag); n.Tag == nil {
+				n.Optional = false
+			}
+		}
+	case *GroupExpr:
+		n.X = w.required(n.X)
+	case *IndexExpr:
+		n.X = w.required(n.X)
+		n.Y = w.required(n.Y)
+	case *DotExpr:
+		n.X = w.required(n.X)
+		if m := w.expr(n.Y); m != nil {
+			n.Y = m
+		}
+	case *Arg:
+		n.Value = w.expr(n.Value)
+		return n.Value != nil
+	case *Args:
+		j := 0
+		for i := range n.List {
+			if m, ok := w.rewrite(&n.List[i], sameKind).(*Arg); ok {
+				n.List[j] = *m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+	case *NewExpr:
+		if n.Args != nil {
+			w.rewrite(n.Args, sameKind)
+		}
+		n.X = w.required(n.X)
+	case *CallExpr:
+		w.rewrite(&n.Args, sameKind)
+		n.X = w.required(n.X)
+	case *UnaryExpr:
+		if n.Op == PreIncrToken || n.Op == PreDecrToken || n.Op == PostIncrToken || n.Op == PostDecrToken {
+			// deleting the target deletes the update
+			n.X = w.expr(n.X)
+			return n.X != nil
+		}
+		n.X = w.required(n.X)
+	case *BinaryExpr:
+		if isAssignment(n.Op) {
+			// deleting the target deletes the assignment
+			if n.X = w.expr(n.X); n.X == nil {
+				return false
+			}
+		} else {
+			n.X = w.required(n.X)
+		}
+		n.Y = w.required(n.Y)
+	case *CondExpr:
+		n.Cond = w.required(n.Cond)
+		n.X = w.required(n.X)
+		n.Y = w.required(n.Y)
+	case *YieldExpr:
+		if n.X != nil {
+			if n.X = w.expr(n.X); n.X == nil {
+				n.Generator = false
+			}
+		}
+	case *ArrowFunc:
+		w.blockValue(&n.Body)
+		w.params(&n.Params, &n.Body.Scope)
+	case *CommaExpr:
+		j := 0
+		for _, item := range n.List {
+			if m := w.expr(item); m != nil {
+				n.List[j] = m
+				j++
+			}
+		}
+		n.List = n.List[:j]
+		return len(n.List) != 0
+	case *JSXElement:
+		if m := w.expr(n.Name); m != nil {
+			n.Name = m
+		}
+		j := 0
+		for i := range n.Attrs {
+			if m, ok := w.rewrite(&n.Attrs[i], sameKind).(*JSXAttribute); ok {
+				n.Attrs[j] = *m
+				j++
+			}
+		}
+		n.Attrs = n.Attrs[:j]
+		n.Children = w.exprs(n.Children)
+	case *JSXFragment:
+		n.Children = w.exprs(n.Children)
+	case *JSXAttribute:
+		if n.Value != nil {
+			n.Value = w.expr(n.Value)
+			return n.Value != nil || !n.Spread
+		}
+	case *JSXExprContainer:
+		if n.X != nil {
+			n.X = w.expr(n.X)
+		}
+	}
+	return true
+}
+
+// stmtBlock rewrites a block statement that is deleted when replaced by nil.
+func (w *rewriter) stmtBlock(n *BlockStmt) *BlockStmt {
+	switch m := w.stmt(n).(type) {
+	case *BlockStmt:
+		return m
+	case nil:
+		return nil
+	default:
+		return &BlockStmt{List: []IStmt{m}, Scope: n.Scope, Loc: n.Loc}
+	}
+}
+
+func (w *rewriter) exprs(list []IExpr) []IExpr {
+	j := 0
+	for _, item := range list {
+		if m := w.expr(item); m != nil {
+			list[j] = m
+			j++
+		}
+	}
+	return list[:j]
+}
+
+////////////////////////////////////////////////////////////////
+
+// varCounter counts the occurrences of variables and variable declarations in a node, and keeps them in the order they are visited.
+type varCounter struct {
+	vars      map[*Var]int
+	old       map[*Var]bool // variables in the replaced node
+	decls     map[*VarDecl]int
+	varOrder  []*Var
+	declOrder []*VarDecl
+	delta     int
+}
+
+func (c *varCounter) Enter(n INode) IVisitor {
+	switch n := n.(type) {
+	case *Var:
+		if _, ok := c.vars[n]; !ok {
+			c.varOrder = append(c.varOrder, n)
+		}
+		c.vars[n] += c.delta
+		if c.delta < 0 {
+			c.old[n] = true
+		}
+	case *VarDecl:
+		if _, ok := c.decls[n]; !ok {
+			c.declOrder = append(c.declOrder, n)
+		}
+		c.decls[n] += c.delta
+	}
+	return c
+}
+
+func (c *varCounter) Exit(n INode) {}
+
+// replace updates the variable bookkeeping when replacing node n by m, which is nil when deleted.
+func (w *rewriter) replace(n, m INode) {
+	c := &varCounter{vars: map[*Var]int{}, old: map[*Var]bool{}, decls: map[*VarDecl]int{}, delta: -1}
+	Walk(c, n)
+	if m != nil {
+		c.delta = 1
+		Walk(c, m)
+	}
+
+	// the locations within the replaced node are removed, since they may no longer belong to the same variable
+	loc := NodeLoc(n)
+	// in source order, so that variables are added to the scopes deterministically
+	for _, v := range c.varOrder {
+		if c.old[v] {
+			removeLocs(v, loc)
+		}
+		if delta := c.vars[v]; delta != 0 {
+			w.use(v, delta)
+		}
+	}
+	for _, decl := range c.declOrder {
+		if c.decls[decl] < 0 && decl.Scope != nil {
+			for i, item := range decl.Scope.VarDecls {
+				if item == decl {
+					decl.Scope.VarDecls = append(decl.Scope.VarDecls[:i], decl.Scope.VarDecls[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+// removeLocs removes the locations within loc of a variable and of the variables it links to.
+func removeLocs(v *Var, loc Loc) {
+	if loc.End <= loc.Start {
+		return
+	}
+	for u := v; u != nil; u = u.Link {
+		j := 0
+		for _, l := range u.Locs {
+			if l.Start < loc.Start || loc.End <= l.Start {
+				u.Locs[j] = l
+				j++
+			}
+		}
+		u.Locs = u.Locs[:j]
+	}
+}
+
+// use adds delta to the uses of a variable and of the variables it links to.
+func (w *rewriter) use(v *Var, delta int) {
+	for u := v; u != nil; u = u.Link {
+		if uses := int(u.Uses) + delta; uses < 0 {
+			u.Uses = 0
+		} else {
+			u.Uses = uint16(uses)
+		}
+		if u.Uses == 0 {
+			u.Locs = u.Locs[:0]
+		}
+		v = u
+	}
+
+	if delta < 0 && v.Uses == 0 {
+		// remove unused variable from all scopes
+		for _, s := range w.scopes {
+			for i := 0; i < len(s.Declared); i++ {
+				if s.Declared[i] == v {
+					s.Declared = append(s.Declared[:i], s.Declared[i+1:]...)
+					if i < int(s.NumForDecls) {
+						s.NumForDecls--
+					}
+					if i < int(s.NumFuncArgs) {
+						s.NumFuncArgs--
+					}
+					break
+				}
+			}
+			for i := 0; i < len(s.Undeclared); i++ {
+				if s.Undeclared[i] == v {
+					s.Undeclared = append(s.Undeclared[:i], s.Undeclared[i+1:]...)
+					if i < int(s.NumArgUses) {
+						s.NumArgUses--
+					}
+					break
+				}
+			}
+		}
+	} else if 0 < delta {
+		// add newly used variable to the scopes up to where it is declared
+	Scopes:
+		for i := len(w.scopes) - 1; 0 <= i; i-- {
+			s := w.scopes[i]
+			for _, decl := range s.Declared {
+				if decl == v {
+					break Scopes
+				}
+			}
+			s.AddUndeclared(v)
+		}
+	}
+}
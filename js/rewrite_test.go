@@ -0,0 +1,292 @@
+package js
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+type rewriteFunc func(INode) INode
+
+func (f rewriteFunc) Enter(n INode) IRewriter {
+	return f
+}
+
+func (f rewriteFunc) Exit(n INode) INode {
+	return f(n)
+}
+
+// dropConsole deletes all calls to console.*
+func dropConsole(n INode) INode {
+	if call, ok := n.(*CallExpr); ok {
+		if dot, ok := call.X.(*DotExpr); ok {
+			if v, ok := dot.X.(*Var); ok && bytes.Equal(v.Data, []byte("console")) {
+				return nil
+			}
+		}
+	}
+	return n
+}
+
+func TestRewrite(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{`console.log(a); b()`, `b();`},
+		{`if (a) console.log(a); else b()`, `if (a); else b();`},
+		{`while (a) console.log(a)`, `while (a);`},
+		{`for (;;) console.log(a)`, `for ( ; ; ) {}`},
+		{`a(console.log(b), c)`, `a(c);`},
+		{`x = [console.log(a), b, , c]`, `x = [b, , c];`},
+		{`x = {a: console.log(a), b}`, `x = {b};`},
+		{`x = a + console.log(b)`, `x = a + void 0;`},
+		{"x = `a${console.log(b)}c`", "x = `a${void 0}c`;"},
+		{`x = (console.log(a), b)`, `x = (b);`},
+		{`console.log(a), console.log(b)`, ``},
+		{`function f() { return console.log(a) }`, `function f() {
+    return;
+}`},
+		{`let x = console.log(a)`, `let x;`},
+		{`switch (a) { case 1: console.log(a) }`, `switch (a) {
+case 1:
+}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil && err.Error() != "EOF" {
+				test.Error(t, err)
+			}
+			_, err = Rewrite(rewriteFunc(dropConsole), ast)
+			test.Error(t, err)
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+// dropA deletes all variables named a
+func dropA(n INode) INode {
+	if v, ok := n.(*Var); ok && bytes.Equal(v.Data, []byte("a")) {
+		return nil
+	}
+	return n
+}
+
+func TestRewriteTarget(t *testing.T) {
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{`a = 1; b()`, `b();`},
+		{`a++; --a; b()`, `b();`},
+		{`x = (a += 1, b)`, `x = (b);`},
+		{`f(a = 1)`, `f();`},
+		{`x = a`, `x = void 0;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{})
+			if err != nil && err.Error() != "EOF" {
+				test.Error(t, err)
+			}
+			_, err = Rewrite(rewriteFunc(dropA), ast)
+			test.Error(t, err)
+			test.String(t, ast.JSString(), tt.expected)
+		})
+	}
+}
+
+func TestRewriteReplace(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`const DEBUG = false; if (DEBUG) { log() } function f(x) { return x }`), Options{VarLocs: true})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	decl := ast.List[0].(*VarDecl)
+	debug := decl.List[0].Binding.(*Var)
+	test.T(t, debug.Uses, uint16(2))
+
+	// inline constant and delete its declaration
+	_, err = Rewrite(inliner{v: debug, decl: decl}, ast)
+	test.Error(t, err)
+	test.String(t, ast.JSString(), "if (false) {\n    log();\n}\nfunction f(x) {\n    x();\n}")
+	test.T(t, debug.Uses, uint16(0))
+	test.T(t, len(ast.Scope.Declared), 1) // f
+	test.T(t, len(ast.Scope.VarDecls), 0)
+	test.T(t, debug.Locs, []Loc{})
+}
+
+type inliner struct {
+	v      *Var
+	decl   *VarDecl
+	inDecl bool
+}
+
+func (r inliner) Enter(n INode) IRewriter {
+	if n == r.decl {
+		return inliner{r.v, r.decl, true}
+	}
+	return r
+}
+
+func (r inliner) Exit(n INode) INode {
+	switch n := n.(type) {
+	case *Var:
+		if n == r.v && !r.inDecl {
+			return &LiteralExpr{TokenType: FalseToken, Data: []byte("false")}
+		}
+	case *VarDecl:
+		if n == r.decl {
+			return nil
+		}
+	case *ReturnStmt:
+		// statements can be replaced by expressions
+		return &CallExpr{X: n.Value, Args: Args{}}
+	}
+	return n
+}
+
+func TestRewriteScope(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`let a = 1; function f() { b() }`), Options{})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	a := ast.List[0].(*VarDecl).List[0].Binding.(*Var)
+	f := ast.List[1].(*FuncDecl)
+	test.T(t, len(f.Body.Scope.Undeclared), 1) // b
+
+	// replace b by a in the function, then a is used in the function scope and b is no longer used
+	_, err = Rewrite(rewriteFunc(func(n INode) INode {
+		if v, ok := n.(*Var); ok && bytes.Equal(v.Data, []byte("b")) {
+			return a
+		}
+		return n
+	}), ast)
+	test.Error(t, err)
+	test.String(t, ast.JSString(), "let a = 1;\nfunction f() {\n    a();\n}")
+	test.T(t, a.Uses, uint16(2))
+	test.T(t, len(f.Body.Scope.Undeclared), 1)
+	test.T(t, f.Body.Scope.Undeclared[0], a)
+	test.T(t, len(ast.Scope.Undeclared), 0)
+}
+
+func TestRewriteSkip(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`console.log(a); function f() { console.log(b) }`), Options{})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	var r IRewriter
+	r = skipper{func(n INode) IRewriter {
+		if _, ok := n.(*FuncDecl); ok {
+			return nil
+		}
+		return r
+	}}
+	_, err = Rewrite(r, ast)
+	test.Error(t, err)
+	test.String(t, ast.JSString(), "function f() {\n    console.log(b);\n}")
+}
+
+type skipper struct {
+	enter func(INode) IRewriter
+}
+
+func (s skipper) Enter(n INode) IRewriter {
+	return s.enter(n)
+}
+
+func (s skipper) Exit(n INode) INode {
+	return dropConsole(n)
+}
+
+func TestRewriteLocs(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`let a = 1; console.log(a); f(a)`), Options{VarLocs: true})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	a := ast.List[0].(*VarDecl).List[0].Binding.(*Var)
+	test.T(t, a.Locs, []Loc{{4, 5}, {23, 24}, {29, 30}})
+	_, err = Rewrite(rewriteFunc(dropConsole), ast)
+	test.Error(t, err)
+	test.T(t, a.Uses, uint16(2))
+	test.T(t, a.Locs, []Loc{{4, 5}, {29, 30}})
+
+	ast, err = Parse(parse.NewInputString(`let a = 1; f(a); h(a)`), Options{VarLocs: true})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	// locations within replaced nodes are removed
+	a = ast.List[0].(*VarDecl).List[0].Binding.(*Var)
+	_, err = Rewrite(rewriteFunc(func(n INode) INode {
+		if call, ok := n.(*CallExpr); ok && call.X.(*Var).Data[0] == 'f' {
+			return call.Args.List[0].Value
+		}
+		return n
+	}), ast)
+	test.Error(t, err)
+	test.String(t, ast.JSString(), "let a = 1;\na;\nh(a);")
+	test.T(t, a.Uses, uint16(3))
+	test.T(t, a.Locs, []Loc{{4, 5}, {19, 20}})
+}
+
+func TestRewriteError(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`x = [a]; b`), Options{})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	// expressions cannot be replaced by statements, the array is kept
+	_, err = Rewrite(rewriteFunc(func(n INode) INode {
+		if _, ok := n.(*ArrayExpr); ok {
+			return &EmptyStmt{}
+		}
+		return n
+	}), ast)
+	test.That(t, errors.Is(err, ErrRewrite), "error must wrap ErrRewrite")
+	test.String(t, ast.JSString(), "x = [a];\nb;")
+	test.T(t, len(ast.Scope.Undeclared), 3)
+
+	// the initializer of a for-of statement cannot be deleted, the loop is kept
+	ast, err = Parse(parse.NewInputString(`for (a of c) d()`), Options{})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+	_, err = Rewrite(rewriteFunc(dropA), ast)
+	test.That(t, errors.Is(err, ErrRewrite), "error must wrap ErrRewrite")
+	test.String(t, ast.JSString(), "for (a of c) {\n    d();\n}")
+}
+
+func TestRewriteOrder(t *testing.T) {
+	ast, err := Parse(parse.NewInputString(`function f() { g() }`), Options{})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	// newly used variables are added to the scopes in source order
+	f := ast.List[0].(*FuncDecl)
+	vars := VarArray{}
+	for _, name := range []string{"e", "d", "c", "b", "a"} {
+		vars = append(vars, &Var{Decl: NoDecl, Data: []byte(name)})
+	}
+	_, err = Rewrite(rewriteFunc(func(n INode) INode {
+		if call, ok := n.(*CallExpr); ok {
+			args := Args{}
+			for _, v := range vars {
+				args.List = append(args.List, Arg{Value: v})
+			}
+			return &CallExpr{X: call.X, Args: args}
+		}
+		return n
+	}), ast)
+	test.Error(t, err)
+	test.String(t, ast.JSString(), "function f() {\n    g(e, d, c, b, a);\n}")
+	test.T(t, f.Body.Scope.Undeclared[1:], vars)
+}
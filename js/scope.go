@@ -0,0 +1,511 @@
+package js
+
+import (
+	"sort"
+)
+
+// Resolve returns the variable that v refers to, which is the variable at the end of the links that are set when variable uses are merged. It is the declared variable, or an undeclared variable with Decl equal to NoDecl for globals.
+func (v *Var) Resolve() *Var {
+	for v.Link != nil {
+		v = v.Link
+	}
+	return v
+}
+
+// FreeVars returns the variables that are used in the function but declared outside of it, including undeclared global variables, in order of first use. The implicit arguments variable is not included.
+func (n *FuncDecl) FreeVars() VarArray {
+	loc := n.Loc
+	if n.Name != nil {
+		loc.Start = firstUse(n.Name, loc) + 1 // skip the name of the function declaration
+	}
+	return freeVars(&n.Body.Scope, loc, true)
+}
+
+// FreeVars returns the variables that are used in the arrow function but declared outside of it, including undeclared global variables, in order of first use. Uses of arguments refer to the enclosing function and are included.
+func (n *ArrowFunc) FreeVars() VarArray {
+	return freeVars(&n.Body.Scope, n.Loc, false)
+}
+
+// freeVars returns the resolved undeclared variables of a function scope, where private names of the enclosing classes are skipped. They are sorted by their first location within loc.
+func freeVars(s *Scope, loc Loc, hasArguments bool) VarArray {
+	vars := VarArray{}
+	for _, v := range s.Undeclared {
+		v = v.Resolve()
+		if v.Uses == 0 || 0 < len(v.Data) && v.Data[0] == '#' || hasArguments && v.Decl == NoDecl && string(v.Data) == "arguments" {
+			continue
+		}
+		found := false
+		for _, vorig := range vars {
+			if v == vorig {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vars = append(vars, v)
+		}
+	}
+	sort.SliceStable(vars, func(i, j int) bool {
+		return firstUse(vars[i], loc) < firstUse(vars[j], loc)
+	})
+	return vars
+}
+
+// firstUse returns the offset of the first location of a variable within loc.
+func firstUse(v *Var, loc Loc) int {
+	if l, ok := nextLoc(v, loc.Start); ok {
+		return l.Start
+	}
+	return loc.End
+}
+
+////////////////////////////////////////////////////////////////
+
+// Reference is a declaration or use of a variable in the source.
+type Reference struct {
+	Loc
+	Decl  bool // declaration, such as a binding, parameter, or the name of a function or class
+	Write bool // assigns to the variable, such as a declaration with an initializer, an assignment, an update expression, or the target of a for-in or for-of statement
+}
+
+type varReference struct {
+	Loc
+	v *Var
+}
+
+// ScopeAnalysis relates the identifiers in the source to the variables they refer to, and lists the references of each variable.
+type ScopeAnalysis struct {
+	refs  map[*Var][]Reference
+	index []varReference // in source order
+}
+
+// AnalyzeScopes analyzes the variables of an AST. Identifiers are related to the variables by their locations in Var.Locs, so that the AST must not have been modified without updating those. When the AST was parsed without Options.VarLocs, the references have no locations and Lookup finds no variables, but References and IsReassigned still work.
+func AnalyzeScopes(ast *AST) *ScopeAnalysis {
+	a := &scopeAnalyzer{
+		refs: map[*Var][]Reference{},
+	}
+	a.stmts(ast.List)
+
+	s := &ScopeAnalysis{
+		refs: a.refs,
+	}
+	for v, refs := range a.refs {
+		if len(refs) < len(v.Locs) {
+			// add locations that have not been visited as uses
+			for _, loc := range v.Locs {
+				i := sort.Search(len(refs), func(i int) bool {
+					return loc.Start <= refs[i].Start
+				})
+				if i == len(refs) || refs[i].Start != loc.Start {
+					refs = append(refs, Reference{})
+					copy(refs[i+1:], refs[i:])
+					refs[i] = Reference{Loc: loc}
+				}
+			}
+			s.refs[v] = refs
+		}
+		for _, ref := range refs {
+			if ref.IsSet() {
+				s.index = append(s.index, varReference{ref.Loc, v})
+			}
+		}
+	}
+	sort.Slice(s.index, func(i, j int) bool {
+		return s.index[i].Start < s.index[j].Start
+	})
+	return s
+}
+
+// Lookup returns the variable referred to by the identifier at the given offset in the source, or nil if there is no identifier at offset. The variable is resolved, see Var.Resolve.
+func (s *ScopeAnalysis) Lookup(offset int) *Var {
+	i := sort.Search(len(s.index), func(i int) bool {
+		return offset < s.index[i].End
+	})
+	if i < len(s.index) && s.index[i].Start <= offset {
+		return s.index[i].v
+	}
+	return nil
+}
+
+// References returns all declarations and uses of a variable in source order.
+func (s *ScopeAnalysis) References(v *Var) []Reference {
+	return s.refs[v.Resolve()]
+}
+
+// Declaration returns the location of the first declaration of a variable, or false if it is not declared.
+func (s *ScopeAnalysis) Declaration(v *Var) (Loc, bool) {
+	for _, ref := range s.References(v) {
+		if ref.Decl {
+			return ref.Loc, true
+		}
+	}
+	return Loc{}, false
+}
+
+// IsReassigned returns true if a variable is assigned to outside of its declaration, or if it is declared more than once with a value, as in var a = 1; var a = 2.
+func (s *ScopeAnalysis) IsReassigned(v *Var) bool {
+	writes := 0
+	for _, ref := range s.References(v) {
+		if ref.Write {
+			if !ref.Decl {
+				return true
+			}
+			writes++
+		}
+	}
+	return 1 < writes
+}
+
+////////////////////////////////////////////////////////////////
+
+// scopeAnalyzer visits the nodes in source order to find the location of each variable use.
+type scopeAnalyzer struct {
+	refs   map[*Var][]Reference
+	cursor int // offset of the current node, used to find the location of variables
+}
+
+// at moves the cursor to the start of a node.
+func (a *scopeAnalyzer) at(n INode) {
+	if loc := NodeLoc(n); loc.IsSet() && a.cursor < loc.Start {
+		a.cursor = loc.Start
+	}
+}
+
+// ref adds a reference to a variable at its first location after the cursor, or without a location if the variable has no locations.
+func (a *scopeAnalyzer) ref(v *Var, decl, write bool) {
+	v = v.Resolve()
+	if loc, ok := nextLoc(v, a.cursor); ok {
+		a.cursor = loc.End
+		a.refs[v] = append(a.refs[v], Reference{loc, decl, write})
+	} else if len(v.Locs) == 0 {
+		a.refs[v] = append(a.refs[v], Reference{Decl: decl, Write: write})
+	}
+}
+
+// binding adds the declarations of a binding, where write is true if the binding is assigned a value.
+func (a *scopeAnalyzer) binding(b IBinding, write bool) {
+	a.at(b)
+	switch b := b.(type) {
+	case *Var:
+		a.ref(b, true, write)
+	case *BindingArray:
+		for i := range b.List {
+			a.bindingElement(&b.List[i], write)
+		}
+		if b.Rest != nil {
+			a.binding(b.Rest, write)
+		}
+	case *BindingObject:
+		for _, item := range b.List {
+			if item.Key != nil && item.Key.IsComputed() {
+				a.expr(item.Key.Computed)
+			}
+			a.bindingElement(&item.Value, write)
+		}
+		if b.Rest != nil {
+			a.binding(b.Rest, write)
+		}
+	}
+}
+
+func (a *scopeAnalyzer) bindingElement(element *BindingElement, write bool) {
+	a.at(element)
+	if element.Binding != nil {
+		a.binding(element.Binding, write || element.Default != nil)
+	}
+	a.expr(element.Default)
+}
+
+// varDecl adds the declarations of a variable declaration, where inForInOf is true for the declaration of a for-in or for-of statement that is assigned in every iteration.
+func (a *scopeAnalyzer) varDecl(decl *VarDecl, inForInOf bool) {
+	a.at(decl)
+	for i := range decl.List {
+		a.bindingElement(&decl.List[i], inForInOf)
+	}
+}
+
+func (a *scopeAnalyzer) stmts(list []IStmt) {
+	for _, item := range list {
+		a.stmt(item)
+	}
+}
+
+func (a *scopeAnalyzer) stmt(stmt IStmt) {
+	if stmt == nil {
+		return
+	}
+	a.at(stmt)
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		a.stmts(stmt.List)
+	case *ExprStmt:
+		a.expr(stmt.Value)
+	case *VarDecl:
+		a.varDecl(stmt, false)
+	case *FuncDecl:
+		a.expr(stmt)
+	case *ClassDecl:
+		a.class(stmt)
+	case *IfStmt:
+		a.expr(stmt.Cond)
+		a.stmt(stmt.Body)
+		a.stmt(stmt.Else)
+	case *DoWhileStmt:
+		a.stmt(stmt.Body)
+		a.expr(stmt.Cond)
+	case *WhileStmt:
+		a.expr(stmt.Cond)
+		a.stmt(stmt.Body)
+	case *ForStmt:
+		if decl, ok := stmt.Init.(*VarDecl); ok {
+			a.varDecl(decl, false)
+		} else {
+			a.expr(stmt.Init)
+		}
+		a.expr(stmt.Cond)
+		a.expr(stmt.Post)
+		a.stmt(stmt.Body)
+	case *ForInStmt:
+		a.forInit(stmt.Init)
+		a.expr(stmt.Value)
+		a.stmt(stmt.Body)
+	case *ForOfStmt:
+		a.forInit(stmt.Init)
+		a.expr(stmt.Value)
+		a.stmt(stmt.Body)
+	case *SwitchStmt:
+		a.expr(stmt.Init)
+		for i := range stmt.List {
+			a.at(&stmt.List[i])
+			a.expr(stmt.List[i].Cond)
+			a.stmts(stmt.List[i].List)
+		}
+	case *ReturnStmt:
+		a.expr(stmt.Value)
+	case *WithStmt:
+		a.expr(stmt.Cond)
+		a.stmt(stmt.Body)
+	case *LabelledStmt:
+		a.stmt(stmt.Value)
+	case *ThrowStmt:
+		a.expr(stmt.Value)
+	case *TryStmt:
+		a.stmt(stmt.Body)
+		if stmt.Catch != nil {
+			if stmt.Binding != nil {
+				a.binding(stmt.Binding, true)
+			}
+			a.stmt(stmt.Catch)
+		}
+		if stmt.Finally != nil {
+			a.stmt(stmt.Finally)
+		}
+	case *ExportStmt:
+		if decl, ok := stmt.Decl.(IStmt); ok {
+			a.stmt(decl)
+		} else {
+			a.expr(stmt.Decl)
+		}
+	}
+}
+
+func (a *scopeAnalyzer) forInit(init IExpr) {
+	if decl, ok := init.(*VarDecl); ok {
+		a.varDecl(decl, true)
+	} else {
+		a.target(init)
+	}
+}
+
+// function adds the references in the parameters and body of a function, method, or arrow function.
+func (a *scopeAnalyzer) function(params Params, body *BlockStmt) {
+	for i := range params.List {
+		a.bindingElement(&params.List[i], true)
+	}
+	if params.Rest != nil {
+		a.binding(params.Rest, true)
+	}
+	a.at(body)
+	a.stmts(body.List)
+}
+
+func (a *scopeAnalyzer) method(method *MethodDecl) {
+	a.at(method)
+	for _, decorator := range method.Decorators {
+		a.expr(decorator)
+	}
+	a.elementName(method.Name)
+	a.function(method.Params, &method.Body)
+}
+
+func (a *scopeAnalyzer) elementName(name ClassElementName) {
+	if name.IsComputed() {
+		a.expr(name.Computed)
+	} else if name.Private != nil {
+		a.ref(name.Private, true, false)
+	}
+}
+
+func (a *scopeAnalyzer) class(class *ClassDecl) {
+	for _, decorator := range class.Decorators {
+		a.expr(decorator)
+	}
+	if class.Name != nil {
+		a.ref(class.Name, true, true)
+	}
+	a.expr(class.Extends)
+	for _, item := range class.List {
+		a.at(&item)
+		if item.StaticBlock != nil {
+			a.stmt(item.StaticBlock)
+		} else if item.Method != nil {
+			a.method(item.Method)
+		} else {
+			for _, decorator := range item.Field.Decorators {
+				a.expr(decorator)
+			}
+			a.elementName(item.Field.Name)
+			a.expr(item.Field.Init)
+		}
+	}
+}
+
+// target adds the references in an assignment target, where variables are written to.
+func (a *scopeAnalyzer) target(expr IExpr) {
+	a.at(expr)
+	switch expr := expr.(type) {
+	case *Var:
+		a.ref(expr, false, true)
+	case *GroupExpr:
+		a.target(expr.X)
+	case *ObjectExpr:
+		for i := range expr.List {
+			property := &expr.List[i]
+			a.at(property)
+			if property.Name != nil && property.Name.IsComputed() {
+				a.expr(property.Name.Computed)
+			}
+			a.target(property.Value)
+			a.expr(property.Init)
+		}
+	case *ArrayExpr:
+		for _, item := range expr.List {
+			if item.Value != nil {
+				a.target(item.Value)
+			}
+		}
+	case *BinaryExpr:
+		if expr.Op == EqToken {
+			a.target(expr.X)
+			a.expr(expr.Y)
+		} else {
+			a.expr(expr)
+		}
+	default:
+		a.expr(expr)
+	}
+}
+
+func (a *scopeAnalyzer) expr(expr IExpr) {
+	if expr == nil {
+		return
+	}
+	a.at(expr)
+	switch expr := expr.(type) {
+	case *Var:
+		a.ref(expr, false, false)
+	case *ArrayExpr:
+		for _, item := range expr.List {
+			a.expr(item.Value)
+		}
+	case *ObjectExpr:
+		for i := range expr.List {
+			property := &expr.List[i]
+			a.at(property)
+			if property.Name != nil && property.Name.IsComputed() {
+				a.expr(property.Name.Computed)
+			}
+			if method, ok := property.Value.(*MethodDecl); ok {
+				a.method(method)
+			} else {
+				a.expr(property.Value)
+			}
+			a.expr(property.Init)
+		}
+	case *TemplateExpr:
+		a.expr(expr.Tag)
+		for _, item := range expr.List {
+			a.expr(item.Expr)
+		}
+	case *GroupExpr:
+		a.expr(expr.X)
+	case *IndexExpr:
+		a.expr(expr.X)
+		a.expr(expr.Y)
+	case *DotExpr:
+		a.expr(expr.X)
+		if private, ok := expr.Y.(*Var); ok {
+			a.ref(private, false, false)
+		}
+	case *NewExpr:
+		a.expr(expr.X)
+		if expr.Args != nil {
+			a.args(expr.Args)
+		}
+	case *CallExpr:
+		a.expr(expr.X)
+		a.args(&expr.Args)
+	case *UnaryExpr:
+		if expr.Op == PreIncrToken || expr.Op == PreDecrToken || expr.Op == PostIncrToken || expr.Op == PostDecrToken {
+			a.target(expr.X)
+		} else {
+			a.expr(expr.X)
+		}
+	case *BinaryExpr:
+		if isAssignment(expr.Op) {
+			a.target(expr.X)
+		} else {
+			a.expr(expr.X)
+		}
+		a.expr(expr.Y)
+	case *CondExpr:
+		a.expr(expr.Cond)
+		a.expr(expr.X)
+		a.expr(expr.Y)
+	case *YieldExpr:
+		a.expr(expr.X)
+	case *CommaExpr:
+		for _, item := range expr.List {
+			a.expr(item)
+		}
+	case *ArrowFunc:
+		a.function(expr.Params, &expr.Body)
+	case *FuncDecl:
+		if expr.Name != nil {
+			a.ref(expr.Name, true, true)
+		}
+		a.function(expr.Params, &expr.Body)
+	case *ClassDecl:
+		a.class(expr)
+	case *JSXElement:
+		a.expr(expr.Name)
+		for _, attr := range expr.Attrs {
+			a.expr(attr.Value)
+		}
+		for _, child := range expr.Children {
+			a.expr(child)
+		}
+	case *JSXFragment:
+		for _, child := range expr.Children {
+			a.expr(child)
+		}
+	case *JSXExprContainer:
+		a.expr(expr.X)
+	}
+}
+
+func (a *scopeAnalyzer) args(args *Args) {
+	for _, item := range args.List {
+		a.expr(item.Value)
+	}
+}
@@ -0,0 +1,149 @@
+package js
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestScopeAnalysis(t *testing.T) {
+	// the references of the variable at offset, where d is a declaration and w is a write
+	var tests = []struct {
+		js         string
+		offset     int
+		expected   string
+		reassigned bool
+	}{
+		{"var a = 1; a", 4, "4dw 11", false},
+		{"let a; a = 1; a++; a", 4, "4d 7w 14w 19", true},
+		{"var a = 1; var a = 2", 4, "4dw 15dw", true},
+		{"function f() {} var f", 9, "9dw 20d", false},
+		{"a += 1", 0, "0w", true},
+		{"[a, {b: c = a}] = d", 8, "8w", true},
+		{"[a, {b: c = a}] = d", 1, "1w 12", true},
+		{"({a} = b); ({a = a} = b)", 2, "2w 13w 17", true},
+		{"const {a: [b] = c} = d; b", 11, "11dw 24", false},
+		{"for (const a of b) a; for (a in b) ;", 16, "16 32", false},
+		{"for (const a of b) a", 11, "11dw 19", false},
+		{"for (a in b) ;", 5, "5w", true},
+		{"function f(a, b = a) { a = b }", 11, "11dw 18 23w", true},
+		{"let a; { let a = 1; a } a", 4, "4d 24", false},
+		{"let a; { let a = 1; a } a", 17, "", false},
+		{"try {} catch (e) { e }", 14, "14dw 19", false},
+		{"x = function f() { f }", 13, "13dw 19", false},
+		{"class A { static a = A } new A", 6, "6dw 21 29", false},
+		{"class A { #a; m() { this.#a } }", 10, "10d 25", false},
+		{"{ a } var a", 2, "2 10d", false},
+		{"f(() => { a }); var a = 1", 10, "10 20dw", false},
+		{"x = a ? `${a}` : <a.b c={a}/>", 4, "4 11 18 25", false},
+		{"function f(a) { arguments; a }", 16, "16", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{JSX: true, VarLocs: true})
+			if err != nil {
+				test.Error(t, err)
+			}
+			s := AnalyzeScopes(ast)
+			v := s.Lookup(tt.offset)
+			if v == nil {
+				test.String(t, "", tt.expected)
+				return
+			}
+
+			refs := []string{}
+			for _, ref := range s.References(v) {
+				test.String(t, tt.js[ref.Start:ref.End], string(v.Data))
+				flags := ""
+				if ref.Decl {
+					flags += "d"
+				}
+				if ref.Write {
+					flags += "w"
+				}
+				refs = append(refs, fmt.Sprintf("%d%s", ref.Start, flags))
+			}
+			test.String(t, strings.Join(refs, " "), tt.expected)
+			test.T(t, s.IsReassigned(v), tt.reassigned, "reassigned")
+		})
+	}
+}
+
+func TestScopeLookup(t *testing.T) {
+	js := "let a = 1; function f(a) { return a + b }"
+	ast, err := Parse(parse.NewInputString(js), Options{VarLocs: true})
+	if err != nil {
+		test.Error(t, err)
+	}
+	s := AnalyzeScopes(ast)
+	global := s.Lookup(4)
+	param := s.Lookup(34)
+	test.T(t, global, ast.Scope.Declared[0])
+	test.T(t, param.Decl, ArgumentDecl)
+	test.That(t, global != param)
+	test.T(t, s.Lookup(3), (*Var)(nil))
+	test.T(t, s.Lookup(35), (*Var)(nil))
+	test.T(t, s.Lookup(38).Decl, NoDecl)
+
+	loc, ok := s.Declaration(param)
+	test.That(t, ok)
+	test.T(t, loc, Loc{22, 23})
+	_, ok = s.Declaration(s.Lookup(38))
+	test.That(t, !ok)
+}
+
+type freeVarsVisitor struct {
+	vars []string
+}
+
+func (v *freeVarsVisitor) Enter(n INode) IVisitor {
+	var vars VarArray
+	switch n := n.(type) {
+	case *FuncDecl:
+		vars = n.FreeVars()
+	case *ArrowFunc:
+		vars = n.FreeVars()
+	default:
+		return v
+	}
+	names := []string{}
+	for _, v := range vars {
+		names = append(names, string(v.Data))
+	}
+	v.vars = append(v.vars, strings.Join(names, ","))
+	return nil
+}
+
+func (v *freeVarsVisitor) Exit(n INode) {}
+
+func TestFreeVars(t *testing.T) {
+	// the free variables of the outermost functions
+	var tests = []struct {
+		js       string
+		expected string
+	}{
+		{"function f(a) { var b; a; b; c }", "c"},
+		{"let b; function f(c) { { let e; e; g } b; function h() { b; i } f }", "g,b,i,f"},
+		{"x = function f() { f; y }", "y"},
+		{"let q; x = (c) => { c; q; z }", "q,z"},
+		{"function f() { arguments; () => arguments }", ""},
+		{"x = () => arguments", "arguments"},
+		{"class A { #a; m() { x = () => this.#a + B } }", "B"},
+		{"function f() { a } var a; () => { a; a = b }", "a | a,b"},
+		{"function f() { {a} } a", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			if err != nil {
+				test.Error(t, err)
+			}
+			v := &freeVarsVisitor{}
+			Walk(v, ast)
+			test.String(t, strings.Join(v.vars, " | "), tt.expected)
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package js
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSourceMap is returned when a source map can't be parsed.
+var ErrInvalidSourceMap = errors.New("invalid source map")
+
+// SourceMap is a source map of version 3, see https://sourcemaps.info/spec.html.
+type SourceMap struct {
+	Version        int      `json:"version"`
+	File           string   `json:"file,omitempty"`
+	SourceRoot     string   `json:"sourceRoot,omitempty"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent,omitempty"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+}
+
+// ParseSourceMap parses a source map from JSON.
+func ParseSourceMap(b []byte) (*SourceMap, error) {
+	m := &SourceMap{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidSourceMap, err)
+	} else if m.Version != 3 {
+		return nil, fmt.Errorf("%v: unsupported version %d", ErrInvalidSourceMap, m.Version)
+	}
+	return m, nil
+}
+
+// JSON writes the source map as JSON to writer.
+func (m *SourceMap) JSON(w io.Writer) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Mapping is a decoded segment of the mappings of a source map. Lines and columns are zero-based, and columns are counted in UTF-16 code units. Source and Name are indices into Sources and Names, and are -1 when not set.
+type Mapping struct {
+	GenLine, GenCol int
+	Source          int
+	Line, Col       int
+	Name            int
+}
+
+// DecodeMappings decodes the VLQ-encoded mappings of the source map, ordered by generated position.
+func (m *SourceMap) DecodeMappings() ([]Mapping, error) {
+	mappings := []Mapping{}
+	s := m.Mappings
+	genLine, genCol, source, line, col, name := 0, 0, 0, 0, 0, 0
+	var fields [5]int
+	for 0 < len(s) {
+		if s[0] == ';' {
+			genLine++
+			genCol = 0
+			s = s[1:]
+			continue
+		} else if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+
+		n := 0
+		for 0 < len(s) && s[0] != ',' && s[0] != ';' {
+			if n == len(fields) {
+				return nil, fmt.Errorf("%v: too many fields in segment", ErrInvalidSourceMap)
+			}
+			v, k := decodeVLQ(s)
+			if k == 0 {
+				return nil, fmt.Errorf("%v: bad VLQ in mappings", ErrInvalidSourceMap)
+			}
+			fields[n] = v
+			n++
+			s = s[k:]
+		}
+		if n != 1 && n != 4 && n != 5 {
+			return nil, fmt.Errorf("%v: segment must have 1, 4, or 5 fields", ErrInvalidSourceMap)
+		}
+
+		genCol += fields[0]
+		mapping := Mapping{GenLine: genLine, GenCol: genCol, Source: -1, Name: -1}
+		if 4 <= n {
+			source += fields[1]
+			line += fields[2]
+			col += fields[3]
+			if source < 0 || len(m.Sources) <= source {
+				return nil, fmt.Errorf("%v: source index out of range", ErrInvalidSourceMap)
+			}
+			mapping.Source, mapping.Line, mapping.Col = source, line, col
+			if n == 5 {
+				name += fields[4]
+				if name < 0 || len(m.Names) <= name {
+					return nil, fmt.Errorf("%v: name index out of range", ErrInvalidSourceMap)
+				}
+				mapping.Name = name
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// EncodeMappings sets the mappings of the source map by VLQ-encoding the given mappings, which must be ordered by generated position.
+func (m *SourceMap) EncodeMappings(mappings []Mapping) {
+	b := []byte{}
+	genLine, genCol, source, line, col, name := 0, 0, 0, 0, 0, 0
+	for i, mapping := range mappings {
+		for genLine < mapping.GenLine {
+			b = append(b, ';')
+			genLine++
+			genCol = 0
+		}
+		if 0 < i && b[len(b)-1] != ';' {
+			b = append(b, ',')
+		}
+		b = appendVLQ(b, mapping.GenCol-genCol)
+		genCol = mapping.GenCol
+		if mapping.Source != -1 {
+			b = appendVLQ(b, mapping.Source-source)
+			b = appendVLQ(b, mapping.Line-line)
+			b = appendVLQ(b, mapping.Col-col)
+			source, line, col = mapping.Source, mapping.Line, mapping.Col
+			if mapping.Name != -1 {
+				b = appendVLQ(b, mapping.Name-name)
+				name = mapping.Name
+			}
+		}
+	}
+	m.Mappings = string(b)
+}
+
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// appendVLQ appends a base64 VLQ-encoded value, where the least significant bit is the sign and each digit has a continuation bit.
+func appendVLQ(b []byte, v int) []byte {
+	u := uint(v) << 1
+	if v < 0 {
+		u = uint(-v)<<1 | 1
+	}
+	for {
+		digit := u & 31
+		u >>= 5
+		if u != 0 {
+			digit |= 32
+		}
+		b = append(b, base64VLQ[digit])
+		if u == 0 {
+			return b
+		}
+	}
+}
+
+// decodeVLQ decodes a base64 VLQ-encoded value and returns it with the number of bytes read, which is zero on error.
+func decodeVLQ(s string) (int, int) {
+	u, shift := uint(0), uint(0)
+	for i := 0; i < len(s) && shift < 64; i++ {
+		c := s[i]
+		var digit uint
+		switch {
+		case 'A' <= c && c <= 'Z':
+			digit = uint(c - 'A')
+		case 'a' <= c && c <= 'z':
+			digit = uint(c-'a') + 26
+		case '0' <= c && c <= '9':
+			digit = uint(c-'0') + 52
+		case c == '+':
+			digit = 62
+		case c == '/':
+			digit = 63
+		default:
+			return 0, 0
+		}
+		u |= (digit & 31) << shift
+		shift += 5
+		if digit&32 == 0 {
+			if u&1 == 1 {
+				return -int(u >> 1), i + 1
+			}
+			return int(u >> 1), i + 1
+		}
+	}
+	return 0, 0
+}
+
+// utf16Len returns the length of UTF-8 encoded text in UTF-16 code units.
+func utf16Len(b []byte) int {
+	n := 0
+	for 0 < len(b) {
+		r, size := utf8.DecodeRune(b)
+		if 0x10000 <= r {
+			n += 2
+		} else {
+			n++
+		}
+		b = b[size:]
+	}
+	return n
+}
+
+////////////////////////////////////////////////////////////////
+
+// SourceMapBuilder builds a source map while printing with Print, see PrintOptions.SourceMap. Mappings are added for the start of every statement, expression, and binding with a location, and for every variable with its name. Variables are only mapped when the AST was parsed with Options.VarLocs.
+type SourceMapBuilder struct {
+	file     string
+	src      []byte
+	lines    []int // offsets of the line starts in src
+	input    *SourceMap
+	inputs   []Mapping // mappings of input
+	sources  []string
+	contents []string
+	names    []string
+	nameIdx  map[string]int
+	mappings []Mapping
+	cursor   int // source offset of the last mapping, used to find variable uses
+}
+
+// NewSourceMapBuilder returns a new source map builder for the generated file, which maps back to the given source with contents src that was parsed.
+func NewSourceMapBuilder(file, source string, src []byte) *SourceMapBuilder {
+	lines := []int{0}
+	for i, c := range src {
+		if c == '\n' || c == '\r' && (i+1 == len(src) || src[i+1] != '\n') {
+			lines = append(lines, i+1)
+		}
+	}
+	return &SourceMapBuilder{
+		file:     file,
+		src:      src,
+		lines:    lines,
+		sources:  []string{source},
+		contents: []string{string(src)},
+		nameIdx:  map[string]int{},
+	}
+}
+
+// Compose composes the source map with the source map of the parsed source, so that the mappings refer to the original sources of the input source map instead. It must be called before printing.
+func (b *SourceMapBuilder) Compose(input *SourceMap) error {
+	mappings, err := input.DecodeMappings()
+	if err != nil {
+		return err
+	}
+	b.input = input
+	b.inputs = mappings
+	b.sources = make([]string, len(input.Sources))
+	for i, source := range input.Sources {
+		b.sources[i] = joinSourceRoot(input.SourceRoot, source)
+	}
+	b.contents = input.SourcesContent
+	return nil
+}
+
+// joinSourceRoot returns the source prefixed by the source root, separated by a slash. Absolute sources are returned as is.
+func joinSourceRoot(root, source string) string {
+	if root == "" || strings.HasPrefix(source, "/") || strings.Contains(source, "://") {
+		return source
+	} else if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root + source
+}
+
+// SourceMap returns the source map.
+func (b *SourceMapBuilder) SourceMap() *SourceMap {
+	m := &SourceMap{
+		Version:        3,
+		File:           b.file,
+		Sources:        b.sources,
+		SourcesContent: b.contents,
+		Names:          b.names,
+	}
+	if m.Names == nil {
+		m.Names = []string{}
+	}
+	m.EncodeMappings(b.mappings)
+	return m
+}
+
+// position returns the line and column of an offset in the source.
+func (b *SourceMapBuilder) position(offset int) (int, int) {
+	line := sort.SearchInts(b.lines, offset+1) - 1
+	return line, utf16Len(b.src[b.lines[line]:offset])
+}
+
+func (b *SourceMapBuilder) name(name []byte) int {
+	if i, ok := b.nameIdx[string(name)]; ok {
+		return i
+	}
+	b.names = append(b.names, string(name))
+	b.nameIdx[string(name)] = len(b.names) - 1
+	return len(b.names) - 1
+}
+
+// add adds a mapping from the generated position to a source offset, with an optional name.
+func (b *SourceMapBuilder) add(genLine, genCol, offset int, name []byte) {
+	if offset < 0 || len(b.src) < offset {
+		return
+	}
+	b.cursor = offset
+
+	mapping := Mapping{GenLine: genLine, GenCol: genCol, Source: 0, Name: -1}
+	mapping.Line, mapping.Col = b.position(offset)
+	if b.input != nil {
+		// find the last input mapping at or before the position on the same line
+		i := sort.Search(len(b.inputs), func(i int) bool {
+			m := b.inputs[i]
+			return mapping.Line < m.GenLine || mapping.Line == m.GenLine && mapping.Col < m.GenCol
+		}) - 1
+		if i < 0 || b.inputs[i].GenLine != mapping.Line || b.inputs[i].Source == -1 {
+			return
+		}
+		// keep the offset of the position within the input mapping's segment
+		input := b.inputs[i]
+		offset := mapping.Col - input.GenCol
+		mapping.Source, mapping.Line, mapping.Col = input.Source, input.Line, input.Col+offset
+		if input.Name != -1 && offset == 0 {
+			name = []byte(b.input.Names[input.Name])
+		}
+	}
+	if name != nil {
+		mapping.Name = b.name(name)
+	}
+
+	if n := len(b.mappings); 0 < n && b.mappings[n-1].GenLine == genLine && b.mappings[n-1].GenCol == genCol {
+		// a more specific node starts at the same position
+		b.mappings[n-1] = mapping
+	} else {
+		b.mappings = append(b.mappings, mapping)
+	}
+}
+
+// addVar adds a mapping for a variable use or declaration, which is the first location of the variable after the last mapping.
+func (b *SourceMapBuilder) addVar(genLine, genCol int, v *Var) {
+	if loc, ok := nextLoc(v, b.cursor); ok {
+		b.add(genLine, genCol, loc.Start, v.Data)
+		b.cursor = loc.End
+	}
+}
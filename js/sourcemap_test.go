@@ -0,0 +1,160 @@
+package js
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestVLQ(t *testing.T) {
+	var tests = []struct {
+		v        int
+		expected string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{16, "gB"},
+		{-16, "hB"},
+		{123456, "gkxH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			test.String(t, string(appendVLQ(nil, tt.v)), tt.expected)
+			v, n := decodeVLQ(tt.expected + ",")
+			test.T(t, v, tt.v)
+			test.T(t, n, len(tt.expected))
+		})
+	}
+}
+
+func TestSourceMapMappings(t *testing.T) {
+	m := &SourceMap{Version: 3, Sources: []string{"a.js", "b.js"}, Names: []string{"x"}}
+	mappings := []Mapping{
+		{0, 0, 0, 0, 0, -1},
+		{0, 4, 0, 0, 4, 0},
+		{0, 10, -1, 0, 0, -1},
+		{2, 2, 1, 5, 1, -1},
+		{2, 8, 0, 0, 4, 0},
+	}
+	m.EncodeMappings(mappings)
+	test.String(t, m.Mappings, "AAAA,IAAIA,M;;ECKH,MDLGA")
+
+	decoded, err := m.DecodeMappings()
+	test.Error(t, err)
+	test.T(t, decoded, mappings)
+
+	var errorTests = []struct {
+		mappings string
+		err      string
+	}{
+		{"AA", "invalid source map: segment must have 1, 4, or 5 fields"},
+		{"AAAAAA", "invalid source map: too many fields in segment"},
+		{"AC!A", "invalid source map: bad VLQ in mappings"},
+		{"ACAA", "invalid source map: source index out of range"},
+		{"AAAAC", "invalid source map: name index out of range"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.mappings, func(t *testing.T) {
+			m := &SourceMap{Version: 3, Sources: []string{"a.js"}, Names: []string{"x"}, Mappings: tt.mappings}
+			_, err := m.DecodeMappings()
+			test.That(t, err != nil)
+			test.String(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err = ParseSourceMap([]byte(`{"version":2}`))
+	test.String(t, err.Error(), "invalid source map: unsupported version 2")
+}
+
+func TestSourceMapPrint(t *testing.T) {
+	src := "function add(a, b) {\n  return a + b\n}\nlet x = add(1, 2)"
+	ast, err := Parse(parse.NewInputString(src), Options{VarLocs: true})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	b := NewSourceMapBuilder("out.js", "in.js", []byte(src))
+	sb := &strings.Builder{}
+	test.Error(t, Print(sb, ast, PrintOptions{SourceMap: b}))
+	test.String(t, sb.String(), "function add(a, b) {\n    return a + b;\n}\nlet x = add(1, 2);")
+
+	m := b.SourceMap()
+	test.T(t, m.Sources, []string{"in.js"})
+	test.T(t, m.SourcesContent, []string{src})
+	test.T(t, m.Names, []string{"add", "a", "b", "x"})
+
+	mappings, err := m.DecodeMappings()
+	test.Error(t, err)
+	test.T(t, mappings[0], Mapping{0, 0, 0, 0, 0, -1})    // function
+	test.T(t, mappings[4], Mapping{1, 4, 0, 1, 2, -1})    // return
+	test.T(t, mappings[5], Mapping{1, 11, 0, 1, 9, 1})    // a
+	test.T(t, mappings[9], Mapping{3, 8, 0, 3, 8, 0})     // add
+	test.T(t, mappings[10], Mapping{3, 12, 0, 3, 12, -1}) // 1
+
+	sb.Reset()
+	test.Error(t, m.JSON(sb))
+	m2, err := ParseSourceMap([]byte(sb.String()))
+	test.Error(t, err)
+	test.T(t, m2, m)
+}
+
+func TestSourceMapCompose(t *testing.T) {
+	// input is the output of an earlier transform of orig.ts, where `let x = y` maps to line 2 of orig.ts
+	input := &SourceMap{Version: 3, SourceRoot: "src/", Sources: []string{"orig.ts"}, Names: []string{"z"}}
+	input.EncodeMappings([]Mapping{
+		{0, 0, 0, 2, 0, -1},
+		{0, 4, 0, 2, 6, -1},
+		{0, 8, 0, 2, 10, 0},
+	})
+
+	src := "let x = y"
+	ast, err := Parse(parse.NewInputString(src), Options{VarLocs: true})
+	if err != nil && err.Error() != "EOF" {
+		test.Error(t, err)
+	}
+
+	b := NewSourceMapBuilder("out.js", "in.js", []byte(src))
+	test.Error(t, b.Compose(input))
+	sb := &strings.Builder{}
+	test.Error(t, Print(sb, ast, PrintOptions{SourceMap: b}))
+
+	m := b.SourceMap()
+	test.T(t, m.Sources, []string{"src/orig.ts"})
+	test.T(t, m.Names, []string{"x", "z"})
+	mappings, err := m.DecodeMappings()
+	test.Error(t, err)
+	test.T(t, mappings, []Mapping{
+		{0, 0, 0, 2, 0, -1},
+		{0, 4, 0, 2, 6, 0},
+		{0, 8, 0, 2, 10, 1},
+	})
+
+	// positions within a segment of the input keep their offset from the start of the segment
+	input = &SourceMap{Version: 3, Sources: []string{"orig.ts"}, Names: []string{"z"}}
+	input.EncodeMappings([]Mapping{
+		{0, 0, 0, 2, 2, 0},
+	})
+	b = NewSourceMapBuilder("out.js", "in.js", []byte(src))
+	test.Error(t, b.Compose(input))
+	test.Error(t, Print(&strings.Builder{}, ast, PrintOptions{SourceMap: b}))
+
+	m = b.SourceMap()
+	test.T(t, m.Names, []string{"z", "x", "y"})
+	mappings, err = m.DecodeMappings()
+	test.Error(t, err)
+	test.T(t, mappings, []Mapping{
+		{0, 0, 0, 2, 2, 0},
+		{0, 4, 0, 2, 6, 1},
+		{0, 8, 0, 2, 10, 2},
+	})
+
+	// the source root is joined with a slash
+	input = &SourceMap{Version: 3, SourceRoot: "src", Sources: []string{"a.ts", "/b.ts", "https://c.ts"}}
+	b = NewSourceMapBuilder("out.js", "in.js", []byte(src))
+	test.Error(t, b.Compose(input))
+	test.T(t, b.SourceMap().Sources, []string{"src/a.ts", "/b.ts", "https://c.ts"})
+}
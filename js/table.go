@@ -134,12 +134,14 @@ var Keywords = map[string]TokenType{
 	"public":     PublicToken,
 
 	// extra
-	"as":     AsToken,
-	"async":  AsyncToken,
-	"from":   FromToken,
-	"get":    GetToken,
-	"meta":   MetaToken,
-	"of":     OfToken,
-	"set":    SetToken,
-	"target": TargetToken,
+	"accessor": AccessorToken,
+	"as":       AsToken,
+	"async":    AsyncToken,
+	"from":     FromToken,
+	"get":      GetToken,
+	"meta":     MetaToken,
+	"of":       OfToken,
+	"set":      SetToken,
+	"target":   TargetToken,
+	"using":    UsingToken,
 }
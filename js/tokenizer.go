@@ -0,0 +1,173 @@
+package js
+
+import (
+	"github.com/tdewolff/parse/v2"
+)
+
+// Tokenizer is a lexer that tells regular expressions apart from division operators by itself, so that JS can be tokenized without a parser, for example for syntax highlighting. It tracks the previous significant token and a stack of open parentheses, brackets, braces, and template substitutions to decide whether a / starts a regular expression. Inside the head of a for statement, the contextual keyword of is followed by an expression, unless it is the name of the binding. This follows the grammar for all but a few contrived cases, such as a division right after the body of a function expression or a class body right after extends {}. A class body is followed by a statement for class declarations and by an operator for class expressions. JSX is not supported.
+type Tokenizer struct {
+	l      *Lexer
+	regexp bool // a regular expression may start at the next token
+	prop   bool // the next identifier name is a property name
+	prevTT TokenType
+	stack  []bool // for each open parenthesis, bracket, brace, and template substitution, whether a regular expression may follow its closing token
+
+	forKeyword bool  // the previous tokens are for or for await
+	forHeads   []int // stack depths of the open parentheses of for statements
+	classHeads []classHead
+}
+
+// classHead is a class keyword whose body has not been opened yet.
+type classHead struct {
+	depth   int  // stack depth of the class keyword
+	decl    bool // class declaration instead of class expression
+	extends bool // the extends keyword has been read
+}
+
+// NewTokenizer returns a new Tokenizer for a given io.Reader.
+func NewTokenizer(r *parse.Input) *Tokenizer {
+	return &Tokenizer{
+		l:      NewLexer(r),
+		regexp: true,
+	}
+}
+
+// Err returns the error encountered during lexing, this is often io.EOF but also other errors can be returned.
+func (t *Tokenizer) Err() error {
+	return t.l.Err()
+}
+
+// Next returns the next Token, which is RegExpToken instead of DivToken or DivEqToken where a regular expression may start. It returns ErrorToken when an error was encountered. Using Err() one can retrieve the error message.
+func (t *Tokenizer) Next() (TokenType, []byte) {
+	tt, data := t.l.Next()
+	if t.regexp && (tt == DivToken || tt == DivEqToken) {
+		tt, data = t.l.RegExp()
+	}
+
+	switch tt {
+	case ErrorToken, WhitespaceToken, LineTerminatorToken, CommentToken, CommentLineTerminatorToken:
+		return tt, data
+	}
+
+	body, bodyDecl := t.classBody(tt)
+	switch tt {
+	case OpenParenToken:
+		// a regular expression may follow the condition of if, for, while, and with
+		t.push(t.prevTT == IfToken || t.forKeyword || t.prevTT == WhileToken || t.prevTT == WithToken)
+		if t.forKeyword {
+			t.forHeads = append(t.forHeads, len(t.stack))
+		}
+		t.regexp = true
+	case OpenBraceToken:
+		// a regular expression may follow a block or a class declaration but not an object literal or a class expression
+		if body {
+			t.push(bodyDecl)
+		} else {
+			t.push(t.isBlock())
+		}
+		t.regexp = true
+	case OpenBracketToken:
+		t.push(false)
+		t.regexp = true
+	case CloseParenToken, CloseBracketToken:
+		t.regexp = t.pop(false)
+	case CloseBraceToken:
+		t.regexp = t.pop(true)
+	case TemplateStartToken:
+		t.push(false)
+		t.regexp = true
+	case TemplateMiddleToken:
+		t.pop(false)
+		t.push(false)
+		t.regexp = true
+	case TemplateEndToken:
+		t.pop(false)
+		t.regexp = false
+	case IncrToken, DecrToken:
+		// prefix operators are followed by an operand, postfix operators by an operator
+	case ThisToken, SuperToken, NullToken, TrueToken, FalseToken:
+		t.regexp = false
+	case ClassToken:
+		if !t.prop {
+			// a class at the start of a statement is a declaration, elsewhere an expression
+			decl := !t.regexp || t.isBlock() && t.prevTT != ArrowToken || t.prevTT == ExportToken || t.prevTT == DefaultToken
+			t.classHeads = append(t.classHeads, classHead{len(t.stack), decl, false})
+		}
+		t.regexp = !t.prop
+	case OfToken:
+		t.regexp = !t.prop && t.isForOf()
+	default:
+		if t.prop && IsIdentifierName(tt) {
+			t.regexp = false
+		} else {
+			t.regexp = IsPunctuator(tt) || IsReservedWord(tt)
+		}
+	}
+	t.prop = tt == DotToken || tt == OptChainToken
+	t.forKeyword = tt == ForToken || t.forKeyword && tt == AwaitToken
+	t.prevTT = tt
+	return tt, data
+}
+
+// isBlock returns true if an open brace after the previous token starts a block or a function body, instead of an object literal.
+func (t *Tokenizer) isBlock() bool {
+	switch t.prevTT {
+	case ErrorToken, SemicolonToken, OpenBraceToken, CloseBraceToken, CloseParenToken, ArrowToken, ElseToken, DoToken, TryToken, FinallyToken:
+		return true
+	case ColonToken:
+		// labeled statements and case clauses are at the top level or inside blocks, property values inside object literals
+		return len(t.stack) == 0 || t.stack[len(t.stack)-1]
+	}
+	return false
+}
+
+// isForOf returns true if an of token directly inside the head of a for statement is the operator of a for-of statement, instead of the name of a binding as in for (const of of a).
+func (t *Tokenizer) isForOf() bool {
+	if len(t.forHeads) == 0 || t.forHeads[len(t.forHeads)-1] != len(t.stack) {
+		return false
+	}
+	switch t.prevTT {
+	case CloseBracketToken, CloseBraceToken:
+		return true
+	case OpenParenToken, VarToken, LetToken, ConstToken:
+		return false
+	}
+	return IsIdentifierName(t.prevTT)
+}
+
+// classBody returns true if the token is the open brace of the body of the last class keyword, and whether it is a class declaration. Other tokens directly inside the class head are the name of the class and the extends keyword, or otherwise the class keyword was a property name as in {class: a}.
+func (t *Tokenizer) classBody(tt TokenType) (bool, bool) {
+	if len(t.classHeads) == 0 || t.classHeads[len(t.classHeads)-1].depth != len(t.stack) {
+		return false, false
+	}
+	head := &t.classHeads[len(t.classHeads)-1]
+	if tt == OpenBraceToken && t.prevTT != ExtendsToken {
+		t.classHeads = t.classHeads[:len(t.classHeads)-1]
+		return true, head.decl
+	} else if tt == ExtendsToken && !head.extends {
+		head.extends = true
+	} else if !head.extends && (t.prevTT != ClassToken || !IsIdentifier(tt)) {
+		t.classHeads = t.classHeads[:len(t.classHeads)-1]
+	}
+	return false, false
+}
+
+func (t *Tokenizer) push(regexp bool) {
+	t.stack = append(t.stack, regexp)
+}
+
+// pop returns whether a regular expression may follow the closing token, or the default for unbalanced closing tokens.
+func (t *Tokenizer) pop(deflt bool) bool {
+	if len(t.stack) == 0 {
+		return deflt
+	}
+	if 0 < len(t.forHeads) && t.forHeads[len(t.forHeads)-1] == len(t.stack) {
+		t.forHeads = t.forHeads[:len(t.forHeads)-1]
+	}
+	regexp := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+	for 0 < len(t.classHeads) && len(t.stack) < t.classHeads[len(t.classHeads)-1].depth {
+		t.classHeads = t.classHeads[:len(t.classHeads)-1]
+	}
+	return regexp
+}
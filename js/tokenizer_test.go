@@ -0,0 +1,79 @@
+package js
+
+import (
+	"io"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestTokenizer(t *testing.T) {
+	var tokenTests = []struct {
+		js       string
+		expected []TokenType
+	}{
+		{"/a/g", TTs{RegExpToken}},
+		{"a / b / c", TTs{IdentifierToken, DivToken, IdentifierToken, DivToken, IdentifierToken}},
+		{"a /= 2", TTs{IdentifierToken, DivEqToken, IntegerToken}},
+		{"a = /=/", TTs{IdentifierToken, EqToken, RegExpToken}},
+		{"x = a / /b/", TTs{IdentifierToken, EqToken, IdentifierToken, DivToken, RegExpToken}},
+		{"f(a) / 2", TTs{IdentifierToken, OpenParenToken, IdentifierToken, CloseParenToken, DivToken, IntegerToken}},
+		{"if (a) /b/.test(c)", TTs{IfToken, OpenParenToken, IdentifierToken, CloseParenToken, RegExpToken, DotToken, IdentifierToken, OpenParenToken, IdentifierToken, CloseParenToken}},
+		{"while (a) /b/", TTs{WhileToken, OpenParenToken, IdentifierToken, CloseParenToken, RegExpToken}},
+		{"a[0] / 2", TTs{IdentifierToken, OpenBracketToken, IntegerToken, CloseBracketToken, DivToken, IntegerToken}},
+		{"{} /a/", TTs{OpenBraceToken, CloseBraceToken, RegExpToken}},
+		{"x = {} / 2", TTs{IdentifierToken, EqToken, OpenBraceToken, CloseBraceToken, DivToken, IntegerToken}},
+		{"function f() {} /a/", TTs{FunctionToken, IdentifierToken, OpenParenToken, CloseParenToken, OpenBraceToken, CloseBraceToken, RegExpToken}},
+		{"a: {} /b/", TTs{IdentifierToken, ColonToken, OpenBraceToken, CloseBraceToken, RegExpToken}},
+		{"x = {a: {} / 2}", TTs{IdentifierToken, EqToken, OpenBraceToken, IdentifierToken, ColonToken, OpenBraceToken, CloseBraceToken, DivToken, IntegerToken, CloseBraceToken}},
+		{"return /a/", TTs{ReturnToken, RegExpToken}},
+		{"typeof /a/", TTs{TypeofToken, RegExpToken}},
+		{"this / 2", TTs{ThisToken, DivToken, IntegerToken}},
+		{"a.return / 2", TTs{IdentifierToken, DotToken, ReturnToken, DivToken, IntegerToken}},
+		{"a?.if / 2", TTs{IdentifierToken, OptChainToken, IfToken, DivToken, IntegerToken}},
+		{"a++ / 2", TTs{IdentifierToken, IncrToken, DivToken, IntegerToken}},
+		{"++/a/.lastIndex", TTs{IncrToken, RegExpToken, DotToken, IdentifierToken}},
+		{"'a' / 2", TTs{StringToken, DivToken, IntegerToken}},
+		{"`a` / 2", TTs{TemplateToken, DivToken, IntegerToken}},
+		{"`${/a/}` / 2", TTs{TemplateStartToken, RegExpToken, TemplateEndToken, DivToken, IntegerToken}},
+		{"`${a}${{}}` / 2", TTs{TemplateStartToken, IdentifierToken, TemplateMiddleToken, OpenBraceToken, CloseBraceToken, TemplateEndToken, DivToken, IntegerToken}},
+		{"a\n/b/g", TTs{IdentifierToken, LineTerminatorToken, DivToken, IdentifierToken, DivToken, IdentifierToken}},
+		{"a // b\n/c/", TTs{IdentifierToken, CommentToken, LineTerminatorToken, DivToken, IdentifierToken, DivToken}},
+		{"for (x of /a/g);", TTs{ForToken, OpenParenToken, IdentifierToken, OfToken, RegExpToken, CloseParenToken, SemicolonToken}},
+		{"for await (const [a] of /b/);", TTs{ForToken, AwaitToken, OpenParenToken, ConstToken, OpenBracketToken, IdentifierToken, CloseBracketToken, OfToken, RegExpToken, CloseParenToken, SemicolonToken}},
+		{"for (const of of /a/);", TTs{ForToken, OpenParenToken, ConstToken, OfToken, OfToken, RegExpToken, CloseParenToken, SemicolonToken}},
+		{"for (x of a) /b/", TTs{ForToken, OpenParenToken, IdentifierToken, OfToken, IdentifierToken, CloseParenToken, RegExpToken}},
+		{"for (;of / 2;);", TTs{ForToken, OpenParenToken, SemicolonToken, OfToken, DivToken, IntegerToken, SemicolonToken, CloseParenToken, SemicolonToken}},
+		{"for (x of f(of / 2));", TTs{ForToken, OpenParenToken, IdentifierToken, OfToken, IdentifierToken, OpenParenToken, OfToken, DivToken, IntegerToken, CloseParenToken, CloseParenToken, SemicolonToken}},
+		{"of / 2", TTs{OfToken, DivToken, IntegerToken}},
+		{"class A {}\n/'/.test(x)", TTs{ClassToken, IdentifierToken, OpenBraceToken, CloseBraceToken, LineTerminatorToken, RegExpToken, DotToken, IdentifierToken, OpenParenToken, IdentifierToken, CloseParenToken}},
+		{"class A extends f({}) {} /b/", TTs{ClassToken, IdentifierToken, ExtendsToken, IdentifierToken, OpenParenToken, OpenBraceToken, CloseBraceToken, CloseParenToken, OpenBraceToken, CloseBraceToken, RegExpToken}},
+		{"export default class {} /a/", TTs{ExportToken, DefaultToken, ClassToken, OpenBraceToken, CloseBraceToken, RegExpToken}},
+		{"x = class {} / 2", TTs{IdentifierToken, EqToken, ClassToken, OpenBraceToken, CloseBraceToken, DivToken, IntegerToken}},
+		{"x = class extends class {} {} / 2", TTs{IdentifierToken, EqToken, ClassToken, ExtendsToken, ClassToken, OpenBraceToken, CloseBraceToken, OpenBraceToken, CloseBraceToken, DivToken, IntegerToken}},
+		{"x = {class: 1, b: {} / 2}", TTs{IdentifierToken, EqToken, OpenBraceToken, ClassToken, ColonToken, IntegerToken, CommaToken, IdentifierToken, ColonToken, OpenBraceToken, CloseBraceToken, DivToken, IntegerToken, CloseBraceToken}},
+		{") /a/", TTs{CloseParenToken, DivToken, IdentifierToken, DivToken}},
+		{"a = /b", TTs{IdentifierToken, EqToken, ErrorToken}},
+	}
+
+	for _, tt := range tokenTests {
+		t.Run(tt.js, func(t *testing.T) {
+			l := NewTokenizer(parse.NewInputString(tt.js))
+			tokens := []TokenType{}
+			for {
+				token, _ := l.Next()
+				if token == ErrorToken {
+					if l.Err() != io.EOF {
+						tokens = append(tokens, token)
+					}
+					break
+				} else if token == WhitespaceToken {
+					continue
+				}
+				tokens = append(tokens, token)
+			}
+			test.T(t, tokens, tt.expected, "token types must match")
+		})
+	}
+}
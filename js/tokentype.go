@@ -19,6 +19,9 @@ const (
 	TemplateEndToken
 	RegExpToken
 	PrivateIdentifierToken
+	JSXIdentifierToken // identifier in JSX tags that may contain dashes
+	JSXStringToken     // attribute string in JSX tags that has no escapes
+	JSXTextToken       // text in JSX children
 )
 
 // Numeric token values.
@@ -47,6 +50,7 @@ const (
 	ColonToken                  // :
 	ArrowToken                  // =>
 	EllipsisToken               // ...
+	AtToken                     // @
 )
 
 // Operator token values.
@@ -152,6 +156,7 @@ const (
 // Identifier token values.
 const (
 	IdentifierToken TokenType = 0x1000 + iota
+	AccessorToken
 	AsToken
 	AsyncToken
 	FromToken
@@ -168,6 +173,7 @@ const (
 	SetToken
 	StaticToken
 	TargetToken
+	UsingToken
 )
 
 // IsNumeric return true if token is numeric.
@@ -306,6 +312,7 @@ var reservedWordBytes = [][]byte{
 
 var identifierBytes = [][]byte{
 	[]byte("Identifier"),
+	[]byte("accessor"),
 	[]byte("as"),
 	[]byte("async"),
 	[]byte("from"),
@@ -322,6 +329,7 @@ var identifierBytes = [][]byte{
 	[]byte("set"),
 	[]byte("static"),
 	[]byte("target"),
+	[]byte("using"),
 }
 
 // Bytes returns the string representation of a TokenType.
@@ -359,6 +367,12 @@ func (tt TokenType) Bytes() []byte {
 		return []byte("RegExp")
 	case PrivateIdentifierToken:
 		return []byte("PrivateIdentifier")
+	case JSXIdentifierToken:
+		return []byte("JSXIdentifier")
+	case JSXStringToken:
+		return []byte("JSXString")
+	case JSXTextToken:
+		return []byte("JSXText")
 	case NumericToken:
 		return []byte("Numeric")
 	case DecimalToken:
@@ -399,6 +413,8 @@ func (tt TokenType) Bytes() []byte {
 		return []byte("=>")
 	case EllipsisToken:
 		return []byte("...")
+	case AtToken:
+		return []byte("@")
 	}
 	return nil
 }
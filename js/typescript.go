@@ -0,0 +1,891 @@
+package js
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+)
+
+// TypeScript support strips all type-level syntax while parsing, so that the resulting AST only contains JavaScript. Type annotations, type parameters and arguments, interfaces, type aliases, ambient declarations, type-only imports and exports, `as` and `satisfies` expressions, and non-null assertions are dropped. Enums are converted into JavaScript and parameter properties are assigned in the constructor.
+
+// state is a snapshot of the parser and lexer to backtrack when the TypeScript grammar is ambiguous.
+type state struct {
+	tt                             TokenType
+	data                           []byte
+	start, end, prevStart, prevEnd int
+	prevLT                         bool
+	err                            error
+	comments                       int
+	gap                            []*Comment
+	gapTrailing                    int
+	offset                         int
+	l                              Lexer
+}
+
+func (p *Parser) save() state {
+	s := state{p.tt, p.data, p.start, p.end, p.prevStart, p.prevEnd, p.prevLT, p.err, len(p.comments), p.gap, p.gapTrailing, p.l.r.Offset(), *p.l}
+	s.l.templateLevels = append([]int{}, p.l.templateLevels...)
+	return s
+}
+
+func (p *Parser) restore(s state) {
+	p.tt, p.data = s.tt, s.data
+	p.start, p.end, p.prevStart, p.prevEnd = s.start, s.end, s.prevStart, s.prevEnd
+	p.prevLT, p.err = s.prevLT, s.err
+	p.comments = p.comments[:s.comments]
+	p.gap, p.gapTrailing = s.gap, s.gapTrailing
+	*p.l = s.l
+	p.l.r.Move(s.offset - p.l.r.Offset())
+	p.l.r.Skip()
+}
+
+// try runs f and backtracks when it fails or returns false.
+func (p *Parser) try(f func() bool) bool {
+	s := p.save()
+	if !f() || p.err != nil {
+		p.restore(s)
+		return false
+	}
+	return true
+}
+
+// peekTS returns true if the next token satisfies f, it does not move the parser.
+func (p *Parser) peekTS(f func() bool) bool {
+	s := p.save()
+	p.next()
+	ok := f()
+	p.restore(s)
+	return ok
+}
+
+func (p *Parser) isTSIdentifier(name string) bool {
+	return p.tt == IdentifierToken && string(p.data) == name
+}
+
+////////////////////////////////////////////////////////////////
+
+// parseTSType parses and drops a type.
+func (p *Parser) parseTSType() {
+	if p.tt == LtToken {
+		// generic function type
+		p.parseTSTypeParams()
+		p.parseTSFuncType()
+		return
+	} else if p.tt == NewToken || p.isTSIdentifier("abstract") && p.peekTS(func() bool { return p.tt == NewToken }) {
+		// constructor type
+		if p.tt != NewToken {
+			p.next()
+		}
+		p.next()
+		if p.tt == LtToken {
+			p.parseTSTypeParams()
+		}
+		p.parseTSFuncType()
+		return
+	} else if p.isTSIdentifier("asserts") && p.peekTS(func() bool { return !p.prevLT && (IsIdentifier(p.tt) || p.tt == ThisToken) }) {
+		// assertion signature
+		p.next()
+		p.next()
+		if p.isTSIdentifier("is") && !p.prevLT {
+			p.next()
+			p.parseTSType()
+		}
+		return
+	}
+
+	p.parseTSUnionType()
+	if p.isTSIdentifier("is") && !p.prevLT {
+		// type predicate
+		p.next()
+		p.parseTSType()
+	} else if p.tt == ExtendsToken && !p.prevLT {
+		// conditional type
+		p.next()
+		p.parseTSUnionType()
+		if !p.consume("conditional type", QuestionToken) {
+			return
+		}
+		p.parseTSType()
+		if !p.consume("conditional type", ColonToken) {
+			return
+		}
+		p.parseTSType()
+	}
+}
+
+// parseTSFuncType parses the parameters and return type of a function type.
+func (p *Parser) parseTSFuncType() {
+	if p.tt != OpenParenToken {
+		p.fail("function type", OpenParenToken)
+		return
+	}
+	p.skipTSBalanced()
+	if !p.consume("function type", ArrowToken) {
+		return
+	}
+	p.parseTSType()
+}
+
+func (p *Parser) parseTSUnionType() {
+	if p.tt == BitOrToken {
+		p.next()
+	}
+	p.parseTSIntersectionType()
+	for p.tt == BitOrToken {
+		p.next()
+		p.parseTSIntersectionType()
+	}
+}
+
+func (p *Parser) parseTSIntersectionType() {
+	if p.tt == BitAndToken {
+		p.next()
+	}
+	p.parseTSTypeOperator()
+	for p.tt == BitAndToken {
+		p.next()
+		p.parseTSTypeOperator()
+	}
+}
+
+func (p *Parser) parseTSTypeOperator() {
+	if p.tt == IdentifierToken {
+		switch string(p.data) {
+		case "keyof", "unique", "readonly":
+			if p.peekTS(func() bool {
+				return p.tt != CommaToken && p.tt != GtToken && p.tt != CloseParenToken && p.tt != CloseBracketToken && p.tt != SemicolonToken && p.tt != EqToken
+			}) {
+				p.next()
+				p.parseTSTypeOperator()
+				return
+			}
+		case "infer":
+			if p.peekTS(func() bool { return IsIdentifier(p.tt) }) {
+				p.next()
+				p.next()
+				return
+			}
+		}
+	}
+	p.parseTSPrimaryType()
+	for !p.prevLT && p.tt == OpenBracketToken {
+		// array or indexed access type
+		p.skipTSBalanced()
+	}
+}
+
+func (p *Parser) parseTSPrimaryType() {
+	switch p.tt {
+	case OpenParenToken:
+		// function type or parenthesized type
+		if p.try(func() bool {
+			p.skipTSBalanced()
+			return p.tt == ArrowToken
+		}) {
+			p.next()
+			p.parseTSType()
+			return
+		}
+		p.next()
+		p.parseTSType()
+		p.consume("type", CloseParenToken)
+	case OpenBraceToken, OpenBracketToken:
+		// object type, mapped type, or tuple type
+		p.skipTSBalanced()
+	case StringToken, TrueToken, FalseToken, NullToken, VoidToken, ThisToken, TemplateToken:
+		p.next()
+	case SubToken:
+		p.next()
+		if !IsNumeric(p.tt) {
+			p.fail("type", NumericToken)
+			return
+		}
+		p.next()
+	case TemplateStartToken:
+		// template literal type
+		for p.tt == TemplateStartToken || p.tt == TemplateMiddleToken {
+			p.next()
+			p.parseTSType()
+		}
+		if p.tt != TemplateEndToken {
+			p.fail("template literal type", TemplateToken)
+			return
+		}
+		p.next()
+	case TypeofToken:
+		p.next()
+		if p.tt == ImportToken {
+			p.parseTSPrimaryType()
+			return
+		}
+		p.parseTSTypeReference()
+	case ImportToken:
+		// import type
+		p.next()
+		if p.tt != OpenParenToken {
+			p.fail("import type", OpenParenToken)
+			return
+		}
+		p.skipTSBalanced()
+		for p.tt == DotToken {
+			p.next()
+			if !IsIdentifierName(p.tt) {
+				p.fail("import type", IdentifierToken)
+				return
+			}
+			p.next()
+		}
+		if !p.prevLT && p.tt == LtToken {
+			p.parseTSTypeArgs()
+		}
+	default:
+		if IsNumeric(p.tt) {
+			p.next()
+		} else if IsIdentifierName(p.tt) {
+			p.parseTSTypeReference()
+		} else {
+			p.fail("type")
+		}
+	}
+}
+
+// parseTSTypeReference parses a (qualified) type name with optional type arguments.
+func (p *Parser) parseTSTypeReference() {
+	if !IsIdentifierName(p.tt) {
+		p.fail("type", IdentifierToken)
+		return
+	}
+	p.next()
+	for p.tt == DotToken {
+		p.next()
+		if !IsIdentifierName(p.tt) {
+			p.fail("type", IdentifierToken)
+			return
+		}
+		p.next()
+	}
+	if !p.prevLT && p.tt == LtToken {
+		p.parseTSTypeArgs()
+	}
+}
+
+// parseTSTypeArgs parses type arguments such as <A, B>.
+func (p *Parser) parseTSTypeArgs() {
+	// assume we're at <
+	p.next()
+	for p.tt != ErrorToken {
+		p.parseTSType()
+		if p.tt != CommaToken {
+			break
+		}
+		p.next()
+	}
+	p.consumeTSGt("type arguments")
+}
+
+// parseTSTypeParams parses type parameters such as <const A extends B = C, D>.
+func (p *Parser) parseTSTypeParams() {
+	// assume we're at <
+	p.next()
+	for IsIdentifierName(p.tt) {
+		if (p.tt == ConstToken || p.tt == InToken || p.isTSIdentifier("out")) && p.peekTS(func() bool { return IsIdentifier(p.tt) }) {
+			p.next()
+		}
+		if !IsIdentifier(p.tt) {
+			p.fail("type parameters", IdentifierToken)
+			return
+		}
+		p.next()
+		if p.tt == ExtendsToken {
+			p.next()
+			p.parseTSType()
+		}
+		if p.tt == EqToken {
+			p.next()
+			p.parseTSType()
+		}
+		if p.tt != CommaToken {
+			break
+		}
+		p.next()
+	}
+	p.consumeTSGt("type parameters")
+}
+
+// consumeTSGt consumes a > that may be the start of a longer token, such as the first > in >>.
+func (p *Parser) consumeTSGt(in string) bool {
+	var tt TokenType
+	switch p.tt {
+	case GtToken:
+		p.next()
+		return true
+	case GtGtToken:
+		tt = GtToken
+	case GtGtGtToken:
+		tt = GtGtToken
+	case GtEqToken:
+		tt = EqToken
+	case GtGtEqToken:
+		tt = GtEqToken
+	case GtGtGtEqToken:
+		tt = GtGtEqToken
+	default:
+		p.fail(in, GtToken)
+		return false
+	}
+	p.prevStart, p.prevEnd = p.start, p.start+1
+	p.prevLT = false
+	p.tt, p.data = tt, p.data[1:]
+	p.start++
+	return true
+}
+
+// skipTSBalanced skips over the tokens of a parenthesized, bracketed, or braced group, including its closing token.
+func (p *Parser) skipTSBalanced() {
+	// assume we're at (, [, or {
+	level := 0
+	for {
+		switch p.tt {
+		case ErrorToken:
+			p.fail("type")
+			return
+		case OpenParenToken, OpenBracketToken, OpenBraceToken:
+			level++
+		case CloseParenToken, CloseBracketToken, CloseBraceToken:
+			level--
+		}
+		p.next()
+		if level == 0 {
+			return
+		}
+	}
+}
+
+// parseTSTypeAnnotation parses and drops an optional type annotation such as : T.
+func (p *Parser) parseTSTypeAnnotation() {
+	if p.tt == ColonToken {
+		p.next()
+		p.parseTSType()
+	}
+}
+
+// parseTSTypeArgsInExpr tries to parse type arguments in an expression, such as in f<T>(x). It returns false if the < is a relational operator instead.
+func (p *Parser) parseTSTypeArgsInExpr() bool {
+	// assume we're at <
+	p.parseTSTypeArgs()
+	if p.err != nil {
+		return false
+	}
+	switch p.tt {
+	case OpenParenToken, TemplateToken, TemplateStartToken, CloseParenToken, CloseBracketToken, CloseBraceToken, CommaToken, SemicolonToken, ColonToken, DotToken, OptChainToken, ErrorToken:
+		return true
+	}
+	return p.prevLT
+}
+
+// isTSArrowFuncBeforeColon returns true if the arrow function at => is followed by a colon, by skipping the tokens of its body. It does not move the parser.
+func (p *Parser) isTSArrowFuncBeforeColon() bool {
+	s := p.save()
+	defer p.restore(s)
+	level, conds := 0, 0
+	for p.next(); ; p.next() {
+		switch p.tt {
+		case OpenParenToken, OpenBracketToken, OpenBraceToken, TemplateStartToken:
+			level++
+		case CloseParenToken, CloseBracketToken, CloseBraceToken, TemplateEndToken:
+			if level == 0 {
+				return false
+			}
+			level--
+		case QuestionToken:
+			if level == 0 {
+				conds++
+			}
+		case ColonToken:
+			if level == 0 {
+				if conds == 0 {
+					return true
+				}
+				conds--
+			}
+		case CommaToken, SemicolonToken:
+			if level == 0 {
+				return false
+			}
+		case ErrorToken:
+			return false
+		}
+	}
+}
+
+// parseTSModifiers skips the modifiers of class members and parameter properties.
+func (p *Parser) parseTSModifiers() (abstract, declare, property bool) {
+	for {
+		switch p.tt {
+		case PublicToken, PrivateToken, ProtectedToken:
+		case IdentifierToken:
+			if !p.isTSIdentifier("readonly") && !p.isTSIdentifier("abstract") && !p.isTSIdentifier("override") && !p.isTSIdentifier("declare") {
+				return
+			}
+		default:
+			return
+		}
+		if !p.peekTS(func() bool {
+			return IsIdentifierName(p.tt) || p.tt == StringToken || IsNumeric(p.tt) || p.tt == OpenBracketToken || p.tt == OpenBraceToken || p.tt == PrivateIdentifierToken || p.tt == MulToken
+		}) {
+			return
+		}
+		abstract = abstract || p.isTSIdentifier("abstract")
+		declare = declare || p.isTSIdentifier("declare")
+		property = true
+		p.next()
+	}
+}
+
+// addTSParamProps assigns the parameter properties of a constructor at the start of its body, or after the call to super for derived classes.
+func (p *Parser) addTSParamProps(body *BlockStmt, props []*Var, extends bool) {
+	stmts := make([]IStmt, 0, len(props))
+	for _, v := range props {
+		v.Uses++
+		this := &LiteralExpr{ThisToken, []byte("this"), Loc{}}
+		name := LiteralExpr{IdentifierToken, v.Data, Loc{}}
+		stmts = append(stmts, &ExprStmt{&BinaryExpr{EqToken, &DotExpr{this, name, OpMember, false, Loc{}}, v, Loc{}}, Loc{}})
+	}
+
+	i := 0
+	if extends {
+		for j, stmt := range body.List {
+			if exprStmt, ok := stmt.(*ExprStmt); ok {
+				if call, ok := exprStmt.Value.(*CallExpr); ok {
+					if lit, ok := call.X.(*LiteralExpr); ok && lit.TokenType == SuperToken {
+						i = j + 1
+						break
+					}
+				}
+			}
+		}
+	}
+	list := make([]IStmt, 0, len(body.List)+len(stmts))
+	list = append(list, body.List[:i]...)
+	list = append(list, stmts...)
+	list = append(list, body.List[i:]...)
+	body.List = list
+}
+
+// isTSDeclaration returns true if the current token starts a TypeScript declaration, assuming that we're at a statement.
+func (p *Parser) isTSDeclaration() bool {
+	switch p.tt {
+	case EnumToken:
+		return true
+	case InterfaceToken:
+		return p.peekTS(func() bool { return !p.prevLT && IsIdentifier(p.tt) })
+	case IdentifierToken:
+		switch string(p.data) {
+		case "type":
+			return p.peekTS(func() bool { return !p.prevLT && IsIdentifier(p.tt) })
+		case "declare":
+			return p.peekTS(func() bool { return !p.prevLT && IsIdentifierName(p.tt) })
+		case "abstract":
+			return p.peekTS(func() bool { return !p.prevLT && p.tt == ClassToken })
+		case "namespace", "module":
+			return p.peekTS(func() bool { return !p.prevLT && (IsIdentifier(p.tt) || p.tt == StringToken) })
+		}
+	}
+	return false
+}
+
+// parseTSDeclaration parses a TypeScript declaration and returns the JavaScript statement it compiles to, which is nil for declarations that only exist at the type level.
+func (p *Parser) parseTSDeclaration() IStmt {
+	// assume isTSDeclaration returned true
+	start := p.start
+	if p.tt == EnumToken {
+		if varDecl := p.parseTSEnum(start); varDecl != nil {
+			return varDecl
+		}
+		return nil
+	} else if p.tt == InterfaceToken {
+		p.next()
+		p.tsTypes = append(p.tsTypes, p.data)
+		p.next()
+		if p.tt == LtToken {
+			p.parseTSTypeParams()
+		}
+		if p.tt == ExtendsToken {
+			p.next()
+			for {
+				p.parseTSTypeReference()
+				if p.tt != CommaToken {
+					break
+				}
+				p.next()
+			}
+		}
+		if p.tt != OpenBraceToken {
+			p.fail("interface declaration", OpenBraceToken)
+			return nil
+		}
+		p.skipTSBalanced()
+		return nil
+	}
+
+	switch string(p.data) {
+	case "type":
+		p.next()
+		p.tsTypes = append(p.tsTypes, p.data)
+		p.next()
+		if p.tt == LtToken {
+			p.parseTSTypeParams()
+		}
+		if !p.consume("type alias", EqToken) {
+			return nil
+		}
+		p.parseTSType()
+	case "declare":
+		p.next()
+		p.skipTSAmbient()
+	case "abstract":
+		p.next()
+		classDecl := p.parseClassDecl()
+		classDecl.Loc.Start = start
+		return classDecl
+	default:
+		p.failMessage("TypeScript namespaces are not supported")
+	}
+	return nil
+}
+
+// skipTSAmbient skips an ambient declaration following declare, which does not generate any code.
+func (p *Parser) skipTSAmbient() {
+	level := 0
+	block := false
+	for {
+		switch p.tt {
+		case ErrorToken:
+			if p.l.Err() != io.EOF {
+				p.fail("ambient declaration")
+			}
+			return
+		case ClassToken, EnumToken, InterfaceToken:
+			block = block || level == 0
+		case IdentifierToken:
+			block = block || level == 0 && (p.isTSIdentifier("module") || p.isTSIdentifier("namespace") || p.isTSIdentifier("global"))
+		case OpenParenToken, OpenBracketToken, OpenBraceToken:
+			level++
+		case CloseParenToken, CloseBracketToken, CloseBraceToken:
+			if level == 0 {
+				return
+			}
+			level--
+			if level == 0 && block && p.tt == CloseBraceToken {
+				p.next()
+				return
+			}
+		case SemicolonToken:
+			if level == 0 {
+				p.next()
+				return
+			}
+		}
+		prev := p.tt
+		p.next()
+		if level == 0 && !block && p.prevLT && !isTSContinuation(prev) && !isTSContinuation(p.tt) {
+			return
+		}
+	}
+}
+
+// isTSContinuation returns true if a type continues over a newline before or after the given token.
+func isTSContinuation(tt TokenType) bool {
+	switch tt {
+	case ColonToken, CommaToken, EqToken, BitOrToken, BitAndToken, ArrowToken, DotToken, QuestionToken, ExtendsToken, LtToken:
+		return true
+	}
+	return false
+}
+
+// parseTSEnum parses an enum declaration and converts it into `var E = (function(E){...; return E})(E || {})`.
+func (p *Parser) parseTSEnum(start int) *VarDecl {
+	// assume we're at enum
+	p.next()
+	if !IsIdentifier(p.tt) {
+		p.fail("enum declaration", IdentifierToken)
+		return nil
+	}
+	name, nameLoc := p.data, Loc{p.start, p.end}
+	v, ok := p.declare(VariableDecl, name, nameLoc)
+	if !ok {
+		p.failMessage("identifier %s has already been declared", string(name))
+		return nil
+	}
+	p.next()
+	if !p.consume("enum declaration", OpenBraceToken) {
+		return nil
+	}
+
+	varDecl := &VarDecl{TokenType: VarToken, Scope: p.scope}
+	p.scope.Func.VarDecls = append(p.scope.Func.VarDecls, varDecl)
+
+	f := &FuncDecl{}
+	parent := p.enterScope(&f.Body.Scope, true)
+	enum, _ := p.scope.Declare(ArgumentDecl, name) // cannot fail
+	f.Params.List = []BindingElement{{Binding: enum}}
+	p.scope.MarkFuncArgs()
+
+	var keys [][]byte
+	var prevKey []byte
+	strs := map[string]bool{} // members with a string value
+	next, isNum := 0.0, true
+	for p.tt != CloseBraceToken {
+		memberStart := p.start
+		var key []byte
+		if IsIdentifierName(p.tt) {
+			key = append(append([]byte{'"'}, p.data...), '"')
+			keys = append(keys, p.data)
+		} else if p.tt == StringToken {
+			key = p.data
+			keys = append(keys, p.data[1:len(p.data)-1])
+		} else {
+			p.fail("enum declaration", IdentifierToken, StringToken, CloseBraceToken)
+			return nil
+		}
+		p.next()
+
+		var value IExpr
+		if p.tt == EqToken {
+			p.next()
+			value = p.parseExpression(OpAssign)
+			next, isNum = tsNumber(value)
+			next++
+		} else if isNum {
+			value = tsNumberExpr(next)
+			next++
+		} else {
+			enum.Uses++
+			value = &BinaryExpr{AddToken, &IndexExpr{X: enum, Y: &LiteralExpr{StringToken, prevKey, Loc{}}, Prec: OpMember}, &LiteralExpr{DecimalToken, []byte("1"), Loc{}}, Loc{}}
+		}
+
+		keyExpr := &LiteralExpr{StringToken, key, Loc{}}
+		enum.Uses++
+		assign := &BinaryExpr{EqToken, &IndexExpr{X: enum, Y: keyExpr, Prec: OpMember}, value, p.loc(memberStart)}
+		if isTSString(value, name, strs) {
+			strs[string(keys[len(keys)-1])] = true
+		} else {
+			// add reverse mapping for non-string values
+			enum.Uses++
+			assign = &BinaryExpr{EqToken, &IndexExpr{X: enum, Y: assign, Prec: OpMember}, keyExpr, assign.Loc}
+		}
+		f.Body.List = append(f.Body.List, &ExprStmt{assign, assign.Loc})
+		prevKey = key
+
+		if p.tt != CommaToken {
+			break
+		}
+		p.next()
+	}
+	if !p.consume("enum declaration", CloseBraceToken) {
+		return nil
+	}
+
+	// references to other members by their name are references to properties of the enum
+	for i := 0; i < len(p.scope.Undeclared); i++ {
+		u := p.scope.Undeclared[i]
+		for _, key := range keys {
+			if bytes.Equal(u.Data, key) {
+				for _, stmt := range f.Body.List {
+					stmt.(*ExprStmt).Value = replaceTSVar(stmt.(*ExprStmt).Value, u, enum)
+				}
+				p.scope.Undeclared = append(p.scope.Undeclared[:i], p.scope.Undeclared[i+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	enum.Uses++
+	f.Body.List = append(f.Body.List, &ReturnStmt{enum, Loc{}})
+	p.exitScope(parent)
+
+	v.Uses++
+	call := &CallExpr{&GroupExpr{f, Loc{}}, Args{[]Arg{{&BinaryExpr{OrToken, v, &ObjectExpr{}, Loc{}}, false}}}, OpCall, false, Loc{}}
+	varDecl.List = []BindingElement{{v, call, p.loc(start)}}
+	varDecl.Loc = p.loc(start)
+	return varDecl
+}
+
+// isTSString returns true if the value of an enum member is a string, which is the case for string literals, templates without substitutions, references to members with a string value, and concatenations with strings.
+func isTSString(expr IExpr, enum []byte, strs map[string]bool) bool {
+	switch n := expr.(type) {
+	case *LiteralExpr:
+		return n.TokenType == StringToken
+	case *TemplateExpr:
+		return n.Tag == nil && len(n.List) == 0
+	case *Var:
+		return strs[string(n.Data)]
+	case *DotExpr:
+		v, ok := n.X.(*Var)
+		y, ok2 := n.Y.(LiteralExpr)
+		return ok && ok2 && bytes.Equal(v.Data, enum) && strs[string(y.Data)]
+	case *GroupExpr:
+		return isTSString(n.X, enum, strs)
+	case *BinaryExpr:
+		return n.Op == AddToken && (isTSString(n.X, enum, strs) || isTSString(n.Y, enum, strs))
+	}
+	return false
+}
+
+// replaceTSVar replaces uses of variable u by the property with the same name of enum in constant expressions.
+func replaceTSVar(expr IExpr, u, enum *Var) IExpr {
+	switch n := expr.(type) {
+	case *Var:
+		if n == u {
+			enum.Uses++
+			return &DotExpr{enum, LiteralExpr{IdentifierToken, u.Data, Loc{}}, OpMember, false, Loc{}}
+		}
+	case *GroupExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+	case *UnaryExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+	case *BinaryExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+		n.Y = replaceTSVar(n.Y, u, enum)
+	case *CondExpr:
+		n.Cond = replaceTSVar(n.Cond, u, enum)
+		n.X = replaceTSVar(n.X, u, enum)
+		n.Y = replaceTSVar(n.Y, u, enum)
+	case *IndexExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+		n.Y = replaceTSVar(n.Y, u, enum)
+	case *DotExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+	case *CallExpr:
+		n.X = replaceTSVar(n.X, u, enum)
+		for i := range n.Args.List {
+			n.Args.List[i].Value = replaceTSVar(n.Args.List[i].Value, u, enum)
+		}
+	case *TemplateExpr:
+		for i := range n.List {
+			n.List[i].Expr = replaceTSVar(n.List[i].Expr, u, enum)
+		}
+	}
+	return expr
+}
+
+// tsNumber returns the numeric value of a numeric literal, possibly negated.
+func tsNumber(expr IExpr) (float64, bool) {
+	neg := false
+	if unary, ok := expr.(*UnaryExpr); ok && unary.Op == NegToken {
+		neg = true
+		expr = unary.X
+	}
+	lit, ok := expr.(*LiteralExpr)
+	if !ok {
+		return 0.0, false
+	}
+	data := bytes.ReplaceAll(lit.Data, []byte("_"), nil)
+	var f float64
+	switch lit.TokenType {
+	case DecimalToken:
+		var err error
+		if f, err = strconv.ParseFloat(string(data), 64); err != nil {
+			return 0.0, false
+		}
+	case HexadecimalToken, OctalToken, BinaryToken:
+		base := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[data[1]]
+		i, err := strconv.ParseUint(string(data[2:]), base, 64)
+		if err != nil {
+			return 0.0, false
+		}
+		f = float64(i)
+	default:
+		return 0.0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
+
+// tsNumberExpr returns a numeric literal expression for f.
+func tsNumberExpr(f float64) IExpr {
+	neg := f < 0.0
+	if neg {
+		f = -f
+	}
+	var data []byte
+	if f == math.Trunc(f) && f < 1e15 {
+		data = strconv.AppendInt(nil, int64(f), 10)
+	} else {
+		data = strconv.AppendFloat(nil, f, 'g', -1, 64)
+	}
+	var expr IExpr = &LiteralExpr{DecimalToken, data, Loc{}}
+	if neg {
+		expr = &UnaryExpr{NegToken, expr, Loc{}}
+	}
+	return expr
+}
+
+// elideTSImports removes import bindings that are never used as a value, as they may refer to types only, and removes exports of types.
+func (p *Parser) elideTSImports(module *BlockStmt) {
+	isType := func(name []byte) bool {
+		if module.Scope.findDeclared(name, false) != nil {
+			return false
+		}
+		for _, typ := range p.tsTypes {
+			if bytes.Equal(typ, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	exported := map[string]bool{}
+	elided := map[IStmt]bool{}
+	for _, stmt := range module.List {
+		if exportStmt, ok := stmt.(*ExportStmt); ok && exportStmt.Module == nil && 0 < len(exportStmt.List) {
+			list := exportStmt.List[:0]
+			for _, alias := range exportStmt.List {
+				name := alias.Name
+				if name == nil {
+					name = alias.Binding
+				}
+				if name == nil || !isType(name) {
+					exported[string(name)] = true
+					list = append(list, alias)
+				}
+			}
+			exportStmt.List = list
+			elided[stmt] = len(list) == 0
+		}
+	}
+	isUsed := func(name []byte) bool {
+		if exported[string(name)] {
+			return true
+		}
+		v := module.Scope.findUndeclared(name)
+		return v != nil && 0 < v.Uses
+	}
+
+	list := module.List[:0]
+	for _, stmt := range module.List {
+		if elided[stmt] {
+			continue
+		} else if importStmt, ok := stmt.(*ImportStmt); ok && (importStmt.Default != nil || 0 < len(importStmt.List)) {
+			if importStmt.Default != nil && !isUsed(importStmt.Default) {
+				importStmt.Default = nil
+			}
+			aliases := importStmt.List[:0]
+			for _, alias := range importStmt.List {
+				if alias.Binding != nil && isUsed(alias.Binding) {
+					aliases = append(aliases, alias)
+				}
+			}
+			importStmt.List = aliases
+			if importStmt.Default == nil && len(importStmt.List) == 0 {
+				continue
+			}
+		}
+		list = append(list, stmt)
+	}
+	module.List = list
+}
@@ -1,5 +1,16 @@
 package js
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
 func isLHSExpr(i IExpr) bool {
 	switch i.(type) {
 	case *CommaExpr, *CondExpr, *YieldExpr, *ArrowFunc, *BinaryExpr, *UnaryExpr:
@@ -44,3 +55,368 @@ func AsDecimalLiteral(b []byte) bool {
 	}
 	return i == len(b)
 }
+
+// ErrInvalidEscape is returned when a string or template literal contains an invalid escape sequence.
+var ErrInvalidEscape = errors.New("invalid escape sequence")
+
+// ErrInvalidString is returned when a string or template literal is not enclosed by its delimiters.
+var ErrInvalidString = errors.New("invalid string literal")
+
+// ErrInvalidNumber is returned when a numeric literal is malformed.
+var ErrInvalidNumber = errors.New("invalid numeric literal")
+
+// UnquoteString returns the value of a string literal including its quotes, or of a template literal without substitutions including its backticks. Escape sequences, line continuations, and legacy octal escapes such as \012 are replaced, and lone surrogates are replaced by the replacement character since Go strings hold UTF-8. It returns an error wrapping ErrInvalidString if the literal is not enclosed by matching quotes, or an error wrapping ErrInvalidEscape with the offset of the first invalid escape, in which case invalid escapes are replaced by the escaped character.
+func UnquoteString(b []byte) (string, error) {
+	if len(b) < 2 || b[0] != '"' && b[0] != '\'' && b[0] != '`' || b[len(b)-1] != b[0] || escaped(b, len(b)-1) {
+		return "", fmt.Errorf("%w %s: not enclosed by matching quotes", ErrInvalidString, b)
+	} else if b[0] == '`' {
+		return UnquoteTemplate(b)
+	}
+	s, i := cook(b[1:len(b)-1], false)
+	if i != -1 {
+		return s, fmt.Errorf("%w at offset %d", ErrInvalidEscape, 1+i)
+	}
+	return s, nil
+}
+
+// UnquoteTemplate returns the cooked value of a part of a template literal including its delimiters, such as TemplatePart.Value or TemplateExpr.Tail, which start with ` or } and end with ${ or `. Line terminators are normalized to \n. Legacy octal escapes are not allowed in templates. It returns an error wrapping ErrInvalidString if the part is not enclosed by these delimiters, or an error wrapping ErrInvalidEscape with the offset of the first invalid escape, for which tagged templates receive an undefined cooked value.
+func UnquoteTemplate(b []byte) (string, error) {
+	end := len(b) - 1
+	if 2 < len(b) && b[end] == '{' && b[end-1] == '$' {
+		end--
+	} else if len(b) < 2 || b[end] != '`' {
+		end = 0
+	}
+	if end == 0 || b[0] != '`' && b[0] != '}' || escaped(b, end) {
+		return "", fmt.Errorf("%w %s: not enclosed by ` or } and ${ or `", ErrInvalidString, b)
+	}
+	s, i := cook(b[1:end], true)
+	if i != -1 {
+		return s, fmt.Errorf("%w at offset %d", ErrInvalidEscape, 1+i)
+	}
+	return s, nil
+}
+
+// stringValue returns the value of a string literal or of a part of a template literal including their delimiters. Invalid escapes are replaced by the escaped character, and lone surrogates by the replacement character.
+func stringValue(b []byte) string {
+	if 0 < len(b) && (b[0] == '`' || b[0] == '}') {
+		s, _ := UnquoteTemplate(b)
+		return s
+	}
+	s, _ := UnquoteString(b)
+	return s
+}
+
+// escaped returns true if the character at i is preceded by an odd number of backslashes.
+func escaped(b []byte, i int) bool {
+	n := 0
+	for 0 < i-n && b[i-n-1] == '\\' {
+		n++
+	}
+	return n%2 == 1
+}
+
+// cook returns the value of the text of a string or template literal by replacing the escape sequences, and the offset of the first invalid escape or -1. Invalid escapes are replaced by the escaped character, and lone surrogates by the replacement character. Line terminators of templates are normalized, and templates don't allow legacy octal escapes, nor \8 and \9.
+func cook(b []byte, template bool) (string, int) {
+	if bytes.IndexByte(b, '\\') == -1 && (!template || bytes.IndexByte(b, '\r') == -1) {
+		return string(b), -1
+	}
+
+	invalid := -1
+	var sb strings.Builder
+	for i := 0; i < len(b); i++ {
+		if template && b[i] == '\r' {
+			sb.WriteByte('\n') // normalize CRLF and CR line terminators
+			if i+1 < len(b) && b[i+1] == '\n' {
+				i++
+			}
+			continue
+		} else if b[i] != '\\' || i+1 == len(b) {
+			sb.WriteByte(b[i])
+			continue
+		}
+		i++
+		switch c := b[i]; c {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case '\r':
+			if i+1 < len(b) && b[i+1] == '\n' {
+				i++ // line continuation
+			}
+		case '\n':
+			// line continuation
+		case 0xE2:
+			if i+2 < len(b) && b[i+1] == 0x80 && (b[i+2] == 0xA8 || b[i+2] == 0xA9) {
+				i += 2 // line continuation of LS or PS
+			} else {
+				sb.WriteByte(c)
+			}
+		case 'x', 'u':
+			r, n := unescapeHex(b[i:])
+			if n == 0 {
+				if invalid == -1 {
+					invalid = i - 1
+				}
+				sb.WriteByte(c)
+				break
+			}
+			i += n
+			if utf16.IsSurrogate(r) {
+				if i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+					if r2, n2 := unescapeHex(b[i+2:]); n2 != 0 && utf16.DecodeRune(r, r2) != utf8.RuneError {
+						r = utf16.DecodeRune(r, r2)
+						i += n2 + 2
+					}
+				}
+			}
+			sb.WriteRune(r)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			if c == '0' && (i+1 == len(b) || b[i+1] < '0' || '9' < b[i+1]) {
+				sb.WriteByte(0)
+				break
+			} else if template {
+				if invalid == -1 {
+					invalid = i - 1
+				}
+				sb.WriteByte(c)
+				break
+			}
+
+			// legacy octal escape of at most three digits and a value below 256
+			r := rune(c - '0')
+			n := 2
+			if c <= '3' {
+				n = 3
+			}
+			for j := 1; j < n && i+1 < len(b) && '0' <= b[i+1] && b[i+1] <= '7'; j++ {
+				i++
+				r = r*8 + rune(b[i]-'0')
+			}
+			sb.WriteRune(r)
+		case '8', '9':
+			if template && invalid == -1 {
+				invalid = i - 1
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), invalid
+}
+
+// unescapeHex returns the code point of a hexadecimal escape sequence starting at x or u, and the number of bytes after x or u. It returns zero bytes for invalid escapes.
+func unescapeHex(b []byte) (rune, int) {
+	var hex []byte
+	n := 0
+	if b[0] == 'x' && 2 < len(b) {
+		hex = b[1:3]
+		n = 2
+	} else if b[0] == 'u' && 1 < len(b) && b[1] == '{' {
+		if end := bytes.IndexByte(b[2:], '}'); end != -1 {
+			hex = b[2 : 2+end]
+			n = end + 2
+		}
+	} else if b[0] == 'u' && 4 < len(b) {
+		hex = b[1:5]
+		n = 4
+	}
+	r, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil || utf8.MaxRune < r {
+		return 0, 0
+	}
+	return rune(r), n
+}
+
+// ParseNumber returns the value of a numeric literal that is not a BigInt, such as 1_000, .5e3, 0x1F, 0o17, 0b11, or the legacy octal 017, rounded to the nearest float64. Literals that are too large return Infinity. It returns an error wrapping ErrInvalidNumber if the literal is malformed or a BigInt.
+func ParseNumber(b []byte) (float64, error) {
+	s := string(b)
+	if 2 < len(s) && s[0] == '0' && strings.IndexByte("xXoObB", s[1]) != -1 {
+		digits, err := parseDigits(s, 2, numericBase(s[1]))
+		if err != nil {
+			return 0, err
+		}
+		i, _ := new(big.Int).SetString(digits, numericBase(s[1]))
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, nil
+	} else if 1 < len(s) && s[0] == '0' && '0' <= s[1] && s[1] <= '9' {
+		// legacy octal literal, or decimal literal with a leading zero if it contains 8 or 9
+		i := 1
+		octal := true
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			if '8' <= s[i] {
+				octal = false
+			}
+			i++
+		}
+		if i == len(s) && octal {
+			n, _ := new(big.Int).SetString(s, 8)
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f, nil
+		} else if octal || i < len(s) && s[i] != '.' && s[i] != 'e' && s[i] != 'E' {
+			return 0, fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, i)
+		}
+	} else if 1 < len(s) && s[0] == '0' && s[1] == '_' {
+		return 0, fmt.Errorf("%w %s at offset 1", ErrInvalidNumber, s)
+	}
+
+	// decimal literal
+	i, err := scanDigits(s, 0, 10)
+	if err != nil {
+		return 0, err
+	}
+	n := i
+	if i < len(s) && s[i] == '.' {
+		j, err := scanDigits(s, i+1, 10)
+		if err != nil {
+			return 0, err
+		}
+		n += j - i - 1
+		i = j
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, i)
+	} else if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		j, err := scanDigits(s, i, 10)
+		if err != nil {
+			return 0, err
+		} else if j == i {
+			return 0, fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, i)
+		}
+		i = j
+	}
+	if i != len(s) {
+		return 0, fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, i)
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+	if err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+		return 0, fmt.Errorf("%w %s", ErrInvalidNumber, s)
+	}
+	return f, nil // out of range returns zero or Infinity
+}
+
+// ParseBigInt returns the value of a BigInt literal, such as 10n, 1_000n, or 0xFFn. It returns an error wrapping ErrInvalidNumber if the literal is malformed or not a BigInt.
+func ParseBigInt(b []byte) (*big.Int, error) {
+	s := string(b)
+	if len(s) < 2 || s[len(s)-1] != 'n' {
+		return nil, fmt.Errorf("%w %s: not a BigInt", ErrInvalidNumber, s)
+	}
+	s = s[:len(s)-1]
+
+	base, start := 10, 0
+	if 2 < len(s) && s[0] == '0' && strings.IndexByte("xXoObB", s[1]) != -1 {
+		base, start = numericBase(s[1]), 2
+	} else if 1 < len(s) && s[0] == '0' {
+		return nil, fmt.Errorf("%w %sn at offset 1", ErrInvalidNumber, s)
+	}
+	digits, err := parseDigits(s, start, base)
+	if err != nil {
+		return nil, err
+	}
+	i, _ := new(big.Int).SetString(digits, base)
+	return i, nil
+}
+
+// numericBase returns the base of a numeric literal prefix character.
+func numericBase(c byte) int {
+	switch c {
+	case 'x', 'X':
+		return 16
+	case 'o', 'O':
+		return 8
+	}
+	return 2
+}
+
+// parseDigits returns the digits of a literal that all start at offset i and end at the end of the literal, without numeric separators.
+func parseDigits(s string, i, base int) (string, error) {
+	j, err := scanDigits(s, i, base)
+	if err != nil {
+		return "", err
+	} else if j == i || j != len(s) {
+		return "", fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, j)
+	}
+	return strings.ReplaceAll(s[i:], "_", ""), nil
+}
+
+// scanDigits returns the offset after the digits in the given base starting at offset i. Numeric separators must be between digits.
+func scanDigits(s string, i, base int) (int, error) {
+	start := i
+	for i < len(s) {
+		if s[i] == '_' {
+			if i == start || i+1 == len(s) || s[i-1] == '_' || !isDigit(s[i+1], base) {
+				return 0, fmt.Errorf("%w %s at offset %d", ErrInvalidNumber, s, i)
+			}
+		} else if !isDigit(s[i], base) {
+			break
+		}
+		i++
+	}
+	return i, nil
+}
+
+func isDigit(c byte, base int) bool {
+	if base == 16 {
+		return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+	}
+	return '0' <= c && c < '0'+byte(base)
+}
+
+// QuoteString returns the shortest string literal for s, which is quoted by double quotes unless single quotes need fewer escapes. Control characters, line terminators, backslashes, and the quote are escaped, and invalid UTF-8 is replaced by the replacement character.
+func QuoteString(s string) []byte {
+	quote := byte('"')
+	if strings.Count(s, "'") < strings.Count(s, `"`) {
+		quote = '\''
+	}
+	return appendQuoted(make([]byte, 0, len(s)+2), s, quote)
+}
+
+// appendQuoted appends the string literal for s using the given quote.
+func appendQuoted(b []byte, s string, quote byte) []byte {
+	b = append(b, quote)
+	for i, r := range s {
+		switch r {
+		case rune(quote), '\\':
+			b = append(b, '\\', byte(r))
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\v':
+			b = append(b, '\\', 'v')
+		case '\u2028', '\u2029':
+			b = append(b, `\u202`...)
+			b = append(b, "89"[r-'\u2028'])
+		default:
+			if r == 0 && (i+1 == len(s) || s[i+1] < '0' || '9' < s[i+1]) {
+				b = append(b, '\\', '0')
+			} else if r < 0x20 || r == 0x7f {
+				b = append(b, `\x`...)
+				b = append(b, "0123456789abcdef"[r>>4], "0123456789abcdef"[r&15])
+			} else {
+				b = append(b, string(r)...)
+			}
+		}
+	}
+	return append(b, quote)
+}
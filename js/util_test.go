@@ -1,7 +1,10 @@
 package js
 
 import (
+	"errors"
+	"math"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/tdewolff/test"
 )
@@ -24,3 +27,209 @@ func TestAsDecimalLiteral(t *testing.T) {
 	test.That(t, AsDecimalLiteral([]byte("0")))
 	test.That(t, !AsDecimalLiteral([]byte("00")))
 }
+
+func TestUnquoteString(t *testing.T) {
+	var tests = []struct {
+		s        string
+		expected string
+	}{
+		{`""`, ""},
+		{`'abc'`, "abc"},
+		{`"a\"b"`, `a"b`},
+		{`'\b\f\n\r\t\v\0'`, "\b\f\n\r\t\v\x00"},
+		{`'\x41B\u{43}\u{1F600}'`, "ABC\U0001F600"},
+		{`'😀'`, "\U0001F600"},
+		{`'\uD83D'`, "�"},
+		{"'a\\\nb\\\r\nc\\\u2028d'", "abcd"},
+		{`'\1\12\123\1234\4\45\456'`, "\x01\x0a\x53\x534\x04\x25\x256"},
+		{`'\08\8\9'`, "\x008" + "89"},
+		{`'\a\'\é'`, "a'é"},
+		{`'\\'`, `\`},
+		{"`a\\n\r\nb`", "a\n\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			s, err := UnquoteString([]byte(tt.s))
+			test.Error(t, err)
+			test.String(t, s, tt.expected)
+		})
+	}
+}
+
+func TestUnquoteTemplate(t *testing.T) {
+	var tests = []struct {
+		s        string
+		expected string
+	}{
+		{"`abc`", "abc"},
+		{"`a${", "a"},
+		{"}a${", "a"},
+		{"}a`", "a"},
+		{"`a\r\nb\rc`", "a\nb\nc"},
+		{"`\\r\\n\\0`", "\r\n\x00"},
+		{"`\\u{1F600}\\$\\``", "\U0001F600$`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			s, err := UnquoteTemplate([]byte(tt.s))
+			test.Error(t, err)
+			test.String(t, s, tt.expected)
+		})
+	}
+}
+
+func TestUnquoteError(t *testing.T) {
+	var tests = []struct {
+		s        string
+		template bool
+		err      string
+	}{
+		{`'\x4'`, false, "invalid escape sequence at offset 1"},
+		{`'ab\u12'`, false, "invalid escape sequence at offset 3"},
+		{`'\u{110000}'`, false, "invalid escape sequence at offset 1"},
+		{"`\\1`", true, "invalid escape sequence at offset 1"},
+		{"`\\01`", true, "invalid escape sequence at offset 1"},
+		{"}a\\8${", true, "invalid escape sequence at offset 2"},
+		{"`\\unicode`", true, "invalid escape sequence at offset 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			var err error
+			if tt.template {
+				_, err = UnquoteTemplate([]byte(tt.s))
+			} else {
+				_, err = UnquoteString([]byte(tt.s))
+			}
+			test.That(t, errors.Is(err, ErrInvalidEscape), "must return ErrInvalidEscape")
+			test.String(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestUnquoteDelimiters(t *testing.T) {
+	var tests = []struct {
+		s        string
+		template bool
+	}{
+		{``, false},
+		{`'`, false},
+		{`'a`, false},
+		{`a`, false},
+		{`"a'`, false},
+		{`'a\'`, false},
+		{"`a${", false},
+		{``, true},
+		{"`", true},
+		{"`a", true},
+		{"a`", true},
+		{"}a$", true},
+		{"`a\\`", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			var err error
+			if tt.template {
+				_, err = UnquoteTemplate([]byte(tt.s))
+			} else {
+				_, err = UnquoteString([]byte(tt.s))
+			}
+			test.That(t, errors.Is(err, ErrInvalidString), "must return ErrInvalidString")
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	var tests = []struct {
+		s        string
+		expected float64
+	}{
+		{"0", 0.0},
+		{"1_000", 1000.0},
+		{".5e3", 500.0},
+		{"5.", 5.0},
+		{"1E-2", 0.01},
+		{"0x1F", 31.0},
+		{"0o17", 15.0},
+		{"0B1_1", 3.0},
+		{"017", 15.0},
+		{"019", 19.0},
+		{"08.5", 8.5},
+		{"0.5", 0.5},
+		{"0e1", 0.0},
+		{"0x20000000000001", 9007199254740992.0},
+		{"1e400", math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			f, err := ParseNumber([]byte(tt.s))
+			test.Error(t, err)
+			test.T(t, f, tt.expected)
+		})
+	}
+
+	for _, s := range []string{"", ".", "1_", "1__0", "_1", "1._5", "0_1", "0x", "0x_1", "0b2", "1e", "1e+", "07.5", "1n", "1a"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseNumber([]byte(s))
+			test.That(t, errors.Is(err, ErrInvalidNumber), "must return ErrInvalidNumber")
+		})
+	}
+}
+
+func TestParseBigInt(t *testing.T) {
+	var tests = []struct {
+		s        string
+		expected string
+	}{
+		{"0n", "0"},
+		{"1_000n", "1000"},
+		{"0xFFn", "255"},
+		{"0o17n", "15"},
+		{"0b11n", "3"},
+		{"123456789012345678901234567890n", "123456789012345678901234567890"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			i, err := ParseBigInt([]byte(tt.s))
+			test.Error(t, err)
+			test.String(t, i.String(), tt.expected)
+		})
+	}
+
+	for _, s := range []string{"1", "n", "01n", "1.5n", "1e3n", "1_n", "0xn"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseBigInt([]byte(s))
+			test.That(t, errors.Is(err, ErrInvalidNumber), "must return ErrInvalidNumber")
+		})
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	var tests = []struct {
+		s        string
+		expected string
+	}{
+		{"", `""`},
+		{"abc", `"abc"`},
+		{`a"b`, `'a"b'`},
+		{`a'b"c`, `"a'b\"c"`},
+		{`a'b"c"`, `'a\'b"c"'`},
+		{"\\\b\f\n\r\t\v", `"\\\b\f\n\r\t\v"`},
+		{"\x00a\x001", `"\0a\x001"`},
+		{"\x01\x7f", `"\x01\x7f"`},
+		{"\u2028\u2029", `"\u2028\u2029"`},
+		{"é\U0001F600", `"é😀"`},
+		{"\xff", `"` + "�" + `"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			b := QuoteString(tt.s)
+			test.String(t, string(b), tt.expected)
+
+			s, err := UnquoteString(b)
+			test.Error(t, err)
+			if utf8.ValidString(tt.s) {
+				test.String(t, s, tt.s)
+			}
+		})
+	}
+}
@@ -0,0 +1,1050 @@
+package js
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/buffer"
+	"github.com/tdewolff/parse/v2/js/regexp"
+)
+
+// ValidateOptions are the options for Validate.
+type ValidateOptions struct {
+	Module bool // validate as module code, which is strict mode code where await is reserved
+}
+
+// Validate reports the early errors of the ECMAScript specification that are not reported by Parse. These are redeclared lexical bindings, break and continue outside of loops or to undefined labels, duplicate labels, duplicate __proto__ properties in object literals, getters and setters with the wrong number of parameters, labelled function declarations, special or duplicate constructors, static prototype members, new.target and super outside of functions and methods, optional chains in new expressions and tagged templates, private names that are duplicate or not declared by an enclosing class, duplicate import attributes, invalid regular expressions, octal escapes in templates and strict mode strings, with statements, deleted identifiers and reserved identifiers in strict mode code, using declarations at the top level of scripts, and for modules await as an identifier and duplicate or undeclared exports. The AST must be parsed from src, and all errors are returned with their position in src in source order, or nil if there are none. Errors at identifiers are only positioned exactly when the AST was parsed with Options.VarLocs.
+func Validate(ast *AST, src []byte, o ValidateOptions) ErrorList {
+	v := &validator{
+		src:     src,
+		module:  o.Module,
+		strict:  o.Module || hasUseStrict(ast.List),
+		exports: map[string]bool{},
+	}
+	v.enterScope(true)
+	v.stmts(ast.List)
+	if v.module {
+		for _, export := range v.localExports {
+			if _, ok := v.scope.lexical[string(export.name)]; !ok && !v.scope.vars[string(export.name)] {
+				v.fail(export.offset, "export %s is not declared", export.name)
+			}
+		}
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].offset < v.errs[j].offset
+	})
+	errs := make(ErrorList, 0, len(v.errs))
+	for _, err := range v.errs {
+		errs = append(errs, parse.NewError(buffer.NewReader(src), err.offset, err.message))
+	}
+	return errs
+}
+
+// hasUseStrict returns true if the directive prologue contains a use strict directive. The parser only keeps the first directives as DirectivePrologueStmt, later directives are string expression statements.
+func hasUseStrict(list []IStmt) bool {
+	for _, item := range list {
+		var value []byte
+		switch item := item.(type) {
+		case *DirectivePrologueStmt:
+			value = item.Value
+		case *ExprStmt:
+			lit, ok := item.Value.(*LiteralExpr)
+			if !ok || lit.TokenType != StringToken {
+				return false
+			}
+			value = lit.Data
+		case *Comment:
+			continue
+		default:
+			return false
+		}
+		if string(value) == `"use strict"` || string(value) == `'use strict'` {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////
+
+type validatorError struct {
+	offset  int
+	message string
+}
+
+type validatorExport struct {
+	name   []byte
+	offset int
+}
+
+// declKind is the kind of a declaration in a validator scope.
+type declKind int
+
+const (
+	varDecl       declKind = iota // var and functions at the top-level of functions and scripts
+	lexicalDecl                   // let, const, class, and functions in modules
+	blockFuncDecl                 // functions in blocks, which may be redeclared in sloppy mode
+	paramDecl                     // parameters and simple catch parameters
+	patternDecl                   // destructured catch parameters
+)
+
+// validatorScope keeps the declared names of a block or function scope.
+type validatorScope struct {
+	parent  *validatorScope
+	isFunc  bool
+	lexical map[string]declKind // lexical declarations and parameters
+	vars    map[string]bool     // var declarations in this or nested block scopes
+}
+
+// validatorFunc is the state that is reset for every function.
+type validatorFunc struct {
+	labels                              []validatorLabel
+	loops, switches                     int
+	newTarget, superProperty, superCall bool
+}
+
+type validatorLabel struct {
+	name []byte
+	loop bool
+}
+
+type validator struct {
+	src            []byte
+	module, strict bool
+	scope          *validatorScope
+	fn             validatorFunc
+	cursor         int // offset of the current node, used to find the location of variables
+	errs           []validatorError
+
+	exports      map[string]bool
+	localExports []validatorExport
+	privates     []map[string]bool // private names declared by the enclosing classes
+}
+
+func (v *validator) fail(offset int, message string, a ...interface{}) {
+	if 0 < len(a) {
+		message = fmt.Sprintf(message, a...)
+	}
+	v.errs = append(v.errs, validatorError{offset, message})
+}
+
+// at moves the cursor to the start of a node.
+func (v *validator) at(n INode) {
+	if loc := NodeLoc(n); loc.IsSet() && v.cursor < loc.Start {
+		v.cursor = loc.Start
+	}
+}
+
+// varOffset returns the offset of a variable, which is its first location after the cursor.
+func (v *validator) varOffset(n *Var) int {
+	if loc, ok := nextLoc(n, v.cursor); ok {
+		v.cursor = loc.End
+		return loc.Start
+	}
+	return v.cursor
+}
+
+// exprOffset returns the offset of an expression without moving the cursor.
+func (v *validator) exprOffset(expr IExpr) int {
+	if n, ok := expr.(*Var); ok {
+		if loc, ok := nextLoc(n, v.cursor); ok {
+			return loc.Start
+		}
+	} else if loc := NodeLoc(expr); loc.IsSet() {
+		return loc.Start
+	}
+	return v.cursor
+}
+
+func (v *validator) enterScope(isFunc bool) {
+	v.scope = &validatorScope{
+		parent:  v.scope,
+		isFunc:  isFunc,
+		lexical: map[string]declKind{},
+		vars:    map[string]bool{},
+	}
+}
+
+func (v *validator) exitScope() {
+	v.scope = v.scope.parent
+}
+
+// identifier checks a variable that is declared or used, and returns its offset.
+func (v *validator) identifier(n *Var) int {
+	offset := v.varOffset(n)
+	if v.module && string(n.Data) == "await" {
+		v.fail(offset, "unexpected await in module")
+	} else if v.strict {
+		switch string(n.Data) {
+		case "implements", "interface", "let", "package", "private", "protected", "public", "static", "yield":
+			v.fail(offset, "unexpected %s in strict mode", n.Data)
+		}
+	}
+	return offset
+}
+
+// declare declares a variable in the current scope. Var declarations are added to all scopes up to the function scope, and may not conflict with the lexical declarations in any of them.
+func (v *validator) declare(n *Var, kind declKind) {
+	offset := v.identifier(n)
+	name := string(n.Data)
+	if v.strict && (name == "eval" || name == "arguments") {
+		v.fail(offset, "unexpected %s in strict mode", name)
+	}
+	v.declareName(name, offset, kind)
+}
+
+func (v *validator) declareName(name string, offset int, kind declKind) {
+	if kind == varDecl {
+		for scope := v.scope; scope != nil; scope = scope.parent {
+			if prev, ok := scope.lexical[name]; ok && prev != paramDecl {
+				v.fail(offset, "identifier %s has already been declared", name)
+				return
+			}
+			scope.vars[name] = true
+			if scope.isFunc {
+				break
+			}
+		}
+		return
+	}
+
+	if kind == lexicalDecl && name == "let" && !v.strict {
+		v.fail(offset, "unexpected let in lexical declaration")
+	}
+	prev, ok := v.scope.lexical[name]
+	if ok && kind == blockFuncDecl && prev == blockFuncDecl && !v.strict || ok && kind == paramDecl && prev == paramDecl && !v.strict {
+		return // allowed in sloppy mode
+	} else if ok || v.scope.vars[name] {
+		v.fail(offset, "identifier %s has already been declared", name)
+		return
+	}
+	v.scope.lexical[name] = kind
+}
+
+// binding declares all variables in a binding, and validates the default values in source order.
+func (v *validator) binding(b IBinding, kind declKind) {
+	v.at(b)
+	switch b := b.(type) {
+	case *Var:
+		v.declare(b, kind)
+	case *BindingArray:
+		for i := range b.List {
+			v.bindingElement(&b.List[i], kind)
+		}
+		if b.Rest != nil {
+			v.binding(b.Rest, kind)
+		}
+	case *BindingObject:
+		for _, item := range b.List {
+			if item.Key != nil && item.Key.IsComputed() {
+				v.expr(item.Key.Computed)
+			}
+			v.bindingElement(&item.Value, kind)
+		}
+		if b.Rest != nil {
+			v.binding(b.Rest, kind)
+		}
+	}
+}
+
+func (v *validator) bindingElement(element *BindingElement, kind declKind) {
+	v.at(element)
+	if element.Binding != nil {
+		v.binding(element.Binding, kind)
+	}
+	v.expr(element.Default)
+}
+
+func (v *validator) varDecl(decl *VarDecl) {
+	v.at(decl)
+	for i := range decl.List {
+		if decl.TokenType == VarToken {
+			v.bindingElement(&decl.List[i], varDecl)
+		} else {
+			v.bindingElement(&decl.List[i], lexicalDecl)
+		}
+	}
+}
+
+func (v *validator) stmts(list []IStmt) {
+	for _, item := range list {
+		v.stmt(item)
+	}
+}
+
+func (v *validator) stmt(stmt IStmt) {
+	v.at(stmt)
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		v.enterScope(false)
+		v.stmts(stmt.List)
+		v.exitScope()
+	case *ExprStmt:
+		v.expr(stmt.Value)
+	case *VarDecl:
+		if stmt.TokenType == UsingToken && !v.module && v.scope.parent == nil {
+			v.fail(stmt.Start, "using declaration at top level of script")
+		}
+		v.varDecl(stmt)
+	case *FuncDecl:
+		if stmt.Name != nil {
+			if v.scope.isFunc && (!v.module || v.scope.parent != nil) {
+				v.declare(stmt.Name, varDecl)
+			} else if v.scope.isFunc || stmt.Async || stmt.Generator {
+				v.declare(stmt.Name, lexicalDecl)
+			} else {
+				v.declare(stmt.Name, blockFuncDecl)
+			}
+		}
+		v.function(stmt.Params, &stmt.Body, validatorFunc{newTarget: true})
+	case *ClassDecl:
+		v.class(stmt, true)
+	case *IfStmt:
+		v.expr(stmt.Cond)
+		v.stmt(stmt.Body)
+		if stmt.Else != nil {
+			v.stmt(stmt.Else)
+		}
+	case *DoWhileStmt:
+		v.loopBody(stmt.Body)
+		v.expr(stmt.Cond)
+	case *WhileStmt:
+		v.expr(stmt.Cond)
+		v.loopBody(stmt.Body)
+	case *ForStmt:
+		v.enterScope(false)
+		if decl, ok := stmt.Init.(*VarDecl); ok {
+			v.varDecl(decl)
+		} else {
+			v.expr(stmt.Init)
+		}
+		v.expr(stmt.Cond)
+		v.expr(stmt.Post)
+		v.loopBody(stmt.Body)
+		v.exitScope()
+	case *ForInStmt:
+		v.enterScope(false)
+		v.forInit(stmt.Init)
+		v.expr(stmt.Value)
+		v.loopBody(stmt.Body)
+		v.exitScope()
+	case *ForOfStmt:
+		v.enterScope(false)
+		v.forInit(stmt.Init)
+		v.expr(stmt.Value)
+		v.loopBody(stmt.Body)
+		v.exitScope()
+	case *SwitchStmt:
+		v.expr(stmt.Init)
+		v.enterScope(false)
+		v.fn.switches++
+		for i := range stmt.List {
+			v.at(&stmt.List[i])
+			v.expr(stmt.List[i].Cond)
+			v.stmts(stmt.List[i].List)
+		}
+		v.fn.switches--
+		v.exitScope()
+	case *BranchStmt:
+		v.branch(stmt)
+	case *ReturnStmt:
+		v.expr(stmt.Value)
+	case *WithStmt:
+		if v.strict {
+			v.fail(stmt.Start, "with statement in strict mode")
+		}
+		v.expr(stmt.Cond)
+		v.stmt(stmt.Body)
+	case *LabelledStmt:
+		if v.module && string(stmt.Label) == "await" {
+			v.fail(stmt.Start, "unexpected await in module")
+		}
+		for _, label := range v.fn.labels {
+			if bytes.Equal(label.name, stmt.Label) {
+				v.fail(stmt.Start, "label %s has already been declared", stmt.Label)
+			}
+		}
+		body := stmt.Value
+		for {
+			if labelled, ok := body.(*LabelledStmt); ok {
+				body = labelled.Value
+				continue
+			}
+			break
+		}
+		isLoop := false
+		switch body := body.(type) {
+		case *DoWhileStmt, *WhileStmt, *ForStmt, *ForInStmt, *ForOfStmt:
+			isLoop = true
+		case *FuncDecl:
+			if v.strict || body.Async || body.Generator {
+				v.fail(stmt.Start, "labelled function declaration")
+			}
+		}
+		v.fn.labels = append(v.fn.labels, validatorLabel{stmt.Label, isLoop})
+		v.stmt(stmt.Value)
+		v.fn.labels = v.fn.labels[:len(v.fn.labels)-1]
+	case *ThrowStmt:
+		v.expr(stmt.Value)
+	case *TryStmt:
+		v.stmt(stmt.Body)
+		if stmt.Catch != nil {
+			v.enterScope(false)
+			if stmt.Binding != nil {
+				if _, ok := stmt.Binding.(*Var); ok {
+					v.binding(stmt.Binding, paramDecl)
+				} else {
+					v.binding(stmt.Binding, patternDecl)
+				}
+			}
+			v.at(stmt.Catch)
+			v.stmts(stmt.Catch.List)
+			v.exitScope()
+		}
+		if stmt.Finally != nil {
+			v.stmt(stmt.Finally)
+		}
+	case *ImportStmt:
+		if !v.module {
+			v.fail(stmt.Start, "import statement outside of module")
+		}
+		v.importAttributes(stmt.Attributes, stmt.Start)
+		if stmt.Default != nil {
+			v.declareName(string(stmt.Default), stmt.Start, lexicalDecl)
+		}
+		for _, alias := range stmt.List {
+			if alias.Binding != nil {
+				v.declareName(string(alias.Binding), stmt.Start, lexicalDecl)
+			}
+		}
+	case *ExportStmt:
+		v.export(stmt)
+	case *DirectivePrologueStmt:
+		v.octalEscapes(stmt.Value, stmt.Start)
+	}
+}
+
+func (v *validator) loopBody(body IStmt) {
+	v.fn.loops++
+	v.stmt(body)
+	v.fn.loops--
+}
+
+func (v *validator) forInit(init IExpr) {
+	if decl, ok := init.(*VarDecl); ok {
+		v.varDecl(decl)
+	} else {
+		v.pattern(init)
+	}
+}
+
+func (v *validator) branch(stmt *BranchStmt) {
+	if stmt.Label == nil {
+		if stmt.Type == BreakToken && v.fn.loops == 0 && v.fn.switches == 0 {
+			v.fail(stmt.Start, "break statement outside of loop or switch")
+		} else if stmt.Type == ContinueToken && v.fn.loops == 0 {
+			v.fail(stmt.Start, "continue statement outside of loop")
+		}
+		return
+	}
+	for i := len(v.fn.labels) - 1; 0 <= i; i-- {
+		if bytes.Equal(v.fn.labels[i].name, stmt.Label) {
+			if stmt.Type == ContinueToken && !v.fn.labels[i].loop {
+				v.fail(stmt.Start, "continue label %s does not refer to a loop", stmt.Label)
+			}
+			return
+		}
+	}
+	v.fail(stmt.Start, "undefined label %s", stmt.Label)
+}
+
+func (v *validator) export(stmt *ExportStmt) {
+	if !v.module {
+		v.fail(stmt.Start, "export statement outside of module")
+	}
+	v.importAttributes(stmt.Attributes, stmt.Start)
+	for _, alias := range stmt.List {
+		if alias.Binding == nil {
+			continue // trailing comma
+		} else if stmt.Module == nil {
+			local := alias.Name
+			if local == nil {
+				local = alias.Binding
+			}
+			v.localExports = append(v.localExports, validatorExport{local, stmt.Start})
+		} else if alias.Name == nil && string(alias.Binding) == "*" {
+			continue // export * from
+		}
+		v.exportName(alias.Binding, stmt.Start)
+	}
+
+	if stmt.Default {
+		v.exportName([]byte("default"), stmt.Start)
+	}
+	switch decl := stmt.Decl.(type) {
+	case *VarDecl:
+		v.varDecl(decl)
+		for _, item := range decl.List {
+			for _, name := range boundNames(item.Binding) {
+				v.exportName(name.Data, stmt.Start)
+			}
+		}
+	case *FuncDecl:
+		if !stmt.Default {
+			v.exportName(decl.Name.Data, stmt.Start)
+		}
+		v.stmt(decl)
+	case *ClassDecl:
+		if !stmt.Default {
+			v.exportName(decl.Name.Data, stmt.Start)
+		}
+		v.stmt(decl)
+	default:
+		v.expr(stmt.Decl)
+	}
+}
+
+// importAttributes validates the with clause of an import or export statement, whose keys must be unique.
+func (v *validator) importAttributes(attributes []ImportAttribute, offset int) {
+	keys := map[string]bool{}
+	for _, attribute := range attributes {
+		key := string(attribute.Key)
+		if 0 < len(key) && (key[0] == '"' || key[0] == '\'') {
+			key = stringValue(attribute.Key)
+		}
+		if keys[key] {
+			v.fail(offset, "duplicate import attribute %s", key)
+		}
+		keys[key] = true
+	}
+}
+
+func (v *validator) exportName(name []byte, offset int) {
+	if 1 < len(name) && (name[0] == '"' || name[0] == '\'') {
+		name = name[1 : len(name)-1]
+	}
+	if v.exports[string(name)] {
+		v.fail(offset, "duplicate export %s", name)
+	}
+	v.exports[string(name)] = true
+}
+
+// boundNames returns all variables declared by a binding.
+func boundNames(b IBinding) []*Var {
+	switch b := b.(type) {
+	case *Var:
+		return []*Var{b}
+	case *BindingArray:
+		names := []*Var{}
+		for _, item := range b.List {
+			names = append(names, boundNames(item.Binding)...)
+		}
+		return append(names, boundNames(b.Rest)...)
+	case *BindingObject:
+		names := []*Var{}
+		for _, item := range b.List {
+			names = append(names, boundNames(item.Value.Binding)...)
+		}
+		if b.Rest != nil {
+			names = append(names, b.Rest)
+		}
+		return names
+	}
+	return nil
+}
+
+// function validates the parameters and body of a function, method, or arrow function in a new function scope with the given state.
+func (v *validator) function(params Params, body *BlockStmt, fn validatorFunc) {
+	strict := v.strict
+	if hasUseStrict(body.List) {
+		v.strict = true
+		if !isSimpleParams(params) {
+			v.fail(body.Start, "use strict directive in function with non-simple parameters")
+		}
+	}
+	fn, v.fn = v.fn, fn
+
+	v.enterScope(true)
+	for i := range params.List {
+		v.bindingElement(&params.List[i], paramDecl)
+	}
+	if params.Rest != nil {
+		v.binding(params.Rest, paramDecl)
+	}
+	v.at(body)
+	v.stmts(body.List)
+	v.exitScope()
+
+	v.fn = fn
+	v.strict = strict
+}
+
+func isSimpleParams(params Params) bool {
+	if params.Rest != nil {
+		return false
+	}
+	for _, item := range params.List {
+		if _, ok := item.Binding.(*Var); !ok || item.Default != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// method validates a method of an object literal or class.
+func (v *validator) method(method *MethodDecl, superCall bool) {
+	v.at(method)
+	for _, decorator := range method.Decorators {
+		v.expr(decorator)
+	}
+	if method.Name.IsComputed() {
+		v.expr(method.Name.Computed)
+	} else if method.Name.Private != nil {
+		v.varOffset(method.Name.Private)
+	}
+	if method.Get && (len(method.Params.List) != 0 || method.Params.Rest != nil) {
+		v.fail(method.Start, "getter must not have parameters")
+	} else if method.Set && (len(method.Params.List) != 1 || method.Params.Rest != nil) {
+		v.fail(method.Start, "setter must have exactly one parameter")
+	}
+	v.function(method.Params, &method.Body, validatorFunc{newTarget: true, superProperty: true, superCall: superCall})
+}
+
+// class validates a class declaration or expression, which is strict mode code.
+func (v *validator) class(class *ClassDecl, declaration bool) {
+	strict := v.strict
+	v.strict = true
+	for _, decorator := range class.Decorators {
+		v.expr(decorator)
+	}
+	if class.Name != nil && declaration {
+		v.declare(class.Name, lexicalDecl)
+	} else if class.Name != nil {
+		v.identifier(class.Name)
+	}
+	v.expr(class.Extends)
+	// private names may only be declared twice by a getter and setter pair, the kinds are 1 for getters, 2 for setters, and 3 otherwise, plus 4 when static
+	privates := map[string]bool{}
+	kinds := map[string]int{}
+	for _, item := range class.List {
+		var private *Var
+		var offset int
+		kind, static := 3, false
+		if item.Method != nil {
+			private, static, offset = item.Method.Name.Private, item.Method.Static, item.Method.Start
+			if item.Method.Get {
+				kind = 1
+			} else if item.Method.Set {
+				kind = 2
+			}
+		} else if item.StaticBlock == nil {
+			private, static, offset = item.Field.Name.Private, item.Field.Static, item.Field.Start
+		}
+		if private == nil {
+			continue
+		} else if static {
+			kind |= 4
+		}
+
+		name := string(private.Data)
+		if name == "#constructor" {
+			v.fail(offset, "invalid private name #constructor")
+		} else if prev, ok := kinds[name]; ok && prev^kind != 3 {
+			v.fail(offset, "duplicate private name %s", name)
+		}
+		privates[name] = true
+		kinds[name] |= kind
+	}
+	v.privates = append(v.privates, privates)
+	hasConstructor := false
+	for _, item := range class.List {
+		v.at(&item)
+		if item.StaticBlock != nil {
+			fn := v.fn
+			v.fn = validatorFunc{newTarget: true, superProperty: true}
+			v.enterScope(true)
+			v.stmts(item.StaticBlock.List)
+			v.exitScope()
+			v.fn = fn
+		} else if item.Method != nil {
+			constructor := !item.Method.Static && item.Method.Name.IsIdent([]byte("constructor"))
+			if constructor && hasConstructor {
+				v.fail(item.Method.Start, "duplicate constructor in class")
+			} else if constructor && (item.Method.Get || item.Method.Set || item.Method.Async || item.Method.Generator) {
+				v.fail(item.Method.Start, "special method constructor in class")
+				constructor = false
+			} else if item.Method.Static && item.Method.Name.IsIdent([]byte("prototype")) {
+				v.fail(item.Method.Start, "static prototype in class")
+			}
+			hasConstructor = hasConstructor || constructor
+			v.method(item.Method, constructor && class.Extends != nil)
+		} else {
+			if item.Field.Name.IsIdent([]byte("constructor")) {
+				v.fail(item.Field.Start, "field constructor in class")
+			} else if item.Field.Static && item.Field.Name.IsIdent([]byte("prototype")) {
+				v.fail(item.Field.Start, "static prototype in class")
+			}
+			for _, decorator := range item.Field.Decorators {
+				v.expr(decorator)
+			}
+			if item.Field.Name.IsComputed() {
+				v.expr(item.Field.Name.Computed)
+			} else if item.Field.Name.Private != nil {
+				v.varOffset(item.Field.Name.Private)
+			}
+			fn := v.fn
+			v.fn = validatorFunc{newTarget: true, superProperty: true}
+			v.expr(item.Field.Init)
+			v.fn = fn
+		}
+	}
+	v.privates = v.privates[:len(v.privates)-1]
+	v.strict = strict
+}
+
+// privateName validates the use of a private name, which must be declared by an enclosing class.
+func (v *validator) privateName(n *Var) {
+	offset := v.varOffset(n)
+	for _, privates := range v.privates {
+		if privates[string(n.Data)] {
+			return
+		}
+	}
+	v.fail(offset, "undeclared private name %s", n.Data)
+}
+
+// pattern validates an assignment target, which is a simple target or a destructuring pattern where object literals may have duplicate __proto__ properties.
+func (v *validator) pattern(expr IExpr) {
+	v.at(expr)
+	switch expr := expr.(type) {
+	case *ObjectExpr:
+		for i := range expr.List {
+			property := &expr.List[i]
+			v.at(property)
+			if property.Name != nil && property.Name.IsComputed() {
+				v.expr(property.Name.Computed)
+			}
+			if property.Spread {
+				if i+1 < len(expr.List) {
+					v.fail(property.Start, "rest element must be last element")
+				}
+				v.simpleTarget(property.Value, "invalid rest element")
+			} else {
+				v.patternElement(property.Value)
+			}
+			v.expr(property.Init)
+		}
+	case *ArrayExpr:
+		for i, item := range expr.List {
+			if item.Value == nil {
+				continue
+			} else if item.Spread {
+				if i+1 < len(expr.List) {
+					v.fail(v.exprOffset(item.Value), "rest element must be last element")
+				}
+				v.pattern(item.Value)
+			} else {
+				v.patternElement(item.Value)
+			}
+		}
+	default:
+		v.simpleTarget(expr, "invalid assignment target")
+	}
+}
+
+// patternElement validates an element of a destructuring pattern, which may have a default value.
+func (v *validator) patternElement(expr IExpr) {
+	if binary, ok := expr.(*BinaryExpr); ok && binary.Op == EqToken {
+		v.at(binary)
+		v.pattern(binary.X)
+		v.expr(binary.Y)
+	} else {
+		v.pattern(expr)
+	}
+}
+
+// simpleTarget validates the operand of compound assignments and update expressions, which is a variable or a property access outside of an optional chain, possibly parenthesized.
+func (v *validator) simpleTarget(expr IExpr, message string) {
+	v.at(expr)
+	switch x := expr.(type) {
+	case *Var:
+		v.target(x)
+		return
+	case *GroupExpr:
+		v.simpleTarget(x.X, message)
+		return
+	case *DotExpr, *IndexExpr:
+		if !isOptionalChain(expr) {
+			v.expr(expr)
+			return
+		}
+	}
+	v.fail(v.exprOffset(expr), message)
+	v.expr(expr)
+}
+
+// target validates a variable that is assigned to.
+func (v *validator) target(n *Var) {
+	offset := v.identifier(n)
+	if v.strict && (string(n.Data) == "eval" || string(n.Data) == "arguments") {
+		v.fail(offset, "unexpected %s in strict mode", n.Data)
+	}
+}
+
+func isAssignment(op TokenType) bool {
+	switch op {
+	case EqToken, MulEqToken, DivEqToken, ModEqToken, ExpEqToken, AddEqToken, SubEqToken, LtLtEqToken, GtGtEqToken, GtGtGtEqToken, BitAndEqToken, BitXorEqToken, BitOrEqToken, AndEqToken, OrEqToken, NullishEqToken:
+		return true
+	}
+	return false
+}
+
+func isSuper(expr IExpr) bool {
+	lit, ok := expr.(*LiteralExpr)
+	return ok && lit.TokenType == SuperToken
+}
+
+func (v *validator) expr(expr IExpr) {
+	if expr == nil {
+		return
+	}
+	v.at(expr)
+	switch expr := expr.(type) {
+	case *Var:
+		if 0 < len(expr.Data) && expr.Data[0] == '#' {
+			v.privateName(expr) // #x in obj
+		} else {
+			v.identifier(expr)
+		}
+	case *LiteralExpr:
+		v.literal(expr)
+	case *ArrayExpr:
+		for _, item := range expr.List {
+			v.expr(item.Value)
+		}
+	case *ObjectExpr:
+		hasProto := false
+		for i := range expr.List {
+			property := &expr.List[i]
+			v.at(property)
+			if property.Name != nil && property.Name.IsComputed() {
+				v.expr(property.Name.Computed)
+			} else if property.Name != nil && !isShorthand(property) && isProtoName(property.Name.Literal) {
+				if hasProto {
+					v.fail(property.Start, "duplicate __proto__ property in object literal")
+				}
+				hasProto = true
+			}
+			if method, ok := property.Value.(*MethodDecl); ok {
+				v.method(method, false)
+			} else {
+				v.expr(property.Value)
+			}
+			v.expr(property.Init)
+		}
+	case *TemplateExpr:
+		if expr.Tag != nil && (expr.Optional || isOptionalChain(expr.Tag)) {
+			v.fail(expr.Start, "tagged template in optional chain")
+		}
+		v.expr(expr.Tag)
+		for _, item := range expr.List {
+			if expr.Tag == nil {
+				v.templateEscapes(item.Value, v.templateOffset(item.Value))
+			}
+			v.expr(item.Expr)
+		}
+		if expr.Tag == nil {
+			v.templateEscapes(expr.Tail, v.templateOffset(expr.Tail))
+		}
+	case *GroupExpr:
+		v.expr(expr.X)
+	case *IndexExpr:
+		v.optionalNew(expr.X, expr.Optional)
+		v.superProperty(expr.X)
+		v.expr(expr.Y)
+	case *DotExpr:
+		v.optionalNew(expr.X, expr.Optional)
+		v.superProperty(expr.X)
+		if private, ok := expr.Y.(*Var); ok {
+			v.privateName(private)
+		}
+	case *NewTargetExpr:
+		if !v.fn.newTarget {
+			v.fail(expr.Start, "new.target outside of function")
+		}
+	case *ImportMetaExpr:
+		if !v.module {
+			v.fail(expr.Start, "import.meta outside of module")
+		}
+	case *NewExpr:
+		v.expr(expr.X)
+		if expr.Args != nil {
+			v.args(expr.Args)
+		}
+	case *CallExpr:
+		v.optionalNew(expr.X, expr.Optional)
+		if isSuper(expr.X) {
+			if !v.fn.superCall {
+				v.fail(expr.Start, "super call outside of derived class constructor")
+			}
+		} else {
+			v.expr(expr.X)
+		}
+		v.args(&expr.Args)
+	case *UnaryExpr:
+		if expr.Op == PreIncrToken || expr.Op == PreDecrToken || expr.Op == PostIncrToken || expr.Op == PostDecrToken {
+			v.simpleTarget(expr.X, "invalid update target")
+			return
+		} else if _, ok := expr.X.(*Var); ok && expr.Op == DeleteToken && v.strict {
+			v.fail(expr.Start, "delete of an unqualified identifier in strict mode")
+		}
+		v.expr(expr.X)
+	case *BinaryExpr:
+		if expr.Op == EqToken {
+			v.pattern(expr.X)
+		} else if isAssignment(expr.Op) {
+			v.simpleTarget(expr.X, "invalid assignment target")
+		} else {
+			v.expr(expr.X)
+		}
+		v.expr(expr.Y)
+	case *CondExpr:
+		v.expr(expr.Cond)
+		v.expr(expr.X)
+		v.expr(expr.Y)
+	case *YieldExpr:
+		v.expr(expr.X)
+	case *CommaExpr:
+		for _, item := range expr.List {
+			v.expr(item)
+		}
+	case *ArrowFunc:
+		// arrow functions inherit new.target and super from the enclosing function
+		v.function(expr.Params, &expr.Body, validatorFunc{newTarget: v.fn.newTarget, superProperty: v.fn.superProperty, superCall: v.fn.superCall})
+	case *FuncDecl:
+		if expr.Name != nil {
+			v.identifier(expr.Name)
+		}
+		v.function(expr.Params, &expr.Body, validatorFunc{newTarget: true})
+	case *ClassDecl:
+		v.class(expr, false)
+	}
+}
+
+// optionalNew validates that an optional chain doesn't start at a new expression without arguments, such as new a?.b(). Empty arguments are not kept, so they are found in the source.
+func (v *validator) optionalNew(x IExpr, optional bool) {
+	n, ok := x.(*NewExpr)
+	if !ok || !optional || n.Args != nil {
+		return
+	} else if loc := NodeLoc(n.X); loc.IsSet() && loc.End < n.End {
+		return
+	} else if !loc.IsSet() && n.End <= len(v.src) && 0 < n.End && v.src[n.End-1] == ')' {
+		return
+	}
+	v.fail(n.Start, "optional chain in new expression")
+}
+
+func (v *validator) args(args *Args) {
+	for _, item := range args.List {
+		v.expr(item.Value)
+	}
+}
+
+func (v *validator) superProperty(x IExpr) {
+	if isSuper(x) {
+		if !v.fn.superProperty {
+			v.fail(NodeLoc(x).Start, "super outside of method")
+		}
+	} else {
+		v.expr(x)
+	}
+}
+
+// isShorthand returns true for shorthand properties {a}, whose name and value are at the same location.
+func isShorthand(property *Property) bool {
+	if n, ok := property.Value.(*Var); ok && !property.Name.IsComputed() {
+		for n.Link != nil && len(n.Locs) == 0 {
+			n = n.Link
+		}
+		for _, loc := range n.Locs {
+			if loc == property.Name.Literal.Loc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isProtoName(name LiteralExpr) bool {
+	switch string(name.Data) {
+	case "__proto__", `"__proto__"`, `'__proto__'`:
+		return true
+	}
+	return false
+}
+
+func (v *validator) literal(lit *LiteralExpr) {
+	switch lit.TokenType {
+	case StringToken:
+		v.octalEscapes(lit.Data, lit.Start)
+	case RegExpToken:
+		if _, err := regexp.ParseLiteral(lit.Data); err != nil {
+			if err, ok := err.(*regexp.Error); ok {
+				v.fail(lit.Start+err.Offset, "invalid regular expression: %s", err.Message)
+			}
+		}
+	}
+}
+
+// templateOffset returns the offset of the next part of a template, which are not kept in the AST.
+func (v *validator) templateOffset(part []byte) int {
+	if i := bytes.Index(v.src[v.cursor:], part); i != -1 {
+		v.cursor += i + len(part)
+		return v.cursor - len(part)
+	}
+	return v.cursor
+}
+
+// templateEscapes reports invalid escape sequences in a part of a template without a tag, such as \u{ or legacy octal escapes.
+func (v *validator) templateEscapes(part []byte, offset int) {
+	if len(part) < 2 {
+		return
+	}
+	end := len(part) - 1
+	if 2 < len(part) && part[end] == '{' && part[end-1] == '$' {
+		end--
+	}
+	for i := 1; i+1 < end; i += 2 {
+		_, j := cook(part[i:end], true)
+		if j == -1 {
+			return
+		}
+		i += j
+		if c := part[i+1]; '0' <= c && c <= '9' {
+			v.fail(offset+i, "octal escape sequence in template")
+		} else {
+			v.fail(offset+i, "invalid escape sequence in template")
+		}
+	}
+}
+
+// octalEscapes reports legacy octal escapes \0 followed by a digit, \1 to \7, and the escapes \8 and \9, which are not allowed in strict mode strings.
+func (v *validator) octalEscapes(b []byte, offset int) {
+	if !v.strict {
+		return
+	}
+	for i := 0; i < len(b)-1; i++ {
+		if b[i] == '\\' {
+			i++
+			if c := b[i]; '1' <= c && c <= '9' || c == '0' && i+1 < len(b) && '0' <= b[i+1] && b[i+1] <= '9' {
+				v.fail(offset+i-1, "octal escape sequence in strict mode")
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package js
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/test"
+)
+
+func TestValidate(t *testing.T) {
+	var tests = []struct {
+		js       string
+		module   bool
+		expected string
+	}{
+		{"let a; { var b; let c }", false, ""},
+		{"{function f(){} let f}", false, "identifier f has already been declared at 1:21"},
+		{"{function f(){} function f(){}}", false, ""},
+		{"'use strict'; {function f(){} function f(){}}", false, "identifier f has already been declared at 1:40"},
+		{"function f(){} function f(){}", false, ""},
+		{"function f(){} function f(){}", true, "identifier f has already been declared at 1:25"},
+		{"for (let a of b) { let a }", false, ""},
+		{"import a from 'a'; let a", true, "identifier a has already been declared at 1:24"},
+		{"for (let a;;) { let a }", false, ""},
+		{"try {} catch (e) { var e }", false, ""},
+		{"try {} catch ([e]) { var e }", false, "identifier e has already been declared at 1:26"},
+		{"let let = 1", false, "unexpected let in lexical declaration at 1:5"},
+		{"break", false, "break statement outside of loop or switch at 1:1"},
+		{"while (a) { function f() { continue } }", false, "continue statement outside of loop at 1:28"},
+		{"switch (a) { case 1: break }", false, ""},
+		{"a: { break a }", false, ""},
+		{"a: { continue a }", false, "continue label a does not refer to a loop at 1:6"},
+		{"a: b: while (1) { continue a }", false, ""},
+		{"a: while (1) { break b }", false, "undefined label b at 1:16"},
+		{"a: { a: ; }", false, "label a has already been declared at 1:6"},
+		{"x = {__proto__: 1, '__proto__': 2}", false, "duplicate __proto__ property in object literal at 1:20"},
+		{"x = {__proto__: 1, __proto__}", false, ""},
+		{"x = {__proto__: 1, ['__proto__']: 2, __proto__() {}}", false, ""},
+		{"({__proto__: a, __proto__: b} = c)", false, ""},
+		{"x = '\\01'", false, ""},
+		{"'use strict'; x = '\\01' + '\\0' + '\\8'", false, "octal escape sequence in strict mode at 1:20\noctal escape sequence in strict mode at 1:35"},
+		{"function f() { '\\01'; 'use strict' }", false, "octal escape sequence in strict mode at 1:17"},
+		{"x = `\\01`; y = `a${b}\\1`; z = f`\\01`", false, "octal escape sequence in template at 1:6\noctal escape sequence in template at 1:22"},
+		{"x = `\\u{`; y = `${a}\\x1`; z = f`\\u{`", false, "invalid escape sequence in template at 1:6\ninvalid escape sequence in template at 1:21"},
+		{"x?.y`t`; x?.y.z`t`; (x?.y)`t`", false, "tagged template in optional chain at 1:1\ntagged template in optional chain at 1:10"},
+		{"1 = 2; f() = 1; x?.y = 1; x?.y.z += 1", false, "invalid assignment target at 1:1\ninvalid assignment target at 1:8\ninvalid assignment target at 1:17\ninvalid assignment target at 1:27"},
+		{"x = 1 ++; --f(); a?.[0]++", false, "invalid update target at 1:5\ninvalid update target at 1:13\ninvalid update target at 1:18"},
+		{"for (1 of x){} for (f() in x){}", false, "invalid assignment target at 1:6\ninvalid assignment target at 1:21"},
+		{"[a, 1] = b; ({a: 1} = b); (a, b) = 1; ({a() {}} = b)", false, "invalid assignment target at 1:5\ninvalid assignment target at 1:18\ninvalid assignment target at 1:28\ninvalid assignment target at 1:41"},
+		{"[...a, b] = c; [...a = 1] = b; ({...a, b} = c); ({...{a}} = b)", false, "rest element must be last element at 1:5\ninvalid assignment target at 1:20\nrest element must be last element at 1:34\ninvalid rest element at 1:54"},
+		{"(a) = 1; [a.b, (c), d = 1, ...e] = f; ({a: b.c, d = 1, ...e[0]} = f); (a.b) += 1; [[a], {b}] = c; for ([a] of b);", false, ""},
+		{"class A { constructor() {} static constructor() {} 'constructor'() {} }", false, "duplicate constructor in class at 1:52"},
+		{"with (a) {}", false, ""},
+		{"'use strict'; with (a) {}", false, "with statement in strict mode at 1:15"},
+		{"'use strict'; delete a; delete a.b", false, "delete of an unqualified identifier in strict mode at 1:15"},
+		{"'use strict'; eval = 1; arguments++; var eval", false, "unexpected eval in strict mode at 1:15\nunexpected arguments in strict mode at 1:25\nunexpected eval in strict mode at 1:42"},
+		{"'use strict'; var static; implements()", false, "unexpected static in strict mode at 1:19\nunexpected implements in strict mode at 1:27"},
+		{"class A { m(eval) {} }", false, "unexpected eval in strict mode at 1:13"},
+		{"function f(a = 1) { 'use strict' }", false, "use strict directive in function with non-simple parameters at 1:19"},
+		{"function f() { var await; await: ; }", false, ""},
+		{"function f() { var await; await: ; }", true, "unexpected await in module at 1:20\nunexpected await in module at 1:27"},
+		{"new.target", false, "new.target outside of function at 1:1"},
+		{"function f() { () => new.target }", false, ""},
+		{"super.a", false, "super outside of method at 1:1"},
+		{"x = { m() { super.a } }; class A { x = super.b; static { super.c } }", false, ""},
+		{"function f() { super.a }", false, "super outside of method at 1:16"},
+		{"class A { constructor() { super() } }", false, "super call outside of derived class constructor at 1:27"},
+		{"class A extends B { constructor() { () => super() } }", false, ""},
+		{"x = /a{2,1}/; y = /(?<a>)(?<a>)/u", false, "invalid regular expression: numbers out of order in quantifier at 1:7\ninvalid regular expression: duplicate capture group name at 1:26"},
+		{"import.meta", false, "import.meta outside of module at 1:1"},
+		{"import a from 'a'; export { a, a as b }; export default 1", true, ""},
+		{"export const a = 1; export { b as a }; export default 1; export default 2", true, "duplicate export a at 1:21\nexport b is not declared at 1:21\nduplicate export default at 1:58"},
+		{"export * from 'a'; export * from 'b'; export * as c from 'c'; export { c } from 'd'", true, "duplicate export c at 1:63"},
+		{"class A { #a; m(o) { return #a in o && o.#a && (() => this.#b) } }", false, "undeclared private name #b at 1:60"},
+		{"class A { #a; m() { class B { n(o) { return #a in o } } } }; #a in o", false, "undeclared private name #a at 1:62"},
+		{"@(x.#a) class A { @(x.#a) #a }", false, "undeclared private name #a at 1:5"},
+		{"using a = b; { using c = d }", false, "using declaration at top level of script at 1:1"},
+		{"using a = b", true, ""},
+		{"export function f() {}; import a from 'a'", false, "export statement outside of module at 1:1\nimport statement outside of module at 1:25"},
+		{"a: function f() {}", false, ""},
+		{"'use strict'; a: function f() {} b: async function g() {}", false, "labelled function declaration at 1:15\nlabelled function declaration at 1:34"},
+		{"x = { get a(b) {}, set a() {}, set b(c, d) {}, set c(...d) {}, get d() {}, set d(e) {} }", false, "getter must not have parameters at 1:7\nsetter must have exactly one parameter at 1:20\nsetter must have exactly one parameter at 1:32\nsetter must have exactly one parameter at 1:48"},
+		{"class A { get constructor() {} async constructor() {} *constructor() {} static get constructor() {} }", false, "special method constructor in class at 1:11\nspecial method constructor in class at 1:32\nspecial method constructor in class at 1:55"},
+		{"class A { static prototype() {} static prototype = 1; prototype() {} constructor = 1 }", false, "static prototype in class at 1:11\nstatic prototype in class at 1:33\nfield constructor in class at 1:70"},
+		{"new a?.b(); new a?.[b]; new a()?.b; (new a)?.b; new a.b()?.c", false, "optional chain in new expression at 1:1\noptional chain in new expression at 1:13"},
+		{"a?.`x`", false, "tagged template in optional chain at 1:1"},
+		{"class A { static #x; static #x; get #y() {} set #y(a) {} get #z() {} static set #z(a) {} #constructor }", false, "duplicate private name #x at 1:22\nduplicate private name #z at 1:70\ninvalid private name #constructor at 1:90"},
+		{"import a from 'a' with { type: 'json', 'type': 'css' }; export * from 'b' with { type: 'json', a: 'b' }", true, "duplicate import attribute type at 1:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.js, func(t *testing.T) {
+			ast, err := Parse(parse.NewInputString(tt.js), Options{VarLocs: true})
+			if err != io.EOF {
+				test.Error(t, err)
+			}
+			errs := []string{}
+			for _, err := range Validate(ast, []byte(tt.js), ValidateOptions{Module: tt.module}) {
+				errs = append(errs, fmt.Sprintf("%s at %d:%d", err.Message, err.Line, err.Column))
+			}
+			test.String(t, strings.Join(errs, "\n"), tt.expected)
+		})
+	}
+}
@@ -33,6 +33,8 @@ func Walk(v IVisitor, n INode) {
 		}
 	case *EmptyStmt:
 		return
+	case *BadStmt:
+		return
 	case *ExprStmt:
 		Walk(v, n.Value)
 	case *IfStmt:
@@ -180,13 +182,22 @@ func Walk(v IVisitor, n INode) {
 			Walk(v, n.Name)
 		}
 	case *MethodDecl:
+		for _, decorator := range n.Decorators {
+			Walk(v, decorator)
+		}
 		Walk(v, &n.Body)
 		Walk(v, &n.Params)
 		Walk(v, &n.Name)
 	case *Field:
+		for _, decorator := range n.Decorators {
+			Walk(v, decorator)
+		}
 		Walk(v, &n.Name)
 		Walk(v, n.Init)
 	case *ClassDecl:
+		for _, decorator := range n.Decorators {
+			Walk(v, decorator)
+		}
 		if n.Name != nil {
 			Walk(v, n.Name)
 		}
@@ -282,6 +293,26 @@ func Walk(v IVisitor, n INode) {
 		for _, item := range n.List {
 			Walk(v, item)
 		}
+	case *JSXElement:
+		Walk(v, n.Name)
+
+		if n.Attrs != nil {
+			for i := 0; i < len(n.Attrs); i++ {
+				Walk(v, &n.Attrs[i])
+			}
+		}
+
+		for _, item := range n.Children {
+			Walk(v, item)
+		}
+	case *JSXFragment:
+		for _, item := range n.Children {
+			Walk(v, item)
+		}
+	case *JSXAttribute:
+		Walk(v, n.Value)
+	case *JSXExprContainer:
+		Walk(v, n.X)
 	default:
 		return
 	}
@@ -47,6 +47,22 @@ func TestWalk(t *testing.T) {
 	})
 }
 
+func TestWalkDecorators(t *testing.T) {
+	js := `@x class A { @x.y m() {} @x(1) accessor z }`
+
+	ast, err := Parse(parse.NewInputString(js), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Walk(&walker{}, ast)
+
+	re := regexp.MustCompile("\n *")
+	src := ast.JSString()
+	src = re.ReplaceAllString(src, " ")
+	test.String(t, src, "@obj class A { @obj.y m () {} @obj(1) accessor z; }")
+}
+
 func TestWalkNilNode(t *testing.T) {
 	nodes := []INode{
 		&AST{},
@@ -106,6 +122,10 @@ func TestWalkNilNode(t *testing.T) {
 		&CondExpr{},
 		&YieldExpr{},
 		&ArrowFunc{},
+		&JSXElement{},
+		&JSXFragment{},
+		&JSXAttribute{},
+		&JSXExprContainer{},
 	}
 
 	t.Run("TestWalkNilNode", func(t *testing.T) {
@@ -10,8 +10,12 @@ import (
 // Position returns the line and column number for a certain position in a file. It is useful for recovering the position in a file that caused an error.
 // It only treates \n, \r, and \r\n as newlines, which might be different from some languages also recognizing \f, \u2028, and \u2029 to be newlines.
 func Position(r io.Reader, offset int) (line, col int, context string) {
-	l := NewInput(r)
-	line = 1
+	return position(NewInput(r), offset, 0, 0)
+}
+
+// position returns the line and column number for a position in the buffer, where the buffer starts at the given zero-based line and column.
+func position(l *Input, offset, line0, col0 int) (line, col int, context string) {
+	line = line0 + 1
 	for l.Pos() < offset {
 		c := l.Peek(0)
 		n := 1
@@ -48,6 +52,9 @@ func Position(r io.Reader, offset int) (line, col int, context string) {
 
 	col = len([]rune(string(l.Lexeme()))) + 1
 	context = positionContext(l, line, col)
+	if line == line0+1 {
+		col += col0
+	}
 	return
 }
 
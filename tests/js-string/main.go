@@ -4,7 +4,6 @@ package fuzz
 
 import (
 	"fmt"
-	"strings"
 	"unicode/utf8"
 
 	"github.com/tdewolff/parse/v2"
@@ -23,13 +22,15 @@ func Fuzz(data []byte) int {
 		src := ast.JSString()
 		input2 := parse.NewInputString(src)
 		if ast2, err := js.Parse(input2, o); err != nil {
-			if !strings.HasPrefix(err.Error(), "too many nested") {
+			if _, ok := err.(*js.LimitError); !ok {
 				panic(err)
 			}
-		} else if src2 := ast2.JSString(); src != src2 {
+		} else if !js.Equal(ast, ast2) {
 			fmt.Println("JS1:", src)
-			fmt.Println("JS2:", src2)
+			fmt.Println("JS2:", ast2.JSString())
 			panic("ASTs not equal")
+		} else if js.Hash(ast) != js.Hash(ast2) {
+			panic("hashes of equal ASTs not equal")
 		}
 		return 1
 	}
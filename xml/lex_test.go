@@ -5,6 +5,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tdewolff/parse/v2"
 	"github.com/tdewolff/test"
@@ -248,6 +249,30 @@ func TestOffset(t *testing.T) {
 	test.T(t, z.Offset(), 26) // </div>
 }
 
+func TestStreamLexer(t *testing.T) {
+	xml := "<?xml version=\"1.0\"?><!DOCTYPE note [<!ENTITY x \"y\">]><note a=\"b\" c='d'>text<!-- comment --><![CDATA[<data>]]><empty/></note>"
+	lex := func(l *Lexer, z *parse.Input) []string {
+		tokens := []string{}
+		for {
+			tt, data := l.Next()
+			if tt == ErrorToken {
+				test.T(t, l.Err(), io.EOF)
+				return tokens
+			}
+			tokens = append(tokens, fmt.Sprintf("%v %q %q %q %d", tt, data, l.Text(), l.AttrVal(), z.Offset()))
+		}
+	}
+
+	z := parse.NewInputString(xml)
+	expected := lex(NewLexer(z), z)
+	for _, size := range []int{1, 2, 3, 16} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			z := parse.NewStreamInputSize(iotest.OneByteReader(strings.NewReader(xml)), size)
+			test.T(t, lex(NewStreamLexer(z), &z.Input), expected)
+		})
+	}
+}
+
 ////////////////////////////////////////////////////////////////
 
 func ExampleNewLexer() {